@@ -27,8 +27,23 @@ happened before and after it.
 
 Source URIs:
   cloudwatch:///log-group      AWS CloudWatch Logs (use -p for profile)
+  cloudwatch:///app/*          Several log groups (or ?groups=a,b ?prefix=/app/ ?tag=key:value)
   file:///path/to/file.log     Local file
   /var/log/app.log             Local file (shorthand)
+  s3://bucket/prefix/          S3 objects under a prefix
+  journal:///var/log/journal   systemd journal (?unit=name filters by unit)
+  -, stdin://                  Standard input (spooled to a temp file)
+  docker://container           Docker container logs (json-file driver)
+  k8s-node:///ns/pod/container Kubernetes pod logs on this node (globs allowed)
+  loki://host:3100/{app="api"} Grafana Loki stream selector (?tls=true, ?org=tenant)
+  es://host:9200/logs-*        Elasticsearch/OpenSearch index (?time= ?message= ?stream= fields)
+  https://host/path/app.log    Log file over HTTP(S) (alias headers: for auth)
+  archive:///path/bundle.tgz   Logs inside a tar or zip archive (?glob=var/log/*.log)
+  syslog+udp://0.0.0.0:5514    Syslog receiver, or syslog+tcp:// (?spool=file keeps messages)
+  otlp://:4318                 OpenTelemetry (OTLP/HTTP) log receiver (?buffer=N records)
+  sqlite:///path/app.db        SQLite table of log rows (?table=logs ?ts= ?msg= columns)
+  case://case-id               Evidence collected in a case (or case:///path/export.zip)
+  <scheme>://...               Source plugin (a clew-source-<scheme> executable)
   @alias-name                  Config alias
 
 Examples:
@@ -954,25 +954,86 @@ func runCaseKeep(cmd *cobra.Command, args []string) error {
 
 	switch ptrType {
 	case source.PtrTypeLocal:
-		// Local file pointer - use local source
+		// Local file pointer - reopen with the cached format, so config
+		// parsers, log formats and grok expressions parse it as the query did
 		sourceType = "local"
-		info, ok := source.ParseLocalPtr(ptr)
-		if !ok {
-			return fmt.Errorf("invalid local pointer: %s", ptr)
+		var metadata *source.SourceMetadata
+		if ptrMeta != nil && ptrMeta.SourceURI != "" {
+			metadata = &source.SourceMetadata{URI: ptrMeta.SourceURI}
 		}
-		sourceURI = "file://" + info.FilePath
 
-		src, err := source.OpenWithOptions(sourceURI, source.OpenOptions{})
+		src, err := source.OpenFromPtr(ptr, metadata)
 		if err != nil {
 			return fmt.Errorf("failed to open local source: %w", err)
 		}
 		defer func() { _ = src.Close() }()
+		sourceURI = "file://" + src.Metadata().URI
 
 		entry, err = src.GetRecord(ctx, ptr)
 		if err != nil {
 			return fmt.Errorf("failed to fetch local log record: %w", err)
 		}
 
+	case source.PtrTypeS3:
+		// S3 pointer - reopen with cached metadata (endpoint, region, format)
+		sourceType = "s3"
+		info, ok := source.ParseS3Ptr(ptr)
+		if !ok {
+			return fmt.Errorf("invalid S3 pointer: %s", ptr)
+		}
+		sourceURI = fmt.Sprintf("s3://%s/%s", info.Bucket, info.Key)
+
+		profile = app.GetProfile()
+		if ptrMeta != nil && ptrMeta.Profile != "" {
+			profile = ptrMeta.Profile
+		}
+		metadata := &source.SourceMetadata{
+			Profile: profile,
+			Region:  app.GetRegion(),
+		}
+		if ptrMeta != nil {
+			metadata.URI = ptrMeta.SourceURI
+			accountID = ptrMeta.AccountID
+		}
+
+		src, err := source.OpenFromPtr(ptr, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to open S3 source: %w", err)
+		}
+		defer func() { _ = src.Close() }()
+
+		entry, err = src.GetRecord(ctx, ptr)
+		if err != nil {
+			return fmt.Errorf("failed to fetch S3 log record: %w", err)
+		}
+
+	case source.PtrTypeJournal, source.PtrTypeContainer, source.PtrTypeLoki, source.PtrTypeElasticsearch,
+		source.PtrTypeHTTP, source.PtrTypeArchive, source.PtrTypeSyslog, source.PtrTypeOTLP, source.PtrTypeSQLite,
+		source.PtrTypePlugin:
+		// Journal, container, Loki, Elasticsearch, HTTP, archive, receiver, SQLite and plugin pointers - reopen
+		// with cached unit filters, message format, member glob, columns or server and field settings
+		var metadata *source.SourceMetadata
+		if ptrMeta != nil && ptrMeta.SourceURI != "" {
+			metadata = &source.SourceMetadata{URI: ptrMeta.SourceURI}
+		}
+
+		src, err := source.OpenFromPtr(ptr, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to open %s source: %w", ptrType, err)
+		}
+		defer func() { _ = src.Close() }()
+
+		sourceType = src.Type()
+		sourceURI = src.Metadata().URI
+		if metadata != nil {
+			sourceURI = metadata.URI
+		}
+
+		entry, err = src.GetRecord(ctx, ptr)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s record: %w", ptrType, err)
+		}
+
 	case source.PtrTypeCloudWatch:
 		// CloudWatch pointer - use CloudWatch source
 		sourceType = "cloudwatch"
@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/jmurray2011/clew/internal/cases"
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/jmurray2011/clew/internal/ui"
 	"github.com/jmurray2011/clew/pkg/timeutil"
 	"github.com/spf13/cobra"
 )
@@ -485,7 +493,7 @@ func TestSetAndGetApp(t *testing.T) {
 	app := NewAppWithConfig(cfg, nil, nil)
 
 	// Create a context with the app
-	ctx := SetApp(t.Context(), app)
+	ctx := SetApp(context.Background(), app)
 
 	// Create a mock command with the context
 	cmd := &cobra.Command{}
@@ -500,3 +508,158 @@ func TestSetAndGetApp(t *testing.T) {
 		t.Error("expected verbose to be true")
 	}
 }
+
+func TestCountByTimeBucket(t *testing.T) {
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	entries := []source.Entry{
+		{Timestamp: base.Add(1 * time.Minute)},
+		{Timestamp: base.Add(4 * time.Minute)},
+		{Timestamp: base.Add(6 * time.Minute)},
+		{Timestamp: base.Add(16 * time.Minute)},
+		{Message: "no timestamp"},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string // time_bucket=count
+	}{
+		{
+			name: "all buckets newest first",
+			want: []string{"2025-01-15 10:15:00.000=1", "2025-01-15 10:05:00.000=1", "2025-01-15 10:00:00.000=2"},
+		},
+		{
+			name:  "limited",
+			limit: 2,
+			want:  []string{"2025-01-15 10:15:00.000=1", "2025-01-15 10:05:00.000=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := countByTimeBucket(entries, StatsBucketSize, tt.limit)
+
+			var got []string
+			for _, r := range results {
+				if !r.Timestamp.IsZero() || r.Message != "" {
+					t.Errorf("stats row should have no timestamp or message: %+v", r)
+				}
+				got = append(got, r.Fields["time_bucket"]+"="+r.Fields["count"])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFormatHint(t *testing.T) {
+	saved := logFormat
+	t.Cleanup(func() { logFormat = saved })
+	logFormat = "logfmt+python"
+
+	abs, err := filepath.Abs("app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"app.log", "file://" + abs + "?format=logfmt%2Bpython"},
+		{"/var/log/app.log", "file:///var/log/app.log?format=logfmt%2Bpython"},
+		{"-", "stdin://?format=logfmt%2Bpython"},
+		{"s3://bucket/logs/?profile=prod", "s3://bucket/logs/?profile=prod&format=logfmt%2Bpython"},
+		{"docker://web", "docker://web?format=logfmt%2Bpython"},
+		{"cloudwatch:///app/logs", "cloudwatch:///app/logs"},
+		{"loki://localhost:3100/{app=\"api\"}", "loki://localhost:3100/{app=\"api\"}"},
+		{"es://localhost:9200/logs-*", "es://localhost:9200/logs-*"},
+		{"jsonl:///var/log/app.jsonl", "jsonl:///var/log/app.jsonl"},
+		{"@prod", "@prod"},
+	}
+
+	for _, tt := range tests {
+		if got := withFormatHint(tt.uri); got != tt.want {
+			t.Errorf("withFormatHint(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestCheckQueryable(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "syslog.log")
+
+	for _, tt := range []struct {
+		uri     string
+		wantErr bool
+	}{
+		{"syslog+udp://127.0.0.1:0", true},
+		{"syslog+udp://127.0.0.1:0?spool=" + spool, false},
+	} {
+		src, err := source.Open(tt.uri)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", tt.uri, err)
+		}
+		err = checkQueryable(src)
+		_ = src.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkQueryable(%s) = %v, want error %v", tt.uri, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunCaseKeep_ConfigParser(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+
+	err := local.RegisterParser("keep-billing", source.ParserConfig{
+		Pattern:   `^(?P<ts>\S+) (?P<level>[A-Z]+) \[(?P<thread>[^\]]+)\] (?P<msg>.*)$`,
+		Timestamp: "ts",
+		Message:   "msg",
+	})
+	if err != nil {
+		t.Fatalf("RegisterParser failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "billing.log")
+	if err := os.WriteFile(path, []byte("2024-03-01T10:00:01Z ERROR [worker-3] charge failed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Query with the parser, caching pointers as `clew query` does
+	src, err := source.Open("file://" + path + "?format=keep-billing")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	entries, err := src.Query(ctx, source.QueryParams{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query = %v, %v", entries, err)
+	}
+	cachePtrsFromEntries(ctx, entries, src)
+
+	mgr, err := cases.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.CreateCase(ctx, "Keep", "keep"); err != nil {
+		t.Fatalf("CreateCase failed: %v", err)
+	}
+	app := NewAppWithConfig(Config{}, ui.NewRendererWithOptions(ui.WithOutput(io.Discard)), mgr)
+	cmd := &cobra.Command{}
+	cmd.SetContext(SetApp(ctx, app))
+	if err := runCaseKeep(cmd, []string{"1"}); err != nil {
+		t.Fatalf("runCaseKeep failed: %v", err)
+	}
+
+	// The evidence is parsed with the parser, not the detected format
+	evidence, err := mgr.GetEvidence(ctx)
+	if err != nil || len(evidence) != 1 {
+		t.Fatalf("GetEvidence = %v, %v", evidence, err)
+	}
+	item := evidence[0]
+	if item.Message != "charge failed" || item.RawFields["thread"] != "worker-3" || item.RawFields["level"] != "ERROR" {
+		t.Errorf("evidence = %+v", item)
+	}
+	if want := "file://" + path + "?format=keep-billing"; item.SourceURI != want {
+		t.Errorf("SourceURI = %q, want %q", item.SourceURI, want)
+	}
+}
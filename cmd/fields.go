@@ -72,6 +72,7 @@ func runFields(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
 	var logGroup string
+	var logGroups []string // Several log groups, sampled in one query
 
 	// Handle source argument or legacy -g flag
 	if len(args) > 0 {
@@ -93,6 +94,17 @@ func runFields(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("fields command only supports CloudWatch sources (got %s)", src.Type())
 		}
 		logGroup = cwSrc.LogGroup()
+		groups, err := cwSrc.LogGroups(ctx)
+		if err != nil {
+			return err
+		}
+		if len(groups) > 1 {
+			// A sample only needs one query's worth of groups
+			if len(groups) > cloudwatch.MaxQueryLogGroups {
+				groups = groups[:cloudwatch.MaxQueryLogGroups]
+			}
+			logGroups = groups
+		}
 	} else if fieldsLogGroup != "" {
 		// Legacy style: -g flag (deprecated)
 		logGroup = resolveLogGroup(fieldsLogGroup)
@@ -136,6 +148,7 @@ func runFields(cmd *cobra.Command, args []string) error {
 
 	results, err := logsClient.RunInsightsQuery(ctx, cloudwatch.QueryParams{
 		LogGroup:  logGroup,
+		LogGroups: logGroups,
 		StartTime: startParsed,
 		EndTime:   endParsed,
 		Query:     query,
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// initFormats loads the grok pattern files and registers the access log
+// formats and regex parsers defined in the config.
+func initFormats() {
+	cfg, err := source.LoadConfig()
+	if err != nil {
+		return
+	}
+
+	// Patterns are loaded first, as parsers may refer to them
+	for _, path := range cfg.GrokPatterns {
+		if err := local.LoadGrokPatterns(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: grok patterns: %v\n", err)
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.LogFormats) {
+		if err := local.RegisterLogFormat(name, cfg.LogFormats[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: log format %q: %v\n", name, err)
+		}
+	}
+	for _, name := range sortedKeys(cfg.Parsers) {
+		if err := local.RegisterParser(name, cfg.Parsers[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: parser %q: %v\n", name, err)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a config map in name order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -24,6 +24,17 @@ Pointers are unique identifiers returned in query results. They can be:
   - A short number (e.g., "45") referencing a recent query result
   - A CloudWatch @ptr string (base64-encoded)
   - A file pointer (e.g., "file:///path/to/file#linenum")
+  - An S3 pointer (e.g., "s3://bucket/key#byteoffset")
+  - A journal pointer (e.g., "journal:///var/log/journal#<cursor>")
+  - A container log pointer (e.g., "docker:///var/lib/docker/containers/<id>/<id>-json.log#linenum")
+  - A Loki pointer (e.g., "loki://host:3100/<stream labels>#<unix nanoseconds>")
+  - An Elasticsearch pointer (e.g., "es://host:9200/<index>/<document id>")
+  - An HTTP(S) pointer (e.g., "https://host/path/app.log#byteoffset")
+  - An archive pointer (e.g., "archive:///path/bundle.tar.gz?member=var/log/app.log#linenum")
+  - A syslog receiver pointer (e.g., "syslog+udp://0.0.0.0:5514#<sequence number>"; valid while the receiver runs)
+  - An OTLP receiver pointer (e.g., "otlp://[::]:4318#<sequence number>"; valid while the receiver runs)
+  - A SQLite pointer (e.g., "sqlite:///path/app.db?table=logs#<rowid>"; "#key=<primary key>" for WITHOUT ROWID tables)
+  - A source plugin pointer (a URI with the plugin's scheme, e.g., "jsonl:///path/app.jsonl#linenum")
 
 Examples:
   # Get by short reference from recent query
@@ -35,6 +46,15 @@ Examples:
   # Get a local file line
   clew get "file:///var/log/app.log#1542"
 
+  # Get an entry from an S3 object
+  clew get "s3://my-logs/app/app.log.gz#20480"
+
+  # Get a journal entry by cursor
+  clew get "journal:///var/log/journal#s=6e3a1f0c...;i=2a"
+
+  # Get a Kubernetes container log entry
+  clew get "k8s-node:///var/log/pods/default_api-7d4b9c_0f1e.../app/0.log#12"
+
   # Output as JSON for parsing
   clew get 45 -o json`,
 	Args: cobra.ExactArgs(1),
@@ -89,8 +109,37 @@ func resolvePtr(ctx context.Context, ptrArg string) (string, *source.SourceMetad
 		return resolvePtrBySuffix(ctx, ptrArg)
 	}
 
-	// It's a full pointer
-	return ptrArg, nil, nil
+	// It's a full pointer; keep the settings of the query that returned it, if cached
+	return ptrArg, lookupPtrMetadata(ctx, ptrArg), nil
+}
+
+// lookupPtrMetadata returns the cached metadata of a full pointer, or nil if
+// no recent query returned it.
+func lookupPtrMetadata(ctx context.Context, ptr string) *source.SourceMetadata {
+	mgr, err := cases.NewManager()
+	if err != nil {
+		return nil
+	}
+	entry := mgr.LookupPtrMetadata(ctx, ptr)
+	if entry == nil {
+		return nil
+	}
+	return ptrEntryMetadata(*entry)
+}
+
+// ptrEntryMetadata builds source metadata from a pointer cache entry.
+func ptrEntryMetadata(entry cases.PtrEntry) *source.SourceMetadata {
+	// Prefer new fields, fall back to deprecated fields for backward compat
+	uri := entry.SourceURI
+	if uri == "" {
+		uri = entry.LogGroup
+	}
+	return &source.SourceMetadata{
+		Type:      entry.SourceType,
+		URI:       uri,
+		Profile:   entry.Profile,
+		AccountID: entry.AccountID,
+	}
 }
 
 // resolvePtrFromCache looks up pointer #N from the cached query results.
@@ -115,20 +164,7 @@ func resolvePtrFromCache(ctx context.Context, num int) (string, *source.SourceMe
 
 	entry := entries[idx]
 
-	// Build metadata from cache entry
-	// Prefer new fields, fall back to deprecated fields for backward compat
-	uri := entry.SourceURI
-	if uri == "" {
-		uri = entry.LogGroup
-	}
-	metadata := &source.SourceMetadata{
-		Type:      entry.SourceType,
-		URI:       uri,
-		Profile:   entry.Profile,
-		AccountID: entry.AccountID,
-	}
-
-	return entry.Ptr, metadata, nil
+	return entry.Ptr, ptrEntryMetadata(entry), nil
 }
 
 // resolvePtrBySuffix finds a cached pointer by its suffix (last N chars).
@@ -159,18 +195,5 @@ func resolvePtrBySuffix(ctx context.Context, suffix string) (string, *source.Sou
 		return "", nil, fmt.Errorf("pointer suffix %q is ambiguous (matches %d entries)", suffix, len(matches))
 	}
 
-	entry := matches[0]
-	// Prefer new fields, fall back to deprecated fields for backward compat
-	uri := entry.SourceURI
-	if uri == "" {
-		uri = entry.LogGroup
-	}
-	metadata := &source.SourceMetadata{
-		Type:      entry.SourceType,
-		URI:       uri,
-		Profile:   entry.Profile,
-		AccountID: entry.AccountID,
-	}
-
-	return entry.Ptr, metadata, nil
+	return matches[0].Ptr, ptrEntryMetadata(matches[0]), nil
 }
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/jmurray2011/clew/internal/plugin"
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/jmurray2011/clew/internal/ui"
+	pluginapi "github.com/jmurray2011/clew/pkg/plugin"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pluginsOnce sync.Once
+	plugins     []plugin.Plugin // discovered source plugins
+)
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List source plugins",
+	Long: `List source plugins found on PATH or in the configuration file.
+
+A source plugin is an executable named clew-source-<scheme> that serves
+<scheme>:// URIs, such as clew-source-clickhouse for clickhouse:// URIs.
+Plugins on PATH are found automatically; others can be configured by scheme
+in ~/.clew/config.yaml:
+
+  plugins:
+    clickhouse: ~/bin/clew-clickhouse
+
+Built-in sources take precedence over plugins for the same scheme.
+
+Examples:
+  # List plugins
+  clew plugins
+
+  # Check a plugin follows the protocol
+  clew plugins check clickhouse://localhost/logs`,
+	Args: cobra.NoArgs,
+	RunE: runPlugins,
+}
+
+var pluginsCheckCmd = &cobra.Command{
+	Use:   "check <uri>",
+	Short: "Check a source plugin follows the plugin protocol",
+	Long: `Run the plugin conformance checks against a source URI served by a plugin.
+
+The checks cover the open handshake, query limits, ordering, filters and time
+ranges, each optional method the plugin declares, and shutdown. The URI should
+name a source with at least a few timestamped entries.
+
+Examples:
+  clew plugins check clickhouse://localhost/logs`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPluginsCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsCheckCmd)
+}
+
+// initPlugins registers the source plugins found on PATH or in the config.
+func initPlugins() {
+	pluginsOnce.Do(func() {
+		var configured map[string]string
+		if cfg, err := source.LoadConfig(); err == nil {
+			configured = cfg.Plugins
+		}
+		plugins = plugin.Register(configured)
+	})
+}
+
+func runPlugins(cmd *cobra.Command, args []string) error {
+	app := GetApp(cmd)
+
+	if len(plugins) == 0 {
+		app.Render.Info("No source plugins found.")
+		app.Render.Newline()
+		app.Render.Info("Install a clew-source-<scheme> executable on PATH, or configure one in %s:", source.ConfigPath())
+		fmt.Println()
+		fmt.Println("  plugins:")
+		fmt.Println("    clickhouse: ~/bin/clew-clickhouse")
+		return nil
+	}
+
+	// Find max scheme length for alignment
+	maxLen := 0
+	for _, p := range plugins {
+		if len(p.Scheme)+3 > maxLen {
+			maxLen = len(p.Scheme) + 3
+		}
+	}
+
+	for _, p := range plugins {
+		scheme := ui.LabelStyle.Render(fmt.Sprintf("%-*s", maxLen, p.Scheme+"://"))
+		_, _ = fmt.Fprintf(os.Stdout, "%s  %s", scheme, p.Path)
+		if p.Configured {
+			_, _ = fmt.Fprint(os.Stdout, "  [config]")
+		}
+		if !p.Registered {
+			_, _ = fmt.Fprint(os.Stdout, "  [shadowed by built-in source]")
+		}
+		_, _ = fmt.Fprintln(os.Stdout)
+	}
+
+	return nil
+}
+
+func runPluginsCheck(cmd *cobra.Command, args []string) error {
+	app := GetApp(cmd)
+
+	u, err := url.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid source URI %q: %w", args[0], err)
+	}
+	var found *plugin.Plugin
+	for i := range plugins {
+		if plugins[i].Scheme == u.Scheme {
+			found = &plugins[i]
+			break
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("no source plugin for %s:// (run 'clew plugins' to list plugins)", u.Scheme)
+	}
+
+	app.Render.Status("Checking %s against %s", found.Path, args[0])
+	failed := 0
+	for _, result := range pluginapi.Check(cmd.Context(), found.Path, args[0]) {
+		switch {
+		case result.Skipped != "":
+			app.Render.Info("SKIP  %s (%s)", result.Name, result.Skipped)
+		case result.Err != nil:
+			failed++
+			app.Render.Error("%s: %v", result.Name, result.Err)
+		default:
+			app.Render.Success("PASS  %s", result.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
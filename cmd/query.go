@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -23,6 +28,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// StatsBucketSize is the time bucket --stats counts matches in, matching the
+// CloudWatch stats query
+const StatsBucketSize = 5 * time.Minute
+
 var (
 	startTime     string
 	endTime       string
@@ -48,8 +57,23 @@ var queryCmd = &cobra.Command{
 
 Source URIs:
   cloudwatch:///log-group      AWS CloudWatch Logs (use -p for profile)
+  cloudwatch:///app/*          Several log groups (or ?groups=a,b ?prefix=/app/ ?tag=key:value)
   file:///path/to/file.log     Local file
   /var/log/app.log             Local file (shorthand)
+  s3://bucket/prefix/          S3 objects under a prefix
+  journal:///var/log/journal   systemd journal (?unit=name filters by unit)
+  -, stdin://                  Standard input (spooled to a temp file)
+  docker://container           Docker container logs (json-file driver)
+  k8s-node:///ns/pod/container Kubernetes pod logs on this node (globs allowed)
+  loki://host:3100/{app="api"} Grafana Loki stream selector (?tls=true, ?org=tenant)
+  es://host:9200/logs-*        Elasticsearch/OpenSearch index (?time= ?message= ?stream= fields)
+  https://host/path/app.log    Log file over HTTP(S) (alias headers: for auth)
+  archive:///path/bundle.tgz   Logs inside a tar or zip archive (?glob=var/log/*.log)
+  syslog+udp://0.0.0.0:5514    Syslog receiver, or syslog+tcp:// (?spool=file keeps messages)
+  otlp://:4318                 OpenTelemetry (OTLP/HTTP) log receiver (?buffer=N records)
+  sqlite:///path/app.db        SQLite table of log rows (?table=logs ?ts= ?msg= columns)
+  case://case-id               Evidence collected in a case (or case:///path/export.zip)
+  <scheme>://...               Source plugin (a clew-source-<scheme> executable)
   @alias-name                  Config alias
 
 Supports both RFC3339 timestamps and relative time formats:
@@ -61,10 +85,44 @@ Examples:
   clew query "cloudwatch:///app/logs" -p prod -s 2h -f "error"
   clew query @prod-api -s 1h -f "exception"
 
+  # Several log groups in one query (by wildcard or tag)
+  clew query "cloudwatch:///app/*" -p prod -s 1h -f "error"
+  clew query "cloudwatch://?tag=service:api" -p prod -s 1h -f "error"
+
   # Local files
   clew query /var/log/app.log -f "error"
   clew query "file:///var/log/*.log" -s 1h -f "timeout"
 
+  # S3 objects (use ?endpoint= for S3-compatible storage)
+  clew query "s3://my-logs/app/2025/01/" -p prod -s 1d -f "error"
+
+  # systemd journal, filtered to a unit
+  clew query "journal:///var/log/journal?unit=nginx" -s 1h -f "upstream"
+
+  # Container logs, read from the node's log files
+  clew query docker://web -s 1h -f "error"
+  clew query "k8s-node:///default/api-*/app" -s 30m -f "timeout"
+
+  # Grafana Loki (-q appends LogQL pipeline stages to the selector)
+  clew query 'loki://localhost:3100/{app="api"}' -s 1h -f "error"
+  clew query 'loki://localhost:3100/{app="api"}' -s 1h -q '| json | status >= 500'
+
+  # Elasticsearch or OpenSearch (-q is a Lucene query string)
+  clew query "es://localhost:9200/logs-*" -s 1h -f "denied"
+  clew query "es://localhost:9200/logs-*" -s 1d -q 'event.action:"user-login" AND event.outcome:failure'
+
+  # A CI job log or build artifact over HTTP(S), read with range requests
+  clew query "https://ci.example.com/job/42/log.txt" -f "FAILED" -B 20
+
+  # Logs inside a support bundle, without extracting it
+  clew query "archive:///tmp/support-bundle.tar.gz?glob=var/log/*.log" -f "panic"
+
+  # Messages sent to a syslog receiver, kept in a spool file
+  clew query "syslog+udp://0.0.0.0:5514?spool=/tmp/syslog.log" -s 1h -f "sshd"
+
+  # Piped input
+  kubectl logs deploy/api | clew query - -f "error" -s 1d
+
   # Show context lines
   clew query @prod-api -s 2h -f "exception" -B 10
 
@@ -74,6 +132,9 @@ Examples:
   # Multiple files (shell-expanded glob)
   clew query ./*.log -s 1h -f "error"
 
+  # Several sources at once, merged by timestamp
+  clew query @prod-api @worker /var/log/nginx/access.log -s 1h -f "timeout"
+
   # Use default_source from config (if no source specified)
   clew query -s 1h -f "error"`,
 	Args: cobra.ArbitraryArgs,
@@ -86,7 +147,7 @@ func init() {
 	queryCmd.Flags().StringVarP(&startTime, "since", "s", "1h", "Start time - RFC3339 or relative (e.g., 2h, 30m, 7d)")
 	queryCmd.Flags().StringVarP(&endTime, "until", "u", "now", "End time - RFC3339 or relative")
 	queryCmd.Flags().StringVarP(&filter, "filter", "f", "", "Regex filter for messages")
-	queryCmd.Flags().StringVarP(&queryString, "query", "q", "", "Full query (CloudWatch Insights syntax for cloudwatch sources)")
+	queryCmd.Flags().StringVarP(&queryString, "query", "q", "", "Full query (CloudWatch Insights syntax for cloudwatch sources, LogQL for loki, Lucene query string for es)")
 	queryCmd.Flags().IntVarP(&limit, "limit", "l", 500, "Max results to return")
 	queryCmd.Flags().IntVarP(&contextLines, "context", "C", 0, "Show N lines of context before each match")
 	queryCmd.Flags().StringVar(&exportFile, "export", "", "Export results to file")
@@ -96,7 +157,7 @@ func init() {
 	queryCmd.Flags().IntVar(&watchInterval, "watch", 0, "Re-run query every N seconds (0 = disabled)")
 	queryCmd.Flags().BoolVar(&markQuery, "mark", false, "Mark this query as significant in the active case")
 	queryCmd.Flags().BoolVar(&noCapture, "no-capture", false, "Don't add this query to the active case timeline")
-	queryCmd.Flags().StringVar(&logFormat, "format", "auto", "Log format hint for local files: auto, plain, json, syslog, java")
+	queryCmd.Flags().StringVar(&logFormat, "format", "auto", "Log format hint for local files and S3 objects: auto, plain, json, syslog, java, evtx, alb, elb, cloudfront, cloudtrail, vpcflow, logfmt, common, combined, nginx-error, grok:<expression>, <format>+<multiline profile> such as json+python, or a config log_formats or parsers name")
 
 	// Backward compatibility aliases
 	queryCmd.Flags().IntVarP(&contextLines, "before", "B", 0, "Alias for --context")
@@ -106,7 +167,8 @@ func init() {
 func runQuery(cmd *cobra.Command, args []string) error {
 	app := GetApp(cmd)
 	var sourceURI string
-	var multiFiles []string // For shell-expanded globs
+	var multiFiles []string   // For shell-expanded globs
+	var multiSources []string // For a mix of source types
 
 	// Handle source argument or default_source
 	if len(args) == 0 {
@@ -127,20 +189,15 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		// Handle multiple arguments (shell-expanded glob)
 		multiFiles = args
 		sourceURI = args[0] // Use first file for display, actual files handled separately
+	} else if len(args) > 1 {
+		// Handle a mix of sources, queried together and merged
+		multiSources = args
+		sourceURI = strings.Join(args, " ")
 	} else {
 		sourceURI = args[0]
 	}
-
-	// Add format hint for local files if specified
-	if logFormat != "auto" && !strings.HasPrefix(sourceURI, "cloudwatch://") && !strings.HasPrefix(sourceURI, "@") {
-		if strings.Contains(sourceURI, "?") {
-			sourceURI += "&format=" + logFormat
-		} else if strings.HasPrefix(sourceURI, "file://") {
-			sourceURI += "?format=" + logFormat
-		} else {
-			// Bare path - convert to file:// with format
-			sourceURI = "file://" + sourceURI + "?format=" + logFormat
-		}
+	if len(multiFiles) == 0 && len(multiSources) == 0 {
+		sourceURI = withFormatHint(sourceURI)
 	}
 
 	// Parse time range
@@ -181,6 +238,30 @@ func runQuery(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to open files: %w", err)
 		}
 		sourceURI = src.Metadata().URI // Update for display
+	} else if len(multiSources) > 0 {
+		opts := source.OpenOptions{
+			Profile: app.GetProfile(),
+			Region:  app.GetRegion(),
+		}
+		// Sources that fail to open are skipped so the others can still be queried
+		composite := source.NewComposite()
+		for _, arg := range multiSources {
+			member, err := source.OpenWithOptions(withFormatHint(arg), opts)
+			if err == nil {
+				if err = checkQueryable(member); err != nil {
+					_ = member.Close()
+				}
+			}
+			if err != nil {
+				app.Render.Warning("failed to open source %s: %v", arg, err)
+				continue
+			}
+			composite.Add(arg, member)
+		}
+		if len(composite.Labels()) == 0 {
+			return fmt.Errorf("failed to open any of the %d sources", len(multiSources))
+		}
+		src = composite
 	} else {
 		opts := source.OpenOptions{
 			Profile: app.GetProfile(),
@@ -190,6 +271,10 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to open source: %w", err)
 		}
+		if err := checkQueryable(src); err != nil {
+			_ = src.Close()
+			return err
+		}
 	}
 	defer func() { _ = src.Close() }()
 
@@ -206,7 +291,12 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		// Handle --url
 		if showURL {
 			defer func() {
-				consoleURL := buildConsoleURL(cwSrc.Region(), []string{cwSrc.LogGroup()}, start, end, queryString)
+				logGroups, err := cwSrc.LogGroups(ctx)
+				if err != nil {
+					app.Render.Warning("cannot build console URL: %v", err)
+					return
+				}
+				consoleURL := buildConsoleURL(cwSrc.Region(), logGroups, start, end, queryString)
 				app.Render.Newline()
 				app.Render.Info("AWS Console URL:")
 				app.Render.Info("  %s", consoleURL)
@@ -239,14 +329,31 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	// For CloudWatch with stats mode, build a special query
 	if showStats && src.Type() == "cloudwatch" && queryString == "" {
 		params.Query = cloudwatch.BuildStatsQuery(filter, limit)
+	} else if showStats && src.Type() != "cloudwatch" {
+		// Other sources are counted here, so every match is needed
+		params.Limit = 0
+		params.Context = 0
 	}
 
 	// Run query
 	app.Render.Status("Querying %s...", sourceURI)
 	results, err := src.Query(ctx, params)
-	if err != nil {
+	var partial *source.PartialError
+	if errors.As(err, &partial) {
+		// Show the results of the sources that succeeded
+		for _, e := range partial.Errors {
+			app.Render.Warning("%v", e)
+		}
+	} else if err != nil {
 		return err
 	}
+	bucketed := showStats && src.Type() != "cloudwatch"
+	if bucketed {
+		results = countByTimeBucket(results, StatsBucketSize, limit)
+	}
+	if src.Type() == "stdin" {
+		app.Debugf("Input spooled to %s", src.Metadata().URI)
+	}
 
 	// Determine output writer
 	writer := os.Stdout
@@ -263,7 +370,15 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	if filter != "" && !showStats {
 		formatter.WithHighlight(filter)
 	}
-	if err := formatter.FormatEntries(results); err != nil {
+	if src.Type() == "composite" {
+		formatter.WithSources()
+	}
+	if bucketed {
+		err = formatter.FormatStats(results)
+	} else {
+		err = formatter.FormatEntries(results)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -325,10 +440,19 @@ func runWatchModeNew(ctx context.Context, app *App, src source.Source, sourceURI
 			params.EndTime = end
 
 			results, err := src.Query(ctx, params)
-			if err != nil {
+			var partial *source.PartialError
+			if errors.As(err, &partial) {
+				for _, e := range partial.Errors {
+					app.Render.Warning("query failed: %v", e)
+				}
+			} else if err != nil {
 				app.Render.Warning("query failed: %v", err)
 				continue
 			}
+			bucketed := showStats && src.Type() != "cloudwatch"
+			if bucketed {
+				results = countByTimeBucket(results, StatsBucketSize, limit)
+			}
 
 			// Clear screen and show timestamp
 			fmt.Print("\033[2J\033[H")
@@ -339,48 +463,105 @@ func runWatchModeNew(ctx context.Context, app *App, src source.Source, sourceURI
 			if filterPattern != "" && !showStats {
 				formatter.WithHighlight(filterPattern)
 			}
-			if err := formatter.FormatEntries(results); err != nil {
+			if src.Type() == "composite" {
+				formatter.WithSources()
+			}
+			if bucketed {
+				err = formatter.FormatStats(results)
+			} else {
+				err = formatter.FormatEntries(results)
+			}
+			if err != nil {
 				app.Render.Warning("format error: %v", err)
 			}
 		}
 	}
 }
 
-// estimateCloudWatchCost estimates CloudWatch query cost.
+// countByTimeBucket counts entries per time bucket, newest bucket first, for
+// --stats on sources without server-side aggregation. The rows have the same
+// fields as CloudWatch stats results. Entries without a timestamp are not counted.
+func countByTimeBucket(entries []source.Entry, bucket time.Duration, limit int) []source.Entry {
+	counts := make(map[time.Time]int)
+	for _, e := range entries {
+		if !e.Timestamp.IsZero() {
+			counts[e.Timestamp.UTC().Truncate(bucket)]++
+		}
+	}
+
+	buckets := make([]time.Time, 0, len(counts))
+	for b := range counts {
+		buckets = append(buckets, b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].After(buckets[j])
+	})
+	if limit > 0 && len(buckets) > limit {
+		buckets = buckets[:limit]
+	}
+
+	results := make([]source.Entry, len(buckets))
+	for i, b := range buckets {
+		results[i] = source.Entry{
+			Fields: map[string]string{
+				"time_bucket": b.Format("2006-01-02 15:04:05.000"),
+				"count":       strconv.Itoa(counts[b]),
+			},
+		}
+	}
+	return results
+}
+
+// estimateCloudWatchCost estimates CloudWatch query cost across the source's log groups.
 func estimateCloudWatchCost(ctx context.Context, app *App, src *cloudwatch.Source, start, end time.Time) error {
 	duration := end.Sub(start)
 
-	group, err := src.Client().GetLogGroup(ctx, src.LogGroup())
+	logGroups, err := src.LogGroups(ctx)
 	if err != nil {
-		return fmt.Errorf("could not get log group info: %w", err)
+		return err
 	}
 
+	var estimates []ui.LogGroupEstimate
 	var estimatedBytes int64
-	if group.CreationTime.IsZero() || group.StoredBytes == 0 {
-		app.Render.Info("Cost estimate unavailable (no data)")
-		return nil
-	}
+	for _, name := range logGroups {
+		group, err := src.Client().GetLogGroup(ctx, name)
+		if err != nil {
+			return fmt.Errorf("could not get log group info: %w", err)
+		}
+
+		if group.CreationTime.IsZero() || group.StoredBytes == 0 {
+			continue
+		}
 
-	groupAge := time.Since(group.CreationTime)
-	if groupAge <= 0 {
-		groupAge = 24 * time.Hour
+		groupAge := time.Since(group.CreationTime)
+		if groupAge <= 0 {
+			groupAge = 24 * time.Hour
+		}
+
+		ratio := float64(duration) / float64(groupAge)
+		if ratio > 1 {
+			ratio = 1
+		}
+		groupBytes := int64(float64(group.StoredBytes) * ratio)
+		estimatedBytes += groupBytes
+
+		estimates = append(estimates, ui.LogGroupEstimate{
+			Name:          name,
+			TotalSize:     timeutil.FormatBytes(group.StoredBytes),
+			EstimatedScan: timeutil.FormatBytes(groupBytes),
+		})
 	}
 
-	ratio := float64(duration) / float64(groupAge)
-	if ratio > 1 {
-		ratio = 1
+	if len(estimates) == 0 {
+		app.Render.Info("Cost estimate unavailable (no data)")
+		return nil
 	}
-	estimatedBytes = int64(float64(group.StoredBytes) * ratio)
 
 	costPerGB := 0.005
 	costEstimate := float64(estimatedBytes) / (1024 * 1024 * 1024) * costPerGB
 
 	app.Render.RenderCostEstimate(ui.CostEstimate{
-		LogGroups: []ui.LogGroupEstimate{{
-			Name:          src.LogGroup(),
-			TotalSize:     timeutil.FormatBytes(group.StoredBytes),
-			EstimatedScan: timeutil.FormatBytes(estimatedBytes),
-		}},
+		LogGroups:     estimates,
 		TimeRange:     fmt.Sprintf("%s to %s (%s)", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), timeutil.FormatDuration(duration)),
 		TotalBytes:    timeutil.FormatBytes(estimatedBytes),
 		EstimatedCost: costEstimate,
@@ -399,7 +580,13 @@ func captureQueryToCaseNew(ctx context.Context, sourceURI string, src source.Sou
 	// Build command string
 	var cmdParts []string
 	cmdParts = append(cmdParts, "clew query")
-	cmdParts = append(cmdParts, fmt.Sprintf("%q", sourceURI))
+	if c, ok := src.(*source.Composite); ok {
+		for _, label := range c.Labels() {
+			cmdParts = append(cmdParts, fmt.Sprintf("%q", label))
+		}
+	} else {
+		cmdParts = append(cmdParts, fmt.Sprintf("%q", sourceURI))
+	}
 	cmdParts = append(cmdParts, fmt.Sprintf("-s %s", startTime))
 	if endTime != "now" && endTime != "" {
 		cmdParts = append(cmdParts, fmt.Sprintf("-e %s", endTime))
@@ -430,13 +617,24 @@ func captureQueryToCaseNew(ctx context.Context, sourceURI string, src source.Sou
 	_ = mgr.AddQueryToTimeline(ctx, entry)
 }
 
+// entryMetadataSource is implemented by sources whose entries come from other
+// sources, such as composites and cases.
+type entryMetadataSource interface {
+	EntryMetadata(entry source.Entry) source.SourceMetadata
+}
+
 // cachePtrsFromEntries caches pointer values for evidence collection.
 func cachePtrsFromEntries(ctx context.Context, entries []source.Entry, src source.Source) {
 	var ptrEntries []cases.PtrEntry
 	meta := src.Metadata()
+	origins, _ := src.(entryMetadataSource)
 
 	for _, e := range entries {
 		if e.Ptr != "" {
+			// Entries of composite and case sources keep the metadata of their own source
+			if origins != nil {
+				meta = origins.EntryMetadata(e)
+			}
 			ptrEntries = append(ptrEntries, cases.PtrEntry{
 				Ptr:        e.Ptr,
 				SourceURI:  meta.URI,
@@ -485,6 +683,59 @@ func buildConsoleURL(region string, logGroups []string, start, end time.Time, qu
 	)
 }
 
+// checkQueryable returns an error for sources a one-shot query cannot read
+// anything from: a syslog receiver without a spool file has only received
+// what arrived since query started it.
+func checkQueryable(src source.Source) error {
+	if src.Type() != "syslog" {
+		return nil
+	}
+	if u, err := url.Parse(src.Metadata().URI); err == nil && u.Query().Get("spool") != "" {
+		return nil
+	}
+	return fmt.Errorf("querying a syslog receiver requires a spool file: add ?spool=/path/file.log, " +
+		"and keep a receiver running with the same spool (e.g. clew tail) to collect messages")
+}
+
+// formatHintSchemes are the schemes of sources that parse log lines
+// themselves and read the format parameter.
+var formatHintSchemes = []string{"file", "stdin", "s3", "http", "https", "archive", "docker", "k8s-node"}
+
+// withFormatHint adds the --format hint to the URI of a source that reads
+// the format parameter. Other URIs and aliases are returned unchanged; bare
+// paths become absolute file:// URIs.
+func withFormatHint(sourceURI string) string {
+	if sourceURI == "-" {
+		sourceURI = "stdin://"
+	}
+	if logFormat == "auto" || strings.HasPrefix(sourceURI, "@") {
+		return sourceURI
+	}
+	format := url.QueryEscape(logFormat)
+
+	scheme, _, isURI := strings.Cut(sourceURI, "://")
+	if !isURI {
+		// Bare path - convert to file:// with format
+		path := sourceURI
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return "file://" + path + "?format=" + format
+	}
+	if !slices.Contains(formatHintSchemes, scheme) {
+		return sourceURI
+	}
+	if strings.Contains(sourceURI, "?") {
+		return sourceURI + "&format=" + format
+	}
+	return sourceURI + "?format=" + format
+}
+
 // looksLikeLocalFiles checks if all arguments appear to be local file paths
 // (not URIs like cloudwatch:// or aliases like @prod).
 func looksLikeLocalFiles(args []string) bool {
@@ -497,6 +748,10 @@ func looksLikeLocalFiles(args []string) bool {
 		if strings.HasPrefix(arg, "@") {
 			return false
 		}
+		// Skip standard input
+		if arg == "-" {
+			return false
+		}
 	}
 	return true
 }
@@ -4,7 +4,18 @@ import (
 	"fmt"
 	"os"
 
-	_ "github.com/jmurray2011/clew/internal/local" // Register file:// source
+	_ "github.com/jmurray2011/clew/internal/archive"       // Register archive:// source
+	_ "github.com/jmurray2011/clew/internal/cases"         // Register case:// source
+	_ "github.com/jmurray2011/clew/internal/container"     // Register docker:// and k8s-node:// sources
+	_ "github.com/jmurray2011/clew/internal/elasticsearch" // Register es:// source
+	_ "github.com/jmurray2011/clew/internal/httplog"       // Register http:// and https:// sources
+	_ "github.com/jmurray2011/clew/internal/journal"       // Register journal:// source
+	_ "github.com/jmurray2011/clew/internal/local"         // Register file:// source
+	_ "github.com/jmurray2011/clew/internal/loki"          // Register loki:// source
+	_ "github.com/jmurray2011/clew/internal/otlp"          // Register otlp:// source
+	_ "github.com/jmurray2011/clew/internal/s3"            // Register s3:// source
+	_ "github.com/jmurray2011/clew/internal/sqlite"        // Register sqlite:// source
+	_ "github.com/jmurray2011/clew/internal/syslog"        // Register syslog+udp:// and syslog+tcp:// sources
 	"github.com/jmurray2011/clew/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -94,7 +105,7 @@ func SetVersion(v string) {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initRenderer)
+	cobra.OnInitialize(initConfig, initRenderer, initPlugins, initFormats)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.clew/config.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "Default AWS profile (can be overridden in URI)")
@@ -29,6 +29,24 @@ Examples:
   # List files matching a local pattern
   clew streams "file:///var/log/*.log"
 
+  # List objects under an S3 prefix
+  clew streams "s3://my-logs/app/2025/"
+
+  # List systemd journal files
+  clew streams "journal:///var/log/journal"
+
+  # List a Docker container's log files, including rotated ones
+  clew streams docker://web
+
+  # List Loki streams matching a selector that logged in the last day
+  clew streams 'loki://localhost:3100/{env="prod"}'
+
+  # List the hosts logging to an Elasticsearch index pattern
+  clew streams "es://localhost:9200/logs-*"
+
+  # List the log files inside a support bundle
+  clew streams "archive:///tmp/support-bundle.zip?glob=*/var/log/*"
+
   # Limit results
   clew streams @prod-api -l 50`,
 	Args: cobra.ExactArgs(1),
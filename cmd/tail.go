@@ -40,8 +40,21 @@ var tailCmd = &cobra.Command{
 
 Source URIs:
   cloudwatch:///log-group      AWS CloudWatch Logs (use -p for profile)
+  cloudwatch:///app/*          Several log groups (or ?groups=a,b ?prefix=/app/ ?tag=key:value)
   file:///path/to/file.log     Local file
   /var/log/app.log             Local file (shorthand)
+  s3://bucket/prefix/          S3 objects under a prefix
+  journal:///var/log/journal   systemd journal (?unit=name filters by unit)
+  -, stdin://                  Standard input (spooled to a temp file)
+  docker://container           Docker container logs (json-file driver)
+  k8s-node:///ns/pod/container Kubernetes pod logs on this node (globs allowed)
+  loki://host:3100/{app="api"} Grafana Loki stream selector (?tls=true, ?org=tenant)
+  es://host:9200/logs-*        Elasticsearch/OpenSearch index (?time= ?message= ?stream= fields)
+  https://host/path/app.log    Log file over HTTP(S) (alias headers: for auth)
+  syslog+udp://0.0.0.0:5514    Syslog receiver, or syslog+tcp:// (?spool=file keeps messages)
+  otlp://:4318                 OpenTelemetry (OTLP/HTTP) log receiver (?buffer=N records)
+  sqlite:///path/app.db        SQLite table of log rows (?table=logs ?ts= ?msg= columns)
+  <scheme>://...               Source plugin (a clew-source-<scheme> executable)
   @alias-name                  Config alias
 
 Examples:
@@ -51,6 +64,24 @@ Examples:
   # Tail a local file
   clew tail /var/log/app.log
 
+  # Follow the systemd journal for one unit
+  clew tail "journal:///var/log/journal?unit=sshd"
+
+  # Follow piped output; the tail ends with the input
+  kubectl logs -f deploy/api | clew tail - -f "error"
+
+  # Follow a Kubernetes container's logs across restarts
+  clew tail "k8s-node:///default/api-7d4b9c/app"
+
+  # Follow a log served over HTTP(S) as it grows
+  clew tail "https://ci.example.com/job/42/log.txt"
+
+  # Follow a Loki stream selector
+  clew tail 'loki://localhost:3100/{app="api", env="prod"}'
+
+  # Receive logs from OpenTelemetry SDKs (OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318)
+  clew tail otlp://:4318
+
   # Tail with a filter
   clew tail @prod-api -f "error|exception"
 
@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+)
+
+// errStopWalk ends a walk early without an error.
+var errStopWalk = errors.New("stop walk")
+
+// Signatures at the start of a zip file with members, and of an empty one
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+)
+
+// member is a regular file inside an archive.
+type member struct {
+	name    string // path within the archive, without a leading ./ or /
+	size    int64  // size of the file, before decompressing a compressed member
+	modTime time.Time
+}
+
+// memberFunc is called with a member's decompressed content and a parser for
+// its detected format.
+type memberFunc func(m member, r io.Reader, parser local.Parser) error
+
+// walk calls fn for each member selected by match, in archive order. Other
+// members are skipped without being decompressed, so match can also be used
+// to list members. The archive may be a zip
+// file or a tar file, optionally gzip, zstd or bzip2 compressed; members
+// compressed on their own (such as rotated logs) are decompressed too.
+// fn may return errStopWalk to end the walk early.
+func (s *Source) walk(ctx context.Context, match func(m member) bool, fn memberFunc) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, len(zipMagic))
+	n, _ := io.ReadFull(f, header)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if bytes.Equal(header[:n], zipMagic) || bytes.Equal(header[:n], zipEmptyMagic) {
+		err = s.walkZip(ctx, f, match, fn)
+	} else {
+		err = s.walkTar(ctx, f, match, fn)
+	}
+	if errors.Is(err, errStopWalk) {
+		return nil
+	}
+	return err
+}
+
+// walkZip walks the members of a zip file, reading only the selected ones.
+func (s *Source) walkZip(ctx context.Context, f *os.File, match func(m member) bool, fn memberFunc) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("%s is not a valid zip archive: %w", s.path, err)
+	}
+
+	for _, zf := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m := member{name: cleanName(zf.Name), size: int64(zf.UncompressedSize64), modTime: zf.Modified}
+		if !zf.Mode().IsRegular() || !match(m) {
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+		err = readMember(m, rc, s.format, fn)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar walks the members of a (possibly compressed) tar file. Tar files
+// have no index, so members before the last selected one are read through.
+func (s *Source) walkTar(ctx context.Context, f *os.File, match func(m member) bool, fn memberFunc) error {
+	r, compression, err := local.NewDecompressReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to open %s stream in %s: %w", compression, s.path, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	tr := tar.NewReader(r)
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if first {
+				return fmt.Errorf("%s is not a tar or zip archive: %w", s.path, err)
+			}
+			return fmt.Errorf("error reading %s: %w", s.path, err)
+		}
+
+		m := member{name: cleanName(hdr.Name), size: hdr.Size, modTime: hdr.ModTime}
+		if hdr.Typeflag != tar.TypeReg || !match(m) {
+			continue
+		}
+		if err := readMember(m, tr, s.format, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// readMember decompresses a member if needed, detects its format unless one
+// was given, and calls fn.
+func readMember(m member, r io.Reader, format local.Format, fn memberFunc) error {
+	d, compression, err := local.NewDecompressReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open %s stream in %s: %w", compression, m.name, err)
+	}
+	defer func() { _ = d.Close() }()
+
+	br := bufio.NewReaderSize(d, FormatDetectionSampleSize)
+	if format == local.FormatAuto {
+		// Peek returns what there is for members shorter than the sample
+		sample, _ := br.Peek(FormatDetectionSampleSize)
+		format = local.DetectFormatReader(bytes.NewReader(sample))
+	}
+
+	return fn(m, br, local.NewParser(format))
+}
+
+// cleanName normalizes a member path, so "./var/log/app.log" and
+// "var/log/app.log" name the same member.
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,325 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// FormatDetectionSampleSize is how many bytes of a member are sampled to detect its log format
+	FormatDetectionSampleSize = 64 * 1024
+)
+
+func init() {
+	source.Register("archive", openSource)
+}
+
+// Source implements source.Source for log files inside a tar or zip archive,
+// such as a support bundle. Members are read in place, without extracting
+// them, and each member's format is detected separately. Pointers name the
+// archive, the member and a line number in it.
+type Source struct {
+	path   string // archive file
+	glob   string // members to read (path.Match syntax); empty for all
+	format local.Format
+	uri    string
+}
+
+// openSource opens an archive source from a parsed URL.
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	archivePath := u.Path
+	if archivePath == "" {
+		return nil, fmt.Errorf("archive:// URI requires a path (e.g., archive:///tmp/support-bundle.tar.gz)")
+	}
+
+	// Expand ~ to home directory
+	if strings.HasPrefix(archivePath, "/~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			archivePath = filepath.Join(home, archivePath[3:])
+		}
+	}
+
+	query := u.Query()
+	return NewSource(archivePath, query.Get("glob"), query.Get("format"))
+}
+
+// NewSource creates a source for the members of the archive at archivePath
+// matching glob. The formatHint specifies the log format of every member
+// (auto, plain, json, syslog, java); auto detects it per member.
+func NewSource(archivePath, glob, formatHint string) (*Source, error) {
+	if err := local.CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(archivePath); err != nil {
+		return nil, fmt.Errorf("cannot open archive: %w", err)
+	}
+	glob = strings.TrimPrefix(glob, "/")
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid member pattern %q: %w", glob, err)
+	}
+
+	return &Source{
+		path:   archivePath,
+		glob:   glob,
+		format: local.ParseFormat(formatHint),
+		uri:    buildURI(archivePath, glob, formatHint),
+	}, nil
+}
+
+// buildURI builds the canonical URI for a source, keeping the member glob
+// and format hint so sources reopened from cached pointers read the same members.
+func buildURI(archivePath, glob, formatHint string) string {
+	uri := (&url.URL{Scheme: "archive", Path: archivePath}).String()
+
+	var params []string
+	if glob != "" {
+		params = append(params, "glob="+queryEscape(glob))
+	}
+	if f := local.ParseFormat(formatHint); f != local.FormatAuto {
+		params = append(params, "format="+url.QueryEscape(f.String()))
+	}
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return uri
+}
+
+// queryEscape escapes a query value, keeping the slashes and wildcards of
+// member paths and globs readable.
+func queryEscape(s string) string {
+	return strings.NewReplacer("%2F", "/", "%2A", "*").Replace(url.QueryEscape(s))
+}
+
+// matches reports whether a member is selected by the source's glob.
+func (s *Source) matches(m member) bool {
+	if s.glob == "" {
+		return true
+	}
+	ok, _ := path.Match(s.glob, m.name)
+	return ok
+}
+
+// Query returns log entries matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	var results []source.Entry
+	members := 0
+
+	err := s.walk(ctx, s.matches, func(m member, r io.Reader, parser local.Parser) error {
+		members++
+		scanner := local.NewEntryScanner(r, parser, m.name)
+		for scanner.Scan(ctx) {
+			entry := s.convertEntry(scanner.Entry(), m.name, scanner.Line())
+			if local.MatchesParams(entry, params) {
+				results = append(results, entry)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error reading %s: %w", s.path, err)
+	}
+	if members == 0 && s.glob != "" {
+		return nil, fmt.Errorf("no members of %s match %q", s.path, s.glob)
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// convertEntry replaces the file-based stream, source and pointer set by the
+// parser with the member path, archive and archive pointer. The full member
+// path is kept as the stream, as bundles often hold logs with the same name
+// from several hosts or services.
+func (s *Source) convertEntry(entry source.Entry, name string, lineNum int) source.Entry {
+	entry.Stream = name
+	entry.Source = s.path
+	entry.Ptr = source.MakeArchivePtr(s.path, name, lineNum)
+	return entry
+}
+
+// Tail is not supported for archives, which are not appended to.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	return nil, fmt.Errorf("streaming tail not supported for archive sources")
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseArchivePtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid archive pointer: %s", ptr)
+	}
+	if info.ArchivePath != s.path {
+		return nil, fmt.Errorf("pointer %s is not in %s", ptr, s.path)
+	}
+
+	var entry *source.Entry
+	found := false
+	err := s.walk(ctx, isMember(info.Member), func(m member, r io.Reader, parser local.Parser) error {
+		found = true
+		// Scan entries from the start so multiline entries are assembled
+		// the same way Query produced them
+		scanner := local.NewEntryScanner(r, parser, m.name)
+		for scanner.Scan(ctx) {
+			if scanner.Line() == info.LineNum {
+				e := s.convertEntry(scanner.Entry(), m.name, info.LineNum)
+				entry = &e
+				return errStopWalk
+			}
+			if scanner.Line() > info.LineNum {
+				return fmt.Errorf("no log entry starts at line %d in %s", info.LineNum, m.name)
+			}
+		}
+		if scanner.Err() != nil {
+			return scanner.Err()
+		}
+		return fmt.Errorf("line %d not found in %s", info.LineNum, m.name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s not found in %s", info.Member, s.path)
+	}
+
+	return entry, nil
+}
+
+// isMember returns a match function selecting a single member.
+func isMember(name string) func(member) bool {
+	return func(m member) bool {
+		return m.name == name
+	}
+}
+
+// FetchContext retrieves context lines around a log entry.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseArchivePtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid archive pointer: %s", entry.Ptr)
+	}
+
+	var beforeLines, afterLines []source.Event
+	found := false
+
+	err := s.walk(ctx, isMember(info.Member), func(m member, r io.Reader, _ local.Parser) error {
+		lines := local.NewLineReader(r)
+		for lineNum := 1; lineNum <= info.LineNum+after; lineNum++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			line, _, err := lines.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case lineNum < info.LineNum:
+				// Keep only the last N lines before the target
+				if before > 0 {
+					if len(beforeLines) == before {
+						beforeLines = beforeLines[1:]
+					}
+					beforeLines = append(beforeLines, source.Event{Message: line, Stream: m.name})
+				}
+			case lineNum == info.LineNum:
+				found = true
+			default:
+				afterLines = append(afterLines, source.Event{Message: line, Stream: m.name})
+			}
+		}
+		return errStopWalk
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !found {
+		return nil, nil, fmt.Errorf("line %d out of range", info.LineNum)
+	}
+
+	return beforeLines, afterLines, nil
+}
+
+// ListStreams returns the members of the archive matching the glob.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	var streams []source.StreamInfo
+
+	// Members are listed without being read
+	err := s.walk(ctx, func(m member) bool {
+		if s.matches(m) {
+			streams = append(streams, source.StreamInfo{
+				Name:     m.name,
+				Size:     m.size,
+				LastTime: m.modTime,
+			})
+		}
+		return false
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "archive"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "archive",
+		URI:  s.uri,
+	}
+}
+
+// Close releases any resources held by the source.
+func (s *Source) Close() error {
+	return nil
+}
@@ -0,0 +1,288 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// testMember is a file written into a test archive.
+type testMember struct {
+	name    string
+	content string
+}
+
+var bundleMembers = []testMember{
+	{name: "./manifest.txt", content: "support bundle for node-1\n"},
+	{name: "./var/log/app.log", content: `{"timestamp":"2025-01-15T10:00:00Z","level":"info","message":"starting"}
+{"timestamp":"2025-01-15T10:00:05Z","level":"error","message":"connection refused"}
+{"timestamp":"2025-01-15T10:00:10Z","level":"info","message":"retrying"}
+`},
+	{name: "./var/log/server.log", content: `2025-01-15 10:00:01,000 INFO [main] com.example.Server - listening
+2025-01-15 10:00:06,000 ERROR [pool-1] com.example.Server - request failed
+java.net.ConnectException: connection refused
+	at com.example.Client.call(Client.java:42)
+2025-01-15 10:00:07,000 INFO [main] com.example.Server - recovered
+`},
+	{name: "./var/log/syslog.1.gz", content: "Jan 15 10:00:02 node-1 kernel: eth0 link up\nJan 15 10:00:03 node-1 sshd[42]: connection refused for admin\n"},
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// memberData returns a member's bytes, compressing .gz members.
+func memberData(t *testing.T, m testMember) []byte {
+	if strings.HasSuffix(m.name, ".gz") {
+		return gzipped(t, m.content)
+	}
+	return []byte(m.content)
+}
+
+// writeTarGz writes members to a gzip-compressed tar file, with a directory entry.
+func writeTarGz(t *testing.T, members []testMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	modTime := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	if err := tw.WriteHeader(&tar.Header{Name: "./var/log/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range members {
+		data := memberData(t, m)
+		if err := tw.WriteHeader(&tar.Header{Name: m.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data)), ModTime: modTime}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "support-bundle.tar.gz")
+	if err := os.WriteFile(path, gzipped(t, buf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeZip writes members to a zip file.
+func writeZip(t *testing.T, members []testMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		w, err := zw.Create(strings.TrimPrefix(m.name, "./"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(memberData(t, m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "support-bundle.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSource_Query(t *testing.T) {
+	for _, kind := range []string{"tar.gz", "zip"} {
+		t.Run(kind, func(t *testing.T) {
+			path := writeTarGz(t, bundleMembers)
+			if kind == "zip" {
+				path = writeZip(t, bundleMembers)
+			}
+
+			src, err := source.Open("archive://" + path + "?glob=var/log/*")
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			results, err := src.Query(context.Background(), source.QueryParams{
+				Filter: regexp.MustCompile("(?i)refused|failed"),
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+
+			// Each member is parsed in its own format, newest first (syslog
+			// timestamps have no year, so they are taken as this year's)
+			want := []struct {
+				stream  string
+				message string
+			}{
+				{"var/log/syslog.1.gz", "connection refused for admin"},
+				{"var/log/server.log", "request failed\njava.net.ConnectException: connection refused\n\tat com.example.Client.call(Client.java:42)"},
+				{"var/log/app.log", "connection refused"},
+			}
+			if len(results) != len(want) {
+				t.Fatalf("expected %d results, got %d: %+v", len(want), len(results), results)
+			}
+			for i, w := range want {
+				if results[i].Stream != w.stream {
+					t.Errorf("result %d: expected stream %q, got %q", i, w.stream, results[i].Stream)
+				}
+				if !strings.Contains(results[i].Message, w.message) {
+					t.Errorf("result %d: expected message containing %q, got %q", i, w.message, results[i].Message)
+				}
+				if results[i].Source != path {
+					t.Errorf("result %d: expected source %q, got %q", i, path, results[i].Source)
+				}
+			}
+			if results[2].Fields["level"] != "error" {
+				t.Errorf("expected JSON fields for app.log, got %v", results[2].Fields)
+			}
+			if want := source.MakeArchivePtr(path, "var/log/server.log", 2); results[1].Ptr != want {
+				t.Errorf("expected ptr %q, got %q", want, results[1].Ptr)
+			}
+		})
+	}
+}
+
+func TestSource_GetRecordAndContext(t *testing.T) {
+	path := writeTarGz(t, bundleMembers)
+	src, err := source.Open("archive://" + path + "?glob=var/log/*.log")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ctx := context.Background()
+
+	results, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("request failed"), Context: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if len(results[0].Context.Before) != 1 || len(results[0].Context.After) != 1 {
+		t.Errorf("expected 1 context line each side, got %+v", results[0].Context)
+	}
+
+	// Pointers reopen the archive with the source's glob
+	ptrSrc, err := source.OpenFromPtr(results[0].Ptr, &source.SourceMetadata{URI: src.Metadata().URI})
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	entry, err := ptrSrc.GetRecord(ctx, results[0].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != results[0].Message || entry.Stream != "var/log/server.log" {
+		t.Errorf("unexpected record %+v", entry)
+	}
+
+	before, after, err := ptrSrc.FetchContext(ctx, *entry, 5, 5)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || len(after) != 3 {
+		t.Errorf("expected 1 before and 3 after, got %d and %d", len(before), len(after))
+	}
+	if after[0].Stream != "var/log/server.log" {
+		t.Errorf("expected context stream var/log/server.log, got %q", after[0].Stream)
+	}
+
+	// Continuation lines do not start entries
+	if _, err := ptrSrc.GetRecord(ctx, source.MakeArchivePtr(path, "var/log/server.log", 3)); err == nil {
+		t.Error("expected an error for a continuation line")
+	}
+	if _, err := ptrSrc.GetRecord(ctx, source.MakeArchivePtr(path, "var/log/missing.log", 1)); err == nil {
+		t.Error("expected an error for a missing member")
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	path := writeZip(t, bundleMembers)
+	src, err := NewSource(path, "var/log/*.log", "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 2 || streams[0].Name != "var/log/app.log" || streams[1].Name != "var/log/server.log" {
+		t.Fatalf("unexpected streams %+v", streams)
+	}
+	if streams[0].Size != int64(len(bundleMembers[1].content)) {
+		t.Errorf("expected size %d, got %d", len(bundleMembers[1].content), streams[0].Size)
+	}
+}
+
+func TestOpenSource(t *testing.T) {
+	bundle := writeTarGz(t, bundleMembers)
+	notArchive := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(notArchive, []byte("just a log line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		uri      string
+		wantURI  string
+		wantErr  bool
+		queryErr string
+	}{
+		{name: "all members", uri: "archive://" + bundle, wantURI: "archive://" + bundle},
+		{name: "glob and format", uri: "archive://" + bundle + "?glob=/var/log/*.log&format=json", wantURI: "archive://" + bundle + "?glob=var/log/*.log&format=json"},
+		{name: "no path", uri: "archive://", wantErr: true},
+		{name: "missing archive", uri: "archive:///no/such/bundle.tar.gz", wantErr: true},
+		{name: "bad glob", uri: "archive://" + bundle + "?glob=[", wantErr: true},
+		{name: "glob matches nothing", uri: "archive://" + bundle + "?glob=opt/*", wantURI: "archive://" + bundle + "?glob=opt/*", queryErr: "no members"},
+		{name: "not an archive", uri: "archive://" + notArchive, wantURI: "archive://" + notArchive, queryErr: "not a tar or zip archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if got := src.Metadata().URI; got != tt.wantURI {
+				t.Errorf("expected URI %q, got %q", tt.wantURI, got)
+			}
+
+			_, err = src.Query(context.Background(), source.QueryParams{})
+			if tt.queryErr == "" && err != nil {
+				t.Errorf("Query failed: %v", err)
+			}
+			if tt.queryErr != "" && (err == nil || !strings.Contains(err.Error(), tt.queryErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.queryErr, err)
+			}
+		})
+	}
+}
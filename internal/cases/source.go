@@ -0,0 +1,291 @@
+package cases
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/source"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	source.Register("case", openSource)
+}
+
+// Source implements source.Source over the evidence of a case, read either
+// from the case directory or from a zip written by `case export`. Entries
+// keep the pointers, timestamps and fields the evidence was collected with,
+// so they can be filtered, counted and exported like any other logs.
+type Source struct {
+	caseID string
+	items  []EvidenceItem // oldest first
+	uri    string
+}
+
+// openSource opens a case source from a parsed URL: case://<case-id> for a
+// saved case, or case:///path/export.zip for an exported one.
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	if u.Host != "" {
+		mgr, err := NewManager()
+		if err != nil {
+			return nil, err
+		}
+		return openCase(context.Background(), mgr, u.Host)
+	}
+
+	exportPath := u.Path
+	if exportPath == "" {
+		return nil, fmt.Errorf("case:// URI requires a case ID or export path (e.g., case://2024-03-01-login-spike or case:///tmp/case.zip)")
+	}
+
+	// Expand ~ to home directory
+	if strings.HasPrefix(exportPath, "/~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			exportPath = filepath.Join(home, exportPath[3:])
+		}
+	}
+
+	c, err := readExport(exportPath)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(c, (&url.URL{Scheme: "case", Path: exportPath}).String()), nil
+}
+
+// openCase opens the evidence of a saved case.
+func openCase(ctx context.Context, mgr *Manager, id string) (*Source, error) {
+	c, err := mgr.LoadCase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(c, "case://"+id), nil
+}
+
+// readExport reads the case from a zip written by `case export`.
+func readExport(path string) (*Case, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open case export: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	f, err := zr.Open("case.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a case export: no case.yaml", path)
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read case.yaml: %w", err)
+	}
+
+	var c Case
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse case.yaml: %w", err)
+	}
+	return &c, nil
+}
+
+// newSource creates a source for the evidence of a case.
+func newSource(c *Case, uri string) *Source {
+	items := make([]EvidenceItem, len(c.Evidence))
+	copy(items, c.Evidence)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Timestamp.Before(items[j].Timestamp)
+	})
+
+	return &Source{
+		caseID: c.ID,
+		items:  items,
+		uri:    uri,
+	}
+}
+
+// entry converts an evidence item into a log entry. The source and stream
+// are those the evidence was collected from, and the annotation, if any, is
+// added to the raw fields.
+func (s *Source) entry(item EvidenceItem) source.Entry {
+	entry := source.Entry{
+		Timestamp: item.Timestamp,
+		Message:   item.Message,
+		Stream:    item.Stream,
+		Source:    item.SourceURI,
+		Ptr:       item.Ptr,
+		Fields:    make(map[string]string, len(item.RawFields)+1),
+	}
+
+	// Prefer SourceURI and Stream over deprecated LogGroup and LogStream
+	if entry.Stream == "" {
+		entry.Stream = item.LogStream
+	}
+	if entry.Source == "" {
+		entry.Source = item.LogGroup
+	}
+
+	for k, v := range item.RawFields {
+		entry.Fields[k] = v
+	}
+	if item.Annotation != "" {
+		if _, ok := entry.Fields["annotation"]; !ok {
+			entry.Fields["annotation"] = item.Annotation
+		}
+	}
+
+	return entry
+}
+
+// event converts an evidence item into a context event.
+func (s *Source) event(item EvidenceItem) source.Event {
+	e := s.entry(item)
+	return source.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream}
+}
+
+// matches reports whether an entry is within the time range and matches the
+// filter. Evidence without a timestamp is not filtered by time.
+func matches(entry source.Entry, params source.QueryParams) bool {
+	if !entry.Timestamp.IsZero() {
+		if !params.StartTime.IsZero() && entry.Timestamp.Before(params.StartTime) {
+			return false
+		}
+		if !params.EndTime.IsZero() && entry.Timestamp.After(params.EndTime) {
+			return false
+		}
+	}
+	return params.Filter == nil || params.Filter.MatchString(entry.Message)
+}
+
+// Query returns the evidence matching the given parameters, newest first.
+// Context lines are the evidence collected before and after each item.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []source.Entry
+	for i := len(s.items) - 1; i >= 0; i-- {
+		entry := s.entry(s.items[i])
+		if !matches(entry, params) {
+			continue
+		}
+		if params.Context > 0 {
+			before, after := s.neighbours(i, params.Context, params.Context)
+			entry.Context = source.EntryContext{Before: before, After: after}
+		}
+		results = append(results, entry)
+		if params.Limit > 0 && len(results) == params.Limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// neighbours returns the evidence before and after the item at index i.
+func (s *Source) neighbours(i, before, after int) ([]source.Event, []source.Event) {
+	var beforeEvents, afterEvents []source.Event
+	for j := max(0, i-before); j < i; j++ {
+		beforeEvents = append(beforeEvents, s.event(s.items[j]))
+	}
+	for j := i + 1; j < len(s.items) && j <= i+after; j++ {
+		afterEvents = append(afterEvents, s.event(s.items[j]))
+	}
+	return beforeEvents, afterEvents
+}
+
+// index returns the index of the evidence item with a pointer, or -1.
+func (s *Source) index(ptr string) int {
+	for i, item := range s.items {
+		if item.Ptr == ptr {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tail is not supported for cases, whose evidence is collected by hand.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	return nil, fmt.Errorf("streaming tail not supported for case sources")
+}
+
+// GetRecord retrieves the evidence item with the given pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	i := s.index(ptr)
+	if i < 0 {
+		return nil, fmt.Errorf("evidence with @ptr %s not found in case %q", ptr, s.caseID)
+	}
+	entry := s.entry(s.items[i])
+	return &entry, nil
+}
+
+// FetchContext retrieves the evidence collected around an entry.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	i := s.index(entry.Ptr)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("evidence with @ptr %s not found in case %q", entry.Ptr, s.caseID)
+	}
+	beforeEvents, afterEvents := s.neighbours(i, before, after)
+	return beforeEvents, afterEvents, nil
+}
+
+// ListStreams returns the streams evidence was collected from, with the
+// number of items from each as the size.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	var streams []source.StreamInfo
+	byName := make(map[string]int)
+
+	for _, item := range s.items {
+		entry := s.entry(item)
+		i, ok := byName[entry.Stream]
+		if !ok {
+			i = len(streams)
+			byName[entry.Stream] = i
+			streams = append(streams, source.StreamInfo{Name: entry.Stream, FirstTime: entry.Timestamp})
+		}
+		streams[i].Size++
+		streams[i].LastTime = entry.Timestamp
+	}
+
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "case"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "case",
+		URI:  s.uri,
+	}
+}
+
+// EntryMetadata returns the metadata of the source an evidence item was
+// collected from, so its pointer is cached with the source that resolves it.
+func (s *Source) EntryMetadata(entry source.Entry) source.SourceMetadata {
+	i := s.index(entry.Ptr)
+	if i < 0 {
+		return s.Metadata()
+	}
+	item := s.items[i]
+	return source.SourceMetadata{
+		Type:      item.SourceType,
+		URI:       item.SourceURI,
+		Profile:   item.Profile,
+		AccountID: item.AccountID,
+	}
+}
+
+// Close releases any resources held by the source.
+func (s *Source) Close() error {
+	return nil
+}
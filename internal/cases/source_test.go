@@ -0,0 +1,208 @@
+package cases
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+	"gopkg.in/yaml.v3"
+)
+
+// newEvidenceCase returns a case holding three evidence items from two
+// sources, collected out of timestamp order.
+func newEvidenceCase() *Case {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	return &Case{
+		ID:     "login-spike",
+		Title:  "Login spike",
+		Status: StatusActive,
+		Evidence: []EvidenceItem{
+			{
+				Ptr:        "file:///var/log/auth.log#12",
+				Message:    "Failed password for root from 198.51.100.7",
+				Timestamp:  base.Add(2 * time.Minute),
+				SourceURI:  "file:///var/log/auth.log",
+				SourceType: "local",
+				Stream:     "auth.log",
+				Annotation: "first brute force attempt",
+				RawFields:  map[string]string{"user": "root"},
+			},
+			{
+				Ptr:        "CmAKJgoiMTIzNDU2Nzg5MDEyOi9hcHAvYXBpEAAaBwiAgICAgAEQAw",
+				Message:    "ERROR login rate limit exceeded",
+				Timestamp:  base,
+				SourceURI:  "cloudwatch:///app/api",
+				SourceType: "cloudwatch",
+				Stream:     "api-1",
+				Profile:    "prod",
+				AccountID:  "123456789012",
+				RawFields:  map[string]string{"level": "ERROR"},
+			},
+			{
+				Ptr:        "file:///var/log/auth.log#40",
+				Message:    "Accepted password for root from 198.51.100.7",
+				Timestamp:  base.Add(5 * time.Minute),
+				SourceURI:  "file:///var/log/auth.log",
+				SourceType: "local",
+				Stream:     "auth.log",
+			},
+		},
+	}
+}
+
+func TestSource_Query(t *testing.T) {
+	mgr := newTestManager(t)
+	if err := mgr.EnsureDirectories(testCtx()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SaveCase(testCtx(), newEvidenceCase()); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := openCase(testCtx(), mgr, "login-spike")
+	if err != nil {
+		t.Fatalf("openCase failed: %v", err)
+	}
+	if meta := src.Metadata(); meta.Type != "case" || meta.URI != "case://login-spike" {
+		t.Errorf("Metadata = %+v", meta)
+	}
+
+	entries, err := src.Query(testCtx(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	// Newest first, whatever order the evidence was collected in
+	if entries[0].Ptr != "file:///var/log/auth.log#40" || entries[2].Stream != "api-1" {
+		t.Errorf("entries out of order: %+v", entries)
+	}
+
+	e := entries[1]
+	if e.Source != "file:///var/log/auth.log" || e.Stream != "auth.log" || e.Ptr != "file:///var/log/auth.log#12" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Fields["user"] != "root" || e.Fields["annotation"] != "first brute force attempt" {
+		t.Errorf("Fields = %v", e.Fields)
+	}
+
+	filtered, err := src.Query(testCtx(), source.QueryParams{
+		Filter:    regexp.MustCompile(`password`),
+		StartTime: time.Date(2024, 3, 1, 10, 3, 0, 0, time.UTC),
+		Context:   1,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(filtered) != 1 || !strings.HasPrefix(filtered[0].Message, "Accepted") {
+		t.Fatalf("filtered = %+v", filtered)
+	}
+	if before := filtered[0].Context.Before; len(before) != 1 || !strings.HasPrefix(before[0].Message, "Failed") {
+		t.Errorf("Context.Before = %+v", before)
+	}
+
+	limited, err := src.Query(testCtx(), source.QueryParams{Limit: 2})
+	if err != nil || len(limited) != 2 {
+		t.Errorf("limited = %d entries, %v", len(limited), err)
+	}
+}
+
+func TestSource_Records(t *testing.T) {
+	src := newSource(newEvidenceCase(), "case://login-spike")
+
+	record, err := src.GetRecord(testCtx(), "file:///var/log/auth.log#12")
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if record.Fields["user"] != "root" {
+		t.Errorf("GetRecord = %+v", record)
+	}
+	if _, err := src.GetRecord(testCtx(), "file:///var/log/auth.log#13"); err == nil {
+		t.Error("GetRecord should fail for pointers not in the case")
+	}
+
+	before, after, err := src.FetchContext(testCtx(), *record, 5, 5)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || before[0].Stream != "api-1" || len(after) != 1 || after[0].Stream != "auth.log" {
+		t.Errorf("context = %+v, %+v", before, after)
+	}
+
+	streams, err := src.ListStreams(testCtx())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 2 || streams[0].Name != "api-1" || streams[1].Name != "auth.log" || streams[1].Size != 2 {
+		t.Errorf("streams = %+v", streams)
+	}
+
+	// Pointers are cached with the source the evidence came from
+	cw, _ := src.GetRecord(testCtx(), "CmAKJgoiMTIzNDU2Nzg5MDEyOi9hcHAvYXBpEAAaBwiAgICAgAEQAw")
+	meta := src.EntryMetadata(*cw)
+	if meta.Type != "cloudwatch" || meta.URI != "cloudwatch:///app/api" || meta.Profile != "prod" || meta.AccountID != "123456789012" {
+		t.Errorf("EntryMetadata = %+v", meta)
+	}
+
+	if _, err := src.Tail(testCtx(), source.TailParams{}); err == nil {
+		t.Error("Tail should fail for case sources")
+	}
+}
+
+func TestOpenSource_Export(t *testing.T) {
+	data, err := yaml.Marshal(newEvidenceCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "login-spike.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("case.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := source.Open("case://" + path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if src.Metadata().URI != "case://"+path {
+		t.Errorf("URI = %q", src.Metadata().URI)
+	}
+	entries, err := src.Query(testCtx(), source.QueryParams{})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Query = %d entries, %v", len(entries), err)
+	}
+
+	notExport := filepath.Join(t.TempDir(), "other.zip")
+	f, err = os.Create(notExport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zip.NewWriter(f).Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	if _, err := source.Open("case://" + notExport); err == nil || !strings.Contains(err.Error(), "not a case export") {
+		t.Errorf("error = %v, want not a case export", err)
+	}
+}
@@ -0,0 +1,223 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limits for resolving multiple log groups
+const (
+	// MaxQueryLogGroups is the most log groups AWS allows in one Insights query
+	MaxQueryLogGroups = 50
+
+	// MaxResolvedLogGroups caps how many log groups a pattern, prefix or tag
+	// may resolve to
+	MaxResolvedLogGroups = 1000
+
+	// MaxConcurrentQueries limits Insights queries run at once when the log
+	// groups are split into batches
+	MaxConcurrentQueries = 5
+
+	// MaxConcurrentTagLookups limits parallel tag lookups when selecting log groups by tag
+	MaxConcurrentTagLookups = 10
+)
+
+// groupSelector selects the log groups a source queries. A selector with no
+// fields set means the source's single log group.
+type groupSelector struct {
+	names    []string // Explicit list from ?groups=a,b,c
+	pattern  string   // Glob from the URI path, e.g. /app/*
+	prefix   string   // Name prefix from ?prefix=
+	tagKey   string   // Tag from ?tag=key:value (or ?tag=key for any value)
+	tagValue string
+}
+
+// parseGroupSelector reads a group selector from a cloudwatch URI path and
+// query. A path without wildcards and no selector parameters is a single group.
+func parseGroupSelector(logGroup string, query url.Values) (groupSelector, error) {
+	var sel groupSelector
+
+	if groups := query.Get("groups"); groups != "" {
+		for _, name := range strings.Split(groups, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				sel.names = append(sel.names, name)
+			}
+		}
+	}
+	sel.prefix = query.Get("prefix")
+	if tag := query.Get("tag"); tag != "" {
+		sel.tagKey, sel.tagValue, _ = strings.Cut(tag, ":")
+	}
+	if strings.ContainsAny(logGroup, "*?[") {
+		sel.pattern = logGroup
+	} else if logGroup != "" && sel.multi() {
+		return groupSelector{}, fmt.Errorf("cloudwatch URI cannot combine log group %s with groups, prefix or tag parameters", logGroup)
+	}
+
+	if len(sel.names) > 0 && (sel.pattern != "" || sel.prefix != "" || sel.tagKey != "") {
+		return groupSelector{}, fmt.Errorf("cloudwatch ?groups= cannot be combined with a pattern, prefix or tag")
+	}
+	if sel.pattern != "" {
+		if _, err := path.Match(sel.pattern, ""); err != nil {
+			return groupSelector{}, fmt.Errorf("invalid log group pattern %q: %w", sel.pattern, err)
+		}
+	}
+
+	return sel, nil
+}
+
+// multi reports whether the selector selects groups other than a single named group.
+func (g groupSelector) multi() bool {
+	return len(g.names) > 0 || g.pattern != "" || g.prefix != "" || g.tagKey != ""
+}
+
+// String returns the selector in URI form (path and query), so a source
+// can be reopened from its metadata.
+func (g groupSelector) String() string {
+	query := url.Values{}
+	if len(g.names) > 0 {
+		query.Set("groups", strings.Join(g.names, ","))
+	}
+	if g.prefix != "" {
+		query.Set("prefix", g.prefix)
+	}
+	if g.tagKey != "" {
+		tag := g.tagKey
+		if g.tagValue != "" {
+			tag += ":" + g.tagValue
+		}
+		query.Set("tag", tag)
+	}
+	if len(query) == 0 {
+		return g.pattern
+	}
+	return g.pattern + "?" + query.Encode()
+}
+
+// listPrefix returns the name prefix to list candidate groups with.
+func (g groupSelector) listPrefix() string {
+	if g.prefix != "" {
+		return g.prefix
+	}
+	if i := strings.IndexAny(g.pattern, "*?[\\"); i >= 0 {
+		return g.pattern[:i]
+	}
+	return g.pattern
+}
+
+// matches reports whether a group name is selected by the prefix and pattern.
+func (g groupSelector) matches(name string) bool {
+	if g.prefix != "" && !strings.HasPrefix(name, g.prefix) {
+		return false
+	}
+	if g.pattern != "" {
+		ok, _ := path.Match(g.pattern, name)
+		return ok
+	}
+	return true
+}
+
+// resolveLogGroups returns the names of the log groups the selector selects,
+// sorted by name.
+func resolveLogGroups(ctx context.Context, client LogsClient, sel groupSelector) ([]string, error) {
+	if len(sel.names) > 0 {
+		return sel.names, nil
+	}
+
+	candidates, err := client.ListLogGroups(ctx, sel.listPrefix(), MaxResolvedLogGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []LogGroupInfo
+	for _, g := range candidates {
+		if sel.matches(g.Name) {
+			matched = append(matched, g)
+		}
+	}
+
+	if sel.tagKey != "" {
+		matched, err = filterByTag(ctx, client, matched, sel.tagKey, sel.tagValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no log groups match %s", sel)
+	}
+
+	names := make([]string, len(matched))
+	for i, g := range matched {
+		names[i] = g.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// filterByTag returns the groups tagged with key, and with value if it is not empty.
+func filterByTag(ctx context.Context, client LogsClient, groups []LogGroupInfo, key, value string) ([]LogGroupInfo, error) {
+	sem := make(chan struct{}, MaxConcurrentTagLookups)
+	tagged := make([]bool, len(groups))
+	errs := make([]error, len(groups))
+
+	var wg sync.WaitGroup
+	for i, g := range groups {
+		wg.Add(1)
+		go func(i int, arn string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			tags, err := client.ListLogGroupTags(ctx, arn)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			v, ok := tags[key]
+			tagged[i] = ok && (value == "" || v == value)
+		}(i, g.Arn)
+	}
+	wg.Wait()
+
+	var result []LogGroupInfo
+	for i, g := range groups {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to read tags of %s: %w", g.Name, errs[i])
+		}
+		if tagged[i] {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+// batchLogGroups splits groups into batches small enough for one Insights query.
+func batchLogGroups(groups []string) [][]string {
+	var batches [][]string
+	for len(groups) > MaxQueryLogGroups {
+		batches = append(batches, groups[:MaxQueryLogGroups])
+		groups = groups[MaxQueryLogGroups:]
+	}
+	return append(batches, groups)
+}
+
+// groupFromLogField returns the log group name from an Insights @log value,
+// which is prefixed with the account ID (e.g. "123456789012:/app/logs").
+func groupFromLogField(log string) string {
+	if _, name, ok := strings.Cut(log, ":"); ok {
+		return name
+	}
+	return log
+}
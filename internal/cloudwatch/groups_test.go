@@ -0,0 +1,258 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+func TestParseGroupSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		want      groupSelector
+		wantMulti bool
+		wantErr   bool
+	}{
+		{
+			name: "single group",
+			uri:  "cloudwatch:///app/logs",
+		},
+		{
+			name:      "wildcard",
+			uri:       "cloudwatch:///app/*",
+			want:      groupSelector{pattern: "/app/*"},
+			wantMulti: true,
+		},
+		{
+			name:      "list",
+			uri:       "cloudwatch://?groups=/app/api,%20/app/worker",
+			want:      groupSelector{names: []string{"/app/api", "/app/worker"}},
+			wantMulti: true,
+		},
+		{
+			name:      "prefix",
+			uri:       "cloudwatch:///?prefix=/app/",
+			want:      groupSelector{prefix: "/app/"},
+			wantMulti: true,
+		},
+		{
+			name:      "tag with value",
+			uri:       "cloudwatch://?tag=service:api&profile=prod",
+			want:      groupSelector{tagKey: "service", tagValue: "api"},
+			wantMulti: true,
+		},
+		{
+			name:      "tag key only with wildcard",
+			uri:       "cloudwatch:///app/*?tag=team",
+			want:      groupSelector{pattern: "/app/*", tagKey: "team"},
+			wantMulti: true,
+		},
+		{
+			name:    "group with tag",
+			uri:     "cloudwatch:///app/logs?tag=service:api",
+			wantErr: true,
+		},
+		{
+			name:    "list with prefix",
+			uri:     "cloudwatch://?groups=a,b&prefix=/app/",
+			wantErr: true,
+		},
+		{
+			name:    "bad pattern",
+			uri:     "cloudwatch:///app/[*",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatalf("url.Parse failed: %v", err)
+			}
+			logGroup := u.Path
+			if logGroup == "/" {
+				logGroup = ""
+			}
+
+			got, err := parseGroupSelector(logGroup, u.Query())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if got.multi() != tt.wantMulti {
+				t.Errorf("multi() = %v, want %v", got.multi(), tt.wantMulti)
+			}
+
+			// The URI form parses back to the same selector
+			if got.multi() {
+				back, err := url.Parse("cloudwatch://" + got.String())
+				if err != nil {
+					t.Fatalf("url.Parse(%q) failed: %v", got.String(), err)
+				}
+				again, err := parseGroupSelector(back.Path, back.Query())
+				if err != nil || !reflect.DeepEqual(again, got) {
+					t.Errorf("String() = %q parses to %+v, %v", got.String(), again, err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveLogGroups(t *testing.T) {
+	mock := &mockLogsClient{
+		logGroups: []LogGroupInfo{
+			{Name: "/app/worker", Arn: "arn:worker"},
+			{Name: "/app/api", Arn: "arn:api"},
+			{Name: "/app/api/audit", Arn: "arn:audit"},
+			{Name: "/other/api", Arn: "arn:other"},
+		},
+		tags: map[string]map[string]string{
+			"arn:api":   {"service": "api"},
+			"arn:audit": {"service": "audit"},
+			"arn:other": {"service": "api"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		sel     groupSelector
+		want    []string
+		wantErr bool
+	}{
+		{"list", groupSelector{names: []string{"/b", "/a"}}, []string{"/b", "/a"}, false},
+		{"wildcard", groupSelector{pattern: "/app/*"}, []string{"/app/api", "/app/worker"}, false},
+		{"prefix", groupSelector{prefix: "/app/"}, []string{"/app/api", "/app/api/audit", "/app/worker"}, false},
+		{"tag value", groupSelector{tagKey: "service", tagValue: "api"}, []string{"/app/api", "/other/api"}, false},
+		{"tag key", groupSelector{prefix: "/app/", tagKey: "service"}, []string{"/app/api", "/app/api/audit"}, false},
+		{"no match", groupSelector{pattern: "/missing/*"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLogGroups(context.Background(), mock, tt.sel)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_Query_MultipleGroups(t *testing.T) {
+	var groups []LogGroupInfo
+	for i := 0; i < 120; i++ {
+		groups = append(groups, LogGroupInfo{Name: fmt.Sprintf("/svc/%03d", i)})
+	}
+	now := time.Now().UTC()
+	mock := &mockLogsClient{
+		logGroups: groups,
+		queryResults: []LogResult{
+			{
+				Timestamp: now.Format("2006-01-02 15:04:05.000"),
+				LogStream: "stream-1",
+				Message:   "from a batch",
+				Fields:    map[string]string{"@log": "123456789012:/svc/007", "@ptr": "p1"},
+			},
+		},
+	}
+
+	src := NewSourceWithClient("", mock)
+	src.selector = groupSelector{pattern: "/svc/*"}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: now.Add(-time.Hour),
+		EndTime:   now,
+		Limit:     2,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// 120 groups are queried in batches of at most 50
+	if len(mock.queryCalls) != 3 {
+		t.Fatalf("expected 3 queries, got %d", len(mock.queryCalls))
+	}
+	var sizes []int
+	seen := make(map[string]bool)
+	for _, call := range mock.queryCalls {
+		sizes = append(sizes, len(call.LogGroups))
+		for _, g := range call.LogGroups {
+			seen[g] = true
+		}
+		if !strings.Contains(call.Query, "@log") {
+			t.Errorf("query should select @log: %s", call.Query)
+		}
+	}
+	if len(seen) != 120 {
+		t.Errorf("expected all 120 groups queried once, got %d (batch sizes %v)", len(seen), sizes)
+	}
+
+	// Results from all batches are merged and limited
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries after limit, got %d", len(entries))
+	}
+	if entries[0].Source != "/svc/007" {
+		t.Errorf("Source = %q, want group from @log", entries[0].Source)
+	}
+}
+
+func TestMergeBatchResults(t *testing.T) {
+	batches := [][]LogResult{
+		{
+			{Fields: map[string]string{"time_bucket": "2025-01-15 10:05:00.000", "count": "2"}},
+			{Fields: map[string]string{"time_bucket": "2025-01-15 10:00:00.000", "count": "1"}},
+		},
+		{
+			{Fields: map[string]string{"time_bucket": "2025-01-15 10:10:00.000", "count": "4"}},
+			{Fields: map[string]string{"time_bucket": "2025-01-15 10:05:00.000", "count": "3"}},
+		},
+	}
+
+	got := mergeBatchResults(batches, 0)
+
+	want := []struct{ bucket, count string }{
+		{"2025-01-15 10:10:00.000", "4"},
+		{"2025-01-15 10:05:00.000", "5"},
+		{"2025-01-15 10:00:00.000", "1"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rows, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Fields["time_bucket"] != w.bucket || got[i].Fields["count"] != w.count {
+			t.Errorf("row %d = %v, want %s=%s", i, got[i].Fields, w.bucket, w.count)
+		}
+	}
+
+	events := mergeBatchResults([][]LogResult{
+		{{Timestamp: "2025-01-15 10:00:02.000"}, {Timestamp: "2025-01-15 10:00:00.000"}},
+		{{Timestamp: "2025-01-15 10:00:01.000"}},
+	}, 2)
+	if len(events) != 2 || events[0].Timestamp != "2025-01-15 10:00:02.000" || events[1].Timestamp != "2025-01-15 10:00:01.000" {
+		t.Errorf("events = %+v, want two newest", events)
+	}
+}
@@ -27,6 +27,9 @@ const (
 
 	// ContextLookbackWindow is how far back to search for context lines
 	ContextLookbackWindow = 30 * time.Minute
+
+	// MaxDescribeLogGroupsPage is the max page size of the DescribeLogGroups API
+	MaxDescribeLogGroupsPage = 50
 )
 
 // Pre-compiled regexes (avoids repeated compilation)
@@ -46,6 +49,9 @@ type LogsClient interface {
 	// ListLogGroups returns available log groups.
 	ListLogGroups(ctx context.Context, prefix string, limit int) ([]LogGroupInfo, error)
 
+	// ListLogGroupTags returns the tags of a log group, identified by its ARN.
+	ListLogGroupTags(ctx context.Context, logGroupArn string) (map[string]string, error)
+
 	// FilterLogEvents returns log events matching a filter pattern (for tailing).
 	FilterLogEvents(ctx context.Context, logGroup, filter string, startTime, endTime time.Time) ([]TailEvent, error)
 
@@ -81,6 +87,7 @@ func NewClient(client *cloudwatchlogs.Client) *Client {
 // QueryParams holds parameters for running a Logs Insights query.
 type QueryParams struct {
 	LogGroup  string
+	LogGroups []string // Several log groups (names or ARNs), used instead of LogGroup
 	StartTime time.Time
 	EndTime   time.Time
 	Query     string
@@ -114,6 +121,7 @@ type StreamInfo struct {
 // LogGroupInfo represents information about a log group.
 type LogGroupInfo struct {
 	Name          string
+	Arn           string
 	StoredBytes   int64
 	CreationTime  time.Time
 	RetentionDays int
@@ -143,6 +151,7 @@ func (c *Client) GetLogGroup(ctx context.Context, name string) (LogGroupInfo, er
 		if aws.ToString(g.LogGroupName) == name {
 			group := LogGroupInfo{
 				Name: aws.ToString(g.LogGroupName),
+				Arn:  aws.ToString(g.LogGroupArn),
 			}
 			if g.StoredBytes != nil {
 				group.StoredBytes = *g.StoredBytes
@@ -162,8 +171,12 @@ func (c *Client) GetLogGroup(ctx context.Context, name string) (LogGroupInfo, er
 
 // ListLogGroups returns available log groups.
 func (c *Client) ListLogGroups(ctx context.Context, prefix string, limit int) ([]LogGroupInfo, error) {
+	pageSize := limit
+	if pageSize > MaxDescribeLogGroupsPage {
+		pageSize = MaxDescribeLogGroupsPage
+	}
 	input := &cloudwatchlogs.DescribeLogGroupsInput{
-		Limit: aws.Int32(int32(limit)),
+		Limit: aws.Int32(int32(pageSize)),
 	}
 
 	if prefix != "" {
@@ -186,6 +199,7 @@ func (c *Client) ListLogGroups(ctx context.Context, prefix string, limit int) ([
 
 			group := LogGroupInfo{
 				Name: aws.ToString(g.LogGroupName),
+				Arn:  aws.ToString(g.LogGroupArn),
 			}
 
 			if g.StoredBytes != nil {
@@ -205,6 +219,17 @@ func (c *Client) ListLogGroups(ctx context.Context, prefix string, limit int) ([
 	return groups, nil
 }
 
+// ListLogGroupTags returns the tags of a log group, identified by its ARN.
+func (c *Client) ListLogGroupTags(ctx context.Context, logGroupArn string) (map[string]string, error) {
+	result, err := c.client.ListTagsForResource(ctx, &cloudwatchlogs.ListTagsForResourceInput{
+		ResourceArn: &logGroupArn,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log group tags: %w", err)
+	}
+	return result.Tags, nil
+}
+
 // FilterLogEvents returns log events matching a filter pattern (for tailing).
 func (c *Client) FilterLogEvents(ctx context.Context, logGroup, filter string, startTime, endTime time.Time) ([]TailEvent, error) {
 	input := &cloudwatchlogs.FilterLogEventsInput{
@@ -269,13 +294,25 @@ func convertToFilterPattern(filter string) string {
 
 // RunInsightsQuery executes a Logs Insights query and returns the results.
 func (c *Client) RunInsightsQuery(ctx context.Context, params QueryParams) ([]LogResult, error) {
-	startQuery, err := c.client.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
-		LogGroupName: &params.LogGroup,
-		StartTime:    aws.Int64(params.StartTime.Unix()),
-		EndTime:      aws.Int64(params.EndTime.Unix()),
-		QueryString:  &params.Query,
-		Limit:        aws.Int32(int32(params.Limit)),
-	})
+	input := &cloudwatchlogs.StartQueryInput{
+		StartTime:   aws.Int64(params.StartTime.Unix()),
+		EndTime:     aws.Int64(params.EndTime.Unix()),
+		QueryString: &params.Query,
+		Limit:       aws.Int32(int32(params.Limit)),
+	}
+
+	// Several groups are passed by name, or as identifiers if any is an ARN
+	// (needed for groups in linked accounts)
+	switch {
+	case len(params.LogGroups) == 0:
+		input.LogGroupName = &params.LogGroup
+	case hasLogGroupArn(params.LogGroups):
+		input.LogGroupIdentifiers = params.LogGroups
+	default:
+		input.LogGroupNames = params.LogGroups
+	}
+
+	startQuery, err := c.client.StartQuery(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start query: %w", err)
 	}
@@ -314,6 +351,16 @@ func (c *Client) RunInsightsQuery(ctx context.Context, params QueryParams) ([]Lo
 	}
 }
 
+// hasLogGroupArn reports whether any of the log groups is given as an ARN.
+func hasLogGroupArn(groups []string) bool {
+	for _, g := range groups {
+		if strings.HasPrefix(g, "arn:") {
+			return true
+		}
+	}
+	return false
+}
+
 // parseResults converts AWS SDK results to our LogResult type.
 func parseResults(results [][]types.ResultField) []LogResult {
 	var logResults []LogResult
@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmurray2011/clew/internal/logging"
@@ -34,9 +38,13 @@ func init() {
 	source.Register("cloudwatch", openSource)
 }
 
-// Source implements source.Source for AWS CloudWatch Logs.
+// Source implements source.Source for AWS CloudWatch Logs. A source reads
+// either one log group or several, selected by a pattern, prefix, list or tag.
 type Source struct {
-	logGroup  string
+	logGroup  string        // Log group name, or the selector in URI form
+	selector  groupSelector // Selects several log groups, if set
+	groups    []string      // Resolved log groups, set on first use
+	groupsMu  sync.Mutex
 	client    LogsClient
 	profile   string
 	region    string
@@ -87,9 +95,22 @@ func NewSourceWithClient(logGroup string, client LogsClient) *Source {
 }
 
 // openSource is the SourceOpener for the cloudwatch scheme.
+// Besides a single log group, the URI may select several groups:
+//   - cloudwatch:///app/* (glob pattern)
+//   - cloudwatch://?groups=/app/api,/app/worker
+//   - cloudwatch://?prefix=/app/
+//   - cloudwatch://?tag=service:api (or ?tag=service for any value)
 func openSource(u *url.URL, opts source.OpenOptions) (source.Source, error) {
 	logGroup := u.Path
-	if logGroup == "" {
+	if logGroup == "/" {
+		logGroup = ""
+	}
+
+	selector, err := parseGroupSelector(logGroup, u.Query())
+	if err != nil {
+		return nil, err
+	}
+	if logGroup == "" && !selector.multi() {
 		return nil, fmt.Errorf("cloudwatch URI requires a log group path")
 	}
 
@@ -104,7 +125,15 @@ func openSource(u *url.URL, opts source.OpenOptions) (source.Source, error) {
 		region = r
 	}
 
-	return NewSource(logGroup, profile, region)
+	s, err := NewSource(logGroup, profile, region)
+	if err != nil {
+		return nil, err
+	}
+	if selector.multi() {
+		s.selector = selector
+		s.logGroup = selector.String()
+	}
+	return s, nil
 }
 
 // Query returns log entries matching the given parameters.
@@ -128,14 +157,20 @@ func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source
 		Limit:     params.Limit,
 	}
 
-	results, err := s.client.RunInsightsQuery(ctx, cwParams)
+	var results []LogResult
+	var err error
+	if s.selector.multi() {
+		results, err = s.queryGroups(ctx, cwParams)
+	} else {
+		results, err = s.client.RunInsightsQuery(ctx, cwParams)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Fetch context if requested
 	if params.Context > 0 {
-		results, err = s.client.FetchContext(ctx, s.logGroup, results, params.Context)
+		results, err = s.fetchResultsContext(ctx, results, params.Context)
 		if err != nil {
 			return nil, err
 		}
@@ -145,8 +180,135 @@ func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source
 	return s.convertResults(results), nil
 }
 
-// Tail streams log events in real-time.
+// queryGroups runs the query against all selected log groups. Groups beyond
+// the per-query limit are queried in concurrent batches and the results merged.
+func (s *Source) queryGroups(ctx context.Context, params QueryParams) ([]LogResult, error) {
+	groups, err := s.LogGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := batchLogGroups(groups)
+	batchResults := make([][]LogResult, len(batches))
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, MaxConcurrentQueries)
+
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			batchParams := params
+			batchParams.LogGroups = batch
+			batchResults[i], errs[i] = s.client.RunInsightsQuery(ctx, batchParams)
+		}(i, batch)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			if len(batches) == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("query of log groups %d-%d failed: %w", i*MaxQueryLogGroups+1, i*MaxQueryLogGroups+len(batches[i]), err)
+		}
+	}
+
+	if len(batchResults) == 1 {
+		return batchResults[0], nil
+	}
+	return mergeBatchResults(batchResults, params.Limit), nil
+}
+
+// fetchResultsContext fetches context lines for results, per log group.
+func (s *Source) fetchResultsContext(ctx context.Context, results []LogResult, contextLines int) ([]LogResult, error) {
+	if !s.selector.multi() {
+		return s.client.FetchContext(ctx, s.logGroup, results, contextLines)
+	}
+
+	byGroup := make(map[string][]int)
+	for i, r := range results {
+		group := groupFromLogField(r.Fields["@log"])
+		byGroup[group] = append(byGroup[group], i)
+	}
+
+	for group, indexes := range byGroup {
+		if group == "" {
+			continue
+		}
+		groupResults := make([]LogResult, len(indexes))
+		for j, i := range indexes {
+			groupResults[j] = results[i]
+		}
+		groupResults, err := s.client.FetchContext(ctx, group, groupResults, contextLines)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range indexes {
+			results[i] = groupResults[j]
+		}
+	}
+
+	return results, nil
+}
+
+// mergeBatchResults merges the results of queries over batches of log
+// groups. Log events are merged newest first; stats rows from a
+// `stats count() ... by time_bucket` query are summed per bucket.
+func mergeBatchResults(batches [][]LogResult, limit int) []LogResult {
+	var merged []LogResult
+	buckets := make(map[string]int) // time_bucket -> index in merged
+	for _, results := range batches {
+		for _, r := range results {
+			bucket, isBucket := r.Fields["time_bucket"]
+			count, err := strconv.Atoi(r.Fields["count"])
+			if !isBucket || err != nil {
+				merged = append(merged, r)
+				continue
+			}
+			if i, ok := buckets[bucket]; ok {
+				total, _ := strconv.Atoi(merged[i].Fields["count"])
+				merged[i].Fields["count"] = strconv.Itoa(total + count)
+				continue
+			}
+			buckets[bucket] = len(merged)
+			merged = append(merged, r)
+		}
+	}
+
+	// Insights timestamps and time buckets sort correctly as strings
+	sortKey := func(r LogResult) string {
+		if r.Timestamp != "" {
+			return r.Timestamp
+		}
+		return r.Fields["time_bucket"]
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return sortKey(merged[i]) > sortKey(merged[j])
+	})
+
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// Tail streams log events in real-time. Each poll reads every selected log group.
 func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	groups, err := s.LogGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	eventChan := make(chan source.Event, DefaultEventChanBuffer)
 
 	go func() {
@@ -172,11 +334,20 @@ func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan sou
 					filterStr = params.Filter.String()
 				}
 
-				events, err := s.client.FilterLogEvents(ctx, s.logGroup, filterStr, lastTime, endTime)
-				if err != nil {
-					// Log transient errors for debugging but don't fail
-					logging.Debug("CloudWatch tail transient error: %v", err)
-					continue
+				var events []TailEvent
+				for _, group := range groups {
+					groupEvents, err := s.client.FilterLogEvents(ctx, group, filterStr, lastTime, endTime)
+					if err != nil {
+						// Log transient errors for debugging but don't fail
+						logging.Debug("CloudWatch tail transient error: %v", err)
+						continue
+					}
+					events = append(events, groupEvents...)
+				}
+				if len(groups) > 1 {
+					sort.SliceStable(events, func(i, j int) bool {
+						return events[i].Timestamp.Before(events[j].Timestamp)
+					})
 				}
 
 				for _, e := range events {
@@ -234,7 +405,15 @@ func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, a
 		return nil, nil, err
 	}
 
-	events, err := s.client.GetLogEvents(ctx, s.logGroup, entry.Stream, ts.Add(-ContextLookbackWindow), ts, before)
+	group := s.logGroup
+	if s.selector.multi() {
+		group = groupFromLogField(entry.Fields["@log"])
+		if group == "" {
+			return nil, nil, fmt.Errorf("entry has no @log field to identify its log group")
+		}
+	}
+
+	events, err := s.client.GetLogEvents(ctx, group, entry.Stream, ts.Add(-ContextLookbackWindow), ts, before)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -251,21 +430,33 @@ func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, a
 	return beforeEvents, nil, nil
 }
 
-// ListStreams returns available log streams.
+// ListStreams returns available log streams. For several log groups, stream
+// names are prefixed with their group.
 func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
-	streams, err := s.client.ListStreams(ctx, s.logGroup, "", MaxListStreamsLimit, "LastEventTime")
+	groups, err := s.LogGroups(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var result []source.StreamInfo
-	for _, st := range streams {
-		result = append(result, source.StreamInfo{
-			Name:      st.Name,
-			Size:      st.StoredBytes,
-			FirstTime: st.FirstEventTime,
-			LastTime:  st.LastEventTime,
-		})
+	for _, group := range groups {
+		streams, err := s.client.ListStreams(ctx, group, "", MaxListStreamsLimit, "LastEventTime")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, st := range streams {
+			name := st.Name
+			if s.selector.multi() {
+				name = group + ": " + name
+			}
+			result = append(result, source.StreamInfo{
+				Name:      name,
+				Size:      st.StoredBytes,
+				FirstTime: st.FirstEventTime,
+				LastTime:  st.LastEventTime,
+			})
+		}
 	}
 
 	return result, nil
@@ -292,11 +483,33 @@ func (s *Source) Close() error {
 	return nil
 }
 
-// LogGroup returns the log group name.
+// LogGroup returns the log group name. For a source over several log
+// groups, it returns the selector in URI form (e.g. /app/* or ?tag=service:api).
 func (s *Source) LogGroup() string {
 	return s.logGroup
 }
 
+// LogGroups returns the log groups this source reads. Groups selected by a
+// pattern, prefix or tag are looked up on first use.
+func (s *Source) LogGroups(ctx context.Context) ([]string, error) {
+	if !s.selector.multi() {
+		return []string{s.logGroup}, nil
+	}
+
+	s.groupsMu.Lock()
+	defer s.groupsMu.Unlock()
+
+	if s.groups == nil {
+		groups, err := resolveLogGroups(ctx, s.client, s.selector)
+		if err != nil {
+			return nil, err
+		}
+		logging.Debug("CloudWatch log groups for %s: %s", s.logGroup, strings.Join(groups, ", "))
+		s.groups = groups
+	}
+	return s.groups, nil
+}
+
 // Profile returns the AWS profile.
 func (s *Source) Profile() string {
 	return s.profile
@@ -329,6 +542,9 @@ func (s *Source) convertResults(results []LogResult) []source.Entry {
 			Fields:   r.Fields,
 			Ptr:      r.Fields["@ptr"], // CloudWatch @ptr
 		}
+		if group := groupFromLogField(r.Fields["@log"]); group != "" {
+			entry.Source = group
+		}
 
 		// Parse timestamp
 		if r.Timestamp != "" {
@@ -361,12 +577,12 @@ func buildInsightsQuery(filter string, limit int) string {
 	}
 
 	if filter == "" {
-		return fmt.Sprintf(`fields @timestamp, @message, @logStream, @ptr
+		return fmt.Sprintf(`fields @timestamp, @message, @logStream, @log, @ptr
 | sort @timestamp desc
 | limit %d`, limit)
 	}
 
-	return fmt.Sprintf(`fields @timestamp, @message, @logStream, @ptr
+	return fmt.Sprintf(`fields @timestamp, @message, @logStream, @log, @ptr
 | filter @message like /(?i)(%s)/
 | sort @timestamp desc
 | limit %d`, filter, limit)
@@ -3,6 +3,7 @@ package cloudwatch
 import (
 	"context"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,8 +18,12 @@ type mockLogsClient struct {
 	tailEvents      []TailEvent
 	logEvents       []LogEvent
 	logRecord       LogResult
+	tags            map[string]map[string]string // log group ARN -> tags
 	err             error
 	filterCallCount int
+
+	mu         sync.Mutex
+	queryCalls []QueryParams
 }
 
 func (m *mockLogsClient) GetLogGroup(ctx context.Context, name string) (LogGroupInfo, error) {
@@ -48,7 +53,17 @@ func (m *mockLogsClient) FilterLogEvents(ctx context.Context, logGroup, filter s
 	return m.tailEvents, nil
 }
 
+func (m *mockLogsClient) ListLogGroupTags(ctx context.Context, logGroupArn string) (map[string]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tags[logGroupArn], nil
+}
+
 func (m *mockLogsClient) RunInsightsQuery(ctx context.Context, params QueryParams) ([]LogResult, error) {
+	m.mu.Lock()
+	m.queryCalls = append(m.queryCalls, params)
+	m.mu.Unlock()
 	if m.err != nil {
 		return nil, m.err
 	}
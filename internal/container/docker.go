@@ -0,0 +1,142 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDockerRoot is Docker's default data directory
+const DefaultDockerRoot = "/var/lib/docker"
+
+// dockerConfig holds the fields read from a container's config.v2.json.
+type dockerConfig struct {
+	Name   string `json:"Name"`
+	Config struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+}
+
+// findDockerContainers returns the json-file logs of containers under root
+// whose name or ID matches ref. Like the docker CLI, an unambiguous ID prefix
+// selects a container; names may also be glob patterns.
+func findDockerContainers(root, ref string) ([]containerLog, error) {
+	dirs, err := os.ReadDir(filepath.Join(root, "containers"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read docker containers: %w", err)
+	}
+
+	var byName, byID []containerLog
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		log, err := dockerLog(filepath.Join(root, "containers", d.Name()))
+		if err != nil {
+			continue // Not a json-file container, or no logs yet
+		}
+
+		name := log.fields["container"]
+		if name == ref {
+			// An exact name match wins over everything else
+			return []containerLog{log}, nil
+		}
+		if ok, _ := path.Match(ref, name); ok {
+			byName = append(byName, log)
+		} else if strings.HasPrefix(d.Name(), ref) {
+			byID = append(byID, log)
+		}
+	}
+
+	if len(byName) > 0 {
+		return byName, nil
+	}
+	if len(byID) > 1 {
+		return nil, fmt.Errorf("container ID prefix %q is ambiguous (%d matches)", ref, len(byID))
+	}
+	if len(byID) == 0 {
+		return nil, fmt.Errorf("no container matching %q in %s", ref, root)
+	}
+	return byID, nil
+}
+
+// dockerLog returns the log files and metadata of the container in dir.
+// Rotated files (-json.log.1, -json.log.2.gz, ...) are included, oldest first.
+func dockerLog(dir string) (containerLog, error) {
+	id := filepath.Base(dir)
+	active := filepath.Join(dir, id+"-json.log")
+
+	rotated, err := filepath.Glob(active + ".*")
+	if err != nil {
+		return containerLog{}, err
+	}
+	// Higher numbers are older
+	sort.Slice(rotated, func(i, j int) bool {
+		return rotationNumber(rotated[i]) > rotationNumber(rotated[j])
+	})
+
+	files := rotated
+	if _, err := os.Stat(active); err == nil {
+		files = append(files, active)
+	}
+	if len(files) == 0 {
+		return containerLog{}, fmt.Errorf("no json-file logs in %s", dir)
+	}
+
+	return newDockerLog(dir, files, active), nil
+}
+
+// dockerLogForFile returns the container log for a single json-file log file.
+func dockerLogForFile(file string) containerLog {
+	return newDockerLog(filepath.Dir(file), []string{file}, file)
+}
+
+// newDockerLog builds the container log for files in a container directory.
+// The container config is optional so log files copied without it can still
+// be read; the short ID is used as the name instead.
+func newDockerLog(dir string, files []string, active string) containerLog {
+	id := filepath.Base(dir)
+	log := containerLog{
+		files:  files,
+		active: active,
+		name:   id[:min(12, len(id))],
+		format: formatDocker,
+		fields: map[string]string{"container_id": id},
+	}
+
+	if cfg, err := readDockerConfig(dir); err == nil {
+		if cfg.Name != "" {
+			log.name = strings.TrimPrefix(cfg.Name, "/")
+		}
+		if cfg.Config.Image != "" {
+			log.fields["image"] = cfg.Config.Image
+		}
+	}
+	log.fields["container"] = log.name
+
+	return log
+}
+
+func readDockerConfig(dir string) (dockerConfig, error) {
+	var cfg dockerConfig
+	data, err := os.ReadFile(filepath.Join(dir, "config.v2.json"))
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// rotationNumber returns N for a rotated file named <log>.N or <log>.N.gz.
+func rotationNumber(file string) int {
+	ext := strings.TrimSuffix(filepath.Base(file), ".gz")
+	n := 0
+	if i := strings.LastIndex(ext, "."); i >= 0 {
+		_, _ = fmt.Sscanf(ext[i+1:], "%d", &n)
+	}
+	return n
+}
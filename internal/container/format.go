@@ -0,0 +1,254 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+)
+
+// logFormat is the wrapper format a container runtime writes log lines in.
+type logFormat int
+
+const (
+	// formatDocker is Docker's json-file driver:
+	// {"log":"message\n","stream":"stdout","time":"2025-01-15T10:00:00.123456789Z"}
+	formatDocker logFormat = iota
+
+	// formatCRI is the Kubernetes CRI format written by containerd and CRI-O:
+	// 2025-01-15T10:00:00.123456789Z stdout F message
+	formatCRI
+)
+
+// record is one decoded line of a container log file. Runtimes split long
+// output lines into several partial records, which are joined into a message.
+type record struct {
+	time    time.Time
+	stream  string // stdout or stderr
+	text    string
+	partial bool
+}
+
+// dockerRecord is the JSON object written per line by the json-file driver.
+type dockerRecord struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// decodeLine decodes a log file line in the given wrapper format.
+func decodeLine(format logFormat, line string) (record, error) {
+	if format == formatCRI {
+		return decodeCRILine(line)
+	}
+	return decodeDockerLine(line)
+}
+
+// decodeDockerLine decodes a json-file line. Docker ends complete lines with
+// a newline; records without one are partial.
+func decodeDockerLine(line string) (record, error) {
+	var r dockerRecord
+	if err := json.Unmarshal([]byte(line), &r); err != nil {
+		return record{}, fmt.Errorf("invalid docker log line: %w", err)
+	}
+	if r.Time.IsZero() {
+		return record{}, errors.New("invalid docker log line: missing time")
+	}
+
+	text, complete := strings.CutSuffix(r.Log, "\n")
+	return record{
+		time:    r.Time,
+		stream:  r.Stream,
+		text:    strings.TrimSuffix(text, "\r"), // containers with a TTY write CRLF
+		partial: !complete,
+	}, nil
+}
+
+// decodeCRILine decodes a CRI line: "<RFC3339Nano time> <stream> <tags> <message>".
+// The first tag is P for partial records and F for full (final) ones.
+func decodeCRILine(line string) (record, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return record{}, errors.New("invalid CRI log line")
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return record{}, fmt.Errorf("invalid CRI log line: %w", err)
+	}
+
+	tag, _, _ := strings.Cut(parts[2], ":")
+	if tag != "P" && tag != "F" {
+		return record{}, fmt.Errorf("invalid CRI log line: unknown tag %q", parts[2])
+	}
+
+	var text string
+	if len(parts) == 4 {
+		text = parts[3]
+	}
+
+	return record{
+		time:    ts,
+		stream:  parts[1],
+		text:    text,
+		partial: tag == "P",
+	}, nil
+}
+
+// message is a complete log message assembled from one or more records.
+type message struct {
+	time   time.Time // time of the first record
+	stream string
+	text   string
+	line   int // line in the log file of the first record
+}
+
+// joiner assembles partial records into messages. stdout and stderr records
+// can interleave, so partial records are collected per stream.
+type joiner struct {
+	pending map[string]*message
+}
+
+func newJoiner() *joiner {
+	return &joiner{pending: make(map[string]*message)}
+}
+
+// add adds the record read from a log file line, returning the message it
+// completes, if any.
+func (j *joiner) add(r record, line int) (message, bool) {
+	msg, ok := j.pending[r.stream]
+	if !ok {
+		msg = &message{time: r.time, stream: r.stream, line: line}
+	}
+	// Very long output is truncated rather than failing the scan of the whole
+	// file, since line length is limited to local.MaxScanTokenSize
+	if room := local.MaxScanTokenSize - 1 - len(msg.text); len(r.text) > room {
+		r.text = r.text[:max(room, 0)]
+	}
+	msg.text += r.text
+
+	if r.partial {
+		j.pending[r.stream] = msg
+		return message{}, false
+	}
+	delete(j.pending, r.stream)
+	return *msg, true
+}
+
+// flush returns messages left incomplete at the end of a file, in file order.
+func (j *joiner) flush() []message {
+	var msgs []message
+	for _, msg := range j.pending {
+		msgs = append(msgs, *msg)
+	}
+	clear(j.pending)
+
+	sort.Slice(msgs, func(a, b int) bool {
+		return msgs[a].line < msgs[b].line
+	})
+	return msgs
+}
+
+// messageReader reads the messages of a container log file.
+type messageReader struct {
+	lines  *local.LineReader
+	format logFormat
+	joiner *joiner
+	num    int // line number of the last line read
+	queue  []message
+}
+
+func newMessageReader(r io.Reader, format logFormat) *messageReader {
+	return &messageReader{
+		lines:  local.NewLineReader(r),
+		format: format,
+		joiner: newJoiner(),
+	}
+}
+
+// next returns the next message, or io.EOF at the end of the file.
+// Lines that cannot be decoded, such as a line cut short when the runtime
+// was killed, are skipped.
+func (mr *messageReader) next() (message, error) {
+	for len(mr.queue) == 0 {
+		line, _, err := mr.lines.Next()
+		if err == io.EOF {
+			// A partial message at the end of the file is returned as is
+			if mr.queue = mr.joiner.flush(); len(mr.queue) == 0 {
+				return message{}, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return message{}, err
+		}
+		mr.num++
+
+		r, err := decodeLine(mr.format, line)
+		if err != nil {
+			continue
+		}
+		if msg, ok := mr.joiner.add(r, mr.num); ok {
+			return msg, nil
+		}
+	}
+
+	msg := mr.queue[0]
+	mr.queue = mr.queue[1:]
+	return msg, nil
+}
+
+// messageStream presents the messages of a container log as newline-delimited
+// text, so they can be parsed by a local.EntryScanner. Each message becomes one
+// line of the stream; the message behind each stream line is kept by line number.
+type messageStream struct {
+	messages *messageReader
+	buf      []byte
+	byLine   map[int]message
+	count    int // messages written to the stream
+	oldest   int // oldest stream line still kept
+	err      error
+}
+
+func newMessageStream(mr *messageReader) *messageStream {
+	return &messageStream{messages: mr, byLine: make(map[int]message)}
+}
+
+// Read implements io.Reader.
+func (ms *messageStream) Read(p []byte) (int, error) {
+	for len(ms.buf) == 0 {
+		if ms.err != nil {
+			return 0, ms.err
+		}
+
+		msg, err := ms.messages.next()
+		if err != nil {
+			ms.err = err
+			continue
+		}
+
+		ms.count++
+		ms.byLine[ms.count] = msg
+		// Keep one message per line even if a runtime wrote an embedded newline
+		ms.buf = append(ms.buf, strings.ReplaceAll(msg.text, "\n", " ")...)
+		ms.buf = append(ms.buf, '\n')
+	}
+
+	n := copy(p, ms.buf)
+	ms.buf = ms.buf[n:]
+	return n, nil
+}
+
+// message returns the message at a stream line and forgets the messages
+// before it, which the scanner has finished with.
+func (ms *messageStream) message(line int) message {
+	for ; ms.oldest < line; ms.oldest++ {
+		delete(ms.byLine, ms.oldest)
+	}
+	return ms.byLine[line]
+}
@@ -0,0 +1,263 @@
+package container
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeDockerLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    record
+		wantErr bool
+	}{
+		{
+			name: "complete line",
+			line: `{"log":"server started\n","stream":"stdout","time":"2025-01-15T10:00:00.123456789Z"}`,
+			want: record{
+				time:   time.Date(2025, 1, 15, 10, 0, 0, 123456789, time.UTC),
+				stream: "stdout",
+				text:   "server started",
+			},
+		},
+		{
+			name: "partial line",
+			line: `{"log":"first half ","stream":"stderr","time":"2025-01-15T10:00:00Z"}`,
+			want: record{
+				time:    time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				stream:  "stderr",
+				text:    "first half ",
+				partial: true,
+			},
+		},
+		{
+			name: "tty line ending",
+			line: `{"log":"prompt\r\n","stream":"stdout","time":"2025-01-15T10:00:00Z"}`,
+			want: record{
+				time:   time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				stream: "stdout",
+				text:   "prompt",
+			},
+		},
+		{
+			name:    "missing time",
+			line:    `{"log":"no time\n","stream":"stdout"}`,
+			wantErr: true,
+		},
+		{
+			name:    "truncated json",
+			line:    `{"log":"cut sho`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeLine(formatDocker, tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.time.Equal(tt.want.time) || got.stream != tt.want.stream || got.text != tt.want.text || got.partial != tt.want.partial {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCRILine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    record
+		wantErr bool
+	}{
+		{
+			name: "full line",
+			line: "2025-01-15T10:00:00.5Z stdout F GET /health 200",
+			want: record{
+				time:   time.Date(2025, 1, 15, 10, 0, 0, 500000000, time.UTC),
+				stream: "stdout",
+				text:   "GET /health 200",
+			},
+		},
+		{
+			name: "partial line",
+			line: "2025-01-15T10:00:00Z stderr P first half ",
+			want: record{
+				time:    time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				stream:  "stderr",
+				text:    "first half ",
+				partial: true,
+			},
+		},
+		{
+			name: "empty message",
+			line: "2025-01-15T10:00:00Z stdout F",
+			want: record{
+				time:   time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				stream: "stdout",
+			},
+		},
+		{
+			name: "tag with attributes",
+			line: "2025-01-15T12:00:00+02:00 stdout F:extra message",
+			want: record{
+				time:   time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				stream: "stdout",
+				text:   "message",
+			},
+		},
+		{
+			name:    "unknown tag",
+			line:    "2025-01-15T10:00:00Z stdout X message",
+			wantErr: true,
+		},
+		{
+			name:    "invalid time",
+			line:    "yesterday stdout F message",
+			wantErr: true,
+		},
+		{
+			name:    "too few fields",
+			line:    "2025-01-15T10:00:00Z stdout",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeLine(formatCRI, tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.time.Equal(tt.want.time) || got.stream != tt.want.stream || got.text != tt.want.text || got.partial != tt.want.partial {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func readMessages(t *testing.T, format logFormat, content string) []message {
+	t.Helper()
+	mr := newMessageReader(strings.NewReader(content), format)
+	var msgs []message
+	for {
+		msg, err := mr.next()
+		if err == io.EOF {
+			return msgs
+		}
+		if err != nil {
+			t.Fatalf("next failed: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestMessageReader_JoinsPartialRecords(t *testing.T) {
+	// stdout and stderr partial records interleave; each stream is joined separately
+	content := strings.Join([]string{
+		"2025-01-15T10:00:00Z stdout P long ",
+		"2025-01-15T10:00:01Z stderr F warning",
+		"garbage line",
+		"2025-01-15T10:00:02Z stdout P output ",
+		"2025-01-15T10:00:03Z stdout F line",
+		"2025-01-15T10:00:04Z stderr P cut off",
+	}, "\n") + "\n"
+
+	msgs := readMessages(t, formatCRI, content)
+
+	want := []struct {
+		text   string
+		stream string
+		line   int
+		sec    int
+	}{
+		{"warning", "stderr", 2, 1},
+		{"long output line", "stdout", 1, 0},
+		{"cut off", "stderr", 6, 4}, // incomplete at end of file
+	}
+	if len(msgs) != len(want) {
+		t.Fatalf("expected %d messages, got %d: %+v", len(want), len(msgs), msgs)
+	}
+	for i, w := range want {
+		if msgs[i].text != w.text || msgs[i].stream != w.stream || msgs[i].line != w.line || msgs[i].time.Second() != w.sec {
+			t.Errorf("message %d = %+v, want %+v", i, msgs[i], w)
+		}
+	}
+}
+
+func TestMessageReader_Docker(t *testing.T) {
+	content := `{"log":"part one, ","stream":"stdout","time":"2025-01-15T10:00:00Z"}
+{"log":"part two\n","stream":"stdout","time":"2025-01-15T10:00:00.1Z"}
+{"log":"done\n","stream":"stdout","time":"2025-01-15T10:00:01Z"}
+`
+	msgs := readMessages(t, formatDocker, content)
+
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d: %+v", len(msgs), msgs)
+	}
+	if msgs[0].text != "part one, part two" || msgs[0].line != 1 {
+		t.Errorf("first message = %+v", msgs[0])
+	}
+	if msgs[1].text != "done" || msgs[1].line != 3 {
+		t.Errorf("second message = %+v", msgs[1])
+	}
+}
+
+func TestMessageStream(t *testing.T) {
+	content := strings.Join([]string{
+		"2025-01-15T10:00:00Z stdout F first",
+		"2025-01-15T10:00:01Z stdout P second ",
+		"2025-01-15T10:00:01Z stdout F half",
+		"2025-01-15T10:00:02Z stdout F third",
+	}, "\n") + "\n"
+
+	ms := newMessageStream(newMessageReader(strings.NewReader(content), formatCRI))
+	data, err := io.ReadAll(ms)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+
+	if want := "first\nsecond half\nthird\n"; string(data) != want {
+		t.Errorf("stream = %q, want %q", data, want)
+	}
+
+	// Stream lines map back to the file line of the first record
+	if msg := ms.message(2); msg.line != 2 || msg.text != "second half" {
+		t.Errorf("message(2) = %+v", msg)
+	}
+	if msg := ms.message(3); msg.line != 4 {
+		t.Errorf("message(3) = %+v", msg)
+	}
+	if _, ok := ms.byLine[2]; ok {
+		t.Error("expected messages before the requested line to be forgotten")
+	}
+}
+
+func TestMessageStream_EmbeddedNewline(t *testing.T) {
+	content := `{"log":"two\nlines\n","stream":"stdout","time":"2025-01-15T10:00:00Z"}
+`
+	ms := newMessageStream(newMessageReader(strings.NewReader(content), formatDocker))
+	data, err := io.ReadAll(ms)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if want := "two lines\n"; string(data) != want {
+		t.Errorf("stream = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,147 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultPodLogRoot is where the kubelet keeps container logs on a node
+const DefaultPodLogRoot = "/var/log/pods"
+
+// podSelector selects container logs by namespace, pod and container.
+// Empty parts match everything; glob patterns are allowed.
+type podSelector struct {
+	namespace string
+	pod       string
+	container string
+}
+
+// findPodContainers returns the CRI logs of containers under root matching sel.
+// The kubelet stores them as <namespace>_<pod>_<uid>/<container>/<restart>.log.
+func findPodContainers(root string, sel podSelector) ([]containerLog, error) {
+	podDirs, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pod logs: %w", err)
+	}
+
+	var logs []containerLog
+	for _, podDir := range podDirs {
+		namespace, pod, _, ok := parsePodDir(podDir.Name())
+		if !podDir.IsDir() || !ok || !matchPart(sel.namespace, namespace) || !matchPart(sel.pod, pod) {
+			continue
+		}
+
+		containerDirs, err := os.ReadDir(filepath.Join(root, podDir.Name()))
+		if err != nil {
+			continue
+		}
+		for _, containerDir := range containerDirs {
+			if !containerDir.IsDir() || !matchPart(sel.container, containerDir.Name()) {
+				continue
+			}
+			if log, ok := podContainerLog(filepath.Join(root, podDir.Name(), containerDir.Name())); ok {
+				logs = append(logs, log)
+			}
+		}
+	}
+
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no container logs matching %s/%s/%s in %s", orAny(sel.namespace), orAny(sel.pod), orAny(sel.container), root)
+	}
+	return logs, nil
+}
+
+// podContainerLog returns the log files of a container directory, oldest first.
+// Each restart writes <restart>.log; rotated files add a timestamp suffix and
+// may be gzip-compressed.
+func podContainerLog(dir string) (containerLog, bool) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log*"))
+	if err != nil || len(files) == 0 {
+		return containerLog{}, false
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		ri, ai := restartNumber(files[i])
+		rj, aj := restartNumber(files[j])
+		if ri != rj {
+			return ri < rj
+		}
+		// Rotated files precede the active file of the same restart
+		if ai != aj {
+			return aj
+		}
+		return files[i] < files[j]
+	})
+
+	// The active file of the latest restart is the one being written
+	active := files[len(files)-1]
+	return newPodLog(dir, files, active), true
+}
+
+// podLogForFile returns the container log for a single CRI log file.
+func podLogForFile(file string) containerLog {
+	return newPodLog(filepath.Dir(file), []string{file}, file)
+}
+
+// newPodLog builds the container log for files in a container directory,
+// taking pod metadata from the directory names.
+func newPodLog(dir string, files []string, active string) containerLog {
+	container := filepath.Base(dir)
+	fields := map[string]string{"container": container}
+
+	name := container
+	if namespace, pod, uid, ok := parsePodDir(filepath.Base(filepath.Dir(dir))); ok {
+		fields["namespace"] = namespace
+		fields["pod"] = pod
+		fields["pod_uid"] = uid
+		name = namespace + "/" + pod + "/" + container
+	}
+
+	return containerLog{
+		files:  files,
+		active: active,
+		name:   name,
+		format: formatCRI,
+		fields: fields,
+	}
+}
+
+// parsePodDir splits a pod log directory name into namespace, pod and UID.
+// Kubernetes names cannot contain underscores, so the split is unambiguous.
+func parsePodDir(name string) (namespace, pod, uid string, ok bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// restartNumber returns the restart count of a CRI log file and whether it is
+// the active (unrotated) file for that restart.
+func restartNumber(file string) (int, bool) {
+	base := filepath.Base(file)
+	num, rest, _ := strings.Cut(base, ".")
+	n, _ := strconv.Atoi(num)
+	return n, rest == "log"
+}
+
+// matchPart matches a selector part; empty parts match everything.
+func matchPart(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+func orAny(part string) string {
+	if part == "" {
+		return "*"
+	}
+	return part
+}
@@ -0,0 +1,662 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// TailPollInterval is how often container logs are checked for new lines while tailing
+	TailPollInterval = 500 * time.Millisecond
+)
+
+const (
+	schemeDocker = "docker"
+	schemeK8s    = "k8s-node"
+)
+
+func init() {
+	source.Register(schemeDocker, openDockerSource)
+	source.Register(schemeK8s, openPodSource)
+}
+
+// containerLog is the log of one container: its log files, oldest first,
+// and the metadata added to each entry.
+type containerLog struct {
+	files  []string
+	active string // file the runtime is currently writing
+	name   string // stream name shown for entries
+	format logFormat
+	fields map[string]string
+}
+
+// Source implements source.Source for container logs written by Docker's
+// json-file driver or by Kubernetes container runtimes in CRI format. Log
+// files are read directly from the node, so no daemon or API access is needed.
+// Pointers are the log file path and the line of the message's first record.
+type Source struct {
+	scheme        string
+	logs          []containerLog
+	format        local.Format // format of the messages inside the wrapper
+	uri           string
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// openDockerSource opens a Docker container source from a parsed URL.
+// docker://<name-or-id> finds the container under the Docker root;
+// docker:///path/to/<id>-json.log reads log files directly.
+func openDockerSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	query := u.Query()
+	formatHint := query.Get("format")
+	if err := local.CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" {
+		if u.Path == "" {
+			return nil, fmt.Errorf("docker URI requires a container name or ID (e.g., docker://web)")
+		}
+		return newFileSource(schemeDocker, u.Path, formatHint)
+	}
+
+	root := query.Get("root")
+	if root == "" {
+		root = DefaultDockerRoot
+	}
+	return NewDockerSource(u.Host, root, formatHint)
+}
+
+// openPodSource opens a Kubernetes container source from a parsed URL.
+// k8s-node:///namespace/pod/container selects containers under the pod log
+// root; a path to existing log files reads them directly.
+func openPodSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	query := u.Query()
+	formatHint := query.Get("format")
+	if err := local.CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" && isLogFile(u.Path) {
+		return newFileSource(schemeK8s, u.Path, formatHint)
+	}
+
+	// Accept k8s-node://namespace/pod/container as well
+	selector := strings.Trim(u.Host+u.Path, "/")
+	var parts []string
+	if selector != "" {
+		parts = strings.Split(selector, "/")
+	}
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid k8s-node URI %q (expected k8s-node:///namespace/pod/container)", u.String())
+	}
+	parts = append(parts, "", "", "")
+
+	root := query.Get("root")
+	if root == "" {
+		root = DefaultPodLogRoot
+	}
+	return NewPodSource(parts[0], parts[1], parts[2], root, formatHint)
+}
+
+// NewDockerSource creates a source for the containers under a Docker root
+// directory matching ref: a container name, a name glob pattern or an ID prefix.
+// The formatHint specifies the format of the messages (auto, plain, json, syslog, java).
+func NewDockerSource(ref, root, formatHint string) (*Source, error) {
+	logs, err := findDockerContainers(root, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	if root != DefaultDockerRoot {
+		query.Set("root", root)
+	}
+	return newSource(schemeDocker, logs, formatHint, "docker://"+ref, query), nil
+}
+
+// NewPodSource creates a source for the containers under a kubelet pod log
+// directory matching a namespace, pod and container. Empty parts match
+// everything and glob patterns are allowed.
+// The formatHint specifies the format of the messages (auto, plain, json, syslog, java).
+func NewPodSource(namespace, pod, container, root, formatHint string) (*Source, error) {
+	logs, err := findPodContainers(root, podSelector{namespace: namespace, pod: pod, container: container})
+	if err != nil {
+		return nil, err
+	}
+
+	// Trailing parts that match everything are left out of the URI
+	parts := []string{namespace, pod, container}
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	for i := range parts {
+		parts[i] = orAny(parts[i])
+	}
+	selector := strings.Join(parts, "/")
+	query := url.Values{}
+	if root != DefaultPodLogRoot {
+		query.Set("root", root)
+	}
+	return newSource(schemeK8s, logs, formatHint, "k8s-node:///"+selector, query), nil
+}
+
+// newFileSource creates a source for container log files given by path or
+// glob pattern, taking container metadata from the directories they are in.
+func newFileSource(scheme, pattern, formatHint string) (*Source, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match pattern %q", pattern)
+	}
+	sort.Strings(files)
+
+	logs := make([]containerLog, len(files))
+	for i, file := range files {
+		logs[i] = logForFile(scheme, file)
+	}
+
+	return newSource(scheme, logs, formatHint, scheme+"://"+pattern, url.Values{}), nil
+}
+
+func newSource(scheme string, logs []containerLog, formatHint, uri string, query url.Values) *Source {
+	format := local.ParseFormat(formatHint)
+	if format != local.FormatAuto {
+		query.Set("format", format.String())
+	}
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+
+	return &Source{
+		scheme: scheme,
+		logs:   logs,
+		format: format,
+		uri:    uri,
+	}
+}
+
+// logForFile returns the container log for a single log file.
+func logForFile(scheme, file string) containerLog {
+	if scheme == schemeK8s {
+		return podLogForFile(file)
+	}
+	return dockerLogForFile(file)
+}
+
+// isLogFile reports whether p names or globs to existing regular files.
+func isLogFile(p string) bool {
+	if p == "" {
+		return false
+	}
+	matches, _ := filepath.Glob(p)
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+			return true
+		}
+	}
+	return false
+}
+
+// logFor returns the container log a file belongs to.
+func (s *Source) logFor(file string) containerLog {
+	for _, log := range s.logs {
+		if slices.Contains(log.files, file) || log.active == file {
+			return log
+		}
+	}
+	return logForFile(s.scheme, file)
+}
+
+// parserFor returns the parser for the messages in a log file, detecting
+// their format from the first messages unless a format was given.
+func (s *Source) parserFor(log containerLog, file string) local.Parser {
+	format := s.format
+	if format == local.FormatAuto {
+		format = local.FormatPlain
+		if f, err := local.OpenLogFile(file); err == nil {
+			format = local.DetectFormatReader(newMessageStream(newMessageReader(f, log.format)))
+			_ = f.Close()
+		}
+	}
+	return messageParser{Parser: local.NewParser(format), plain: local.NewParser(local.FormatPlain)}
+}
+
+// messageParser parses messages with the parser for the detected format,
+// keeping messages that parser does not recognize as plain text. Containers
+// often mix structured logs with plain output such as startup banners.
+type messageParser struct {
+	local.Parser
+	plain local.Parser
+}
+
+func (p messageParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	if entry := p.Parser.ParseLine(line, lineNum, filePath); entry != nil {
+		return entry
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	return p.plain.ParseLine(line, lineNum, filePath)
+}
+
+// Query returns log entries matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	var results []source.Entry
+
+	for _, log := range s.logs {
+		for _, file := range log.files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			entries, err := s.queryFile(ctx, log, file, params)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, fmt.Errorf("error reading %s: %w", file, err)
+			}
+			results = append(results, entries...)
+		}
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// queryFile reads and filters a single container log file.
+func (s *Source) queryFile(ctx context.Context, log containerLog, file string, params source.QueryParams) ([]source.Entry, error) {
+	// Rotated files last written before the query range cannot match
+	if info, err := os.Stat(file); err == nil && !params.StartTime.IsZero() && info.ModTime().Before(params.StartTime) {
+		return nil, nil
+	}
+
+	f, err := local.OpenLogFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	stream := newMessageStream(newMessageReader(f, log.format))
+	scanner := local.NewEntryScanner(stream, s.parserFor(log, file), file)
+
+	var results []source.Entry
+	for scanner.Scan(ctx) {
+		entry := s.convertEntry(scanner.Entry(), log, file, stream.message(scanner.Line()))
+		if local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+	}
+
+	return results, scanner.Err()
+}
+
+// convertEntry completes an entry parsed from a message with the container
+// metadata. The runtime's timestamp is used, as it is always present and
+// precise; the timestamp the application logged is left in the message.
+func (s *Source) convertEntry(entry source.Entry, log containerLog, file string, msg message) source.Entry {
+	fields := make(map[string]string, len(entry.Fields)+len(log.fields)+1)
+	maps.Copy(fields, entry.Fields)
+	maps.Copy(fields, log.fields)
+	fields["stream"] = msg.stream
+
+	entry.Timestamp = msg.time
+	entry.Stream = log.name
+	entry.Source = file
+	entry.Ptr = source.MakeContainerPtr(s.scheme, file, msg.line)
+	entry.Fields = fields
+	return entry
+}
+
+// Tail streams new messages by polling each container's active log file.
+// Messages already logged when Tail is called are not replayed.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	followers := make([]*follower, len(s.logs))
+	for i, log := range s.logs {
+		followers[i] = s.newFollower(log)
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	go s.tailLoop(ctx, followers, params, events)
+
+	return events, nil
+}
+
+// tailLoop polls for new messages until the context is cancelled.
+func (s *Source) tailLoop(ctx context.Context, followers []*follower, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+	defer func() {
+		for _, fl := range followers {
+			fl.close()
+		}
+	}()
+
+	ticker := time.NewTicker(TailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, fl := range followers {
+			for _, msg := range fl.poll() {
+				s.emitMessage(fl, msg, params, events)
+			}
+		}
+	}
+}
+
+// follower follows the active log file of a container across rotations.
+type follower struct {
+	log     containerLog
+	parser  local.Parser
+	file    *os.File
+	offset  int64
+	pending []byte // incomplete last line
+	joiner  *joiner
+}
+
+// newFollower starts following a container log at the end of its active file.
+func (s *Source) newFollower(log containerLog) *follower {
+	fl := &follower{
+		log:    log,
+		parser: s.parserFor(log, log.active),
+		joiner: newJoiner(),
+	}
+	// The active file may not exist yet; poll opens it once it does
+	if f, err := os.Open(log.active); err == nil {
+		fl.file = f
+		if info, err := f.Stat(); err == nil {
+			fl.offset = info.Size()
+		}
+	}
+	return fl
+}
+
+// poll returns the messages completed since the previous poll. When the
+// runtime rotates the log, the rest of the old file is read before the new one.
+func (fl *follower) poll() []message {
+	var msgs []message
+	if fl.file != nil {
+		msgs = fl.readAppended()
+	}
+
+	info, err := os.Stat(fl.log.active)
+	if err != nil {
+		// Between rotating the old file and creating the new one
+		return msgs
+	}
+	if fl.file != nil {
+		if current, err := fl.file.Stat(); err == nil && os.SameFile(info, current) {
+			return msgs
+		}
+	}
+
+	f, err := os.Open(fl.log.active)
+	if err != nil {
+		return msgs
+	}
+	fl.close()
+	fl.file, fl.offset, fl.pending = f, 0, nil
+
+	return append(msgs, fl.readAppended()...)
+}
+
+// readAppended reads the lines appended to the open file since the last read.
+func (fl *follower) readAppended() []message {
+	info, err := fl.file.Stat()
+	if err != nil {
+		logging.Debug("Failed to stat %s: %v", fl.log.active, err)
+		return nil
+	}
+	if info.Size() < fl.offset {
+		// Truncated in place; start again from the beginning
+		fl.offset, fl.pending = 0, nil
+	}
+	if info.Size() == fl.offset {
+		return nil
+	}
+
+	data := make([]byte, info.Size()-fl.offset)
+	n, err := fl.file.ReadAt(data, fl.offset)
+	if err != nil && err != io.EOF {
+		logging.Debug("Failed to read %s: %v", fl.log.active, err)
+		return nil
+	}
+	fl.offset += int64(n)
+
+	data = append(fl.pending, data[:n]...)
+	end := bytes.LastIndexByte(data, '\n')
+	fl.pending = append([]byte(nil), data[end+1:]...)
+
+	var msgs []message
+	for _, line := range bytes.Split(data[:max(end, 0)], []byte{'\n'}) {
+		r, err := decodeLine(fl.log.format, string(line))
+		if err != nil {
+			continue
+		}
+		if msg, ok := fl.joiner.add(r, 0); ok {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (fl *follower) close() {
+	if fl.file != nil {
+		_ = fl.file.Close()
+		fl.file = nil
+	}
+}
+
+// emitMessage sends a message to the events channel if it matches the filter.
+func (s *Source) emitMessage(fl *follower, msg message, params source.TailParams, events chan<- source.Event) {
+	text := msg.text
+	if entry := fl.parser.ParseLine(text, 0, fl.log.active); entry != nil {
+		text = entry.Message
+	}
+
+	// Apply filter
+	if params.Filter != nil && !params.Filter.MatchString(text) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: msg.time,
+		Message:   text,
+		Stream:    fl.log.name,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseContainerPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid container pointer: %s", ptr)
+	}
+
+	f, err := local.OpenLogFile(info.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// Scan entries from the start so multiline entries are assembled
+	// the same way Query produced them
+	log := s.logFor(info.FilePath)
+	stream := newMessageStream(newMessageReader(f, log.format))
+	scanner := local.NewEntryScanner(stream, s.parserFor(log, info.FilePath), info.FilePath)
+	for scanner.Scan(ctx) {
+		msg := stream.message(scanner.Line())
+		if msg.line == info.LineNum {
+			entry := s.convertEntry(scanner.Entry(), log, info.FilePath, msg)
+			return &entry, nil
+		}
+		if msg.line > info.LineNum {
+			return nil, fmt.Errorf("no log entry starts at line %d in %s", info.LineNum, info.FilePath)
+		}
+	}
+
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	return nil, fmt.Errorf("line %d not found in %s", info.LineNum, info.FilePath)
+}
+
+// FetchContext retrieves the messages logged before and after an entry by
+// the same container.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseContainerPtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid container pointer: %s", entry.Ptr)
+	}
+
+	f, err := local.OpenLogFile(info.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	log := s.logFor(info.FilePath)
+	messages := newMessageReader(f, log.format)
+
+	var beforeLines, afterLines []source.Event
+	found := false
+
+	for !found || len(afterLines) < after {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		msg, err := messages.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		event := source.Event{Timestamp: msg.time, Message: msg.text, Stream: log.name}
+
+		switch {
+		case msg.line < info.LineNum:
+			// Keep only the last N messages before the target
+			if before > 0 {
+				if len(beforeLines) == before {
+					beforeLines = beforeLines[1:]
+				}
+				beforeLines = append(beforeLines, event)
+			}
+		case msg.line == info.LineNum:
+			found = true
+		case !found:
+			return nil, nil, fmt.Errorf("no message starts at line %d in %s", info.LineNum, info.FilePath)
+		default:
+			afterLines = append(afterLines, event)
+		}
+	}
+
+	if !found {
+		return nil, nil, fmt.Errorf("line %d out of range", info.LineNum)
+	}
+
+	return beforeLines, afterLines, nil
+}
+
+// ListStreams returns the log files of the source's containers.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	var streams []source.StreamInfo
+
+	for _, log := range s.logs {
+		for _, file := range log.files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue // Skip files we can't stat
+			}
+
+			streams = append(streams, source.StreamInfo{
+				Name:     file,
+				Size:     info.Size(),
+				LastTime: info.ModTime(),
+			})
+		}
+	}
+
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return s.scheme
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: s.scheme,
+		URI:  s.uri,
+	}
+}
+
+// Close releases any resources held by the source.
+func (s *Source) Close() error {
+	return nil
+}
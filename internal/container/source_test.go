@@ -0,0 +1,571 @@
+package container
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+const (
+	webID    = "3f1c2a9d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a10"
+	workerID = "3f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f98"
+)
+
+// dockerLine formats a json-file log line; text ending in "\n" is a complete line.
+func dockerLine(stream string, ts time.Time, text string) string {
+	return fmt.Sprintf(`{"log":%q,"stream":%q,"time":%q}`, text, stream, ts.Format(time.RFC3339Nano)) + "\n"
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func writeGzipFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close %s: %v", path, err)
+	}
+}
+
+var testBase = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func at(sec int) time.Time {
+	return testBase.Add(time.Duration(sec) * time.Second)
+}
+
+// newDockerRoot creates a Docker data directory with two containers: "web",
+// which logs JSON and has a rotated, compressed log file, and "worker",
+// which logs Java-style lines with a stack trace.
+func newDockerRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	webDir := filepath.Join(root, "containers", webID)
+	writeFile(t, filepath.Join(webDir, "config.v2.json"), `{"Name":"/web","Config":{"Image":"nginx:1.27"}}`)
+	writeGzipFile(t, filepath.Join(webDir, webID+"-json.log.1.gz"),
+		dockerLine("stdout", at(0), `{"level":"info","msg":"server starting","port":8080}`+"\n"))
+	writeFile(t, filepath.Join(webDir, webID+"-json.log"),
+		dockerLine("stdout", at(1), `{"level":"info","msg":"GET /health","status":200}`+"\n")+
+			dockerLine("stderr", at(2), `{"level":"error","msg":"upstream `)+
+			dockerLine("stderr", at(2), `timed out","status":504}`+"\n")+
+			dockerLine("stdout", at(3), `{"level":"info","msg":"GET /","status":200}`+"\n"))
+
+	workerDir := filepath.Join(root, "containers", workerID)
+	writeFile(t, filepath.Join(workerDir, "config.v2.json"), `{"Name":"/worker","Config":{"Image":"worker:2"}}`)
+	writeFile(t, filepath.Join(workerDir, workerID+"-json.log"),
+		dockerLine("stdout", at(10), "2025-01-15 10:00:10,000 INFO [main] Job started\n")+
+			dockerLine("stderr", at(11), "2025-01-15 10:00:11,000 ERROR [main] Job failed\n")+
+			dockerLine("stderr", at(11), "java.lang.IllegalStateException: bad input\n")+
+			dockerLine("stderr", at(11), "\tat com.example.Job.run(Job.java:42)\n")+
+			dockerLine("stdout", at(12), "2025-01-15 10:00:12,000 INFO [main] Retrying\n"))
+
+	return root
+}
+
+// newPodRoot creates a kubelet pod log directory with an nginx container
+// that restarted once, and a sidecar in the same pod.
+func newPodRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	podDir := filepath.Join(root, "default_web-7d4b9c_0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0")
+
+	writeFile(t, filepath.Join(podDir, "nginx", "0.log"),
+		"2025-01-15T10:00:00Z stdout F starting nginx\n"+
+			"2025-01-15T10:00:01Z stderr F crashed\n")
+	writeFile(t, filepath.Join(podDir, "nginx", "1.log"),
+		"2025-01-15T10:00:05Z stdout F starting nginx\n"+
+			"2025-01-15T10:00:06Z stdout P GET /health \n"+
+			"2025-01-15T10:00:06Z stdout F 200\n")
+	writeFile(t, filepath.Join(podDir, "sidecar", "0.log"),
+		"2025-01-15T10:00:02Z stdout F sidecar ready\n")
+
+	return root
+}
+
+func messages(entries []source.Entry) []string {
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestOpenDockerSource(t *testing.T) {
+	root := newDockerRoot(t)
+	activeLog := filepath.Join(root, "containers", webID, webID+"-json.log")
+
+	tests := []struct {
+		name      string
+		uri       string
+		wantNames []string
+		wantURI   string
+		wantErr   bool
+	}{
+		{
+			name:      "container name",
+			uri:       "docker://web?root=" + root,
+			wantNames: []string{"web"},
+			wantURI:   "docker://web?root=" + url.QueryEscape(root),
+		},
+		{
+			name:      "name glob",
+			uri:       "docker://w*?root=" + root + "&format=json",
+			wantNames: []string{"web", "worker"},
+			wantURI:   "docker://w*?format=json&root=" + url.QueryEscape(root),
+		},
+		{
+			name:      "unique ID prefix",
+			uri:       "docker://3f1c?root=" + root,
+			wantNames: []string{"web"},
+		},
+		{
+			name:    "ambiguous ID prefix",
+			uri:     "docker://3f?root=" + root,
+			wantErr: true,
+		},
+		{
+			name:    "no such container",
+			uri:     "docker://db?root=" + root,
+			wantErr: true,
+		},
+		{
+			name:      "log file path",
+			uri:       "docker://" + activeLog,
+			wantNames: []string{"web"},
+			wantURI:   "docker://" + activeLog,
+		},
+		{
+			name:    "no container",
+			uri:     "docker://",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			s := src.(*Source)
+			var names []string
+			for _, log := range s.logs {
+				names = append(names, log.name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("containers = %v, want %v", names, tt.wantNames)
+			}
+			if tt.wantURI != "" && s.Metadata().URI != tt.wantURI {
+				t.Errorf("URI = %q, want %q", s.Metadata().URI, tt.wantURI)
+			}
+			if s.Type() != "docker" {
+				t.Errorf("Type = %q, want docker", s.Type())
+			}
+		})
+	}
+}
+
+func TestDockerLog_RotatedFilesOldestFirst(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), webID)
+	for _, name := range []string{"-json.log", "-json.log.1", "-json.log.2.gz", "-json.log.10.gz"} {
+		writeFile(t, filepath.Join(dir, webID+name), "")
+	}
+
+	log, err := dockerLog(dir)
+	if err != nil {
+		t.Fatalf("dockerLog failed: %v", err)
+	}
+
+	var got []string
+	for _, file := range log.files {
+		got = append(got, strings.TrimPrefix(filepath.Base(file), webID))
+	}
+	want := []string{"-json.log.10.gz", "-json.log.2.gz", "-json.log.1", "-json.log"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+	// Without config.v2.json the short ID names the container
+	if log.name != webID[:12] {
+		t.Errorf("name = %q, want %q", log.name, webID[:12])
+	}
+}
+
+func TestSource_QueryDocker(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("web", root, "")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// Newest first; the partial records are joined and the rotated file is read
+	want := []string{"GET /", "upstream timed out", "GET /health", "server starting"}
+	if got := messages(entries); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("messages = %q, want %q", got, want)
+	}
+
+	e := entries[1]
+	if !e.Timestamp.Equal(at(2)) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, at(2))
+	}
+	if e.Stream != "web" {
+		t.Errorf("Stream = %q, want web", e.Stream)
+	}
+	wantFields := map[string]string{
+		"level":        "error",
+		"status":       "504",
+		"stream":       "stderr",
+		"container":    "web",
+		"container_id": webID,
+		"image":        "nginx:1.27",
+	}
+	for k, v := range wantFields {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, e.Fields[k], v)
+		}
+	}
+	wantPtr := "docker://" + filepath.Join(root, "containers", webID, webID+"-json.log") + "#2"
+	if e.Ptr != wantPtr {
+		t.Errorf("Ptr = %q, want %q", e.Ptr, wantPtr)
+	}
+}
+
+func TestSource_QueryMixedOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), webID)
+	writeFile(t, filepath.Join(dir, webID+"-json.log"),
+		dockerLine("stdout", at(0), `{"level":"info","msg":"listening"}`+"\n")+
+			dockerLine("stdout", at(1), "plain banner line\n")+
+			dockerLine("stdout", at(2), "\n"))
+
+	src, err := source.Open("docker://" + filepath.Join(dir, webID+"-json.log"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// Plain output between JSON logs is kept; empty lines are not
+	want := []string{"plain banner line", "listening"}
+	if got := messages(entries); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestSource_QueryMultilineAndFilter(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("worker", root, "")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		Filter: regexp.MustCompile("Exception"),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d: %q", len(entries), messages(entries))
+	}
+
+	// The stack trace records are joined to the line that logged it
+	e := entries[0]
+	if !strings.Contains(e.Message, "Job failed") || !strings.Contains(e.Message, "Job.java:42") {
+		t.Errorf("Message = %q, want joined stack trace", e.Message)
+	}
+	if e.Fields["stream"] != "stderr" || e.Fields["image"] != "worker:2" {
+		t.Errorf("Fields = %v", e.Fields)
+	}
+	if !strings.HasSuffix(e.Ptr, "#2") {
+		t.Errorf("Ptr = %q, want line 2", e.Ptr)
+	}
+}
+
+func TestSource_QueryPods(t *testing.T) {
+	root := newPodRoot(t)
+
+	tests := []struct {
+		name      string
+		uri       string
+		params    source.QueryParams
+		want      []string
+		wantNames []string
+	}{
+		{
+			name: "container",
+			uri:  "k8s-node:///default/web-7d4b9c/nginx?root=" + root,
+			want: []string{"GET /health 200", "starting nginx", "crashed", "starting nginx"},
+		},
+		{
+			name: "pod glob",
+			uri:  "k8s-node:///default/web-*?root=" + root,
+			params: source.QueryParams{
+				StartTime: at(1),
+				EndTime:   at(5),
+			},
+			want: []string{"starting nginx", "sidecar ready", "crashed"},
+		},
+		{
+			name: "no match",
+			uri:  "k8s-node:///kube-system?root=" + root,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			entries, err := src.Query(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if got := messages(entries); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_PodFields(t *testing.T) {
+	root := newPodRoot(t)
+	src, err := NewPodSource("default", "", "nginx", root, "plain")
+	if err != nil {
+		t.Fatalf("NewPodSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	e := entries[0]
+	if e.Stream != "default/web-7d4b9c/nginx" {
+		t.Errorf("Stream = %q", e.Stream)
+	}
+	wantFields := map[string]string{
+		"namespace": "default",
+		"pod":       "web-7d4b9c",
+		"pod_uid":   "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0",
+		"container": "nginx",
+		"stream":    "stdout",
+	}
+	for k, v := range wantFields {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, e.Fields[k], v)
+		}
+	}
+	if want := "k8s-node:///default/*/nginx?format=plain&root=" + url.QueryEscape(root); src.Metadata().URI != want {
+		t.Errorf("URI = %q, want %q", src.Metadata().URI, want)
+	}
+}
+
+func TestSource_GetRecordFromPtr(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("worker", root, "")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	for _, want := range entries {
+		// Pointers reopen the log file directly, as `clew get` does
+		ptrSrc, err := source.OpenFromPtr(want.Ptr, &source.SourceMetadata{Type: "docker", URI: src.Metadata().URI})
+		if err != nil {
+			t.Fatalf("OpenFromPtr(%q) failed: %v", want.Ptr, err)
+		}
+
+		got, err := ptrSrc.GetRecord(context.Background(), want.Ptr)
+		if err != nil {
+			t.Fatalf("GetRecord(%q) failed: %v", want.Ptr, err)
+		}
+		if got.Message != want.Message || !got.Timestamp.Equal(want.Timestamp) || got.Ptr != want.Ptr {
+			t.Errorf("GetRecord(%q) = %+v, want %+v", want.Ptr, got, want)
+		}
+		if got.Stream != "worker" || got.Fields["image"] != "worker:2" {
+			t.Errorf("GetRecord(%q) lost container metadata: %+v", want.Ptr, got)
+		}
+	}
+
+	// Line 3 is a continuation of the entry at line 2
+	file := filepath.Join(root, "containers", workerID, workerID+"-json.log")
+	if _, err := src.GetRecord(context.Background(), source.MakeContainerPtr("docker", file, 3)); err == nil {
+		t.Error("expected error for a line inside an entry")
+	}
+}
+
+func TestSource_FetchContext(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("web", root, "")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	file := filepath.Join(root, "containers", webID, webID+"-json.log")
+	entry := source.Entry{Ptr: source.MakeContainerPtr("docker", file, 2)}
+
+	before, after, err := src.FetchContext(context.Background(), entry, 5, 5)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || !strings.Contains(before[0].Message, "GET /health") {
+		t.Errorf("before = %+v", before)
+	}
+	if len(after) != 1 || !strings.Contains(after[0].Message, `"GET /"`) || !after[0].Timestamp.Equal(at(3)) {
+		t.Errorf("after = %+v", after)
+	}
+
+	// Line 3 holds the second half of the message starting at line 2
+	entry.Ptr = source.MakeContainerPtr("docker", file, 3)
+	if _, _, err := src.FetchContext(context.Background(), entry, 1, 1); err == nil {
+		t.Error("expected error for a line inside a message")
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	root := newPodRoot(t)
+	src, err := NewPodSource("default", "web-7d4b9c", "nginx", root, "")
+	if err != nil {
+		t.Fatalf("NewPodSource failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("GET")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	active := filepath.Join(root, "default_web-7d4b9c_0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0", "nginx", "1.log")
+	f, err := os.OpenFile(active, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	// The second half of the partial message arrives in a later write
+	_, _ = f.WriteString("2025-01-15T10:00:07Z stdout F ignored\n2025-01-15T10:00:08Z stdout P GET /metr")
+	_ = f.Sync()
+	time.Sleep(2 * TailPollInterval)
+	_, _ = f.WriteString("ics\n2025-01-15T10:00:08Z stdout F  200\n")
+	_ = f.Close()
+
+	select {
+	case event := <-events:
+		if event.Message != "GET /metrics 200" {
+			t.Errorf("Message = %q, want %q", event.Message, "GET /metrics 200")
+		}
+		if event.Stream != "default/web-7d4b9c/nginx" || !event.Timestamp.Equal(at(8)) {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tail event")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestSource_TailFollowsRotation(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("web", root, "plain")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	// Docker renames the active file and starts a new one
+	active := filepath.Join(root, "containers", webID, webID+"-json.log")
+	if err := os.Rename(active, active+".1"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	writeFile(t, active, dockerLine("stdout", at(20), "after rotation\n"))
+
+	select {
+	case event := <-events:
+		if event.Message != "after rotation" {
+			t.Errorf("Message = %q, want %q", event.Message, "after rotation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tail event")
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	root := newDockerRoot(t)
+	src, err := NewDockerSource("web", root, "")
+	if err != nil {
+		t.Fatalf("NewDockerSource failed: %v", err)
+	}
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 streams, got %d", len(streams))
+	}
+	if !strings.HasSuffix(streams[0].Name, "-json.log.1.gz") || !strings.HasSuffix(streams[1].Name, "-json.log") {
+		t.Errorf("streams = %+v", streams)
+	}
+}
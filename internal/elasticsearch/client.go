@@ -0,0 +1,125 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client configuration values
+const (
+	// RequestTimeout is the maximum time to wait for a search API request
+	RequestTimeout = 60 * time.Second
+
+	// maxErrorBodySize is how much of an error response body is included in errors
+	maxErrorBodySize = 1024
+)
+
+// Client calls the Elasticsearch (or OpenSearch) REST API. Credentials are
+// read from ES_USERNAME and ES_PASSWORD for basic auth, or ES_API_KEY.
+type Client struct {
+	baseURL  string // e.g. http://localhost:9200
+	username string
+	password string
+	apiKey   string
+	http     *http.Client
+}
+
+// NewClient creates a client for the cluster at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: os.Getenv("ES_USERNAME"),
+		password: os.Getenv("ES_PASSWORD"),
+		apiKey:   os.Getenv("ES_API_KEY"),
+		http:     &http.Client{Timeout: RequestTimeout},
+	}
+}
+
+// Hit is a document returned by a search or get request.
+type Hit struct {
+	Index  string            `json:"_index"`
+	ID     string            `json:"_id"`
+	Source json.RawMessage   `json:"_source"`
+	Sort   []json.RawMessage `json:"sort"`
+}
+
+// SearchResponse is the part of a _search response clew uses.
+type SearchResponse struct {
+	Hits struct {
+		Hits []Hit `json:"hits"`
+	} `json:"hits"`
+	Aggregations json.RawMessage `json:"aggregations"`
+}
+
+// Search runs a search request body against an index or index pattern.
+func (c *Client) Search(ctx context.Context, index string, body map[string]any) (*SearchResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp SearchResponse
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", bytes.NewReader(data), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Get retrieves a document by index and ID.
+func (c *Client) Get(ctx context.Context, index, id string) (*Hit, error) {
+	var resp struct {
+		Hit
+		Found bool `json:"found"`
+	}
+	err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Found {
+		return nil, fmt.Errorf("document %s not found in %s", id, index)
+	}
+	return &resp.Hit, nil
+}
+
+// do performs a request against the API and decodes the JSON response.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("elasticsearch request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// A missing document is reported in the body of a 404 response
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusNotFound && strings.Contains(path, "/_doc/")) {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("elasticsearch returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid elasticsearch response: %w", err)
+	}
+	return nil
+}
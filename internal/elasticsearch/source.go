@@ -0,0 +1,647 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// DefaultTimeField is the document field holding the event time (ECS and Beats default)
+	DefaultTimeField = "@timestamp"
+
+	// DefaultMessageField is the document field holding the log message
+	DefaultMessageField = "message"
+
+	// DefaultStreamField groups documents into streams for context and stream listing
+	DefaultStreamField = "host.name"
+
+	// QueryPageSize is the number of documents requested per search
+	QueryPageSize = 1000
+
+	// MaxListedStreams caps the number of streams returned by ListStreams
+	MaxListedStreams = 1000
+
+	// TailPollInterval is how often new documents are searched for while tailing
+	TailPollInterval = 2 * time.Second
+)
+
+func init() {
+	source.Register("es", openSource)
+}
+
+// Source implements source.Source for Elasticsearch and OpenSearch indices.
+// Documents are searched over the REST API; their flattened _source becomes
+// entry fields. Pointers are the index and document ID.
+type Source struct {
+	client        *Client
+	host          string
+	index         string // index name or pattern, e.g. logs-*
+	timeField     string
+	messageField  string
+	streamField   string
+	uri           string
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// Options configures the document fields a source reads.
+type Options struct {
+	TimeField    string // defaults to DefaultTimeField
+	MessageField string // defaults to DefaultMessageField
+	StreamField  string // defaults to DefaultStreamField
+	TLS          bool
+}
+
+// openSource opens an Elasticsearch source from a parsed URL:
+// es://host:9200/logs-*?time=@timestamp&message=message&stream=host.name&tls=true
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("es URI needs a host, e.g. es://localhost:9200/logs-*")
+	}
+	index := strings.Trim(u.Path, "/")
+	if index == "" {
+		return nil, fmt.Errorf("es URI needs an index or pattern, e.g. es://%s/logs-*", u.Host)
+	}
+	// A pointer path (index/id) names a single index
+	index, _, _ = strings.Cut(index, "/")
+
+	query := u.Query()
+	opts := Options{
+		TimeField:    query.Get("time"),
+		MessageField: query.Get("message"),
+		StreamField:  query.Get("stream"),
+	}
+	if v := query.Get("tls"); v != "" {
+		var err error
+		if opts.TLS, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid es tls value %q", v)
+		}
+	}
+
+	return NewSource(u.Host, index, opts), nil
+}
+
+// NewSource creates a source for an index or index pattern on the cluster at host.
+func NewSource(host, index string, opts Options) *Source {
+	if opts.TimeField == "" {
+		opts.TimeField = DefaultTimeField
+	}
+	if opts.MessageField == "" {
+		opts.MessageField = DefaultMessageField
+	}
+	if opts.StreamField == "" {
+		opts.StreamField = DefaultStreamField
+	}
+
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+	}
+
+	return &Source{
+		client:       NewClient(scheme + "://" + host),
+		host:         host,
+		index:        index,
+		timeField:    opts.TimeField,
+		messageField: opts.MessageField,
+		streamField:  opts.StreamField,
+		uri:          buildURI(host, index, opts),
+	}
+}
+
+// buildURI builds the canonical URI for a source, keeping non-default field
+// settings so sources reopened from cached pointers read documents the same way.
+func buildURI(host, index string, opts Options) string {
+	query := url.Values{}
+	if opts.TimeField != DefaultTimeField {
+		query.Set("time", opts.TimeField)
+	}
+	if opts.MessageField != DefaultMessageField {
+		query.Set("message", opts.MessageField)
+	}
+	if opts.StreamField != DefaultStreamField {
+		query.Set("stream", opts.StreamField)
+	}
+	if opts.TLS {
+		query.Set("tls", "true")
+	}
+
+	uri := "es://" + host + "/" + index
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return uri
+}
+
+// timeRange returns a range filter on the time field. Bounds that are zero are omitted.
+func (s *Source) timeRange(bounds map[string]time.Time) map[string]any {
+	rng := map[string]any{"format": "strict_date_optional_time_nanos"}
+	for op, t := range bounds {
+		if !t.IsZero() {
+			rng[op] = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return map[string]any{"range": map[string]any{s.timeField: rng}}
+}
+
+// filterQuery translates a filter regex into a search clause on the message
+// field. Alternatives of plain words become a query_string of wildcards and
+// phrases, which uses the full-text index; anything else becomes a regexp
+// query. Both match per analyzed term, so results are checked against the
+// regex again after searching.
+func (s *Source) filterQuery(filter *regexp.Regexp) map[string]any {
+	pattern := filter.String()
+	caseInsensitive := strings.HasPrefix(pattern, "(?i)")
+	pattern = strings.TrimPrefix(pattern, "(?i)")
+
+	if terms, ok := plainAlternatives(pattern); ok {
+		parts := make([]string, len(terms))
+		for i, term := range terms {
+			if strings.ContainsAny(term, " \t") {
+				parts[i] = strconv.Quote(term)
+			} else {
+				parts[i] = "*" + escapeQueryString(term) + "*"
+			}
+		}
+		return map[string]any{"query_string": map[string]any{
+			"query":            strings.Join(parts, " OR "),
+			"default_field":    s.messageField,
+			"analyze_wildcard": true,
+		}}
+	}
+
+	return map[string]any{"regexp": map[string]any{s.messageField: map[string]any{
+		"value":            ".*" + pattern + ".*",
+		"case_insensitive": caseInsensitive,
+	}}}
+}
+
+// plainAlternatives splits a pattern of literal alternatives such as
+// "error|timed out" into its terms. It reports false for any other regex.
+func plainAlternatives(pattern string) ([]string, bool) {
+	terms := strings.Split(pattern, "|")
+	for _, term := range terms {
+		if term == "" || regexp.QuoteMeta(term) != term {
+			return nil, false
+		}
+	}
+	return terms, true
+}
+
+// queryStringEscaper escapes query_string reserved characters.
+var queryStringEscaper = strings.NewReplacer(
+	`\`, `\\`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `&`, `\&`, `|`, `\|`, `!`, `\!`,
+	`(`, `\(`, `)`, `\)`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`, `^`, `\^`,
+	`"`, `\"`, `~`, `\~`, `*`, `\*`, `?`, `\?`, `:`, `\:`, `/`, `\/`,
+)
+
+func escapeQueryString(s string) string {
+	return queryStringEscaper.Replace(s)
+}
+
+// searchBody builds a search request body for the query parameters.
+func (s *Source) searchBody(params source.QueryParams, size int, order string) map[string]any {
+	filters := []any{s.timeRange(map[string]time.Time{"gte": params.StartTime, "lte": params.EndTime})}
+	if params.Filter != nil {
+		filters = append(filters, s.filterQuery(params.Filter))
+	}
+	boolQuery := map[string]any{"filter": filters}
+	if q := strings.TrimSpace(params.Query); q != "" {
+		// -q is a Lucene query_string, as typed in Kibana or OpenSearch Dashboards
+		boolQuery["must"] = []any{map[string]any{"query_string": map[string]any{"query": q}}}
+	}
+
+	return map[string]any{
+		"size":             size,
+		"track_total_hits": false,
+		"query":            map[string]any{"bool": boolQuery},
+		// _doc breaks ties between documents with the same timestamp
+		"sort": []any{
+			map[string]any{s.timeField: map[string]any{"order": order, "unmapped_type": "date"}},
+			"_doc",
+		},
+	}
+}
+
+// Query returns log entries matching the given parameters, newest first.
+// Results are paged with search_after until the limit is reached.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	pageSize := QueryPageSize
+	if params.Limit > 0 && params.Limit < pageSize {
+		pageSize = params.Limit
+	}
+
+	body := s.searchBody(params, pageSize, "desc")
+	seen := make(map[string]bool)
+	var results []source.Entry
+
+	for {
+		resp, err := s.client.Search(ctx, s.index, body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("error reading %s: %w", s.uri, err)
+		}
+
+		hits := resp.Hits.Hits
+		for _, hit := range hits {
+			entry, err := s.convertHit(hit)
+			if err != nil {
+				logging.Debug("Skipping document %s/%s: %v", hit.Index, hit.ID, err)
+				continue
+			}
+			// Without a point in time, documents indexed between pages can shift ties
+			if seen[entry.Ptr] {
+				continue
+			}
+			seen[entry.Ptr] = true
+			if local.MatchesParams(entry, params) {
+				results = append(results, entry)
+			}
+		}
+
+		if len(hits) < pageSize || (params.Limit > 0 && len(results) >= params.Limit) {
+			break
+		}
+		body["search_after"] = hits[len(hits)-1].Sort
+	}
+
+	// Sort by timestamp (newest first), as time fields of mixed indices may sort differently
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// convertHit converts a document to an entry. The flattened _source becomes
+// the entry's fields, except the message field.
+func (s *Source) convertHit(hit Hit) (source.Entry, error) {
+	fields, err := flattenSource(hit.Source)
+	if err != nil {
+		return source.Entry{}, err
+	}
+
+	timestamp, ok := parseTime(fields[s.timeField])
+	if !ok && len(hit.Sort) > 0 {
+		// The time field may be a runtime field; the sort value is epoch millis
+		timestamp, ok = parseTime(string(hit.Sort[0]))
+	}
+	if !ok {
+		return source.Entry{}, fmt.Errorf("no valid %s field", s.timeField)
+	}
+
+	message, ok := fields[s.messageField]
+	if ok {
+		delete(fields, s.messageField)
+	} else {
+		// Documents without a message field are shown whole
+		message = string(compactJSON(hit.Source))
+	}
+
+	stream := fields[s.streamField]
+	if stream == "" {
+		stream = hit.Index
+	}
+
+	return source.Entry{
+		Timestamp: timestamp,
+		Message:   message,
+		Stream:    stream,
+		Source:    hit.Index,
+		Ptr:       source.MakeElasticsearchPtr(s.host, hit.Index, hit.ID),
+		Fields:    fields,
+	}, nil
+}
+
+// flattenSource flattens a document's _source into dotted field names.
+// Arrays are kept as JSON.
+func flattenSource(data json.RawMessage) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid _source: %w", err)
+	}
+
+	fields := make(map[string]string)
+	flattenInto(fields, "", doc)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]string, prefix string, doc map[string]any) {
+	for key, value := range doc {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenInto(fields, name, v)
+		case string:
+			fields[name] = v
+		case nil:
+			fields[name] = ""
+		case json.Number:
+			fields[name] = v.String()
+		case bool:
+			fields[name] = strconv.FormatBool(v)
+		default:
+			data, _ := json.Marshal(v)
+			fields[name] = string(data)
+		}
+	}
+}
+
+// compactJSON returns JSON without insignificant whitespace.
+func compactJSON(data json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// parseTime parses a time field value: an ISO 8601 date as Elasticsearch
+// accepts it (without a zone meaning UTC), or epoch milliseconds.
+func parseTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if millis, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.UnixMicro(int64(millis * 1000)), true
+	}
+	return time.Time{}, false
+}
+
+// Tail streams new documents by searching for documents newer than the last
+// one seen. Documents indexed late with an older timestamp are not shown.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	go s.tailLoop(ctx, time.Now(), params, events)
+
+	return events, nil
+}
+
+// tailLoop polls for new documents until the context is cancelled.
+func (s *Source) tailLoop(ctx context.Context, since time.Time, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+
+	ticker := time.NewTicker(TailPollInterval)
+	defer ticker.Stop()
+
+	// Documents at the last seen timestamp, which the next search includes again
+	seen := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		body := s.searchBody(source.QueryParams{StartTime: since}, QueryPageSize, "asc")
+		resp, err := s.client.Search(ctx, s.index, body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The cluster may be briefly unavailable; try again next poll
+			logging.Debug("Failed to search %s: %v", s.uri, err)
+			continue
+		}
+
+		for _, hit := range resp.Hits.Hits {
+			entry, err := s.convertHit(hit)
+			if err != nil || seen[entry.Ptr] {
+				continue
+			}
+			if entry.Timestamp.After(since) {
+				since = entry.Timestamp
+				seen = make(map[string]bool)
+			}
+			seen[entry.Ptr] = true
+			s.emitEntry(&entry, params, events)
+		}
+	}
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, params source.TailParams, events chan<- source.Event) {
+	// Apply filter
+	if params.Filter != nil && !params.Filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// GetRecord retrieves a single document by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseElasticsearchPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid Elasticsearch pointer: %s", ptr)
+	}
+
+	hit, err := s.client.Get(ctx, info.Index, info.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.convertHit(*hit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read document %s/%s: %w", info.Index, info.ID, err)
+	}
+	return &entry, nil
+}
+
+// FetchContext retrieves the documents logged just before and after an entry
+// with the same stream field value (e.g. the same host).
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	var beforeLines, afterLines []source.Event
+
+	if before > 0 {
+		hits, err := s.neighbours(ctx, entry, "lt", "desc", before)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range hits {
+			beforeLines = append([]source.Event{e}, beforeLines...)
+		}
+	}
+
+	if after > 0 {
+		hits, err := s.neighbours(ctx, entry, "gt", "asc", after)
+		if err != nil {
+			return nil, nil, err
+		}
+		afterLines = hits
+	}
+
+	return beforeLines, afterLines, nil
+}
+
+// neighbours returns up to n documents of the entry's stream on one side of
+// its timestamp, nearest first.
+func (s *Source) neighbours(ctx context.Context, entry source.Entry, op, order string, n int) ([]source.Event, error) {
+	filters := []any{s.timeRange(map[string]time.Time{op: entry.Timestamp})}
+	if value := entry.Fields[s.streamField]; value != "" {
+		filters = append(filters, map[string]any{"match_phrase": map[string]any{s.streamField: value}})
+	}
+
+	body := map[string]any{
+		"size":             n,
+		"track_total_hits": false,
+		"query":            map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort": []any{
+			map[string]any{s.timeField: map[string]any{"order": order, "unmapped_type": "date"}},
+			"_doc",
+		},
+	}
+
+	resp, err := s.client.Search(ctx, s.index, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []source.Event
+	for _, hit := range resp.Hits.Hits {
+		e, err := s.convertHit(hit)
+		if err != nil {
+			continue
+		}
+		events = append(events, source.Event{
+			Timestamp: e.Timestamp,
+			Message:   e.Message,
+			Stream:    e.Stream,
+		})
+	}
+	return events, nil
+}
+
+// ListStreams returns the values of the stream field with their document
+// counts and time ranges.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	body := map[string]any{
+		"size":             0,
+		"track_total_hits": false,
+		"aggs": map[string]any{
+			"streams": map[string]any{
+				"terms": map[string]any{"field": s.streamField, "size": MaxListedStreams},
+				"aggs": map[string]any{
+					"first": map[string]any{"min": map[string]any{"field": s.timeField}},
+					"last":  map[string]any{"max": map[string]any{"field": s.timeField}},
+				},
+			},
+		},
+	}
+
+	resp, err := s.client.Search(ctx, s.index, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list %s values (it must be a keyword field; set ?stream=): %w", s.streamField, err)
+	}
+
+	var aggs struct {
+		Streams struct {
+			Buckets []struct {
+				Key      any   `json:"key"`
+				DocCount int64 `json:"doc_count"`
+				First    struct {
+					Value json.Number `json:"value"`
+				} `json:"first"`
+				Last struct {
+					Value json.Number `json:"value"`
+				} `json:"last"`
+			} `json:"buckets"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(resp.Aggregations, &aggs); err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch aggregation: %w", err)
+	}
+
+	streams := make([]source.StreamInfo, 0, len(aggs.Streams.Buckets))
+	for _, b := range aggs.Streams.Buckets {
+		info := source.StreamInfo{
+			Name: fmt.Sprint(b.Key),
+			Size: b.DocCount,
+		}
+		info.FirstTime, _ = parseTime(b.First.Value.String())
+		info.LastTime, _ = parseTime(b.Last.Value.String())
+		streams = append(streams, info)
+	}
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "elasticsearch"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "elasticsearch",
+		URI:  s.uri,
+	}
+}
+
+// Close releases resources. There are no persistent connections to close.
+func (s *Source) Close() error {
+	return nil
+}
@@ -0,0 +1,506 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// testDoc is a document stored by the fake cluster.
+type testDoc struct {
+	index  string
+	id     string
+	source map[string]any
+}
+
+// fakeES serves _search and _doc over fixed documents. Searches support the
+// time range and match_phrase filters, time field sorting and search_after;
+// other clauses are recorded but match every document.
+type fakeES struct {
+	docs []testDoc
+
+	mu       sync.Mutex
+	searches []map[string]any
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	switch {
+	case len(parts) == 2 && parts[1] == "_search":
+		var body map[string]any
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.searches = append(f.searches, body)
+		f.mu.Unlock()
+		f.search(w, parts[0], body)
+	case len(parts) == 3 && parts[1] == "_doc":
+		for _, d := range f.docs {
+			if d.index == parts[0] && d.id == parts[2] {
+				writeJSON(w, map[string]any{"_index": d.index, "_id": d.id, "found": true, "_source": d.source})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]any{"_index": parts[0], "_id": parts[2], "found": false})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeES) search(w http.ResponseWriter, pattern string, body map[string]any) {
+	filters, _ := body["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+	order := "asc"
+	if sorts, ok := body["sort"].([]any); ok {
+		order = sorts[0].(map[string]any)["@timestamp"].(map[string]any)["order"].(string)
+	}
+
+	type hit struct {
+		doc testDoc
+		ts  int64
+		pos int
+	}
+	var hits []hit
+	for pos, d := range f.docs {
+		if ok, _ := matchPattern(pattern, d.index); !ok {
+			continue
+		}
+		ts, _ := time.Parse(time.RFC3339Nano, d.source["@timestamp"].(string))
+		if matchesFilters(d, ts, filters) {
+			hits = append(hits, hit{d, ts.UnixMilli(), pos})
+		}
+	}
+
+	less := func(a, b hit) bool {
+		if a.ts != b.ts {
+			return (a.ts < b.ts) == (order == "asc")
+		}
+		return a.pos < b.pos
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return less(hits[i], hits[j]) })
+
+	if after, ok := body["search_after"].([]any); ok {
+		ts, _ := after[0].(json.Number).Int64()
+		pos, _ := after[1].(json.Number).Int64()
+		cursor := hit{ts: ts, pos: int(pos)}
+		for len(hits) > 0 && !less(cursor, hits[0]) {
+			hits = hits[1:]
+		}
+	}
+
+	size, _ := body["size"].(json.Number).Int64()
+	if len(hits) > int(size) {
+		hits = hits[:size]
+	}
+
+	result := make([]map[string]any, len(hits))
+	for i, h := range hits {
+		result[i] = map[string]any{"_index": h.doc.index, "_id": h.doc.id, "_source": h.doc.source, "sort": []any{h.ts, h.pos}}
+	}
+	writeJSON(w, map[string]any{"hits": map[string]any{"hits": result}})
+}
+
+// matchPattern matches an index name against a comma-separated index pattern.
+func matchPattern(pattern, index string) (bool, error) {
+	for _, p := range strings.Split(pattern, ",") {
+		re := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*") + "$"
+		if regexp.MustCompile(re).MatchString(index) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesFilters applies the range and match_phrase filters of a search.
+func matchesFilters(d testDoc, ts time.Time, filters []any) bool {
+	for _, f := range filters {
+		clause := f.(map[string]any)
+		if rng, ok := clause["range"].(map[string]any); ok {
+			for op, v := range rng["@timestamp"].(map[string]any) {
+				if op == "format" {
+					continue
+				}
+				bound, _ := time.Parse(time.RFC3339Nano, v.(string))
+				if (op == "gte" && ts.Before(bound)) || (op == "lte" && ts.After(bound)) ||
+					(op == "gt" && !ts.After(bound)) || (op == "lt" && !ts.Before(bound)) {
+					return false
+				}
+			}
+		}
+		if phrase, ok := clause["match_phrase"].(map[string]any); ok {
+			for field, v := range phrase {
+				host, _ := d.source["host"].(map[string]any)
+				if field != "host.name" || host["name"] != v {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// base is the time of the first test document.
+var base = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// testDocs returns n documents per host, one second apart, alternating hosts.
+func testDocs(n int, hosts ...string) []testDoc {
+	var docs []testDoc
+	for i := 0; i < n; i++ {
+		for _, host := range hosts {
+			ts := base.Add(time.Duration(i) * time.Second)
+			docs = append(docs, testDoc{
+				index: "logs-" + ts.Format("2006.01.02"),
+				id:    host + "-" + strconv.Itoa(i),
+				source: map[string]any{
+					"@timestamp": ts.Format(time.RFC3339Nano),
+					"message":    host + " event " + strconv.Itoa(i),
+					"host":       map[string]any{"name": host},
+					"http":       map[string]any{"status": 200 + i%2*300},
+				},
+			})
+		}
+	}
+	return docs
+}
+
+// newTestSource starts a fake cluster and opens a source for pattern against it.
+func newTestSource(t *testing.T, f *fakeES, pattern string) *Source {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	src, err := source.Open("es://" + strings.TrimPrefix(server.URL, "http://") + "/" + pattern)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return src.(*Source)
+}
+
+func TestSource_QueryPaging(t *testing.T) {
+	f := &fakeES{docs: testDocs(QueryPageSize, "web-1", "web-2")}
+	src := newTestSource(t, f, "logs-*")
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: base,
+		EndTime:   base.Add(time.Hour),
+		Limit:     QueryPageSize + 10,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(entries) != QueryPageSize+10 {
+		t.Fatalf("expected %d entries, got %d", QueryPageSize+10, len(entries))
+	}
+	if len(f.searches) != 2 {
+		t.Errorf("expected 2 searches, got %d", len(f.searches))
+	}
+	if _, ok := f.searches[1]["search_after"]; !ok {
+		t.Error("expected the second page to use search_after")
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		if i > 0 && e.Timestamp.After(entries[i-1].Timestamp) {
+			t.Fatalf("entries not sorted newest first at %d", i)
+		}
+		if seen[e.Ptr] {
+			t.Fatalf("duplicate entry %s", e.Ptr)
+		}
+		seen[e.Ptr] = true
+	}
+
+	// The flattened _source becomes fields, without the message
+	e := entries[0]
+	if e.Fields["host.name"] == "" || e.Fields["http.status"] != "500" {
+		t.Errorf("Fields = %v", e.Fields)
+	}
+	if _, ok := e.Fields["message"]; ok {
+		t.Error("expected message to be removed from fields")
+	}
+	if e.Stream != e.Fields["host.name"] {
+		t.Errorf("Stream = %q, want host name", e.Stream)
+	}
+}
+
+func TestSource_QueryFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "word",
+			filter: "(?i)denied",
+			want:   `{"query_string":{"analyze_wildcard":true,"default_field":"message","query":"*denied*"}}`,
+		},
+		{
+			name:   "alternatives and phrases",
+			filter: "timeout|access denied|a:b",
+			want:   `{"query_string":{"analyze_wildcard":true,"default_field":"message","query":"*timeout* OR \"access denied\" OR *a\\:b*"}}`,
+		},
+		{
+			name:   "regex",
+			filter: `(?i)user \d+ failed`,
+			want:   `{"regexp":{"message":{"case_insensitive":true,"value":".*user \\d+ failed.*"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := NewSource("localhost:9200", "logs-*", Options{})
+			got, _ := json.Marshal(src.filterQuery(regexp.MustCompile(tt.filter)))
+			if string(got) != tt.want {
+				t.Errorf("filterQuery(%q) = %s, want %s", tt.filter, got, tt.want)
+			}
+		})
+	}
+
+	// Results are checked against the regex, as the search matches per term
+	f := &fakeES{docs: testDocs(5, "web-1")}
+	src := newTestSource(t, f, "logs-*")
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: base,
+		EndTime:   base.Add(time.Hour),
+		Filter:    regexp.MustCompile("event 3$"),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "web-1 event 3" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestSource_GetRecordAndContext(t *testing.T) {
+	f := &fakeES{docs: testDocs(5, "web-1", "web-2")}
+	src := newTestSource(t, f, "logs-*")
+
+	target := f.docs[4] // web-1, third second
+	ptr := source.MakeElasticsearchPtr(src.host, target.index, target.id)
+
+	entry, err := src.GetRecord(context.Background(), ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != target.source["message"] || entry.Ptr != ptr {
+		t.Errorf("GetRecord = %q (%s)", entry.Message, entry.Ptr)
+	}
+
+	before, after, err := src.FetchContext(context.Background(), *entry, 5, 1)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	// Context comes from the same host only, oldest first
+	var got []string
+	for _, e := range append(before, after...) {
+		got = append(got, e.Message)
+	}
+	want := []string{"web-1 event 0", "web-1 event 1", "web-1 event 3"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("context = %v, want %v", got, want)
+	}
+
+	if _, err := src.GetRecord(context.Background(), source.MakeElasticsearchPtr(src.host, target.index, "missing")); err == nil {
+		t.Error("expected error for a missing document")
+	}
+}
+
+func TestSource_PointerReopens(t *testing.T) {
+	f := &fakeES{docs: testDocs(2, "web-1")}
+	server := httptest.NewServer(f)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	src, err := source.Open("es://" + host + "/logs-*?stream=host.name&message=message&time=@timestamp")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	entries, err := src.Query(context.Background(), source.QueryParams{StartTime: base, EndTime: base.Add(time.Minute), Limit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query = %d entries, %v", len(entries), err)
+	}
+
+	// As with `clew get` in a later invocation, the pointer and cached metadata reopen the source
+	meta := src.Metadata()
+	reopened, err := source.OpenFromPtr(entries[0].Ptr, &meta)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	if got := reopened.Metadata().URI; got != "es://"+host+"/logs-*" {
+		t.Errorf("URI = %q, want the index pattern", got)
+	}
+	got, err := reopened.GetRecord(context.Background(), entries[0].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if got.Message != entries[0].Message {
+		t.Errorf("Message = %q, want %q", got.Message, entries[0].Message)
+	}
+}
+
+func TestConvertHit(t *testing.T) {
+	src := NewSource("localhost:9200", "logs-*", Options{TimeField: "ts", MessageField: "log", StreamField: "service"})
+
+	tests := []struct {
+		name        string
+		hit         Hit
+		wantTime    time.Time
+		wantMessage string
+		wantStream  string
+		wantErr     bool
+	}{
+		{
+			name:        "configured fields",
+			hit:         Hit{Index: "app", ID: "1", Source: json.RawMessage(`{"ts":"2025-01-15T10:00:00.123Z","log":"hello","service":"api","tags":["a","b"]}`)},
+			wantTime:    base.Add(123 * time.Millisecond),
+			wantMessage: "hello",
+			wantStream:  "api",
+		},
+		{
+			name:        "epoch millis and no message",
+			hit:         Hit{Index: "app", ID: "2", Source: json.RawMessage(`{"ts": 1736935200000, "level": "info"}`)},
+			wantTime:    base,
+			wantMessage: `{"ts":1736935200000,"level":"info"}`,
+			wantStream:  "app",
+		},
+		{
+			name:     "time from sort value",
+			hit:      Hit{Index: "app", ID: "3", Source: json.RawMessage(`{"log":"x"}`), Sort: []json.RawMessage{json.RawMessage("1736935200000")}},
+			wantTime: base, wantMessage: "x", wantStream: "app",
+		},
+		{
+			name:    "no time",
+			hit:     Hit{Index: "app", ID: "4", Source: json.RawMessage(`{"log":"x"}`)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := src.convertHit(tt.hit)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertHit failed: %v", err)
+			}
+			if !entry.Timestamp.Equal(tt.wantTime) {
+				t.Errorf("Timestamp = %v, want %v", entry.Timestamp, tt.wantTime)
+			}
+			if entry.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", entry.Message, tt.wantMessage)
+			}
+			if entry.Stream != tt.wantStream {
+				t.Errorf("Stream = %q, want %q", entry.Stream, tt.wantStream)
+			}
+		})
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	f := &fakeES{}
+	src := newTestSource(t, f, "logs-*")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	// A document indexed after the tail started is picked up by the next poll
+	f.mu.Lock()
+	f.docs = append(f.docs, testDoc{index: "logs-today", id: "new", source: map[string]any{
+		"@timestamp": time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano),
+		"message":    "new event",
+	}})
+	f.mu.Unlock()
+
+	select {
+	case event := <-events:
+		if event.Message != "new event" {
+			t.Errorf("Message = %q", event.Message)
+		}
+	case <-time.After(3 * TailPollInterval):
+		t.Fatal("timed out waiting for tail event")
+	}
+
+	cancel()
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail did not stop after cancel")
+	}
+}
+
+func TestOpenSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr bool
+		wantURI string
+	}{
+		{
+			name:    "defaults",
+			uri:     "es://localhost:9200/logs-*/",
+			wantURI: "es://localhost:9200/logs-*",
+		},
+		{
+			name:    "fields and tls",
+			uri:     "es://search.example.com/filebeat-*,auditbeat-*?tls=true&time=event.created&stream=agent.name&message=message",
+			wantURI: "es://search.example.com/filebeat-*,auditbeat-*?stream=agent.name&time=event.created&tls=true",
+		},
+		{
+			name:    "no index",
+			uri:     "es://localhost:9200",
+			wantErr: true,
+		},
+		{
+			name:    "invalid tls value",
+			uri:     "es://localhost:9200/logs?tls=maybe",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s", tt.uri)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if got := src.Metadata().URI; got != tt.wantURI {
+				t.Errorf("URI = %q, want %q", got, tt.wantURI)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client configuration values
+const (
+	// ConnectTimeout bounds how long to wait for a server's response headers.
+	// Bodies may be large, so reading them is bounded by the context only.
+	ConnectTimeout = 30 * time.Second
+
+	// maxErrorBodySize is how much of an error response body is included in errors
+	maxErrorBodySize = 512
+)
+
+// errRangeNotSatisfiable is returned when a requested offset is past the end of the content.
+var errRangeNotSatisfiable = errors.New("requested range is past the end of the content")
+
+// Client fetches log content over HTTP(S) with optional extra headers, such
+// as Authorization headers from an alias.
+type Client struct {
+	http    *http.Client
+	headers http.Header
+}
+
+// NewClient creates a client that sends headers with every request.
+// Environment variables in header values ($VAR or ${VAR}) are expanded, so
+// secrets can stay out of the config file.
+func NewClient(headers map[string]string) *Client {
+	h := http.Header{}
+	for name, value := range headers {
+		h.Set(name, os.ExpandEnv(value))
+	}
+	// Byte offsets are into the content as stored; never let the server
+	// re-encode it, which would also disable transparent decompression
+	h.Set("Accept-Encoding", "identity")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = ConnectTimeout
+
+	return &Client{
+		http:    &http.Client{Transport: transport},
+		headers: h,
+	}
+}
+
+// Stat returns the size and last modification time of the content at rawURL.
+// The size is -1 if the server does not report it.
+func (c *Client) Stat(ctx context.Context, rawURL string) (int64, time.Time, error) {
+	resp, err := c.do(ctx, http.MethodHead, rawURL, "")
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	_ = resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.ContentLength, modTime, nil
+}
+
+// Get opens the content at rawURL from byte start to byte end (inclusive).
+// An end of -1 reads to the end of the content. Servers that ignore Range
+// requests are handled by skipping to start, at the cost of downloading it.
+func (c *Client) Get(ctx context.Context, rawURL string, start, end int64) (io.ReadCloser, error) {
+	var rangeHeader string
+	if start > 0 || end >= 0 {
+		rangeHeader = "bytes=" + strconv.FormatInt(start, 10) + "-"
+		if end >= 0 {
+			rangeHeader += strconv.FormatInt(end, 10)
+		}
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, rawURL, rangeHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK && start > 0 {
+		if _, err := io.CopyN(io.Discard, resp.Body, start); err != nil {
+			_ = resp.Body.Close()
+			return nil, errRangeNotSatisfiable
+		}
+	}
+	if resp.StatusCode == http.StatusOK && end >= 0 {
+		return readCloser{io.LimitReader(resp.Body, end-start+1), resp.Body}, nil
+	}
+	return resp.Body, nil
+}
+
+// do sends a request and returns the response if it succeeded.
+func (c *Client) do(ctx context.Context, method, rawURL, rangeHeader string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.headers.Clone()
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = resp.Body.Close()
+		return nil, errRangeNotSatisfiable
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		_ = resp.Body.Close()
+		if msg := strings.TrimSpace(string(body)); msg != "" && method != http.MethodHead {
+			return nil, fmt.Errorf("%s returned %s: %s", rawURL, resp.Status, msg)
+		}
+		return nil, fmt.Errorf("%s returned %s", rawURL, resp.Status)
+	}
+}
+
+// readCloser reads from a limited view of a body and closes the body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
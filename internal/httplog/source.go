@@ -0,0 +1,643 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// FormatDetectionSampleSize is how many bytes are sampled to detect the log format
+	FormatDetectionSampleSize = 64 * 1024
+
+	// ContextWindowSize is how many bytes on each side of an entry are first
+	// requested for a record or its context; windows grow until they hold enough lines
+	ContextWindowSize = 16 * 1024
+
+	// TailPollInterval is how often the content length is checked while tailing
+	TailPollInterval = 2 * time.Second
+)
+
+// errEntryIncomplete is returned when a window of the content ends before the entry does.
+var errEntryIncomplete = errors.New("entry continues past the end of the window")
+
+func init() {
+	source.Register("http", openSource)
+	source.Register("https", openSource)
+}
+
+// Source implements source.Source for a log served over HTTP(S), such as a
+// CI job log or build artifact. Content is read with Range requests where
+// possible, so records and context are fetched without downloading the whole
+// log. Pointers use the byte offset of an entry, as for S3.
+type Source struct {
+	url           string // log URL as requested, without clew's format parameter
+	client        *Client
+	format        local.Format
+	uri           string
+	droppedEvents int64 // atomic counter for dropped events during tail
+
+	probeOnce  sync.Once
+	probed     content
+	probeError error
+}
+
+// content describes how the content at the URL is stored.
+type content struct {
+	compression local.Compression
+	format      local.Format
+}
+
+// openSource opens an HTTP source from a parsed URL. A format query
+// parameter (added by --format) is taken as the format hint and not sent to
+// the server; headers come from the alias the URL was opened through.
+func openSource(u *url.URL, opts source.OpenOptions) (source.Source, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s URI requires a host (e.g., https://ci.example.com/job/42/log.txt)", u.Scheme)
+	}
+
+	query := u.Query()
+	formatHint := query.Get("format")
+	query.Del("format")
+	if err := local.CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	logURL := *u
+	logURL.RawQuery = query.Encode()
+	logURL.Fragment = ""
+
+	return NewSource(logURL.String(), formatHint, opts.Headers), nil
+}
+
+// NewSource creates a source for the log at rawURL. The formatHint specifies
+// the log format (auto, plain, json, syslog, java).
+func NewSource(rawURL, formatHint string, headers map[string]string) *Source {
+	return &Source{
+		url:    rawURL,
+		client: NewClient(headers),
+		format: local.ParseFormat(formatHint),
+		uri:    buildURI(rawURL, formatHint),
+	}
+}
+
+// buildURI builds the canonical URI for a source, keeping the format hint so
+// sources reopened from cached pointers parse the log the same way. Headers
+// are not kept, as they usually hold credentials.
+func buildURI(rawURL, formatHint string) string {
+	f := local.ParseFormat(formatHint)
+	if f == local.FormatAuto {
+		return rawURL
+	}
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + "format=" + url.QueryEscape(f.String())
+}
+
+// name returns the name passed to parsers, from which the stream name is taken.
+func (s *Source) name() string {
+	if u, err := url.Parse(s.url); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return s.url
+}
+
+// probe detects the compression and log format from the start of the
+// content, using a single ranged request.
+func (s *Source) probe(ctx context.Context) (content, error) {
+	s.probeOnce.Do(func() {
+		body, err := s.client.Get(ctx, s.url, 0, FormatDetectionSampleSize-1)
+		if err != nil {
+			if errors.Is(err, errRangeNotSatisfiable) {
+				// Empty content
+				s.probed = content{format: s.format}
+				return
+			}
+			s.probeError = err
+			return
+		}
+		defer func() { _ = body.Close() }()
+
+		sample, err := io.ReadAll(io.LimitReader(body, FormatDetectionSampleSize))
+		if err != nil {
+			s.probeError = err
+			return
+		}
+
+		s.probed.compression = local.DetectCompression(sample)
+		s.probed.format = s.format
+		if s.format != local.FormatAuto {
+			return
+		}
+
+		// A compressed sample decompresses to what it holds; a truncated
+		// final block is expected and ignored
+		r, _, err := local.NewDecompressReader(bytes.NewReader(sample))
+		if err != nil {
+			s.probed.format = local.FormatPlain
+			return
+		}
+		decompressed, _ := io.ReadAll(io.LimitReader(r, FormatDetectionSampleSize))
+		s.probed.format = local.DetectFormatReader(bytes.NewReader(decompressed))
+	})
+	return s.probed, s.probeError
+}
+
+// Query returns log entries matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	obj, err := s.openAt(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", s.url, err)
+	}
+	defer func() { _ = obj.Close() }()
+
+	var results []source.Entry
+	scanner := local.NewEntryScanner(obj, obj.parser, s.name())
+
+	for scanner.Scan(ctx) {
+		entry := s.convertEntry(scanner.Entry(), scanner.Offset())
+		if local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error reading %s: %w", s.url, err)
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// convertEntry replaces the file-based source and pointer set by the parser
+// with the URL and byte offset.
+func (s *Source) convertEntry(entry source.Entry, offset int64) source.Entry {
+	entry.Source = s.url
+	entry.Ptr = source.MakeHTTPPtr(s.url, offset)
+	return entry
+}
+
+// object is the content at the URL positioned at a requested offset of its
+// decompressed content, along with the parser for its format.
+type object struct {
+	io.Reader
+	parser  local.Parser
+	closers []io.Closer
+}
+
+// Close closes the decompressor (if any) and the response body.
+func (o *object) Close() error {
+	var firstErr error
+	for i := len(o.closers) - 1; i >= 0; i-- {
+		if err := o.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// openAt opens the content positioned at offset within its decompressed
+// content. Uncompressed content is read with a Range request; compressed
+// content must be decompressed from the start and skipped forward. A non-zero
+// offset must start a line.
+func (s *Source) openAt(ctx context.Context, offset int64) (*object, error) {
+	info, err := s.probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	obj := &object{parser: local.NewParser(info.format)}
+	compressed := info.compression != local.CompressionNone
+
+	// Position one byte early so we can check that offset starts a line
+	start := int64(0)
+	if offset > 0 && !compressed {
+		start = offset - 1
+	}
+
+	body, err := s.client.Get(ctx, s.url, start, -1)
+	if errors.Is(err, errRangeNotSatisfiable) && offset == 0 {
+		// Empty content
+		obj.Reader = strings.NewReader("")
+		return obj, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj.closers = []io.Closer{body}
+	obj.Reader = body
+
+	if compressed {
+		r, _, err := local.NewDecompressReader(body)
+		if err != nil {
+			_ = obj.Close()
+			return nil, fmt.Errorf("failed to decompress %s: %w", s.url, err)
+		}
+		obj.closers = append(obj.closers, r)
+		obj.Reader = bufio.NewReader(r)
+
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, obj.Reader, offset-1); err != nil {
+				_ = obj.Close()
+				return nil, fmt.Errorf("offset %d out of range for %s: %w", offset, s.url, err)
+			}
+		}
+	}
+
+	if offset > 0 {
+		var prev [1]byte
+		if _, err := io.ReadFull(obj.Reader, prev[:]); err != nil || prev[0] != '\n' {
+			_ = obj.Close()
+			return nil, fmt.Errorf("no log entry starts at offset %d in %s", offset, s.url)
+		}
+	}
+
+	return obj, nil
+}
+
+// Tail streams entries appended to the log by polling its content length and
+// requesting only the new bytes. Entries already in the log are not replayed.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	info, err := s.probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Compressed logs are finished archives; new data is never appended in readable form
+	if info.compression != local.CompressionNone {
+		return nil, fmt.Errorf("cannot tail %s-compressed content at %s", info.compression, s.url)
+	}
+
+	size, _, err := s.client.Stat(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("cannot tail %s: the server does not report its content length", s.url)
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	// New bytes are written to a pipe read by one scanner, so entries split
+	// across polls (such as stack traces) are joined as in a local file
+	pr, pw := io.Pipe()
+	go s.pollLoop(ctx, size, pw)
+	go s.scanLoop(ctx, pr, info.format, params, events)
+
+	return events, nil
+}
+
+// pollLoop copies bytes appended after offset to w until the context is cancelled.
+func (s *Source) pollLoop(ctx context.Context, offset int64, w *io.PipeWriter) {
+	ticker := time.NewTicker(TailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = w.CloseWithError(ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		size, _, err := s.client.Stat(ctx, s.url)
+		if err != nil {
+			if ctx.Err() == nil {
+				// The server may be briefly unavailable; try again next poll
+				logging.Debug("Failed to check %s: %v", s.url, err)
+			}
+			continue
+		}
+		if size < offset {
+			logging.Warn("%s shrank from %d to %d bytes; following from its new end", s.url, offset, size)
+			offset = size
+			continue
+		}
+		if size == offset {
+			continue
+		}
+
+		body, err := s.client.Get(ctx, s.url, offset, size-1)
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.Debug("Failed to read %s: %v", s.url, err)
+			}
+			continue
+		}
+		n, err := io.Copy(w, body)
+		_ = body.Close()
+		offset += n
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// scanLoop parses entries from the appended bytes and emits them.
+func (s *Source) scanLoop(ctx context.Context, r *io.PipeReader, format local.Format, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+	defer func() { _ = r.Close() }()
+
+	scanner := local.NewEntryScanner(r, local.NewParser(format), s.name())
+	for scanner.Scan(ctx) {
+		entry := scanner.Entry()
+		s.emitEntry(&entry, params, events)
+	}
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, params source.TailParams, events chan<- source.Event) {
+	// Apply filter
+	if params.Filter != nil && !params.Filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// GetRecord retrieves a single log entry by its pointer. For uncompressed
+// content only a window starting at the entry is requested, widened until it
+// holds the whole entry.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseHTTPPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid HTTP pointer: %s", ptr)
+	}
+	if info.URL != s.url {
+		return nil, fmt.Errorf("pointer %s is not in %s", ptr, s.url)
+	}
+
+	probed, err := s.probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if probed.compression != local.CompressionNone {
+		obj, err := s.openAt(ctx, info.Offset)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = obj.Close() }()
+		return s.firstEntry(ctx, obj, obj.parser, info.Offset, true)
+	}
+
+	// Start one byte early so we can check that the offset starts a line
+	start := max(0, info.Offset-1)
+	for window := int64(ContextWindowSize); ; window *= 4 {
+		data, atEnd, err := s.readWindow(ctx, start, info.Offset+window-1)
+		if err != nil {
+			return nil, err
+		}
+		if info.Offset > 0 {
+			if len(data) == 0 || data[0] != '\n' {
+				return nil, fmt.Errorf("no log entry starts at offset %d in %s", info.Offset, s.url)
+			}
+			data = data[1:]
+		}
+
+		entry, err := s.firstEntry(ctx, bytes.NewReader(data), local.NewParser(probed.format), info.Offset, atEnd)
+		if !errors.Is(err, errEntryIncomplete) {
+			return entry, err
+		}
+	}
+}
+
+// firstEntry returns the entry at the start of r, which is read from offset.
+// Unless r holds the rest of the content, the entry is only known to be
+// complete once the next entry starts; errEntryIncomplete is returned otherwise.
+func (s *Source) firstEntry(ctx context.Context, r io.Reader, parser local.Parser, offset int64, toEnd bool) (*source.Entry, error) {
+	scanner := local.NewEntryScanner(r, parser, s.name())
+	if !scanner.Scan(ctx) {
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+		if !toEnd {
+			return nil, errEntryIncomplete
+		}
+		return nil, fmt.Errorf("offset %d not found in %s", offset, s.url)
+	}
+	if scanner.Offset() != 0 {
+		return nil, fmt.Errorf("no log entry starts at offset %d in %s", offset, s.url)
+	}
+
+	entry := s.convertEntry(scanner.Entry(), offset)
+	if !toEnd && !scanner.Scan(ctx) {
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+		return nil, errEntryIncomplete
+	}
+	return &entry, nil
+}
+
+// readWindow reads bytes start through end of uncompressed content. Unless
+// the content ends within the window (reported by atEnd), the window is cut
+// after its last complete line.
+func (s *Source) readWindow(ctx context.Context, start, end int64) ([]byte, bool, error) {
+	body, err := s.client.Get(ctx, s.url, start, end)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = body.Close() }()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s: %w", s.url, err)
+	}
+	if int64(len(data)) < end-start+1 {
+		return data, true, nil
+	}
+	return data[:bytes.LastIndexByte(data, '\n')+1], false, nil
+}
+
+// FetchContext retrieves context lines around a log entry. For uncompressed
+// content only a window around the entry is requested, widened on either side
+// until it holds enough lines.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseHTTPPtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid HTTP pointer: %s", entry.Ptr)
+	}
+
+	probed, err := s.probe(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if probed.compression != local.CompressionNone {
+		obj, err := s.openAt(ctx, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = obj.Close() }()
+
+		beforeLines, afterLines, found, err := s.contextLines(ctx, obj, 0, info.Offset, before, after)
+		if err == nil && !found {
+			err = fmt.Errorf("offset %d out of range", info.Offset)
+		}
+		return beforeLines, afterLines, err
+	}
+
+	beforeWindow, afterWindow := int64(ContextWindowSize), int64(ContextWindowSize)
+	for {
+		start := max(0, info.Offset-beforeWindow)
+		data, atEnd, err := s.readWindow(ctx, start, info.Offset+afterWindow-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		beforeLines, afterLines, found, err := s.contextLines(ctx, bytes.NewReader(data), start, info.Offset, before, after)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found && atEnd {
+			return nil, nil, fmt.Errorf("offset %d out of range", info.Offset)
+		}
+
+		needBefore := len(beforeLines) < before && start > 0
+		needAfter := !found || (len(afterLines) < after && !atEnd)
+		if !needBefore && !needAfter {
+			return beforeLines, afterLines, nil
+		}
+		if needBefore {
+			beforeWindow *= 4
+		}
+		if needAfter {
+			afterWindow *= 4
+		}
+	}
+}
+
+// contextLines reads the lines around the line at offset from r, which is
+// read from start. A line cut by a start inside it is skipped. It reports
+// whether the line at offset was found.
+func (s *Source) contextLines(ctx context.Context, r io.Reader, start, offset int64, before, after int) ([]source.Event, []source.Event, bool, error) {
+	stream := path.Base(s.name())
+	lines := local.NewLineReader(r)
+
+	var beforeLines, afterLines []source.Event
+	found := false
+
+	for first := true; len(afterLines) < after || !found; first = false {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, false, err
+		}
+
+		line, lineOffset, err := lines.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, err
+		}
+		lineOffset += start
+
+		switch {
+		case first && start > 0 && lineOffset != offset:
+			// Possibly partial
+		case found:
+			afterLines = append(afterLines, source.Event{Message: line, Stream: stream})
+		case lineOffset == offset:
+			found = true
+		case lineOffset > offset:
+			return nil, nil, false, fmt.Errorf("offset %d does not start a line", offset)
+		case before > 0:
+			// Keep only the last N lines before the target
+			if len(beforeLines) == before {
+				beforeLines = beforeLines[1:]
+			}
+			beforeLines = append(beforeLines, source.Event{Message: line, Stream: stream})
+		}
+	}
+
+	return beforeLines, afterLines, found, nil
+}
+
+// ListStreams returns the log at the URL with its size and modification time.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	size, modTime, err := s.client.Stat(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+	return []source.StreamInfo{{
+		Name:     s.url,
+		Size:     max(size, 0),
+		LastTime: modTime,
+	}}, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "http"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "http",
+		URI:  s.uri,
+	}
+}
+
+// Close releases resources. Tail requests end with their context.
+func (s *Source) Close() error {
+	return nil
+}
@@ -0,0 +1,408 @@
+package httplog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// fakeServer serves a log with Range support via http.ServeContent and counts
+// the body bytes sent, so tests can check that only part of a log was read.
+type fakeServer struct {
+	mu       sync.Mutex
+	content  []byte
+	sent     int64
+	requests []*http.Request
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	content := f.content
+	f.requests = append(f.requests, r)
+	f.mu.Unlock()
+
+	cw := &countingWriter{ResponseWriter: w, server: f}
+	http.ServeContent(cw, r, "app.log", time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC), bytes.NewReader(content))
+}
+
+func (f *fakeServer) append(data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = append(f.content, data...)
+}
+
+func (f *fakeServer) bytesSent() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sent
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	server *fakeServer
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.server.mu.Lock()
+	w.server.sent += int64(len(p))
+	w.server.mu.Unlock()
+	return w.ResponseWriter.Write(p)
+}
+
+// testLog builds a JSON log with n entries, one second apart.
+func testLog(n int) string {
+	var b strings.Builder
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		level := "info"
+		if i%10 == 0 {
+			level = "error"
+		}
+		fmt.Fprintf(&b, `{"timestamp":"%s","level":"%s","message":"request %04d handled"}`+"\n",
+			base.Add(time.Duration(i)*time.Second).Format(time.RFC3339), level, i)
+	}
+	return b.String()
+}
+
+func newTestServer(t *testing.T, content []byte) (*fakeServer, *httptest.Server) {
+	t.Helper()
+	f := &fakeServer{content: content}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func TestSource_Query(t *testing.T) {
+	f, srv := newTestServer(t, []byte(testLog(50)))
+	src, err := source.Open(srv.URL + "/job/42/app.log")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	results, err := src.Query(context.Background(), source.QueryParams{
+		Filter: regexp.MustCompile("request 00[0-4]0"),
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	if results[0].Message != "request 0040 handled" {
+		t.Errorf("expected newest first, got %q", results[0].Message)
+	}
+	if results[0].Stream != "app.log" {
+		t.Errorf("expected stream app.log, got %q", results[0].Stream)
+	}
+	if results[0].Source != srv.URL+"/job/42/app.log" {
+		t.Errorf("unexpected source %q", results[0].Source)
+	}
+	if results[0].Fields["level"] != "error" {
+		t.Errorf("expected JSON fields to be parsed, got %v", results[0].Fields)
+	}
+
+	wantPtr := source.MakeHTTPPtr(srv.URL+"/job/42/app.log", int64(strings.Index(testLog(50), `{"timestamp":"2025-01-15T10:00:40Z"`)))
+	if results[0].Ptr != wantPtr {
+		t.Errorf("expected ptr %q, got %q", wantPtr, results[0].Ptr)
+	}
+	if f.bytesSent() == 0 {
+		t.Error("expected the log to be downloaded")
+	}
+}
+
+func TestSource_GetRecordAndContextUseRanges(t *testing.T) {
+	content := testLog(5000)
+	f, srv := newTestServer(t, []byte(content))
+	src, err := source.Open(srv.URL + "/app.log")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ctx := context.Background()
+
+	offset := int64(strings.Index(content, "request 4000 handled"))
+	offset = int64(strings.LastIndex(content[:offset], "\n") + 1)
+	ptr := source.MakeHTTPPtr(srv.URL+"/app.log", offset)
+
+	entry, err := src.GetRecord(ctx, ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "request 4000 handled" {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	if entry.Ptr != ptr {
+		t.Errorf("expected ptr %q, got %q", ptr, entry.Ptr)
+	}
+
+	before, after, err := src.FetchContext(ctx, *entry, 3, 2)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 3 || len(after) != 2 {
+		t.Fatalf("expected 3 before and 2 after, got %d and %d", len(before), len(after))
+	}
+	if !strings.Contains(before[0].Message, "request 3997") || !strings.Contains(after[1].Message, "request 4002") {
+		t.Errorf("unexpected context: %q ... %q", before[0].Message, after[1].Message)
+	}
+
+	for _, r := range f.requests {
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "" {
+			t.Errorf("expected ranged GET requests, got one without a Range header")
+		}
+	}
+
+	// Only the format sample and windows around the entry are downloaded
+	if sent := f.bytesSent(); sent > FormatDetectionSampleSize+4*ContextWindowSize {
+		t.Errorf("expected ranged reads, but %d of %d bytes were sent", sent, len(content))
+	}
+
+	if _, err := src.GetRecord(ctx, source.MakeHTTPPtr(srv.URL+"/app.log", offset+1)); err == nil {
+		t.Error("expected an error for an offset inside a line")
+	}
+}
+
+func TestSource_FetchContextAtStart(t *testing.T) {
+	content := testLog(10)
+	_, srv := newTestServer(t, []byte(content))
+	src, err := source.Open(srv.URL + "/app.log")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	offset := int64(strings.Index(content, `{"timestamp":"2025-01-15T10:00:01Z"`))
+	entry := source.Entry{Ptr: source.MakeHTTPPtr(srv.URL+"/app.log", offset)}
+
+	before, after, err := src.FetchContext(context.Background(), entry, 5, 1)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || len(after) != 1 {
+		t.Errorf("expected 1 before and 1 after, got %d and %d", len(before), len(after))
+	}
+}
+
+func TestSource_Gzip(t *testing.T) {
+	content := testLog(20)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(content))
+	_ = gz.Close()
+
+	_, srv := newTestServer(t, buf.Bytes())
+	src, err := source.Open(srv.URL + "/app.log.gz")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	ctx := context.Background()
+
+	results, err := src.Query(ctx, source.QueryParams{Limit: 100})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 20 {
+		t.Fatalf("expected 20 results, got %d", len(results))
+	}
+
+	// Offsets are into the decompressed content
+	entry, err := src.GetRecord(ctx, results[5].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != results[5].Message {
+		t.Errorf("expected %q, got %q", results[5].Message, entry.Message)
+	}
+
+	before, after, err := src.FetchContext(ctx, *entry, 2, 2)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 2 || len(after) != 2 {
+		t.Errorf("expected 2 before and 2 after, got %d and %d", len(before), len(after))
+	}
+
+	if _, err := src.Tail(ctx, source.TailParams{}); err == nil {
+		t.Error("expected tailing compressed content to fail")
+	}
+}
+
+func TestSource_FormatHint(t *testing.T) {
+	_, srv := newTestServer(t, []byte(testLog(3)))
+	src, err := source.Open(srv.URL + "/app.log?format=plain")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	results, err := src.Query(context.Background(), source.QueryParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if !strings.HasPrefix(results[0].Message, "{") {
+		t.Errorf("expected plain lines, got %q", results[0].Message)
+	}
+	if strings.Contains(results[0].Ptr, "format=") {
+		t.Errorf("expected the format parameter to be kept out of pointers, got %q", results[0].Ptr)
+	}
+	if got := src.Metadata().URI; got != srv.URL+"/app.log?format=plain" {
+		t.Errorf("unexpected metadata URI %q", got)
+	}
+}
+
+func TestSource_AliasHeaders(t *testing.T) {
+	var mu sync.Mutex
+	var auth []string
+	content := []byte(testLog(3))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth = append(auth, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "app.log", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CI_TOKEN", "secret")
+	config := fmt.Sprintf("sources:\n  ci:\n    uri: %s/app.log\n    headers:\n      Authorization: Bearer ${CI_TOKEN}\n", srv.URL)
+	if err := os.MkdirAll(filepath.Join(home, ".clew"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".clew", "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := source.Open("@ci")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	results, err := src.Query(context.Background(), source.QueryParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	// Pointers reopen with the alias headers
+	ptrSrc, err := source.OpenFromPtr(results[0].Ptr, nil)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	if _, err := ptrSrc.GetRecord(context.Background(), results[0].Ptr); err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+
+	// Without the alias, the server rejects the request
+	direct, err := source.Open(srv.URL + "/other.log")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	_, err = direct.Query(context.Background(), source.QueryParams{})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a 401 error, got %v", err)
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	f, srv := newTestServer(t, []byte("2025-01-15 10:00:00 old line\n"))
+	src, err := source.Open(srv.URL + "/app.log?format=plain")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*TailPollInterval+5*time.Second)
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("new")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	f.append("new line one\nskipped\nnew line two\n")
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed early, got %v", got)
+			}
+			got = append(got, ev.Message)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+	if got[0] != "new line one" || got[1] != "new line two" {
+		t.Errorf("unexpected events %v", got)
+	}
+
+	// Only the appended bytes are requested
+	for _, r := range f.requests {
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "bytes=0-" {
+			t.Error("expected tail to request only new bytes")
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestOpenSource(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantURL string
+		wantURI string
+		wantErr bool
+	}{
+		{uri: "https://ci.example.com/job/42/log.txt", wantURL: "https://ci.example.com/job/42/log.txt", wantURI: "https://ci.example.com/job/42/log.txt"},
+		{uri: "http://host/app.log?format=json", wantURL: "http://host/app.log", wantURI: "http://host/app.log?format=json"},
+		{uri: "https://host/raw?file=app.log&format=java", wantURL: "https://host/raw?file=app.log", wantURI: "https://host/raw?file=app.log&format=java"},
+		{uri: "https://host/app.log#frag", wantURL: "https://host/app.log", wantURI: "https://host/app.log"},
+		{uri: "https:///app.log", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			s := src.(*Source)
+			if s.url != tt.wantURL {
+				t.Errorf("expected url %q, got %q", tt.wantURL, s.url)
+			}
+			if got := s.Metadata().URI; got != tt.wantURI {
+				t.Errorf("expected URI %q, got %q", tt.wantURI, got)
+			}
+			if s.Type() != "http" {
+				t.Errorf("expected type http, got %q", s.Type())
+			}
+		})
+	}
+}
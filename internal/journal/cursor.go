@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cursor identifies a journal entry the same way journalctl --show-cursor does:
+// "s=<seqnum id>;i=<seqnum>;b=<boot id>;m=<monotonic>;t=<realtime>;x=<xor hash>".
+type cursor struct {
+	seqnumID  id128
+	seqnum    uint64
+	bootID    id128
+	hasBootID bool
+}
+
+// formatCursor returns the cursor string for an entry in a file.
+func formatCursor(jf *journalFile, e *journalEntry) string {
+	return fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x",
+		jf.header.seqnumID, e.seqnum, e.bootID, e.monotonic, e.realtime, e.xorHash)
+}
+
+// parseCursor parses a cursor string. Only the seqnum ID and seqnum are
+// required to locate an entry; the boot ID is checked when present.
+func parseCursor(s string) (cursor, error) {
+	var c cursor
+	var hasSeqnumID, hasSeqnum bool
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return cursor{}, fmt.Errorf("invalid journal cursor %q", s)
+		}
+
+		var err error
+		switch key {
+		case "s":
+			c.seqnumID, err = parseID128(value)
+			hasSeqnumID = true
+		case "i":
+			c.seqnum, err = strconv.ParseUint(value, 16, 64)
+			hasSeqnum = true
+		case "b":
+			c.bootID, err = parseID128(value)
+			c.hasBootID = true
+		case "m", "t", "x":
+			_, err = strconv.ParseUint(value, 16, 64)
+		}
+		if err != nil {
+			return cursor{}, fmt.Errorf("invalid journal cursor %q: %w", s, err)
+		}
+	}
+
+	if !hasSeqnumID || !hasSeqnum {
+		return cursor{}, fmt.Errorf("invalid journal cursor %q: missing seqnum", s)
+	}
+
+	return c, nil
+}
+
+// parseID128 parses a 32-character hex ID.
+func parseID128(s string) (id128, error) {
+	var id id128
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	}
+	if len(b) != len(id) {
+		return id, fmt.Errorf("invalid id %q", s)
+	}
+	copy(id[:], b)
+	return id, nil
+}
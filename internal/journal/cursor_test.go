@@ -0,0 +1,98 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     string
+		wantErr    bool
+		wantSeqnum uint64
+		wantBootID bool
+	}{
+		{
+			name:       "full cursor",
+			cursor:     "s=5e9a0102030405060708090a0b0c0d0e;i=2a;b=b0070102030405060708090a0b0c0d0e;m=f4628;t=62bbbbd980800;x=9e3779b97f4a7c15",
+			wantSeqnum: 0x2a,
+			wantBootID: true,
+		},
+		{
+			name:       "seqnum only",
+			cursor:     "s=5e9a0102030405060708090a0b0c0d0e;i=1",
+			wantSeqnum: 1,
+		},
+		{
+			name:    "missing seqnum",
+			cursor:  "s=5e9a0102030405060708090a0b0c0d0e;b=b0070102030405060708090a0b0c0d0e",
+			wantErr: true,
+		},
+		{
+			name:    "short seqnum id",
+			cursor:  "s=5e9a;i=1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid hex",
+			cursor:  "s=5e9a0102030405060708090a0b0c0d0e;i=xyz",
+			wantErr: true,
+		},
+		{
+			name:    "not key=value",
+			cursor:  "garbage",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCursor(tt.cursor)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCursor failed: %v", err)
+			}
+			if c.seqnumID != testSeqnumID {
+				t.Errorf("expected seqnum id %s, got %s", testSeqnumID, c.seqnumID)
+			}
+			if c.seqnum != tt.wantSeqnum {
+				t.Errorf("expected seqnum %d, got %d", tt.wantSeqnum, c.seqnum)
+			}
+			if c.hasBootID != tt.wantBootID || (tt.wantBootID && c.bootID != testBootID) {
+				t.Errorf("unexpected boot id %s (present: %v)", c.bootID, c.hasBootID)
+			}
+		})
+	}
+}
+
+func TestFormatCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.journal")
+	writeJournal(t, path, journalOptions{}, testEntries(2))
+
+	jf, err := openJournalFile(path)
+	if err != nil {
+		t.Fatalf("openJournalFile failed: %v", err)
+	}
+	defer func() { _ = jf.Close() }()
+
+	offsets, err := jf.entryOffsets(0)
+	if err != nil {
+		t.Fatalf("entryOffsets failed: %v", err)
+	}
+	e, err := jf.readEntry(offsets[1])
+	if err != nil {
+		t.Fatalf("readEntry failed: %v", err)
+	}
+
+	// Same format as journalctl --show-cursor for this file
+	want := "s=5e9a0102030405060708090a0b0c0d0e;i=2;b=b0070102030405060708090a0b0c0d0e;m=f4a10;t=62bbbbda74a40;x=9e3779b97f4a7c15"
+	if got := formatCursor(jf, e); got != want {
+		t.Errorf("formatCursor = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,470 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// The journal file format is documented at https://systemd.io/JOURNAL_FILE_FORMAT/.
+// Only the parts needed to walk entries in order are read here; the hash
+// tables used by journald for lookups are ignored.
+
+// headerSignature starts every journal file.
+const headerSignature = "LPKSHHRH"
+
+// Header field offsets
+const (
+	offIncompatibleFlags  = 12
+	offFileID             = 24
+	offSeqnumID           = 72
+	offHeaderSize         = 88
+	offArenaSize          = 96
+	offNEntries           = 152
+	offTailEntrySeqnum    = 160
+	offHeadEntrySeqnum    = 168
+	offEntryArrayOffset   = 176
+	offHeadEntryRealtime  = 184
+	offTailEntryRealtime  = 192
+	offTailEntryMonotonic = 200
+
+	// minHeaderSize covers every header field read above
+	minHeaderSize = 208
+)
+
+// Incompatible header flags
+const (
+	flagCompressedXZ   = 1 << 0
+	flagCompressedLZ4  = 1 << 1
+	flagKeyedHash      = 1 << 2
+	flagCompressedZstd = 1 << 3
+	flagCompact        = 1 << 4
+
+	knownIncompatibleFlags = flagCompressedXZ | flagCompressedLZ4 | flagKeyedHash | flagCompressedZstd | flagCompact
+)
+
+// Object types
+const (
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+)
+
+// Data object compression flags
+const (
+	objectCompressedXZ   = 1 << 0
+	objectCompressedLZ4  = 1 << 1
+	objectCompressedZstd = 1 << 2
+)
+
+const (
+	objectHeaderSize = 16
+
+	// maxObjectSize guards against corrupt sizes causing huge allocations
+	maxObjectSize = 64 * 1024 * 1024
+
+	// dataCacheSize bounds the number of decoded data objects kept per file.
+	// Fields such as _HOSTNAME and _BOOT_ID are shared by most entries.
+	dataCacheSize = 4096
+)
+
+// id128 is a 128-bit systemd ID (machine, boot, file or seqnum ID).
+type id128 [16]byte
+
+func (id id128) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// fileHeader holds the header fields used by the reader.
+type fileHeader struct {
+	incompatibleFlags  uint32
+	fileID             id128
+	seqnumID           id128
+	headerSize         uint64
+	arenaSize          uint64
+	nEntries           uint64
+	headEntrySeqnum    uint64
+	tailEntrySeqnum    uint64
+	entryArrayOffset   uint64
+	headEntryRealtime  uint64
+	tailEntryRealtime  uint64
+	tailEntryMonotonic uint64
+}
+
+// journalFile is an open journal file.
+type journalFile struct {
+	path   string
+	f      *os.File
+	header fileHeader
+	cache  map[uint64]string
+}
+
+// journalEntry is an entry object. Fields are decoded separately by loadFields
+// so entries outside a time range can be skipped cheaply.
+type journalEntry struct {
+	seqnum    uint64
+	realtime  uint64 // microseconds since the epoch
+	monotonic uint64
+	bootID    id128
+	xorHash   uint64
+	items     []uint64 // data object offsets
+	fields    map[string]string
+}
+
+// timestamp returns the wallclock time of the entry.
+func (e *journalEntry) timestamp() time.Time {
+	return time.UnixMicro(int64(e.realtime))
+}
+
+// openJournalFile opens a journal file and reads its header.
+func openJournalFile(path string) (*journalFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	jf := &journalFile{path: path, f: f, cache: make(map[uint64]string)}
+	if err := jf.readHeader(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return jf, nil
+}
+
+// Close closes the underlying file.
+func (jf *journalFile) Close() error {
+	return jf.f.Close()
+}
+
+// compact reports whether the file uses the compact format (journald 252+).
+func (jf *journalFile) compact() bool {
+	return jf.header.incompatibleFlags&flagCompact != 0
+}
+
+// readHeader (re)reads the file header. It is called again while tailing to
+// pick up entries appended since the file was opened.
+func (jf *journalFile) readHeader() error {
+	buf := make([]byte, minHeaderSize)
+	if _, err := jf.f.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("failed to read journal header: %w", err)
+	}
+	if string(buf[:len(headerSignature)]) != headerSignature {
+		return errors.New("not a journal file")
+	}
+
+	le := binary.LittleEndian
+	h := fileHeader{
+		incompatibleFlags:  le.Uint32(buf[offIncompatibleFlags:]),
+		headerSize:         le.Uint64(buf[offHeaderSize:]),
+		arenaSize:          le.Uint64(buf[offArenaSize:]),
+		nEntries:           le.Uint64(buf[offNEntries:]),
+		tailEntrySeqnum:    le.Uint64(buf[offTailEntrySeqnum:]),
+		headEntrySeqnum:    le.Uint64(buf[offHeadEntrySeqnum:]),
+		entryArrayOffset:   le.Uint64(buf[offEntryArrayOffset:]),
+		headEntryRealtime:  le.Uint64(buf[offHeadEntryRealtime:]),
+		tailEntryRealtime:  le.Uint64(buf[offTailEntryRealtime:]),
+		tailEntryMonotonic: le.Uint64(buf[offTailEntryMonotonic:]),
+	}
+	copy(h.fileID[:], buf[offFileID:])
+	copy(h.seqnumID[:], buf[offSeqnumID:])
+
+	if unknown := h.incompatibleFlags &^ knownIncompatibleFlags; unknown != 0 {
+		return fmt.Errorf("unsupported journal features (incompatible flags 0x%x)", unknown)
+	}
+	if h.headerSize < minHeaderSize {
+		return fmt.Errorf("journal header too small (%d bytes)", h.headerSize)
+	}
+
+	jf.header = h
+	return nil
+}
+
+// readObject reads the object at offset, checking its type.
+func (jf *journalFile) readObject(offset uint64, objectType byte) ([]byte, error) {
+	if offset%8 != 0 || offset < jf.header.headerSize || offset >= jf.header.headerSize+jf.header.arenaSize {
+		return nil, fmt.Errorf("invalid object offset %d", offset)
+	}
+
+	var head [objectHeaderSize]byte
+	if _, err := jf.f.ReadAt(head[:], int64(offset)); err != nil {
+		return nil, fmt.Errorf("failed to read object at %d: %w", offset, err)
+	}
+	if head[0] != objectType {
+		return nil, fmt.Errorf("object at %d has type %d, expected %d", offset, head[0], objectType)
+	}
+
+	size := binary.LittleEndian.Uint64(head[8:])
+	if size < objectHeaderSize || size > maxObjectSize {
+		return nil, fmt.Errorf("object at %d has invalid size %d", offset, size)
+	}
+
+	obj := make([]byte, size)
+	if _, err := jf.f.ReadAt(obj, int64(offset)); err != nil {
+		return nil, fmt.Errorf("failed to read object at %d: %w", offset, err)
+	}
+
+	return obj, nil
+}
+
+// entryOffsets returns the offsets of the entries counted in the header, in
+// seqnum order, skipping the first skip entries.
+func (jf *journalFile) entryOffsets(skip uint64) ([]uint64, error) {
+	itemSize := uint64(8)
+	if jf.compact() {
+		itemSize = 4
+	}
+
+	var offsets []uint64
+	var seen uint64
+
+	for arrayOffset := jf.header.entryArrayOffset; arrayOffset != 0; {
+		obj, err := jf.readObject(arrayOffset, objectEntryArray)
+		if err != nil {
+			return nil, err
+		}
+		if len(obj) < 24 {
+			return nil, fmt.Errorf("entry array at %d too small", arrayOffset)
+		}
+
+		items := obj[24:]
+		for i := uint64(0); i+itemSize <= uint64(len(items)); i += itemSize {
+			var offset uint64
+			if itemSize == 4 {
+				offset = uint64(binary.LittleEndian.Uint32(items[i:]))
+			} else {
+				offset = binary.LittleEndian.Uint64(items[i:])
+			}
+			// Unused slots at the end of the last array are zero. Entries
+			// linked but not yet counted by a running journald are left for
+			// the next read.
+			if offset == 0 || seen >= jf.header.nEntries {
+				return offsets, nil
+			}
+			if seen >= skip {
+				offsets = append(offsets, offset)
+			}
+			seen++
+		}
+
+		arrayOffset = binary.LittleEndian.Uint64(obj[16:])
+	}
+
+	return offsets, nil
+}
+
+// readEntry reads the entry object at offset without decoding its fields.
+func (jf *journalFile) readEntry(offset uint64) (*journalEntry, error) {
+	obj, err := jf.readObject(offset, objectEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(obj) < 64 {
+		return nil, fmt.Errorf("entry at %d too small", offset)
+	}
+
+	le := binary.LittleEndian
+	e := &journalEntry{
+		seqnum:    le.Uint64(obj[16:]),
+		realtime:  le.Uint64(obj[24:]),
+		monotonic: le.Uint64(obj[32:]),
+		xorHash:   le.Uint64(obj[56:]),
+	}
+	copy(e.bootID[:], obj[40:56])
+
+	items := obj[64:]
+	if jf.compact() {
+		for i := 0; i+4 <= len(items); i += 4 {
+			e.items = append(e.items, uint64(le.Uint32(items[i:])))
+		}
+	} else {
+		// Regular items are an offset followed by the data hash
+		for i := 0; i+16 <= len(items); i += 16 {
+			e.items = append(e.items, le.Uint64(items[i:]))
+		}
+	}
+
+	return e, nil
+}
+
+// readSeqnum reads only the sequence number of the entry at offset.
+func (jf *journalFile) readSeqnum(offset uint64) (uint64, error) {
+	var buf [8]byte
+	if _, err := jf.f.ReadAt(buf[:], int64(offset)+16); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+// loadFields decodes the data objects of an entry into its fields map.
+// Fields that appear more than once keep their first value.
+func (jf *journalFile) loadFields(e *journalEntry) error {
+	if e.fields != nil {
+		return nil
+	}
+
+	fields := make(map[string]string, len(e.items))
+	for _, offset := range e.items {
+		payload, err := jf.readData(offset)
+		if err != nil {
+			return err
+		}
+
+		name, value, ok := bytes.Cut([]byte(payload), []byte("="))
+		if !ok {
+			continue
+		}
+		if _, exists := fields[string(name)]; !exists {
+			fields[string(name)] = string(value)
+		}
+	}
+
+	e.fields = fields
+	return nil
+}
+
+// readData returns the decompressed "FIELD=value" payload of a data object.
+func (jf *journalFile) readData(offset uint64) (string, error) {
+	if payload, ok := jf.cache[offset]; ok {
+		return payload, nil
+	}
+
+	obj, err := jf.readObject(offset, objectData)
+	if err != nil {
+		return "", err
+	}
+
+	start := 64
+	if jf.compact() {
+		start = 72
+	}
+	if len(obj) < start {
+		return "", fmt.Errorf("data object at %d too small", offset)
+	}
+
+	raw, err := decompressPayload(obj[1], obj[start:])
+	if err != nil {
+		return "", fmt.Errorf("data object at %d: %w", offset, err)
+	}
+
+	payload := string(raw)
+	if len(jf.cache) >= dataCacheSize {
+		clear(jf.cache)
+	}
+	jf.cache[offset] = payload
+
+	return payload, nil
+}
+
+// zstdDecoder is shared by all files; DecodeAll is safe for concurrent use.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+
+// decompressPayload decompresses a data object payload according to its flags.
+func decompressPayload(flags byte, payload []byte) ([]byte, error) {
+	switch {
+	case flags&objectCompressedZstd != 0:
+		return zstdDecoder.DecodeAll(payload, nil)
+
+	case flags&objectCompressedXZ != 0:
+		r, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+
+	case flags&objectCompressedLZ4 != 0:
+		// journald prefixes LZ4 blocks with the uncompressed size
+		if len(payload) < 8 {
+			return nil, errors.New("truncated lz4 payload")
+		}
+		size := binary.LittleEndian.Uint64(payload)
+		if size > maxObjectSize {
+			return nil, fmt.Errorf("lz4 payload too large (%d bytes)", size)
+		}
+		return decodeLZ4Block(payload[8:], int(size))
+
+	default:
+		return payload, nil
+	}
+}
+
+// decodeLZ4Block decodes a raw LZ4 block (no frame header) of known size.
+func decodeLZ4Block(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	errCorrupt := errors.New("corrupt lz4 block")
+
+	// readLength extends a 4-bit length with 255-valued continuation bytes
+	readLength := func(n int, i *int) (int, error) {
+		if n != 15 {
+			return n, nil
+		}
+		for {
+			if *i >= len(src) {
+				return 0, errCorrupt
+			}
+			b := src[*i]
+			*i++
+			n += int(b)
+			if b != 255 {
+				return n, nil
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literals, err := readLength(int(token>>4), &i)
+		if err != nil {
+			return nil, err
+		}
+		if i+literals > len(src) || len(dst)+literals > size {
+			return nil, errCorrupt
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		// The last sequence has literals only
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errCorrupt
+		}
+		matchOffset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if matchOffset == 0 || matchOffset > len(dst) {
+			return nil, errCorrupt
+		}
+
+		matchLen, err := readLength(int(token&0x0f), &i)
+		if err != nil {
+			return nil, err
+		}
+		matchLen += 4
+		if len(dst)+matchLen > size {
+			return nil, errCorrupt
+		}
+
+		// Matches may overlap the bytes they produce, so copy byte by byte
+		start := len(dst) - matchOffset
+		for j := 0; j < matchLen; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
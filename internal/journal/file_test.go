@@ -0,0 +1,263 @@
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testBase = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// testEntries returns n nginx entries one second apart.
+func testEntries(n int) []testEntry {
+	entries := make([]testEntry, n)
+	for i := range entries {
+		entries[i] = testEntry{
+			realtime: testBase.Add(time.Duration(i) * time.Second),
+			fields: []string{
+				fmt.Sprintf("MESSAGE=request %d served", i+1),
+				"_SYSTEMD_UNIT=nginx.service",
+				"PRIORITY=6",
+				"_HOSTNAME=web1",
+				"_PID=42",
+				"SYSLOG_IDENTIFIER=nginx",
+			},
+		}
+	}
+	return entries
+}
+
+func TestJournalFile_Layouts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts journalOptions
+	}{
+		{"regular", journalOptions{}},
+		{"compact", journalOptions{compact: true}},
+		{"zstd", journalOptions{compact: true, compression: objectCompressedZstd}},
+		{"xz", journalOptions{compression: objectCompressedXZ}},
+		{"lz4", journalOptions{compression: objectCompressedLZ4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "system.journal")
+			// 6 entries span two entry arrays, the second partly empty
+			writeJournal(t, path, tt.opts, testEntries(6))
+
+			jf, err := openJournalFile(path)
+			if err != nil {
+				t.Fatalf("openJournalFile failed: %v", err)
+			}
+			defer func() { _ = jf.Close() }()
+
+			offsets, err := jf.entryOffsets(0)
+			if err != nil {
+				t.Fatalf("entryOffsets failed: %v", err)
+			}
+			if len(offsets) != 6 {
+				t.Fatalf("expected 6 entries, got %d", len(offsets))
+			}
+
+			for i, offset := range offsets {
+				e, err := jf.readEntry(offset)
+				if err != nil {
+					t.Fatalf("readEntry failed: %v", err)
+				}
+				if err := jf.loadFields(e); err != nil {
+					t.Fatalf("loadFields failed: %v", err)
+				}
+
+				if e.seqnum != uint64(i+1) {
+					t.Errorf("entry %d: expected seqnum %d, got %d", i, i+1, e.seqnum)
+				}
+				if want := testBase.Add(time.Duration(i) * time.Second); !e.timestamp().Equal(want) {
+					t.Errorf("entry %d: expected time %v, got %v", i, want, e.timestamp())
+				}
+				if want := fmt.Sprintf("request %d served", i+1); e.fields["MESSAGE"] != want {
+					t.Errorf("entry %d: expected message %q, got %q", i, want, e.fields["MESSAGE"])
+				}
+				if e.fields["_SYSTEMD_UNIT"] != "nginx.service" || e.fields["_PID"] != "42" {
+					t.Errorf("entry %d: unexpected fields %v", i, e.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestJournalFile_EntryOffsetsSkip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.journal")
+	writeJournal(t, path, journalOptions{}, testEntries(6))
+
+	jf, err := openJournalFile(path)
+	if err != nil {
+		t.Fatalf("openJournalFile failed: %v", err)
+	}
+	defer func() { _ = jf.Close() }()
+
+	all, err := jf.entryOffsets(0)
+	if err != nil {
+		t.Fatalf("entryOffsets failed: %v", err)
+	}
+
+	for _, skip := range []uint64{0, 3, 4, 6, 10} {
+		got, err := jf.entryOffsets(skip)
+		if err != nil {
+			t.Fatalf("entryOffsets(%d) failed: %v", skip, err)
+		}
+		want := all[min(int(skip), len(all)):]
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("entryOffsets(%d) = %v, want %v", skip, got, want)
+		}
+	}
+
+	// Entries not yet counted in the header are not returned
+	jf.header.nEntries = 2
+	got, err := jf.entryOffsets(0)
+	if err != nil {
+		t.Fatalf("entryOffsets failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("expected 2 counted entries, got %d", len(got))
+	}
+}
+
+func TestOpenJournalFile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.journal")
+	writeJournal(t, valid, journalOptions{}, testEntries(1))
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+
+	unknownFlags := bytes.Clone(data)
+	unknownFlags[offIncompatibleFlags] = 0x80
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"not a journal", []byte(strings.Repeat("plain text log line\n", 20)), "not a journal file"},
+		{"truncated header", data[:100], "failed to read journal header"},
+		{"unknown incompatible flags", unknownFlags, "unsupported journal features"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			jf, err := openJournalFile(path)
+			if err == nil {
+				_ = jf.Close()
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestJournalFile_CorruptObject(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.journal")
+	writeJournal(t, path, journalOptions{}, testEntries(2))
+
+	jf, err := openJournalFile(path)
+	if err != nil {
+		t.Fatalf("openJournalFile failed: %v", err)
+	}
+	defer func() { _ = jf.Close() }()
+
+	offsets, err := jf.entryOffsets(0)
+	if err != nil {
+		t.Fatalf("entryOffsets failed: %v", err)
+	}
+
+	// An entry offset read as a data object has the wrong type
+	if _, err := jf.readData(offsets[0]); err == nil {
+		t.Error("expected error reading entry as data object")
+	}
+	// Offsets must be aligned and inside the arena
+	for _, offset := range []uint64{offsets[0] + 1, 8, 1 << 40} {
+		if _, err := jf.readEntry(offset); err == nil {
+			t.Errorf("expected error reading entry at %d", offset)
+		}
+	}
+}
+
+func TestDecodeLZ4Block(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   []byte
+		size    int
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "literals only",
+			block: append([]byte{0x50}, "hello"...),
+			size:  5,
+			want:  "hello",
+		},
+		{
+			// "abc" then a 9-byte match at offset 3 that overlaps its own output
+			name:  "overlapping match",
+			block: []byte{0x35, 'a', 'b', 'c', 3, 0, 0x10, '!'},
+			size:  13,
+			want:  "abcabcabcabc!",
+		},
+		{
+			// 15+5 literals using a length continuation byte
+			name:  "extended literal length",
+			block: append([]byte{0xf0, 5}, strings.Repeat("x", 20)...),
+			size:  20,
+			want:  strings.Repeat("x", 20),
+		},
+		{
+			name:    "match offset before start",
+			block:   []byte{0x10, 'a', 5, 0, 0x00, 'b'},
+			size:    6,
+			wantErr: true,
+		},
+		{
+			name:    "truncated literals",
+			block:   []byte{0x50, 'a', 'b'},
+			size:    5,
+			wantErr: true,
+		},
+		{
+			name:    "size mismatch",
+			block:   append([]byte{0x50}, "hello"...),
+			size:    6,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeLZ4Block(tt.block, tt.size)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeLZ4Block failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
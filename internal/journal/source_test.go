@@ -0,0 +1,637 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// mixedEntries returns entries from nginx, sshd and the kernel, one second apart.
+func mixedEntries() []testEntry {
+	fields := [][]string{
+		{"MESSAGE=nginx started", "_SYSTEMD_UNIT=nginx.service", "PRIORITY=6", "_HOSTNAME=web1", "_PID=42", "SYSLOG_IDENTIFIER=nginx"},
+		{"MESSAGE=Accepted publickey for deploy", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=6", "_HOSTNAME=web1", "_PID=77", "SYSLOG_IDENTIFIER=sshd"},
+		{"MESSAGE=upstream timed out", "_SYSTEMD_UNIT=nginx.service", "PRIORITY=3", "_HOSTNAME=web1", "_PID=42", "SYSLOG_IDENTIFIER=nginx"},
+		{"MESSAGE=Started nginx.service", "UNIT=nginx.service", "_SYSTEMD_UNIT=init.scope", "PRIORITY=6", "_HOSTNAME=web1", "_PID=1", "SYSLOG_IDENTIFIER=systemd"},
+		{"MESSAGE=eth0: link up", "PRIORITY=6", "_HOSTNAME=web1", "SYSLOG_IDENTIFIER=kernel", "_TRANSPORT=kernel"},
+		{"MESSAGE=upstream recovered", "_SYSTEMD_UNIT=nginx.service", "PRIORITY=5", "_HOSTNAME=web1", "_PID=42", "SYSLOG_IDENTIFIER=nginx"},
+	}
+
+	entries := make([]testEntry, len(fields))
+	for i, f := range fields {
+		entries[i] = testEntry{realtime: testBase.Add(time.Duration(i) * time.Second), fields: f}
+	}
+	return entries
+}
+
+// newJournalDir writes mixed entries into a journald-style directory layout.
+func newJournalDir(t *testing.T, opts journalOptions) string {
+	t.Helper()
+	dir := t.TempDir()
+	machineDir := filepath.Join(dir, "0123456789abcdef0123456789abcdef")
+	if err := os.Mkdir(machineDir, 0o755); err != nil {
+		t.Fatalf("failed to create machine dir: %v", err)
+	}
+	writeJournal(t, filepath.Join(machineDir, "system.journal"), opts, mixedEntries())
+	return dir
+}
+
+func messages(entries []source.Entry) []string {
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestOpenSource(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+
+	tests := []struct {
+		name      string
+		uri       string
+		wantUnits []string
+		wantURI   string
+		wantErr   bool
+	}{
+		{
+			name:    "directory",
+			uri:     "journal://" + dir,
+			wantURI: "journal://" + dir,
+		},
+		{
+			name:      "repeated units",
+			uri:       "journal://" + dir + "?unit=nginx&unit=sshd.service",
+			wantUnits: []string{"nginx.service", "sshd.service"},
+			wantURI:   "journal://" + dir + "?unit=nginx.service&unit=sshd.service",
+		},
+		{
+			name:      "comma separated units and globs",
+			uri:       "journal://" + dir + "?unit=nginx,ssh*",
+			wantUnits: []string{"nginx.service", "ssh*"},
+			wantURI:   "journal://" + dir + "?unit=nginx.service&unit=ssh%2A",
+		},
+		{
+			name:    "missing directory",
+			uri:     "journal://" + filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+		{
+			name:    "directory without journal files",
+			uri:     "journal://" + t.TempDir(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			js := src.(*Source)
+			if fmt.Sprint(js.units) != fmt.Sprint(tt.wantUnits) {
+				t.Errorf("expected units %v, got %v", tt.wantUnits, js.units)
+			}
+			if got := js.Metadata().URI; got != tt.wantURI {
+				t.Errorf("expected URI %q, got %q", tt.wantURI, got)
+			}
+		})
+	}
+}
+
+func TestSource_Query(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{compact: true, compression: objectCompressedZstd})
+
+	src, err := NewSource(dir, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d: %v", len(entries), messages(entries))
+	}
+
+	// Newest first
+	if entries[0].Message != "upstream recovered" {
+		t.Errorf("expected newest entry first, got %q", entries[0].Message)
+	}
+
+	latest := entries[0]
+	if !latest.Timestamp.Equal(testBase.Add(5 * time.Second)) {
+		t.Errorf("unexpected timestamp %v", latest.Timestamp)
+	}
+	if latest.Stream != "nginx.service" {
+		t.Errorf("expected stream nginx.service, got %q", latest.Stream)
+	}
+	if !strings.HasSuffix(latest.Source, "system.journal") {
+		t.Errorf("expected source to be the journal file, got %q", latest.Source)
+	}
+	wantFields := map[string]string{"_SYSTEMD_UNIT": "nginx.service", "PRIORITY": "5", "_HOSTNAME": "web1", "_PID": "42"}
+	for name, want := range wantFields {
+		if latest.Fields[name] != want {
+			t.Errorf("expected field %s=%q, got %q", name, want, latest.Fields[name])
+		}
+	}
+	if _, ok := latest.Fields["MESSAGE"]; ok {
+		t.Error("MESSAGE should not be duplicated in fields")
+	}
+	if source.ParsePtrType(latest.Ptr) != source.PtrTypeJournal {
+		t.Errorf("expected journal pointer, got %q", latest.Ptr)
+	}
+
+	// Kernel messages have no unit
+	for _, e := range entries {
+		if e.Message == "eth0: link up" && e.Stream != "kernel" {
+			t.Errorf("expected kernel stream, got %q", e.Stream)
+		}
+	}
+}
+
+func TestSource_Query_Filters(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+
+	tests := []struct {
+		name   string
+		units  []string
+		params source.QueryParams
+		want   []string
+	}{
+		{
+			name:  "unit without suffix",
+			units: []string{"nginx"},
+			// Includes the message systemd logged about the unit
+			want: []string{"upstream recovered", "Started nginx.service", "upstream timed out", "nginx started"},
+		},
+		{
+			name:  "several units",
+			units: []string{"sshd", "nginx"},
+			params: source.QueryParams{
+				Filter: regexp.MustCompile("Accepted|timed out"),
+			},
+			want: []string{"upstream timed out", "Accepted publickey for deploy"},
+		},
+		{
+			name:  "unit glob",
+			units: []string{"ssh*"},
+			want:  []string{"Accepted publickey for deploy"},
+		},
+		{
+			name: "time range",
+			params: source.QueryParams{
+				StartTime: testBase.Add(2 * time.Second),
+				EndTime:   testBase.Add(3 * time.Second),
+			},
+			want: []string{"Started nginx.service", "upstream timed out"},
+		},
+		{
+			name:   "limit",
+			params: source.QueryParams{Limit: 2},
+			want:   []string{"upstream recovered", "eth0: link up"},
+		},
+		{
+			name:   "range after the file",
+			params: source.QueryParams{StartTime: testBase.Add(time.Hour)},
+		},
+		{
+			name:   "range before the file",
+			params: source.QueryParams{EndTime: testBase.Add(-time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := NewSource(dir, tt.units)
+			if err != nil {
+				t.Fatalf("NewSource failed: %v", err)
+			}
+
+			entries, err := src.Query(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if got := messages(entries); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSource_Query_Cancelled(t *testing.T) {
+	src, err := NewSource(newJournalDir(t, journalOptions{}), nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := src.Query(ctx, source.QueryParams{}); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSource_Query_RotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// An archived file and the active file continue the same seqnum sequence
+	all := testEntries(7)
+	writeJournal(t, filepath.Join(dir, "system@5e9a-0000000000000001-0000000000000001.journal"),
+		journalOptions{fileID: id128{1}}, all[:4])
+	writeJournal(t, filepath.Join(dir, "system.journal"),
+		journalOptions{fileID: id128{2}, firstSeqnum: 5}, all[4:])
+
+	src, err := NewSource(dir, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx := context.Background()
+	entries, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 7 {
+		t.Fatalf("expected 7 entries across both files, got %d", len(entries))
+	}
+
+	// Every cursor resolves to the entry that produced it
+	for _, e := range entries {
+		got, err := src.GetRecord(ctx, e.Ptr)
+		if err != nil {
+			t.Fatalf("GetRecord(%s) failed: %v", e.Ptr, err)
+		}
+		if got.Message != e.Message || !got.Timestamp.Equal(e.Timestamp) {
+			t.Errorf("GetRecord(%s) = %q, want %q", e.Ptr, got.Message, e.Message)
+		}
+	}
+}
+
+func TestSource_GetRecord(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{compact: true})
+
+	src, err := NewSource(dir, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx := context.Background()
+	entries, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("timed out")})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query failed: %v (%d entries)", err, len(entries))
+	}
+	ptr := entries[0].Ptr
+	info, _ := source.ParseJournalPtr(ptr)
+
+	tests := []struct {
+		name    string
+		ptr     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "pointer from query",
+			ptr:  ptr,
+			want: "upstream timed out",
+		},
+		{
+			name: "cursor without boot id",
+			ptr:  source.MakeJournalPtr(dir, "s="+testSeqnumID.String()+";i=3"),
+			want: "upstream timed out",
+		},
+		{
+			name:    "unknown seqnum",
+			ptr:     source.MakeJournalPtr(dir, "s="+testSeqnumID.String()+";i=99"),
+			wantErr: true,
+		},
+		{
+			name:    "other seqnum id",
+			ptr:     source.MakeJournalPtr(dir, "s=00000000000000000000000000000001;i=3"),
+			wantErr: true,
+		},
+		{
+			name:    "boot id mismatch",
+			ptr:     source.MakeJournalPtr(dir, strings.Replace(info.Cursor, "b=b007", "b=0000", 1)),
+			wantErr: true,
+		},
+		{
+			name:    "invalid cursor",
+			ptr:     source.MakeJournalPtr(dir, "garbage"),
+			wantErr: true,
+		},
+		{
+			name:    "not a journal pointer",
+			ptr:     "file:///var/log/app.log#1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := src.GetRecord(ctx, tt.ptr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", entry.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRecord failed: %v", err)
+			}
+			if entry.Message != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, entry.Message)
+			}
+			if entry.Fields["PRIORITY"] != "3" {
+				t.Errorf("expected PRIORITY=3, got %q", entry.Fields["PRIORITY"])
+			}
+		})
+	}
+}
+
+func TestSource_OpenFromPtr(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+
+	src, err := source.Open("journal://" + dir + "?unit=nginx")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	entries, err := src.Query(context.Background(), source.QueryParams{Limit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// Reopening from the pointer and cached metadata keeps the unit filter
+	meta := src.Metadata()
+	reopened, err := source.OpenFromPtr(entries[0].Ptr, &meta)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	if got := reopened.(*Source).units; fmt.Sprint(got) != "[nginx.service]" {
+		t.Errorf("expected unit filter to be kept, got %v", got)
+	}
+
+	entry, err := reopened.GetRecord(context.Background(), entries[0].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "upstream recovered" {
+		t.Errorf("unexpected entry %q", entry.Message)
+	}
+}
+
+func TestSource_FetchContext(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+
+	tests := []struct {
+		name       string
+		units      []string
+		target     string
+		before     int
+		after      int
+		wantBefore []string
+		wantAfter  []string
+	}{
+		{
+			name:       "all units",
+			target:     "upstream timed out",
+			before:     1,
+			after:      2,
+			wantBefore: []string{"Accepted publickey for deploy"},
+			wantAfter:  []string{"Started nginx.service", "eth0: link up"},
+		},
+		{
+			name:       "unit filter skips other units",
+			units:      []string{"nginx"},
+			target:     "upstream timed out",
+			before:     2,
+			after:      2,
+			wantBefore: []string{"nginx started"},
+			wantAfter:  []string{"Started nginx.service", "upstream recovered"},
+		},
+		{
+			name:       "at end of journal",
+			target:     "upstream recovered",
+			before:     1,
+			after:      3,
+			wantBefore: []string{"eth0: link up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := NewSource(dir, tt.units)
+			if err != nil {
+				t.Fatalf("NewSource failed: %v", err)
+			}
+
+			ctx := context.Background()
+			entries, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("^" + tt.target + "$")})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("Query failed: %v (%d entries)", err, len(entries))
+			}
+
+			before, after, err := src.FetchContext(ctx, entries[0], tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("FetchContext failed: %v", err)
+			}
+
+			var gotBefore, gotAfter []string
+			for _, e := range before {
+				gotBefore = append(gotBefore, e.Message)
+			}
+			for _, e := range after {
+				gotAfter = append(gotAfter, e.Message)
+			}
+			if fmt.Sprint(gotBefore) != fmt.Sprint(tt.wantBefore) {
+				t.Errorf("expected before %q, got %q", tt.wantBefore, gotBefore)
+			}
+			if fmt.Sprint(gotAfter) != fmt.Sprint(tt.wantAfter) {
+				t.Errorf("expected after %q, got %q", tt.wantAfter, gotAfter)
+			}
+		})
+	}
+}
+
+func TestSource_Query_WithContext(t *testing.T) {
+	src, err := NewSource(newJournalDir(t, journalOptions{}), nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		Filter:  regexp.MustCompile("Accepted"),
+		Context: 1,
+	})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	ctx := entries[0].Context
+	if len(ctx.Before) != 1 || ctx.Before[0].Message != "nginx started" || ctx.Before[0].Stream != "nginx.service" {
+		t.Errorf("unexpected before context: %+v", ctx.Before)
+	}
+	if len(ctx.After) != 1 || ctx.After[0].Message != "upstream timed out" {
+		t.Errorf("unexpected after context: %+v", ctx.After)
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+	// Journals that were not closed cleanly are still listed
+	writeJournal(t, filepath.Join(dir, "user-1000.journal~"), journalOptions{fileID: id128{3}}, testEntries(1))
+
+	src, err := NewSource(dir, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 journal files, got %d", len(streams))
+	}
+
+	system := streams[0]
+	if !strings.HasSuffix(system.Name, "/system.journal") {
+		t.Errorf("unexpected stream name %q", system.Name)
+	}
+	if system.Size == 0 {
+		t.Error("expected non-zero size")
+	}
+	if !system.FirstTime.Equal(testBase) || !system.LastTime.Equal(testBase.Add(5*time.Second)) {
+		t.Errorf("unexpected time range %v - %v", system.FirstTime, system.LastTime)
+	}
+}
+
+func TestSource_readNewEntries(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "system.journal")
+	all := testEntries(6)
+	writeJournal(t, active, journalOptions{}, all[:2])
+
+	src, err := NewSource(dir, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx := context.Background()
+	state := &tailState{seen: make(map[id128]uint64), archived: make(map[string]bool)}
+
+	// Existing entries are not replayed
+	if got, err := src.readNewEntries(ctx, state); err != nil || len(got) != 0 {
+		t.Fatalf("expected no entries on first read, got %v (err %v)", messages(got), err)
+	}
+
+	// Entries appended to the active file
+	writeJournal(t, active, journalOptions{}, all[:4])
+	got, err := src.readNewEntries(ctx, state)
+	if err != nil {
+		t.Fatalf("readNewEntries failed: %v", err)
+	}
+	if want := "[request 3 served request 4 served]"; fmt.Sprint(messages(got)) != want {
+		t.Errorf("expected %s, got %v", want, messages(got))
+	}
+
+	// Rotation renames the active file and starts a new one; the renamed
+	// file keeps its file ID and is not read again
+	if err := os.Rename(active, filepath.Join(dir, "system@5e9a-0000000000000001-0000000000000001.journal")); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	writeJournal(t, active, journalOptions{fileID: id128{9}, firstSeqnum: 5}, all[4:5])
+
+	got, err = src.readNewEntries(ctx, state)
+	if err != nil {
+		t.Fatalf("readNewEntries failed: %v", err)
+	}
+	if want := "[request 5 served]"; fmt.Sprint(messages(got)) != want {
+		t.Errorf("expected %s, got %v", want, messages(got))
+	}
+
+	if got, _ := src.readNewEntries(ctx, state); len(got) != 0 {
+		t.Errorf("expected no new entries, got %v", messages(got))
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "system.journal")
+	all := mixedEntries()
+	writeJournal(t, active, journalOptions{}, all[:1])
+
+	src, err := NewSource(dir, []string{"nginx"})
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("upstream")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	// The sshd entry is outside the unit filter and "Started" fails the text filter
+	writeJournal(t, active, journalOptions{}, all)
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed early; got %v", got)
+			}
+			got = append(got, event.Message)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for events; got %v", got)
+		}
+	}
+
+	if want := "[upstream timed out upstream recovered]"; fmt.Sprint(got) != want {
+		t.Errorf("expected %s, got %v", want, got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestSource_TypeAndMetadata(t *testing.T) {
+	dir := newJournalDir(t, journalOptions{})
+
+	src, err := NewSource(dir, []string{"nginx"})
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	if src.Type() != "journal" {
+		t.Errorf("expected type journal, got %q", src.Type())
+	}
+	meta := src.Metadata()
+	if meta.Type != "journal" || meta.URI != "journal://"+dir+"?unit=nginx.service" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if err := src.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// testEntry is an entry written by writeJournal.
+type testEntry struct {
+	realtime time.Time
+	fields   []string // "FIELD=value" pairs
+}
+
+// journalOptions controls the layout of files written by writeJournal.
+type journalOptions struct {
+	compact     bool
+	compression byte // object compression flag applied to MESSAGE fields
+	fileID      id128
+	seqnumID    id128
+	firstSeqnum uint64
+}
+
+var (
+	testBootID   = id128{0xb0, 0x07, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+	testSeqnumID = id128{0x5e, 0x9a, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+	testFileID   = id128{0xf1, 0x1e, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+)
+
+// testEntryArrayCapacity is small so entries span several chained arrays,
+// with unused slots at the end of the last one.
+const testEntryArrayCapacity = 4
+
+// writeJournal writes a journal file in the format journald uses, so the reader
+// can be tested without systemd. Hash tables are written empty; they are only
+// used for lookups by field value.
+func writeJournal(t *testing.T, path string, opts journalOptions, entries []testEntry) {
+	t.Helper()
+
+	if opts.fileID == (id128{}) {
+		opts.fileID = testFileID
+	}
+	if opts.seqnumID == (id128{}) {
+		opts.seqnumID = testSeqnumID
+	}
+	if opts.firstSeqnum == 0 {
+		opts.firstSeqnum = 1
+	}
+
+	const headerSize = 272
+	le := binary.LittleEndian
+	buf := make([]byte, headerSize)
+	var nObjects uint64
+
+	appendObject := func(objectType, flags byte, body []byte) uint64 {
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		offset := uint64(len(buf))
+		head := make([]byte, objectHeaderSize)
+		head[0] = objectType
+		head[1] = flags
+		le.PutUint64(head[8:], uint64(objectHeaderSize+len(body)))
+		buf = append(buf, head...)
+		buf = append(buf, body...)
+		nObjects++
+		return offset
+	}
+
+	dataHashTable := appendObject(4, 0, make([]byte, 4*16))
+	fieldHashTable := appendObject(5, 0, make([]byte, 4*16))
+
+	dataOffsets := make(map[string]uint64)
+	var entryOffsets []uint64
+	var lastEntry uint64
+	var monotonic uint64 = 1_000_000
+
+	for i, entry := range entries {
+		var items []uint64
+		for _, field := range entry.fields {
+			if offset, ok := dataOffsets[field]; ok {
+				items = append(items, offset)
+				continue
+			}
+
+			payload := []byte(field)
+			var flags byte
+			if opts.compression != 0 && bytes.HasPrefix(payload, []byte("MESSAGE=")) {
+				payload = compressTestPayload(t, opts.compression, payload)
+				flags = opts.compression
+			}
+
+			// hash, next_hash, next_field, entry_offset, entry_array_offset, n_entries
+			body := make([]byte, 48)
+			if opts.compact {
+				// tail_entry_array_offset, tail_entry_array_n_entries
+				body = append(body, make([]byte, 8)...)
+			}
+			offset := appendObject(objectData, flags, append(body, payload...))
+			dataOffsets[field] = offset
+			items = append(items, offset)
+		}
+
+		monotonic += 1000
+		body := make([]byte, 48)
+		le.PutUint64(body[0:], opts.firstSeqnum+uint64(i))
+		le.PutUint64(body[8:], uint64(entry.realtime.UnixMicro()))
+		le.PutUint64(body[16:], monotonic)
+		copy(body[24:], testBootID[:])
+		le.PutUint64(body[40:], uint64(i)*0x9e3779b97f4a7c15)
+		for _, item := range items {
+			if opts.compact {
+				body = le.AppendUint32(body, uint32(item))
+			} else {
+				body = le.AppendUint64(body, item)
+				body = le.AppendUint64(body, 0) // data hash
+			}
+		}
+		lastEntry = appendObject(objectEntry, 0, body)
+		entryOffsets = append(entryOffsets, lastEntry)
+	}
+
+	// Entry arrays are written last and chained through their first field
+	var firstArray, prevArray, tailArray uint64
+	var tailArrayEntries int
+	for start := 0; start < len(entryOffsets); start += testEntryArrayCapacity {
+		body := make([]byte, 8)
+		end := min(start+testEntryArrayCapacity, len(entryOffsets))
+		for j := start; j < start+testEntryArrayCapacity; j++ {
+			var offset uint64
+			if j < end {
+				offset = entryOffsets[j]
+			}
+			if opts.compact {
+				body = le.AppendUint32(body, uint32(offset))
+			} else {
+				body = le.AppendUint64(body, offset)
+			}
+		}
+		array := appendObject(objectEntryArray, 0, body)
+		if prevArray == 0 {
+			firstArray = array
+		} else {
+			le.PutUint64(buf[prevArray+16:], array)
+		}
+		prevArray, tailArray, tailArrayEntries = array, array, end-start
+	}
+	tailObject := prevArray
+	if tailObject == 0 {
+		tailObject = fieldHashTable
+	}
+
+	// Header
+	var incompatible uint32
+	if opts.compact {
+		incompatible |= flagCompact
+	}
+	switch opts.compression {
+	case objectCompressedXZ:
+		incompatible |= flagCompressedXZ
+	case objectCompressedLZ4:
+		incompatible |= flagCompressedLZ4
+	case objectCompressedZstd:
+		incompatible |= flagCompressedZstd
+	}
+
+	copy(buf, headerSignature)
+	le.PutUint32(buf[offIncompatibleFlags:], incompatible)
+	copy(buf[offFileID:], opts.fileID[:])
+	copy(buf[40:], []byte("0123456789abcdef")) // machine_id
+	copy(buf[56:], testBootID[:])              // tail_entry_boot_id
+	copy(buf[offSeqnumID:], opts.seqnumID[:])
+	le.PutUint64(buf[offHeaderSize:], headerSize)
+	le.PutUint64(buf[offArenaSize:], uint64(len(buf)-headerSize))
+	le.PutUint64(buf[104:], dataHashTable+objectHeaderSize)
+	le.PutUint64(buf[112:], 4*16)
+	le.PutUint64(buf[120:], fieldHashTable+objectHeaderSize)
+	le.PutUint64(buf[128:], 4*16)
+	le.PutUint64(buf[136:], tailObject)
+	le.PutUint64(buf[144:], nObjects)
+	le.PutUint64(buf[offNEntries:], uint64(len(entries)))
+	if len(entries) > 0 {
+		le.PutUint64(buf[offTailEntrySeqnum:], opts.firstSeqnum+uint64(len(entries))-1)
+		le.PutUint64(buf[offHeadEntrySeqnum:], opts.firstSeqnum)
+		le.PutUint64(buf[offHeadEntryRealtime:], uint64(entries[0].realtime.UnixMicro()))
+		le.PutUint64(buf[offTailEntryRealtime:], uint64(entries[len(entries)-1].realtime.UnixMicro()))
+		le.PutUint64(buf[offTailEntryMonotonic:], monotonic)
+	}
+	le.PutUint64(buf[offEntryArrayOffset:], firstArray)
+	le.PutUint64(buf[208:], uint64(len(dataOffsets))) // n_data
+	le.PutUint64(buf[232:], uint64((len(entryOffsets)+testEntryArrayCapacity-1)/testEntryArrayCapacity))
+	le.PutUint32(buf[256:], uint32(tailArray))
+	le.PutUint32(buf[260:], uint32(tailArrayEntries))
+	le.PutUint64(buf[264:], lastEntry)
+
+	// Write atomically so a polling reader never sees a partial file
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+}
+
+// compressTestPayload compresses a data payload the way journald does.
+func compressTestPayload(t *testing.T, flag byte, payload []byte) []byte {
+	t.Helper()
+
+	switch flag {
+	case objectCompressedZstd:
+		// journald frames always record the content size, which needs a single segment
+		enc, err := zstd.NewWriter(nil, zstd.WithSingleSegment(true))
+		if err != nil {
+			t.Fatalf("zstd.NewWriter failed: %v", err)
+		}
+		defer func() { _ = enc.Close() }()
+		return enc.EncodeAll(payload, nil)
+
+	case objectCompressedXZ:
+		var out bytes.Buffer
+		w, err := xz.NewWriter(&out)
+		if err != nil {
+			t.Fatalf("xz.NewWriter failed: %v", err)
+		}
+		if _, err := w.Write(payload); err != nil {
+			t.Fatalf("xz write failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("xz close failed: %v", err)
+		}
+		return out.Bytes()
+
+	case objectCompressedLZ4:
+		// Size prefix followed by a block holding a single literal run
+		out := binary.LittleEndian.AppendUint64(nil, uint64(len(payload)))
+		n := len(payload)
+		if n < 15 {
+			return append(append(out, byte(n<<4)), payload...)
+		}
+		out = append(out, 0xf0)
+		for n -= 15; n >= 255; n -= 255 {
+			out = append(out, 255)
+		}
+		out = append(out, byte(n))
+		return append(out, payload...)
+
+	default:
+		t.Fatalf("unknown compression flag %d", flag)
+		return nil
+	}
+}
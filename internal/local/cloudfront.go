@@ -0,0 +1,116 @@
+package local
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// cloudFrontDefaultFields are the fields of CloudFront standard logs, used
+// until a "#Fields:" header names them (such as when tailing a file).
+var cloudFrontDefaultFields = []string{
+	"date", "time", "x-edge-location", "sc-bytes", "c-ip", "cs-method", "cs(Host)",
+	"cs-uri-stem", "sc-status", "cs(Referer)", "cs(User-Agent)", "cs-uri-query",
+	"cs(Cookie)", "x-edge-result-type", "x-edge-request-id", "x-host-header",
+	"cs-protocol", "cs-bytes", "time-taken", "x-forwarded-for", "ssl-protocol",
+	"ssl-cipher", "x-edge-response-result-type", "cs-protocol-version", "fle-status",
+	"fle-encrypted-fields", "c-port", "time-to-first-byte", "x-edge-detailed-result-type",
+	"sc-content-type", "sc-content-len", "sc-range-start", "sc-range-end",
+}
+
+// CloudFront entries are tab-separated and start with the date and time: "2024-03-01\t10:00:00\tIAD89-C1\t..."
+var cloudFrontLinePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\t\d{2}:\d{2}:\d{2}\t\S+\t`)
+
+// cloudFrontFieldName turns a header field name such as "cs(User-Agent)" into
+// a field name like "cs_user_agent".
+var cloudFrontFieldName = strings.NewReplacer("(", "_", ")", "", "-", "_")
+
+// CloudFrontParser handles CloudFront standard (access) logs. The "#Fields:"
+// header names the tab-separated fields of the entries that follow it.
+type CloudFrontParser struct {
+	fields []string
+}
+
+func (p *CloudFrontParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	if line == "" {
+		return nil
+	}
+
+	// Header lines: "#Version: 1.0" and "#Fields: date time ..."
+	if strings.HasPrefix(line, "#") {
+		if names, ok := strings.CutPrefix(line, "#Fields:"); ok {
+			p.fields = strings.Fields(names)
+		}
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+		Fields:  make(map[string]string),
+	}
+
+	names := p.fields
+	if names == nil {
+		names = cloudFrontDefaultFields
+	}
+
+	var date, clock string
+	for i, value := range strings.Split(line, "\t") {
+		if i >= len(names) {
+			break
+		}
+		switch names[i] {
+		case "date":
+			date = value
+			continue
+		case "time":
+			clock = value
+			continue
+		}
+		if value == "-" || value == "" {
+			continue
+		}
+
+		name := strings.ToLower(cloudFrontFieldName.Replace(names[i]))
+		switch name {
+		case "cs_user_agent", "cs_referer", "cs_cookie":
+			// CloudFront URL-encodes these, spaces included
+			if decoded, err := url.PathUnescape(value); err == nil {
+				value = decoded
+			}
+		}
+		entry.Fields[name] = value
+	}
+
+	// Times are in UTC
+	if ts, err := time.Parse("2006-01-02 15:04:05", date+" "+clock); err == nil {
+		entry.Timestamp = ts
+	}
+
+	if method, path := entry.Fields["cs_method"], entry.Fields["cs_uri_stem"]; method != "" && path != "" {
+		if query := entry.Fields["cs_uri_query"]; query != "" {
+			path += "?" + query
+		}
+		entry.Message = method + " " + path
+		if status := entry.Fields["sc_status"]; status != "" {
+			entry.Message += " " + status
+		}
+	}
+
+	return entry
+}
+
+func (p *CloudFrontParser) IsMultiline() bool        { return false }
+func (p *CloudFrontParser) ShouldJoin(string) bool { return false }
+
+// isCloudFrontHeader reports whether a header line names CloudFront fields.
+func isCloudFrontHeader(line string) bool {
+	return strings.HasPrefix(line, "#Fields:") && strings.Contains(line, "x-edge-")
+}
@@ -0,0 +1,88 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+const cloudFrontLog = "#Version: 1.0\n" +
+	"#Fields: date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem sc-status cs(Referer) cs(User-Agent) cs-uri-query x-edge-result-type time-taken\n" +
+	"2024-03-01\t10:00:00\tIAD89-C1\t2390\t192.0.2.10\tGET\td111111abcdef8.cloudfront.net\t/index.html\t200\t-\tMozilla/5.0%20(Windows%20NT%2010.0)\t-\tHit\t0.001\n" +
+	"2024-03-01\t10:00:05\tIAD89-C1\t512\t192.0.2.11\tGET\td111111abcdef8.cloudfront.net\t/api/items\t503\thttps://www.example.com/\tcurl/8.0\tpage=2\tError\t1.234\n"
+
+func TestCloudFrontParser_Headers(t *testing.T) {
+	dir := t.TempDir()
+	path := createTempFile(t, dir, "E2ABC.2024-03-01-10.a1b2c3d4", cloudFrontLog)
+
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	if src.format != FormatCloudFront {
+		t.Fatalf("format = %v, want cloudfront", src.format)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (headers skipped)", len(entries))
+	}
+
+	e := entries[0]
+	if !e.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 5, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", e.Timestamp)
+	}
+	if e.Message != "GET /api/items?page=2 503" {
+		t.Errorf("Message = %q", e.Message)
+	}
+	if e.Ptr != source.MakeLocalPtr(path, 4) {
+		t.Errorf("Ptr = %q", e.Ptr)
+	}
+	wantFields := map[string]string{
+		"x_edge_location":    "IAD89-C1",
+		"c_ip":               "192.0.2.11",
+		"cs_host":            "d111111abcdef8.cloudfront.net",
+		"sc_status":          "503",
+		"cs_referer":         "https://www.example.com/",
+		"x_edge_result_type": "Error",
+		"time_taken":         "1.234",
+	}
+	for k, v := range wantFields {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, e.Fields[k], v)
+		}
+	}
+
+	if ua := entries[1].Fields["cs_user_agent"]; ua != "Mozilla/5.0 (Windows NT 10.0)" {
+		t.Errorf("cs_user_agent = %q, want it URL-decoded", ua)
+	}
+	if _, ok := entries[1].Fields["cs_referer"]; ok {
+		t.Error("cs_referer should be omitted for \"-\"")
+	}
+}
+
+func TestCloudFrontParser_DefaultFields(t *testing.T) {
+	p := &CloudFrontParser{}
+	line := "2024-03-01\t10:00:00\tIAD89-C1\t2390\t192.0.2.10\tGET\td111111abcdef8.cloudfront.net\t/index.html\t404"
+
+	entry := p.ParseLine(line, 7, filepath.Join("/logs", "cf.log"))
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+	if entry.Message != "GET /index.html 404" || entry.Fields["sc_bytes"] != "2390" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+
+	if entry := p.ParseLine("#Version: 1.0", 1, "/logs/cf.log"); entry != nil {
+		t.Error("header lines should be skipped")
+	}
+}
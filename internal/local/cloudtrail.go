@@ -0,0 +1,140 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// CloudTrail log files are a JSON object with a Records array: {"Records":[{...},{...}]}
+var cloudTrailPattern = regexp.MustCompile(`^\s*\{\s*"Records"\s*:\s*\[`)
+
+// cloudTrailDetectSize is how many bytes are examined to detect CloudTrail files.
+const cloudTrailDetectSize = 64
+
+// isCloudTrail reports whether the start of a file is a CloudTrail log file.
+func isCloudTrail(header []byte) bool {
+	return cloudTrailPattern.Match(header)
+}
+
+// readCloudTrail calls fn with each record of a CloudTrail log file and its
+// index, counting from 1, until fn returns false. Records are decoded one at
+// a time, so large files are not held in memory.
+func readCloudTrail(ctx context.Context, r io.Reader, fn func(index int, record map[string]interface{}) bool) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("not a CloudTrail log file: expected a JSON object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != "Records" {
+			// Skip other members
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("not a CloudTrail log file: Records is not an array")
+		}
+		for index := 1; dec.More(); index++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var record map[string]interface{}
+			if err := dec.Decode(&record); err != nil {
+				return fmt.Errorf("record %d: %w", index, err)
+			}
+			if !fn(index, record) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("not a CloudTrail log file: no Records array")
+}
+
+// cloudTrailEntry converts a CloudTrail record into a log entry. The message
+// names the service and API call, e.g. "iam.amazonaws.com:CreateAccessKey",
+// followed by the error code of failed calls. Nested values become fields
+// with dotted names, such as userIdentity.arn.
+func cloudTrailEntry(index int, record map[string]interface{}, filePath string) source.Entry {
+	entry := source.Entry{
+		Stream: filepath.Base(filePath),
+		Source: filePath,
+		Ptr:    source.MakeLocalPtr(filePath, index),
+		Fields: make(map[string]string),
+	}
+
+	if s, ok := record["eventTime"].(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			entry.Timestamp = ts
+			delete(record, "eventTime")
+		}
+	}
+	flattenJSON("", record, entry.Fields)
+
+	entry.Message = entry.Fields["eventSource"] + ":" + entry.Fields["eventName"]
+	if code := entry.Fields["errorCode"]; code != "" {
+		entry.Message += " " + code
+	}
+
+	return entry
+}
+
+// flattenJSON adds the values of a decoded JSON object to fields, naming
+// nested values by their path. Arrays are kept as JSON.
+func flattenJSON(prefix string, v interface{}, fields map[string]string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			flattenJSON(name, child, fields)
+		}
+	case nil:
+		// Omit nulls
+	case string:
+		fields[prefix] = val
+	case json.Number:
+		fields[prefix] = val.String()
+	case bool:
+		fields[prefix] = strconv.FormatBool(val)
+	default:
+		if b, err := json.Marshal(val); err == nil {
+			fields[prefix] = string(b)
+		}
+	}
+}
+
+// readCloudTrailEntries calls fn with the records of a (usually gzipped)
+// CloudTrail log file, until fn returns false.
+func readCloudTrailEntries(ctx context.Context, path string, fn func(source.Entry) bool) error {
+	f, err := OpenLogFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return readCloudTrail(ctx, f, func(index int, record map[string]interface{}) bool {
+		return fn(cloudTrailEntry(index, record, path))
+	})
+}
@@ -0,0 +1,174 @@
+package local
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// cloudTrailLog holds three records in the layout CloudTrail delivers: one
+// line, with the Records array first.
+const cloudTrailLog = `{"Records":[` +
+	`{"eventVersion":"1.08","userIdentity":{"type":"IAMUser","arn":"arn:aws:iam::123456789012:user/alice","accountId":"123456789012","userName":"alice"},` +
+	`"eventTime":"2024-03-01T10:00:00Z","eventSource":"signin.amazonaws.com","eventName":"ConsoleLogin","awsRegion":"us-east-1",` +
+	`"sourceIPAddress":"198.51.100.7","userAgent":"Mozilla/5.0","requestParameters":null,"responseElements":{"ConsoleLogin":"Success"},` +
+	`"additionalEventData":{"MFAUsed":"No"},"readOnly":false,"eventID":"a1"},` +
+	`{"eventVersion":"1.08","userIdentity":{"type":"IAMUser","arn":"arn:aws:iam::123456789012:user/alice","userName":"alice"},` +
+	`"eventTime":"2024-03-01T10:00:05Z","eventSource":"iam.amazonaws.com","eventName":"CreateAccessKey","awsRegion":"us-east-1",` +
+	`"sourceIPAddress":"198.51.100.7","errorCode":"AccessDenied","errorMessage":"User is not authorized",` +
+	`"resources":[{"ARN":"arn:aws:iam::123456789012:user/bob"}],"readOnly":false,"eventID":"a2"},` +
+	`{"eventVersion":"1.08","userIdentity":{"type":"AWSService","invokedBy":"ec2.amazonaws.com"},` +
+	`"eventTime":"2024-03-01T10:00:09Z","eventSource":"sts.amazonaws.com","eventName":"AssumeRole","awsRegion":"us-east-1",` +
+	`"sourceIPAddress":"ec2.amazonaws.com","requestParameters":{"durationSeconds":3600,"roleArn":"arn:aws:iam::123456789012:role/web"},"eventID":"a3"}` +
+	`]}`
+
+// writeCloudTrail writes a gzipped CloudTrail log file, as CloudTrail delivers them.
+func writeCloudTrail(t *testing.T, dir, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "123456789012_CloudTrail_us-east-1_20240301T1000Z_abc.json.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSource_CloudTrail(t *testing.T) {
+	path := writeCloudTrail(t, t.TempDir(), cloudTrailLog)
+	ctx := context.Background()
+
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	if src.format != FormatCloudTrail {
+		t.Fatalf("format = %v, want cloudtrail", src.format)
+	}
+
+	entries, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.Message)
+	}
+	want := "sts.amazonaws.com:AssumeRole|iam.amazonaws.com:CreateAccessKey AccessDenied|signin.amazonaws.com:ConsoleLogin"
+	if strings.Join(messages, "|") != want {
+		t.Fatalf("messages = %q", messages)
+	}
+
+	e := entries[1]
+	if !e.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 5, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", e.Timestamp)
+	}
+	if e.Ptr != source.MakeLocalPtr(path, 2) {
+		t.Errorf("Ptr = %q, want record 2", e.Ptr)
+	}
+	wantFields := map[string]string{
+		"userIdentity.arn":  "arn:aws:iam::123456789012:user/alice",
+		"userIdentity.type": "IAMUser",
+		"sourceIPAddress":   "198.51.100.7",
+		"errorCode":         "AccessDenied",
+		"eventName":         "CreateAccessKey",
+		"readOnly":          "false",
+		"resources":         `[{"ARN":"arn:aws:iam::123456789012:user/bob"}]`,
+	}
+	for k, v := range wantFields {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, e.Fields[k], v)
+		}
+	}
+	if _, ok := e.Fields["eventTime"]; ok {
+		t.Error("eventTime should not be a field")
+	}
+	if got := entries[0].Fields["requestParameters.durationSeconds"]; got != "3600" {
+		t.Errorf("requestParameters.durationSeconds = %q", got)
+	}
+	if _, ok := entries[2].Fields["requestParameters"]; ok {
+		t.Error("null requestParameters should be omitted")
+	}
+
+	// Filters and time ranges apply to records
+	filtered, err := src.Query(ctx, source.QueryParams{
+		Filter:    regexp.MustCompile(`AccessDenied`),
+		StartTime: time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC),
+	})
+	if err != nil || len(filtered) != 1 || filtered[0].Fields["eventID"] != "a2" {
+		t.Errorf("filtered = %+v, %v", filtered, err)
+	}
+
+	record, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 3))
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if record.Fields["eventID"] != "a3" {
+		t.Errorf("GetRecord = %+v", record)
+	}
+	if _, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 4)); err == nil {
+		t.Error("GetRecord past the last record should fail")
+	}
+
+	before, after, err := src.FetchContext(ctx, *record, 5, 5)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 2 || before[0].Message != "signin.amazonaws.com:ConsoleLogin" || len(after) != 0 {
+		t.Errorf("context = %+v, %+v", before, after)
+	}
+
+	if _, err := src.Tail(ctx, source.TailParams{}); err == nil {
+		t.Error("Tail should fail for CloudTrail files")
+	}
+}
+
+func TestReadCloudTrail_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"not an object", `[1, 2]`, "expected a JSON object"},
+		{"no records", `{"Digest":{}}`, "no Records array"},
+		{"records not an array", `{"Records":{}}`, "not an array"},
+		{"truncated", `{"Records":[{"eventName":"A"},{"eventName":`, "record 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := readCloudTrail(context.Background(), strings.NewReader(tt.content), func(int, map[string]interface{}) bool {
+				return true
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsCloudTrail(t *testing.T) {
+	if !isCloudTrail([]byte(cloudTrailLog[:cloudTrailDetectSize])) {
+		t.Error("CloudTrail file not detected")
+	}
+	if !isCloudTrail([]byte("{\n  \"Records\" : [\n")) {
+		t.Error("pretty-printed CloudTrail file not detected")
+	}
+	if isCloudTrail([]byte(`{"message":"Records: ["}`)) {
+		t.Error("JSON log line detected as CloudTrail")
+	}
+}
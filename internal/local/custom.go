@@ -0,0 +1,194 @@
+package local
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// formatCustom is the Format of the first format registered from the config.
+const formatCustom Format = 1000
+
+// customFormat is a named log format defined in the config, or a grok
+// expression given as a format hint.
+type customFormat struct {
+	name      string
+	newParser func() Parser
+	err       error // why the definition could not be used
+}
+
+// customFormats holds the registered formats; the Format of customFormats[i]
+// is formatCustom + i.
+var (
+	customMu      sync.RWMutex
+	customFormats []customFormat
+)
+
+// RegisterLogFormat adds a named access log format, defined in nginx
+// log_format syntax, which ParseFormat then accepts like a built-in format.
+func RegisterLogFormat(name, layout string) error {
+	f, err := compileLogFormat(layout)
+	if err != nil {
+		return err
+	}
+	return registerFormat(name, func() Parser { return &AccessLogParser{format: f} }, nil)
+}
+
+// RegisterParser adds a named format, parsed by a regular expression or by a
+// base format with multiline rules, which ParseFormat then accepts like a
+// built-in format. A definition that does not compile is still registered,
+// so that selecting it reports the error.
+func RegisterParser(name string, cfg source.ParserConfig) error {
+	newParser, compileErr := compileParser(cfg)
+	if err := registerFormat(name, newParser, compileErr); err != nil {
+		return err
+	}
+	return compileErr
+}
+
+// compileParser returns the parser constructor of a parser definition.
+// Without a pattern, lines are parsed by the base format, plain by default,
+// and joined into entries by the definition's multiline rules.
+func compileParser(cfg source.ParserConfig) (func() Parser, error) {
+	if cfg.Pattern != "" && cfg.Format != "" {
+		return nil, fmt.Errorf("pattern and format cannot both be set")
+	}
+	if cfg.Pattern != "" || cfg.Format == "" && cfg.Multiline == (source.MultilineConfig{}) {
+		p, err := newRegexParser(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return func() Parser { return p }, nil
+	}
+
+	base := FormatPlain
+	if cfg.Format != "" {
+		if err := CheckFormat(cfg.Format); err != nil {
+			return nil, err
+		}
+		if base = ParseFormat(cfg.Format); base == FormatAuto {
+			return nil, fmt.Errorf("unknown format %q", cfg.Format)
+		}
+		if base.recordReader() != nil {
+			return nil, fmt.Errorf("format %q reads whole records, not lines", cfg.Format)
+		}
+	}
+	rules, err := newMultilineRules(cfg.Multiline)
+	if err != nil {
+		return nil, err
+	}
+	return func() Parser { return withMultiline(NewParser(base), rules) }, nil
+}
+
+// registerFormat adds or replaces a named format. Names of built-in formats
+// cannot be used. A format registered with an error is known by name but
+// cannot be selected.
+func registerFormat(name string, newParser func() Parser, formatErr error) error {
+	name = strings.ToLower(name)
+	if name == "" || name == "auto" || strings.HasPrefix(name, grokPrefix) {
+		return fmt.Errorf("invalid format name %q", name)
+	}
+	if f := ParseFormat(name); f != FormatAuto && f < formatCustom {
+		return fmt.Errorf("%q is a built-in format", name)
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+	if i := customIndex(name); i >= 0 {
+		customFormats[i].newParser = newParser
+		customFormats[i].err = formatErr
+		return nil
+	}
+	customFormats = append(customFormats, customFormat{name: name, newParser: newParser, err: formatErr})
+	return nil
+}
+
+// customIndex returns the index of the registered format with a name, or -1.
+// The caller holds customMu.
+func customIndex(name string) int {
+	for i, c := range customFormats {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatName returns the name a format hint is registered under. Grok
+// expressions are case-sensitive; other names are not. The + of a
+// "<format>+<profile>" hint typed in a URI query decodes to a space.
+func formatName(hint string) string {
+	if strings.HasPrefix(hint, grokPrefix) {
+		return hint
+	}
+	return strings.ReplaceAll(strings.ToLower(hint), " ", "+")
+}
+
+// custom returns the registered format of f, or nil.
+func (f Format) custom() *customFormat {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	if i := int(f - formatCustom); i >= 0 && i < len(customFormats) {
+		c := customFormats[i]
+		return &c
+	}
+	return nil
+}
+
+// parseCustomFormat returns the usable registered format for a hint, or
+// FormatAuto. Grok expressions and "<format>+<profile>" hints, such as
+// "json+python", are compiled and registered the first time they are seen,
+// so every source using one shares the compiled patterns.
+func parseCustomFormat(hint string) Format {
+	name := formatName(hint)
+
+	customMu.RLock()
+	i := customIndex(name)
+	customMu.RUnlock()
+
+	if i < 0 {
+		var cfg source.ParserConfig
+		if expr, ok := strings.CutPrefix(name, grokPrefix); ok {
+			cfg.Pattern = expr
+		} else if base, profile, ok := strings.Cut(name, "+"); ok {
+			cfg.Format = base
+			cfg.Multiline.Profile = profile
+		} else {
+			return FormatAuto
+		}
+		// Compiled without the lock, as base formats are parsed in turn
+		newParser, err := compileParser(cfg)
+
+		customMu.Lock()
+		if i = customIndex(name); i < 0 {
+			i = len(customFormats)
+			customFormats = append(customFormats, customFormat{name: name, newParser: newParser, err: err})
+		}
+		customMu.Unlock()
+	}
+
+	f := formatCustom + Format(i)
+	if f.custom().err != nil {
+		return FormatAuto
+	}
+	return f
+}
+
+// CheckFormat returns the error of a format hint naming a format that could
+// not be used, such as a config parser or grok expression that does not
+// compile, so sources fail with it rather than detecting another format.
+func CheckFormat(hint string) error {
+	if ParseFormat(hint) != FormatAuto {
+		return nil
+	}
+
+	name := formatName(hint)
+	customMu.RLock()
+	defer customMu.RUnlock()
+	if i := customIndex(name); i >= 0 && customFormats[i].err != nil {
+		return fmt.Errorf("format %q: %w", name, customFormats[i].err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression identifies a compression format by its magic bytes.
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+	CompressionBzip2
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionBzip2:
+		return "bzip2"
+	default:
+		return "none"
+	}
+}
+
+// Magic bytes at the start of each compressed stream
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// DetectCompression identifies the compression format from the first bytes of a stream.
+func DetectCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return CompressionZstd
+	// "BZh" is followed by the block size, '1' through '9'
+	case bytes.HasPrefix(header, bzip2Magic) && len(header) > 3 && header[3] >= '1' && header[3] <= '9':
+		return CompressionBzip2
+	default:
+		return CompressionNone
+	}
+}
+
+// NewDecompressReader returns a reader for the decompressed content of r.
+// The compression is detected from magic bytes; uncompressed input is
+// returned unchanged. Closing the returned reader does not close r.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, Compression, error) {
+	br := bufio.NewReader(r)
+
+	// Peek returns fewer bytes for very short input, which is never compressed
+	header, _ := br.Peek(len(zstdMagic))
+	compression := DetectCompression(header)
+
+	switch compression {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, compression, err
+		}
+		return gz, compression, nil
+
+	case CompressionZstd:
+		// A single decoder goroutine is plenty for sequential log reading
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, compression, err
+		}
+		return zr.IOReadCloser(), compression, nil
+
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(br)), compression, nil
+
+	default:
+		return io.NopCloser(br), compression, nil
+	}
+}
+
+// logFile is an open log file read through its decompressor.
+type logFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+// Close closes the decompressor and the underlying file.
+func (f *logFile) Close() error {
+	err := f.ReadCloser.Close()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// OpenLogFile opens a log file for reading, transparently decompressing
+// gzip, zstd and bzip2 content.
+func OpenLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, compression, err := NewDecompressReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to open %s stream in %s: %w", compression, path, err)
+	}
+
+	return &logFile{ReadCloser: r, file: f}, nil
+}
@@ -0,0 +1,182 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Fixtures in testdata/ hold the same log compressed with each format
+var compressedFixtures = []struct {
+	file        string
+	compression Compression
+}{
+	{"app.log", CompressionNone},
+	{"app.log.1.gz", CompressionGzip},
+	{"app.log.2.zst", CompressionZstd},
+	{"app.log.3.bz2", CompressionBzip2},
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   Compression
+	}{
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, CompressionGzip},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+		{"bzip2", []byte("BZh9"), CompressionBzip2},
+		{"bzip2 without block size", []byte("BZh!"), CompressionNone},
+		{"plain text", []byte("2025"), CompressionNone},
+		{"text starting like bzip2", []byte("BZh"), CompressionNone},
+		{"empty", nil, CompressionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectCompression(tt.header); got != tt.want {
+				t.Errorf("DetectCompression(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDecompressReader(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("testdata", "app.log"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	for _, tt := range compressedFixtures {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("failed to open fixture: %v", err)
+			}
+			defer func() { _ = f.Close() }()
+
+			r, compression, err := NewDecompressReader(f)
+			if err != nil {
+				t.Fatalf("NewDecompressReader failed: %v", err)
+			}
+			defer func() { _ = r.Close() }()
+
+			if compression != tt.compression {
+				t.Errorf("expected compression %v, got %v", tt.compression, compression)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decompressed content mismatch:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestNewDecompressReader_Corrupt(t *testing.T) {
+	// Valid gzip magic followed by garbage
+	r, _, err := NewDecompressReader(bytes.NewReader([]byte{0x1f, 0x8b, 0xff, 0xff, 0xff}))
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if err == nil {
+		t.Error("expected error for corrupt gzip stream")
+	}
+}
+
+func TestSource_CompressedFiles(t *testing.T) {
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatalf("filepath.Abs failed: %v", err)
+	}
+
+	// Format is detected from the decompressed content of the first match
+	src, err := NewSource(filepath.Join(dir, "app.log*"), "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	if src.format != FormatJava {
+		t.Errorf("expected java format, got %v", src.format)
+	}
+
+	ctx := context.Background()
+	entries, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// 4 entries per file, one of them a joined stack trace
+	if len(entries) != 4*len(compressedFixtures) {
+		t.Fatalf("expected %d entries, got %d", 4*len(compressedFixtures), len(entries))
+	}
+
+	perFile := make(map[string]int)
+	for _, e := range entries {
+		perFile[e.Stream]++
+
+		// Pointers resolve against the decompressed stream
+		got, err := src.GetRecord(ctx, e.Ptr)
+		if err != nil {
+			t.Fatalf("GetRecord(%s) failed: %v", e.Ptr, err)
+		}
+		if got.Message != e.Message {
+			t.Errorf("GetRecord(%s): expected %q, got %q", e.Ptr, e.Message, got.Message)
+		}
+	}
+	for _, tt := range compressedFixtures {
+		if perFile[tt.file] != 4 {
+			t.Errorf("expected 4 entries from %s, got %d", tt.file, perFile[tt.file])
+		}
+	}
+}
+
+func TestSource_FetchContext_Compressed(t *testing.T) {
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatalf("filepath.Abs failed: %v", err)
+	}
+
+	for _, tt := range compressedFixtures {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			src, err := NewSource(path, "java")
+			if err != nil {
+				t.Fatalf("NewSource failed: %v", err)
+			}
+
+			entry := source.Entry{Ptr: source.MakeLocalPtr(path, 5)}
+			before, after, err := src.FetchContext(context.Background(), entry, 1, 1)
+			if err != nil {
+				t.Fatalf("FetchContext failed: %v", err)
+			}
+			// The entry before is the whole exception, not its last line
+			if len(before) != 1 || !strings.Contains(before[0].Message, "Connection refused\njava.net.ConnectException") ||
+				!strings.HasSuffix(before[0].Message, "\tat com.example.Db.connect(Db.java:42)") {
+				t.Errorf("unexpected before context: %v", before)
+			}
+			if len(after) != 1 || !strings.Contains(after[0].Message, "Connected") {
+				t.Errorf("unexpected after context: %v", after)
+			}
+		})
+	}
+}
+
+func TestSource_Tail_Compressed(t *testing.T) {
+	src, err := NewSource(filepath.Join("testdata", "app.log.1.gz"), "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	if _, err := src.Tail(context.Background(), source.TailParams{}); err == nil {
+		t.Error("expected error tailing a compressed file")
+	}
+}
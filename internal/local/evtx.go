@@ -0,0 +1,689 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// EVTX is the Windows XML event log format: a 4KB file header followed by
+// 64KB chunks of event records. Each record holds its event as Binary XML,
+// normally an instance of a template (the event's XML with placeholders)
+// defined once per chunk, plus the substitution values for the record.
+
+// EVTX layout sizes
+const (
+	evtxFileHeaderSize   = 4096
+	evtxChunkSize        = 64 * 1024
+	evtxChunkHeaderSize  = 512
+	evtxRecordHeaderSize = 24
+
+	// evtxMaxDepth bounds the nesting of elements and templates in a record
+	evtxMaxDepth = 64
+)
+
+var (
+	evtxFileMagic   = []byte("ElfFile\x00")
+	evtxChunkMagic  = []byte("ElfChnk\x00")
+	evtxRecordMagic = []byte("**\x00\x00")
+
+	errEVTXTruncated = errors.New("truncated Binary XML")
+)
+
+// Binary XML value types
+const (
+	evtxNull       = 0x00
+	evtxString     = 0x01
+	evtxAnsiString = 0x02
+	evtxInt8       = 0x03
+	evtxUint8      = 0x04
+	evtxInt16      = 0x05
+	evtxUint16     = 0x06
+	evtxInt32      = 0x07
+	evtxUint32     = 0x08
+	evtxInt64      = 0x09
+	evtxUint64     = 0x0a
+	evtxFloat32    = 0x0b
+	evtxFloat64    = 0x0c
+	evtxBool       = 0x0d
+	evtxBinary     = 0x0e
+	evtxGUID       = 0x0f
+	evtxSizeT      = 0x10
+	evtxFileTime   = 0x11
+	evtxSystemTime = 0x12
+	evtxSID        = 0x13
+	evtxHexInt32   = 0x14
+	evtxHexInt64   = 0x15
+	evtxBinXML     = 0x21
+	evtxArray      = 0x80
+)
+
+// isEVTX reports whether header starts with the EVTX file signature.
+func isEVTX(header []byte) bool {
+	return bytes.HasPrefix(header, evtxFileMagic)
+}
+
+// xmlElement is an element of a decoded event.
+type xmlElement struct {
+	Name     string
+	Attrs    []xmlAttr
+	Children []*xmlElement
+	Text     string
+}
+
+// xmlAttr is an attribute of a decoded element.
+type xmlAttr struct {
+	Name  string
+	Value string
+}
+
+// child returns the first child element with a name, or nil.
+func (e *xmlElement) child(name string) *xmlElement {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute, or "" if e is nil or has no such attribute.
+func (e *xmlElement) attr(name string) string {
+	if e == nil {
+		return ""
+	}
+	for _, a := range e.Attrs {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// text returns the text content of e, or "" if e is nil.
+func (e *xmlElement) text() string {
+	if e == nil {
+		return ""
+	}
+	return strings.TrimSpace(e.Text)
+}
+
+// evtxRecord is an event record of an EVTX file.
+type evtxRecord struct {
+	ID      uint64
+	Written time.Time
+	Event   *xmlElement
+}
+
+// readEVTX calls fn for each record of an EVTX file in file order, until fn
+// returns false. Records that cannot be decoded are skipped.
+func readEVTX(ctx context.Context, r io.Reader, fn func(evtxRecord) bool) error {
+	header := make([]byte, evtxFileHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("cannot read EVTX file header: %w", err)
+	}
+	if !isEVTX(header) {
+		return fmt.Errorf("not an EVTX file")
+	}
+
+	chunk := make([]byte, evtxChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		// Chunks the log has not used yet are zeroed
+		if !bytes.HasPrefix(chunk, evtxChunkMagic) {
+			continue
+		}
+		if !readEVTXChunk(chunk, fn) {
+			return nil
+		}
+	}
+}
+
+// readEVTXChunk calls fn for each record of a chunk, and reports whether fn
+// asked for more records.
+func readEVTXChunk(chunk []byte, fn func(evtxRecord) bool) bool {
+	// Records end at the chunk's free space offset
+	end := int(binary.LittleEndian.Uint32(chunk[0x30:]))
+	if end < evtxChunkHeaderSize || end > len(chunk) {
+		end = len(chunk)
+	}
+
+	pos := evtxChunkHeaderSize
+	for pos+evtxRecordHeaderSize <= end && bytes.Equal(chunk[pos:pos+4], evtxRecordMagic) {
+		size := int(binary.LittleEndian.Uint32(chunk[pos+4:]))
+		if size < evtxRecordHeaderSize+4 || pos+size > len(chunk) {
+			break
+		}
+
+		rec := evtxRecord{
+			ID:      binary.LittleEndian.Uint64(chunk[pos+8:]),
+			Written: fileTime(binary.LittleEndian.Uint64(chunk[pos+16:])),
+		}
+
+		d := binXMLDecoder{chunk: chunk}
+		root := &xmlElement{}
+		d.content(pos+evtxRecordHeaderSize, pos+size-4, nil, root)
+		switch {
+		case d.err != nil:
+			logging.Debug("Skipping EVTX record %d: %v", rec.ID, d.err)
+		case len(root.Children) == 0:
+			logging.Debug("Skipping EVTX record %d: no event", rec.ID)
+		default:
+			rec.Event = root.Children[0]
+			if !fn(rec) {
+				return false
+			}
+		}
+
+		pos += size
+	}
+	return true
+}
+
+// binXMLValue is a substitution value of a template instance.
+type binXMLValue struct {
+	typ  byte
+	pos  int // chunk offset of the value data
+	size int
+}
+
+// binXMLDecoder decodes the Binary XML of the records in a chunk. Offsets
+// in Binary XML are relative to the chunk, so it decodes from chunk
+// positions. The first out-of-range read sets err and later reads return
+// zero values.
+type binXMLDecoder struct {
+	chunk []byte
+	depth int
+	err   error
+}
+
+func (d *binXMLDecoder) bytesAt(pos, n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if pos < 0 || n < 0 || pos+n > len(d.chunk) {
+		d.err = errEVTXTruncated
+		return nil
+	}
+	return d.chunk[pos : pos+n]
+}
+
+func (d *binXMLDecoder) u8(pos int) byte {
+	if b := d.bytesAt(pos, 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *binXMLDecoder) u16(pos int) uint16 {
+	if b := d.bytesAt(pos, 2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *binXMLDecoder) u32(pos int) uint32 {
+	if b := d.bytesAt(pos, 4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// name reads the name structure at a chunk offset: the offset of the next
+// name, a hash, the length in characters and the UTF-16 characters.
+func (d *binXMLDecoder) name(offset int) string {
+	n := int(d.u16(offset + 6))
+	return utf16String(d.bytesAt(offset+8, 2*n))
+}
+
+// nameSize returns the size of the name structure at a chunk offset.
+func (d *binXMLDecoder) nameSize(offset int) int {
+	return 10 + 2*int(d.u16(offset+6))
+}
+
+// nameRef reads a name offset at pos, and returns the name and the position
+// after the reference. A name used for the first time in a chunk follows
+// its reference inline.
+func (d *binXMLDecoder) nameRef(pos int) (string, int) {
+	offset := int(d.u32(pos))
+	pos += 4
+	name := d.name(offset)
+	if offset == pos {
+		pos += d.nameSize(offset)
+	}
+	return name, pos
+}
+
+// content decodes tokens from pos, adding elements and text to parent,
+// until the end of the fragment or of the parent element. It returns the
+// position after the last token.
+func (d *binXMLDecoder) content(pos, end int, subs []binXMLValue, parent *xmlElement) int {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > evtxMaxDepth {
+		d.err = fmt.Errorf("Binary XML nested too deeply")
+		return end
+	}
+
+	for d.err == nil && pos < end {
+		// Bit 0x40 flags tokens followed by more data of the same kind
+		token := d.u8(pos)
+		switch token & 0xbf {
+		case 0x00, 0x04: // end of fragment, end of element
+			return pos + 1
+		case 0x01: // open start element
+			pos = d.element(pos, end, subs, parent)
+		case 0x0c: // template instance
+			pos = d.templateInstance(pos, parent)
+		case 0x0f: // fragment header: token, major and minor version, flags
+			pos += 4
+		case 0x0a: // processing instruction target
+			_, pos = d.nameRef(pos + 1)
+		case 0x0b: // processing instruction data
+			pos += 3 + 2*int(d.u16(pos+1))
+		default:
+			next, ok := d.text(pos, subs, parent)
+			if !ok {
+				d.err = fmt.Errorf("unexpected Binary XML token 0x%02x at chunk offset %d", token, pos)
+				return end
+			}
+			pos = next
+		}
+	}
+	return pos
+}
+
+// text decodes a token with character data at pos and adds it to el. It
+// returns the position after the token, or false if the token has none.
+func (d *binXMLDecoder) text(pos int, subs []binXMLValue, el *xmlElement) (int, bool) {
+	switch d.u8(pos) & 0xbf {
+	case 0x05: // value: type and, for strings, length and characters
+		if typ := d.u8(pos + 1); typ != evtxString {
+			d.err = fmt.Errorf("unsupported Binary XML value type 0x%02x", typ)
+			return pos, true
+		}
+		n := int(d.u16(pos + 2))
+		el.Text += utf16String(d.bytesAt(pos+4, 2*n))
+		return pos + 4 + 2*n, true
+	case 0x07: // CDATA section
+		n := int(d.u16(pos + 1))
+		el.Text += utf16String(d.bytesAt(pos+3, 2*n))
+		return pos + 3 + 2*n, true
+	case 0x08: // character reference
+		el.Text += string(rune(d.u16(pos + 1)))
+		return pos + 3, true
+	case 0x09: // entity reference
+		name, next := d.nameRef(pos + 1)
+		el.Text += xmlEntity(name)
+		return next, true
+	case 0x0d, 0x0e: // normal and optional substitution: index and value type
+		if i := int(d.u16(pos + 1)); i < len(subs) {
+			if v := subs[i]; v.typ == evtxBinXML {
+				d.content(v.pos, v.pos+v.size, nil, el)
+			} else {
+				el.Text += d.format(v)
+			}
+		}
+		return pos + 4, true
+	}
+	return pos, false
+}
+
+// element decodes the element whose open start element token is at pos
+// and adds it to parent. It returns the position after the element.
+func (d *binXMLDecoder) element(pos, end int, subs []binXMLValue, parent *xmlElement) int {
+	hasAttrs := d.u8(pos)&0x40 != 0
+
+	// Token, dependency identifier, data size and name offset, followed by
+	// the attribute list size and the inline name, in either order
+	nameOffset := int(d.u32(pos + 7))
+	pos += 11
+	if nameOffset == pos {
+		pos += d.nameSize(nameOffset)
+	}
+	if hasAttrs {
+		pos += 4
+	}
+	if nameOffset == pos {
+		pos += d.nameSize(nameOffset)
+	}
+	el := &xmlElement{Name: d.name(nameOffset)}
+
+	if hasAttrs {
+		for d.err == nil {
+			token := d.u8(pos)
+			if token&0xbf != 0x06 {
+				break
+			}
+			name, next := d.nameRef(pos + 1)
+			value := &xmlElement{}
+			for ok := true; ok && d.err == nil; {
+				pos, ok = d.text(next, subs, value)
+				next = pos
+			}
+			el.Attrs = append(el.Attrs, xmlAttr{Name: name, Value: value.Text})
+			if token&0x40 == 0 {
+				break
+			}
+		}
+	}
+
+	switch token := d.u8(pos); token {
+	case 0x02: // close start element, followed by the content
+		pos = d.content(pos+1, end, subs, el)
+	case 0x03: // close empty element
+		pos++
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unexpected Binary XML token 0x%02x in element %s", token, el.Name)
+		}
+	}
+
+	parent.Children = append(parent.Children, el)
+	return pos
+}
+
+// templateInstance decodes the template instance at pos and adds its
+// elements to parent. It returns the position after the substitution values.
+func (d *binXMLDecoder) templateInstance(pos int, parent *xmlElement) int {
+	// Token, unknown byte, template identifier and definition offset
+	defOffset := int(d.u32(pos + 6))
+	pos += 10
+
+	// The definition follows inline the first time a chunk uses the template:
+	// next template offset, GUID, data size and data
+	defSize := int(d.u32(defOffset + 20))
+	if defOffset == pos {
+		pos += 24 + defSize
+	}
+
+	count := int(d.u32(pos))
+	pos += 4
+	if count > len(d.chunk)/4 {
+		d.err = fmt.Errorf("invalid substitution count %d", count)
+		return pos
+	}
+
+	subs := make([]binXMLValue, count)
+	valuePos := pos + 4*count
+	for i := range subs {
+		size := int(d.u16(pos + 4*i))
+		subs[i] = binXMLValue{typ: d.u8(pos + 4*i + 2), pos: valuePos, size: size}
+		valuePos += size
+	}
+	d.bytesAt(pos, valuePos-pos)
+
+	d.content(defOffset+24, defOffset+24+defSize, subs, parent)
+	return valuePos
+}
+
+// format renders a substitution value as Windows Event Viewer shows it in XML.
+func (d *binXMLDecoder) format(v binXMLValue) string {
+	data := d.bytesAt(v.pos, v.size)
+	if data == nil {
+		return ""
+	}
+
+	if v.typ&evtxArray != 0 {
+		elem := v.typ &^ evtxArray
+		var items []string
+		switch size := evtxFixedSize(elem, len(data)); {
+		case elem == evtxString:
+			items = strings.Split(strings.TrimRight(utf16String(data), "\x00"), "\x00")
+		case size > 0:
+			for i := 0; i+size <= len(data); i += size {
+				items = append(items, formatValue(elem, data[i:i+size]))
+			}
+		default:
+			return fmt.Sprintf("%X", data)
+		}
+		return strings.Join(items, ", ")
+	}
+
+	return formatValue(v.typ, data)
+}
+
+// evtxFixedSize returns the size of a value type, or 0 for variable size types.
+func evtxFixedSize(typ byte, dataSize int) int {
+	switch typ {
+	case evtxInt8, evtxUint8:
+		return 1
+	case evtxInt16, evtxUint16:
+		return 2
+	case evtxInt32, evtxUint32, evtxFloat32, evtxBool, evtxHexInt32:
+		return 4
+	case evtxInt64, evtxUint64, evtxFloat64, evtxFileTime, evtxHexInt64:
+		return 8
+	case evtxGUID, evtxSystemTime:
+		return 16
+	case evtxSizeT:
+		if dataSize%8 == 0 {
+			return 8
+		}
+		return 4
+	}
+	return 0
+}
+
+// formatValue renders a single value of a type.
+func formatValue(typ byte, data []byte) string {
+	if size := evtxFixedSize(typ, len(data)); size > 0 && len(data) < size {
+		return fmt.Sprintf("%X", data)
+	}
+
+	le := binary.LittleEndian
+	switch typ {
+	case evtxNull:
+		return ""
+	case evtxString:
+		return strings.TrimRight(utf16String(data), "\x00")
+	case evtxAnsiString:
+		return strings.TrimRight(string(data), "\x00")
+	case evtxInt8:
+		return strconv.Itoa(int(int8(data[0])))
+	case evtxUint8:
+		return strconv.Itoa(int(data[0]))
+	case evtxInt16:
+		return strconv.Itoa(int(int16(le.Uint16(data))))
+	case evtxUint16:
+		return strconv.Itoa(int(le.Uint16(data)))
+	case evtxInt32:
+		return strconv.Itoa(int(int32(le.Uint32(data))))
+	case evtxUint32:
+		return strconv.FormatUint(uint64(le.Uint32(data)), 10)
+	case evtxInt64:
+		return strconv.FormatInt(int64(le.Uint64(data)), 10)
+	case evtxUint64:
+		return strconv.FormatUint(le.Uint64(data), 10)
+	case evtxFloat32:
+		return strconv.FormatFloat(float64(math.Float32frombits(le.Uint32(data))), 'g', -1, 32)
+	case evtxFloat64:
+		return strconv.FormatFloat(math.Float64frombits(le.Uint64(data)), 'g', -1, 64)
+	case evtxBool:
+		return strconv.FormatBool(le.Uint32(data) != 0)
+	case evtxGUID:
+		return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}", le.Uint32(data), le.Uint16(data[4:]), le.Uint16(data[6:]), data[8:10], data[10:16])
+	case evtxSizeT, evtxHexInt32, evtxHexInt64:
+		if len(data) == 4 {
+			return fmt.Sprintf("0x%x", le.Uint32(data))
+		}
+		return fmt.Sprintf("0x%x", le.Uint64(data))
+	case evtxFileTime:
+		return fileTime(le.Uint64(data)).Format(time.RFC3339Nano)
+	case evtxSystemTime:
+		t := time.Date(int(le.Uint16(data)), time.Month(le.Uint16(data[2:])), int(le.Uint16(data[6:])),
+			int(le.Uint16(data[8:])), int(le.Uint16(data[10:])), int(le.Uint16(data[12:])),
+			int(le.Uint16(data[14:]))*int(time.Millisecond), time.UTC)
+		return t.Format(time.RFC3339Nano)
+	case evtxSID:
+		return formatSID(data)
+	}
+	return fmt.Sprintf("%X", data)
+}
+
+// formatSID renders a security identifier, e.g., "S-1-5-18".
+func formatSID(data []byte) string {
+	if len(data) < 8 || len(data) < 8+4*int(data[1]) {
+		return fmt.Sprintf("%X", data)
+	}
+	var authority uint64
+	for _, b := range data[2:8] {
+		authority = authority<<8 | uint64(b)
+	}
+	sid := fmt.Sprintf("S-%d-%d", data[0], authority)
+	for i := 0; i < int(data[1]); i++ {
+		sid += "-" + strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data[8+4*i:])), 10)
+	}
+	return sid
+}
+
+// fileTime converts a Windows FILETIME (100ns intervals since 1601) to a time.
+func fileTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDiff = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100ns
+	return time.Unix(0, 0).Add(time.Duration(int64(ft)-epochDiff) * 100).UTC()
+}
+
+// utf16String decodes little-endian UTF-16.
+func utf16String(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// xmlEntity returns the character an XML entity reference stands for.
+func xmlEntity(name string) string {
+	switch name {
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "amp":
+		return "&"
+	case "quot":
+		return `"`
+	case "apos":
+		return "'"
+	}
+	return "&" + name + ";"
+}
+
+// evtxEntry converts an EVTX record into a log entry. The System values and
+// the EventData (or UserData) values become fields, and the message names
+// the provider and event ID followed by the event data, unless the record
+// carries a rendered message.
+func evtxEntry(rec evtxRecord, filePath string) source.Entry {
+	entry := source.Entry{
+		Timestamp: rec.Written,
+		Stream:    filepath.Base(filePath),
+		Source:    filePath,
+		Ptr:       source.MakeLocalPtr(filePath, int(rec.ID)),
+		Fields:    map[string]string{"RecordID": strconv.FormatUint(rec.ID, 10)},
+	}
+
+	system := rec.Event.child("System")
+	provider := system.child("Provider").attr("Name")
+	eventID := system.child("EventID").text()
+	systemFields := map[string]string{
+		"EventID":  eventID,
+		"Provider": provider,
+		"Channel":  system.child("Channel").text(),
+		"Computer": system.child("Computer").text(),
+		"Level":    system.child("Level").text(),
+		"UserID":   system.child("Security").attr("UserID"),
+	}
+	for k, v := range systemFields {
+		if v != "" {
+			entry.Fields[k] = v
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, system.child("TimeCreated").attr("SystemTime")); err == nil {
+		entry.Timestamp = t
+	}
+
+	var data []string
+	addData := func(name, value string) {
+		entry.Fields[name] = value
+		data = append(data, name+"="+value)
+	}
+	if eventData := rec.Event.child("EventData"); eventData != nil {
+		unnamed := 0
+		for _, c := range eventData.Children {
+			name := c.attr("Name")
+			if name == "" {
+				unnamed++
+				name = c.Name + strconv.Itoa(unnamed)
+			}
+			addData(name, c.text())
+		}
+	} else if userData := rec.Event.child("UserData"); userData != nil {
+		var walk func(e *xmlElement)
+		walk = func(e *xmlElement) {
+			for _, c := range e.Children {
+				if len(c.Children) == 0 {
+					addData(c.Name, c.text())
+				} else {
+					walk(c)
+				}
+			}
+		}
+		walk(userData)
+	}
+
+	entry.Message = rec.Event.child("RenderingInfo").child("Message").text()
+	if entry.Message == "" {
+		entry.Message = strings.TrimSpace(provider + " " + eventID)
+		if len(data) > 0 {
+			entry.Message += ": " + strings.Join(data, " ")
+		}
+	}
+
+	return entry
+}
+
+// readEVTXFile calls fn for each record of an EVTX file, until fn returns false.
+func readEVTXFile(ctx context.Context, path string, fn func(evtxRecord) bool) error {
+	f, err := OpenLogFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return readEVTX(ctx, f, fn)
+}
+
+// readEVTXEntries calls fn with the events of an EVTX file, until fn returns false.
+func readEVTXEntries(ctx context.Context, path string, fn func(source.Entry) bool) error {
+	return readEVTXFile(ctx, path, func(rec evtxRecord) bool {
+		return fn(evtxEntry(rec, path))
+	})
+}
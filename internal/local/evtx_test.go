@@ -0,0 +1,399 @@
+package local
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// testEvent is an event written by evtxBuilder.
+type testEvent struct {
+	RecordID uint64
+	Time     time.Time
+	EventID  uint16
+	Provider string
+	Channel  string
+	Computer string
+	UserSID  []byte // optional
+	Data     [][2]string
+}
+
+// evtxBuilder encodes a chunk of event records the way Windows does: the
+// first record defines the event template inline and later records refer
+// to it, and names are written inline once and referred to afterwards.
+type evtxBuilder struct {
+	buf            []byte
+	names          map[string]int
+	templateOffset int
+}
+
+func newEVTXBuilder() *evtxBuilder {
+	return &evtxBuilder{buf: make([]byte, evtxChunkHeaderSize), names: make(map[string]int)}
+}
+
+func (b *evtxBuilder) u8(v byte)    { b.buf = append(b.buf, v) }
+func (b *evtxBuilder) u16(v uint16) { b.buf = binary.LittleEndian.AppendUint16(b.buf, v) }
+func (b *evtxBuilder) u32(v uint32) { b.buf = binary.LittleEndian.AppendUint32(b.buf, v) }
+func (b *evtxBuilder) u64(v uint64) { b.buf = binary.LittleEndian.AppendUint64(b.buf, v) }
+
+func (b *evtxBuilder) utf16(s string) {
+	for _, c := range utf16.Encode([]rune(s)) {
+		b.u16(c)
+	}
+}
+
+// name writes a name reference. A name used for the first time follows
+// inline, after extra bytes of the enclosing token.
+func (b *evtxBuilder) name(name string, extra []byte) {
+	offset, ok := b.names[name]
+	if !ok {
+		offset = len(b.buf) + 4 + len(extra)
+		b.names[name] = offset
+	}
+	b.u32(uint32(offset))
+	b.buf = append(b.buf, extra...)
+	if ok {
+		return
+	}
+	b.u32(0) // next name
+	b.u16(0) // hash
+	b.u16(uint16(len([]rune(name))))
+	b.utf16(name)
+	b.u16(0)
+}
+
+func (b *evtxBuilder) open(name string, hasAttrs bool) {
+	token := byte(0x01)
+	var attrListSize []byte
+	if hasAttrs {
+		token |= 0x40
+		attrListSize = make([]byte, 4)
+	}
+	b.u8(token)
+	b.u16(0xffff) // dependency identifier
+	b.u32(0)      // data size, unused by the decoder
+	b.name(name, attrListSize)
+}
+
+func (b *evtxBuilder) attr(name string, more bool) {
+	token := byte(0x06)
+	if more {
+		token |= 0x40
+	}
+	b.u8(token)
+	b.name(name, nil)
+}
+
+func (b *evtxBuilder) value(s string) {
+	b.u8(0x05)
+	b.u8(evtxString)
+	b.u16(uint16(len(utf16.Encode([]rune(s)))))
+	b.utf16(s)
+}
+
+func (b *evtxBuilder) sub(id uint16, typ byte, optional bool) {
+	token := byte(0x0d)
+	if optional {
+		token = 0x0e
+	}
+	b.u8(token)
+	b.u16(id)
+	b.u8(typ)
+}
+
+// template writes the body of the event template.
+func (b *evtxBuilder) template() {
+	b.open("Event", true)
+	b.attr("xmlns", false)
+	b.value("http://schemas.microsoft.com/win/2004/08/events/event")
+	b.u8(0x02)
+
+	b.open("System", false)
+	b.u8(0x02)
+	b.open("Provider", true)
+	b.attr("Name", false)
+	b.sub(0, evtxString, false)
+	b.u8(0x03)
+	b.open("EventID", false)
+	b.u8(0x02)
+	b.sub(1, evtxUint16, false)
+	b.u8(0x04)
+	b.open("TimeCreated", true)
+	b.attr("SystemTime", false)
+	b.sub(2, evtxFileTime, false)
+	b.u8(0x03)
+	b.open("EventRecordID", false)
+	b.u8(0x02)
+	b.sub(3, evtxUint64, false)
+	b.u8(0x04)
+	b.open("Channel", false)
+	b.u8(0x02)
+	b.sub(4, evtxString, false)
+	b.u8(0x04)
+	b.open("Computer", false)
+	b.u8(0x02)
+	b.sub(5, evtxString, false)
+	b.u8(0x04)
+	b.open("Security", true)
+	b.attr("UserID", false)
+	b.sub(6, evtxSID, true)
+	b.u8(0x03)
+	b.u8(0x04) // System
+
+	b.sub(7, evtxBinXML, true)
+	b.u8(0x04) // Event
+	b.u8(0x00)
+}
+
+// eventData writes an EventData fragment.
+func (b *evtxBuilder) eventData(data [][2]string) {
+	b.buf = append(b.buf, 0x0f, 0x01, 0x01, 0x00)
+	b.open("EventData", false)
+	b.u8(0x02)
+	for _, kv := range data {
+		b.open("Data", true)
+		b.attr("Name", false)
+		b.value(kv[0])
+		b.u8(0x02)
+		b.value(kv[1])
+		b.u8(0x04)
+	}
+	b.u8(0x04)
+	b.u8(0x00)
+}
+
+func (b *evtxBuilder) record(ev testEvent) {
+	start := len(b.buf)
+	b.buf = append(b.buf, evtxRecordMagic...)
+	b.u32(0) // size, set below
+	b.u64(ev.RecordID)
+	b.u64(toFileTime(ev.Time))
+
+	b.buf = append(b.buf, 0x0f, 0x01, 0x01, 0x00)
+	b.u8(0x0c)
+	b.u8(0x01)
+	b.u32(1) // template identifier
+	if b.templateOffset == 0 {
+		b.templateOffset = len(b.buf) + 4
+		b.u32(uint32(b.templateOffset))
+		b.u32(0)                                   // next template
+		b.buf = append(b.buf, make([]byte, 16)...) // GUID
+		b.u32(0)                                   // data size, set below
+		bodyStart := len(b.buf)
+		b.template()
+		binary.LittleEndian.PutUint32(b.buf[b.templateOffset+20:], uint32(len(b.buf)-bodyStart))
+	} else {
+		b.u32(uint32(b.templateOffset))
+	}
+
+	provider := utf16.Encode([]rune(ev.Provider))
+	channel := utf16.Encode([]rune(ev.Channel))
+	computer := utf16.Encode([]rune(ev.Computer))
+
+	// Substitution descriptors, with the EventData size patched once written
+	b.u32(8)
+	descriptors := len(b.buf)
+	for _, d := range []struct {
+		size int
+		typ  byte
+	}{
+		{2 * len(provider), evtxString},
+		{2, evtxUint16},
+		{8, evtxFileTime},
+		{8, evtxUint64},
+		{2 * len(channel), evtxString},
+		{2 * len(computer), evtxString},
+		{len(ev.UserSID), evtxSID},
+		{0, evtxBinXML},
+	} {
+		typ := d.typ
+		if d.size == 0 {
+			typ = evtxNull
+		}
+		b.u16(uint16(d.size))
+		b.u8(typ)
+		b.u8(0)
+	}
+
+	b.utf16(ev.Provider)
+	b.u16(ev.EventID)
+	b.u64(toFileTime(ev.Time))
+	b.u64(ev.RecordID)
+	b.utf16(ev.Channel)
+	b.utf16(ev.Computer)
+	b.buf = append(b.buf, ev.UserSID...)
+	dataStart := len(b.buf)
+	b.eventData(ev.Data)
+	binary.LittleEndian.PutUint16(b.buf[descriptors+28:], uint16(len(b.buf)-dataStart))
+	b.buf[descriptors+30] = evtxBinXML
+
+	b.u8(0x00)
+	size := len(b.buf) - start + 4
+	b.u32(uint32(size))
+	binary.LittleEndian.PutUint32(b.buf[start+4:], uint32(size))
+}
+
+// chunk returns the chunk with the records written.
+func (b *evtxBuilder) chunk() []byte {
+	chunk := make([]byte, evtxChunkSize)
+	copy(chunk, b.buf)
+	copy(chunk, evtxChunkMagic)
+	binary.LittleEndian.PutUint32(chunk[0x30:], uint32(len(b.buf)))
+	return chunk
+}
+
+func toFileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100 + 116444736000000000)
+}
+
+// writeEVTX writes an EVTX file of events, followed by an unused chunk.
+func writeEVTX(t *testing.T, path string, events []testEvent) {
+	t.Helper()
+	b := newEVTXBuilder()
+	for _, ev := range events {
+		b.record(ev)
+	}
+
+	header := make([]byte, evtxFileHeaderSize)
+	copy(header, evtxFileMagic)
+	data := append(header, b.chunk()...)
+	data = append(data, make([]byte, evtxChunkSize)...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEVTXEvents() []testEvent {
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	return []testEvent{
+		{
+			RecordID: 101, Time: base, EventID: 4624,
+			Provider: "Microsoft-Windows-Security-Auditing", Channel: "Security", Computer: "DC01.corp.local",
+			UserSID: []byte{1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0},
+			Data:    [][2]string{{"TargetUserName", "alice"}, {"LogonType", "2"}},
+		},
+		{
+			RecordID: 102, Time: base.Add(time.Minute), EventID: 4625,
+			Provider: "Microsoft-Windows-Security-Auditing", Channel: "Security", Computer: "DC01.corp.local",
+			Data: [][2]string{{"TargetUserName", "bob"}, {"IpAddress", "10.0.0.9"}},
+		},
+		{
+			RecordID: 103, Time: base.Add(2 * time.Minute), EventID: 7045,
+			Provider: "Service Control Manager", Channel: "System", Computer: "DC01.corp.local",
+			Data: [][2]string{{"ServiceName", "evil"}},
+		},
+	}
+}
+
+func TestSource_EVTX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Security.evtx")
+	writeEVTX(t, path, testEVTXEvents())
+	ctx := context.Background()
+
+	if got := DetectFormat(path); got != FormatEVTX {
+		t.Fatalf("DetectFormat = %v, want evtx", got)
+	}
+
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	results, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 events, got %+v", results)
+	}
+
+	logon := results[2]
+	wantFields := map[string]string{
+		"EventID":        "4624",
+		"Provider":       "Microsoft-Windows-Security-Auditing",
+		"Channel":        "Security",
+		"Computer":       "DC01.corp.local",
+		"RecordID":       "101",
+		"UserID":         "S-1-5-18",
+		"TargetUserName": "alice",
+		"LogonType":      "2",
+	}
+	for k, v := range wantFields {
+		if got := logon.Fields[k]; got != v {
+			t.Errorf("field %s: expected %q, got %q", k, v, got)
+		}
+	}
+	if want := "Microsoft-Windows-Security-Auditing 4624: TargetUserName=alice LogonType=2"; logon.Message != want {
+		t.Errorf("expected message %q, got %q", want, logon.Message)
+	}
+	if !logon.Timestamp.Equal(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp %v", logon.Timestamp)
+	}
+	if logon.Stream != "Security.evtx" || logon.Ptr != source.MakeLocalPtr(path, 101) {
+		t.Errorf("unexpected stream %q or pointer %q", logon.Stream, logon.Ptr)
+	}
+
+	// The second record reuses the template and names of the first
+	if _, ok := results[1].Fields["UserID"]; ok || results[1].Fields["IpAddress"] != "10.0.0.9" {
+		t.Errorf("unexpected fields %v", results[1].Fields)
+	}
+
+	filtered, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("bob"), Context: 1})
+	if err != nil || len(filtered) != 1 {
+		t.Fatalf("filtered Query: got %+v, %v", filtered, err)
+	}
+	ctxEvents := filtered[0].Context
+	if len(ctxEvents.Before) != 1 || ctxEvents.Before[0].Message != logon.Message ||
+		len(ctxEvents.After) != 1 || ctxEvents.After[0].Message != "Service Control Manager 7045: ServiceName=evil" {
+		t.Errorf("unexpected context %+v", ctxEvents)
+	}
+
+	entry, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 103))
+	if err != nil || entry.Fields["ServiceName"] != "evil" {
+		t.Errorf("GetRecord: got %+v, %v", entry, err)
+	}
+	if _, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 5)); err == nil {
+		t.Error("expected an error for a missing record")
+	}
+
+	if _, err := src.Tail(ctx, source.TailParams{}); err == nil {
+		t.Error("expected Tail to fail for an EVTX file")
+	}
+}
+
+func TestReadEVTX_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrupt.evtx")
+	writeEVTX(t, path, testEVTXEvents())
+
+	// Break the template reference of the second record
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newEVTXBuilder()
+	b.record(testEVTXEvents()[0])
+	second := evtxFileHeaderSize + len(b.buf)
+	binary.LittleEndian.PutUint32(data[second+evtxRecordHeaderSize+10:], 0xfff0)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint64
+	err = readEVTXFile(context.Background(), path, func(rec evtxRecord) bool {
+		ids = append(ids, rec.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("readEVTXFile failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 101 || ids[1] != 103 {
+		t.Errorf("expected records 101 and 103, got %v", ids)
+	}
+}
@@ -0,0 +1,228 @@
+package local
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// grokPrefix marks a format hint that is a grok expression, such as
+// "grok:%{COMBINEDAPACHELOG}".
+const grokPrefix = "grok:"
+
+// References in grok expressions: %{SYNTAX}, %{SYNTAX:semantic} or
+// %{SYNTAX:semantic:type}
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+// Lines of grok pattern files: NAME pattern
+var grokPatternLine = regexp.MustCompile(`^(\w+)\s+(.+)$`)
+
+var (
+	grokMu       sync.RWMutex
+	grokPatterns = mustParseGrokPatterns(grokBasePatterns)
+)
+
+// grokExpr is a grok expression compiled to a regular expression.
+type grokExpr struct {
+	pattern *regexp.Regexp
+	fields  []string // field of each group, "" for unnamed groups
+	types   []string // type hint of each group: "", "int" or "float"
+}
+
+// compileGrok compiles a grok expression, expanding pattern references
+// recursively. %{SYNTAX:semantic} captures into the semantic field, and a
+// :int or :float hint normalises the value. Named groups of the regular
+// expression itself, such as (?<queue_id>[0-9A-F]+), are fields too.
+func compileGrok(expr string) (*grokExpr, error) {
+	grokMu.RLock()
+	defer grokMu.RUnlock()
+
+	var captures []grokCapture
+	expanded, err := expandGrok(expr, nil, &captures)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	names := pattern.SubexpNames()
+	g := &grokExpr{
+		pattern: pattern,
+		fields:  make([]string, len(names)),
+		types:   make([]string, len(names)),
+	}
+	for i, name := range names {
+		g.fields[i] = name
+		if n, ok := strings.CutPrefix(name, "grok"); ok {
+			if j, err := strconv.Atoi(n); err == nil && j < len(captures) {
+				g.fields[i] = captures[j].field
+				g.types[i] = captures[j].typ
+			}
+		}
+	}
+	return g, nil
+}
+
+// grokCapture is the field and type hint of a %{SYNTAX:semantic} reference.
+type grokCapture struct {
+	field string
+	typ   string
+}
+
+// expandGrok replaces the pattern references of expr with their regular
+// expressions. Captures are added to captures and named grok<index>.
+// seen holds the patterns being expanded, to detect recursion.
+func expandGrok(expr string, seen []string, captures *[]grokCapture) (string, error) {
+	var b strings.Builder
+	prev := 0
+	for _, loc := range grokReference.FindAllStringSubmatchIndex(expr, -1) {
+		b.WriteString(expr[prev:loc[0]])
+		prev = loc[1]
+
+		name := expr[loc[2]:loc[3]]
+		def, ok := grokPatterns[name]
+		if !ok {
+			return "", fmt.Errorf("unknown grok pattern %q", name)
+		}
+		for _, s := range seen {
+			if s == name {
+				return "", fmt.Errorf("grok pattern %q refers to itself", name)
+			}
+		}
+		inner, err := expandGrok(def, append(seen, name), captures)
+		if err != nil {
+			return "", err
+		}
+
+		if loc[4] < 0 {
+			b.WriteString("(?:" + inner + ")")
+			continue
+		}
+		capture := grokCapture{field: grokFieldName(expr[loc[4]:loc[5]])}
+		if loc[6] >= 0 {
+			capture.typ = expr[loc[6]:loc[7]]
+			if capture.typ != "int" && capture.typ != "float" {
+				return "", fmt.Errorf("unknown type %q in %s", capture.typ, expr[loc[0]:loc[1]])
+			}
+		}
+		fmt.Fprintf(&b, "(?P<grok%d>%s)", len(*captures), inner)
+		*captures = append(*captures, capture)
+	}
+	b.WriteString(expr[prev:])
+	return b.String(), nil
+}
+
+// grokFieldName converts a semantic in Logstash field reference syntax, such
+// as [http][request][method], into a dotted field name.
+func grokFieldName(semantic string) string {
+	if !strings.HasPrefix(semantic, "[") {
+		return semantic
+	}
+	return strings.ReplaceAll(strings.Trim(semantic, "[]"), "][", ".")
+}
+
+// convertGrokValue normalises a captured value with a type hint, so that
+// "007" with :int is "7". Values that are not numbers are kept as they are.
+func convertGrokValue(value, typ string) string {
+	switch typ {
+	case "int":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatInt(int64(f), 10)
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return value
+}
+
+// parseGrokPatterns reads patterns in the format of Logstash pattern files:
+// one "NAME pattern" per line, with blank lines and # comments ignored.
+func parseGrokPatterns(r io.Reader) (map[string]string, error) {
+	patterns := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := grokPatternLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected NAME PATTERN", lineNum)
+		}
+		patterns[m[1]] = m[2]
+	}
+	return patterns, scanner.Err()
+}
+
+// mustParseGrokPatterns parses the built-in pattern library.
+func mustParseGrokPatterns(s string) map[string]string {
+	patterns, err := parseGrokPatterns(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return patterns
+}
+
+// LoadGrokPatterns adds the patterns of a Logstash pattern file, or of every
+// file in a directory, to those grok expressions can refer to. Patterns with
+// the name of a built-in pattern replace it. ~ and $VARS in path are expanded.
+func LoadGrokPatterns(path string) error {
+	path = os.ExpandEnv(path)
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return err
+	} else if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	loaded := make(map[string]string)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		patterns, err := parseGrokPatterns(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for name, pattern := range patterns {
+			loaded[name] = pattern
+		}
+	}
+
+	grokMu.Lock()
+	defer grokMu.Unlock()
+	for name, pattern := range loaded {
+		grokPatterns[name] = pattern
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+func TestCompileGrok(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		line       string
+		wantFields map[string]string
+	}{
+		{
+			name: "combined apache log",
+			expr: "%{COMBINEDAPACHELOG}",
+			line: combinedLine,
+			wantFields: map[string]string{
+				"clientip":    "203.0.113.9",
+				"auth":        "frank",
+				"timestamp":   "10/Oct/2025:13:55:36 -0700",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif?x=1",
+				"httpversion": "1.0",
+				"response":    "200",
+				"bytes":       "2326",
+				"referrer":    `"http://www.example.com/start.html"`,
+			},
+		},
+		{
+			name: "syslog base",
+			expr: "%{SYSLOGBASE} %{GREEDYDATA:message}",
+			line: "Mar  1 10:00:00 web-1 sshd[4211]: Failed password for root",
+			wantFields: map[string]string{
+				"timestamp": "Mar  1 10:00:00",
+				"logsource": "web-1",
+				"program":   "sshd",
+				"pid":       "4211",
+				"message":   "Failed password for root",
+			},
+		},
+		{
+			name: "type hints and regex groups",
+			expr: `%{IP:client} %{NUMBER:bytes:int} %{NUMBER:duration:float} (?<queue_id>[0-9A-F]+)`,
+			line: "2001:db8::1 0042 1.500 3FA2B",
+			wantFields: map[string]string{
+				"client":   "2001:db8::1",
+				"bytes":    "42",
+				"duration": "1.5",
+				"queue_id": "3FA2B",
+			},
+		},
+		{
+			name: "java stack frame",
+			expr: "%{JAVASTACKTRACEPART}",
+			line: "\tat com.example.billing.Charge.run(Charge.java:42)",
+			wantFields: map[string]string{
+				"class":  "com.example.billing.Charge",
+				"method": "run",
+				"file":   "Charge.java",
+				"line":   "42",
+			},
+		},
+		{
+			name:       "field references",
+			expr:       `%{WORD:[http][request][method]} %{URIPATHPARAM:[url][original]}`,
+			line:       "POST /login?next=/",
+			wantFields: map[string]string{"http.request.method": "POST", "url.original": "/login?next=/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := compileGrok(tt.expr)
+			if err != nil {
+				t.Fatalf("compileGrok failed: %v", err)
+			}
+			matches := g.pattern.FindStringSubmatch(tt.line)
+			if matches == nil {
+				t.Fatalf("%s does not match %q", tt.expr, tt.line)
+			}
+			got := make(map[string]string)
+			for i, field := range g.fields {
+				if field != "" && matches[i] != "" {
+					if _, ok := got[field]; !ok {
+						got[field] = convertGrokValue(matches[i], g.types[i])
+					}
+				}
+			}
+			for k, v := range tt.wantFields {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileGrok_Errors(t *testing.T) {
+	grokMu.Lock()
+	grokPatterns["TESTLOOP"] = "a%{TESTLOOP}"
+	grokMu.Unlock()
+	t.Cleanup(func() {
+		grokMu.Lock()
+		delete(grokPatterns, "TESTLOOP")
+		grokMu.Unlock()
+	})
+
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"%{NOSUCHPATTERN:x}", `unknown grok pattern "NOSUCHPATTERN"`},
+		{"%{NUMBER:x:bool}", `unknown type "bool" in %{NUMBER:x:bool}`},
+		{"%{TESTLOOP}", `grok pattern "TESTLOOP" refers to itself`},
+		{"%{WORD:x} (", "missing closing )"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compileGrok(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadGrokPatterns(t *testing.T) {
+	dir := t.TempDir()
+	createTempFile(t, dir, "billing", "# Billing service\nBILLINGID INV-[0-9]{6}\n\nBILLINGLINE %{BILLINGID:invoice} %{NUMBER:amount:float}\n")
+	if err := LoadGrokPatterns(dir); err != nil {
+		t.Fatalf("LoadGrokPatterns failed: %v", err)
+	}
+
+	p, err := newRegexParser(source.ParserConfig{Pattern: "^%{TIMESTAMP_ISO8601:timestamp} %{BILLINGLINE}$"})
+	if err != nil {
+		t.Fatalf("newRegexParser failed: %v", err)
+	}
+	entry := p.ParseLine("2024-03-01T10:00:00Z INV-000042 19.90", 1, "/logs/billing.log")
+	if entry.Fields["invoice"] != "INV-000042" || entry.Fields["amount"] != "19.9" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+
+	bad := createTempFile(t, t.TempDir(), "bad", "BILLINGID\n")
+	if err := LoadGrokPatterns(bad); err == nil || !strings.Contains(err.Error(), "line 1: expected NAME PATTERN") {
+		t.Errorf("error = %v", err)
+	}
+	if err := LoadGrokPatterns(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("error = %v, want not exist", err)
+	}
+}
+
+func TestParseFormat_Grok(t *testing.T) {
+	hint := "grok:%{COMBINEDAPACHELOG}"
+	f := ParseFormat(hint)
+	if f == FormatAuto || f.String() != hint {
+		t.Fatalf("ParseFormat(%q) = %v", hint, f)
+	}
+	if ParseFormat(hint) != f {
+		t.Error("grok formats should be compiled once")
+	}
+
+	entry := NewParser(f).ParseLine(combinedLine, 3, "/logs/access.log")
+	if !entry.Timestamp.Equal(time.Date(2025, 10, 10, 20, 55, 36, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != combinedLine || entry.Fields["response"] != "200" || entry.Fields["clientip"] != "203.0.113.9" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	// Pattern names are case-sensitive
+	if err := CheckFormat("grok:%{combinedapachelog}"); err == nil || !strings.Contains(err.Error(), `unknown grok pattern "combinedapachelog"`) {
+		t.Errorf("CheckFormat = %v", err)
+	}
+	if err := CheckFormat(hint); err != nil {
+		t.Errorf("CheckFormat(%q) = %v", hint, err)
+	}
+	if err := RegisterParser("grok:x", source.ParserConfig{Pattern: ".*"}); err == nil {
+		t.Error("parser names should not start with grok:")
+	}
+}
@@ -0,0 +1,171 @@
+package local
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Field names of Application Load Balancer access log entries, in order.
+// "client" and "target" hold ip:port and are split into *_ip and *_port
+// fields. Fields AWS adds to the end in future are ignored.
+var albFields = []string{
+	"type", "time", "elb", "client", "target",
+	"request_processing_time", "target_processing_time", "response_processing_time",
+	"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol", "target_group_arn",
+	"trace_id", "domain_name", "chosen_cert_arn", "matched_rule_priority",
+	"request_creation_time", "actions_executed", "redirect_url", "error_reason",
+	"target_port_list", "target_status_code_list", "classification",
+	"classification_reason", "conn_trace_id",
+}
+
+// Field names of Classic Load Balancer access log entries, in order.
+var elbFields = []string{
+	"timestamp", "elb", "client", "backend",
+	"request_processing_time", "backend_processing_time", "response_processing_time",
+	"elb_status_code", "backend_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol",
+}
+
+// ALB entries start with the request type and time: "http 2024-03-01T10:00:00.123456Z app/..."
+var albLinePattern = regexp.MustCompile(`^(?:https?|h2|grpcs|wss?) \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d+Z \S+ \S+:\d+ `)
+
+// Classic ELB entries start with the time: "2024-03-01T10:00:00.123456Z my-elb 10.0.0.1:4000 10.0.1.5:80 0.000073"
+var elbLinePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d+Z \S+ \S+:\d+ (?:\S+:\d+|-) -?\d+\.\d+ `)
+
+// ALBParser handles Application Load Balancer access logs.
+type ALBParser struct{}
+
+func (p *ALBParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	return parseLoadBalancerLine(line, lineNum, filePath, albFields, "time")
+}
+
+func (p *ALBParser) IsMultiline() bool        { return false }
+func (p *ALBParser) ShouldJoin(string) bool { return false }
+
+// ELBParser handles Classic Load Balancer access logs.
+type ELBParser struct{}
+
+func (p *ELBParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	return parseLoadBalancerLine(line, lineNum, filePath, elbFields, "timestamp")
+}
+
+func (p *ELBParser) IsMultiline() bool        { return false }
+func (p *ELBParser) ShouldJoin(string) bool { return false }
+
+// parseLoadBalancerLine parses a space-delimited load balancer log entry into
+// named fields. The message is the request line and the status code the load
+// balancer returned, e.g. "GET https://example.com:443/api HTTP/1.1 502".
+func parseLoadBalancerLine(line string, lineNum int, filePath string, names []string, timeField string) *source.Entry {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+		Fields:  make(map[string]string),
+	}
+
+	values := splitQuoted(line)
+	if len(values) < len(names)/2 {
+		// Not an access log entry, keep the line as it is
+		return entry
+	}
+
+	for i, value := range values {
+		if i >= len(names) {
+			break
+		}
+		name := names[i]
+		if name == timeField {
+			if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				entry.Timestamp = ts
+				continue
+			}
+		}
+		// AWS writes "-" for values that do not apply
+		if value == "-" || value == "" {
+			continue
+		}
+
+		switch name {
+		case "client", "target", "backend":
+			if host, port, ok := cutPort(value); ok {
+				entry.Fields[name+"_ip"] = host
+				entry.Fields[name+"_port"] = port
+				continue
+			}
+		case "request":
+			// TCP and TLS listeners log "- - - " in place of a request
+			if strings.TrimSpace(value) == "- - -" {
+				continue
+			}
+			// "GET https://example.com:443/path HTTP/1.1"
+			if parts := strings.SplitN(value, " ", 3); len(parts) == 3 {
+				entry.Fields["request_method"] = parts[0]
+				entry.Fields["request_url"] = parts[1]
+				entry.Fields["request_protocol"] = parts[2]
+			}
+		}
+		entry.Fields[name] = value
+	}
+
+	if request := entry.Fields["request"]; request != "" {
+		entry.Message = request
+		if status := entry.Fields["elb_status_code"]; status != "" {
+			entry.Message += " " + status
+		}
+	}
+
+	return entry
+}
+
+// cutPort splits "ip:port" at the last colon, for IPv6 addresses too.
+func cutPort(s string) (host, port string, ok bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 || i == len(s)-1 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+// splitQuoted splits a line at spaces outside double quotes, removing the
+// quotes. Backslash escapes inside quotes are decoded.
+func splitQuoted(line string) []string {
+	var values []string
+	var current strings.Builder
+	inQuotes, inValue := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inValue = true
+		case c == ' ' && !inQuotes:
+			if inValue {
+				values = append(values, current.String())
+				current.Reset()
+				inValue = false
+			}
+		default:
+			current.WriteByte(c)
+			inValue = true
+		}
+	}
+	if inValue {
+		values = append(values, current.String())
+	}
+	return values
+}
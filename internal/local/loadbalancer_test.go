@@ -0,0 +1,148 @@
+package local
+
+import (
+	"testing"
+	"time"
+)
+
+const albLine = `https 2024-03-01T10:00:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 502 - 34 366 ` +
+	`"GET https://www.example.com:443/api/orders?id=7 HTTP/1.1" "Mozilla/5.0 (X11; \"quoted\")" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 ` +
+	`arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" ` +
+	`"www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2024-03-01T09:59:59.364000Z ` +
+	`"forward" "-" "-" "10.0.0.1:80" "-" "-" "-" TID_1234`
+
+const elbLine = `2024-03-01T10:00:00.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 ` +
+	`"GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+
+func TestALBParser_ParseLine(t *testing.T) {
+	p := &ALBParser{}
+	entry := p.ParseLine(albLine, 3, "/logs/alb.log")
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+
+	want := time.Date(2024, 3, 1, 10, 0, 0, 186641000, time.UTC)
+	if !entry.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entry.Timestamp, want)
+	}
+	if entry.Message != "GET https://www.example.com:443/api/orders?id=7 HTTP/1.1 502" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	if entry.Ptr != "file:///logs/alb.log#3" {
+		t.Errorf("Ptr = %q", entry.Ptr)
+	}
+
+	wantFields := map[string]string{
+		"type":                   "https",
+		"elb":                    "app/my-lb/50dc6c495c0c9188",
+		"client_ip":              "192.168.131.39",
+		"client_port":            "2817",
+		"target_ip":              "10.0.0.1",
+		"target_port":            "80",
+		"target_processing_time": "0.001",
+		"elb_status_code":        "502",
+		"request_method":         "GET",
+		"request_url":            "https://www.example.com:443/api/orders?id=7",
+		"user_agent":             `Mozilla/5.0 (X11; "quoted")`,
+		"trace_id":               "Root=1-58337262-36d228ad5d99923122bbe354",
+		"domain_name":            "www.example.com",
+		"actions_executed":       "forward",
+		"target_port_list":       "10.0.0.1:80",
+		"conn_trace_id":          "TID_1234",
+	}
+	for k, v := range wantFields {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, entry.Fields[k], v)
+		}
+	}
+	for _, k := range []string{"time", "target_status_code", "redirect_url", "client"} {
+		if v, ok := entry.Fields[k]; ok {
+			t.Errorf("Fields[%q] = %q, want it omitted", k, v)
+		}
+	}
+}
+
+func TestELBParser_ParseLine(t *testing.T) {
+	p := &ELBParser{}
+	entry := p.ParseLine(elbLine, 1, "/logs/elb.log")
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+
+	want := time.Date(2024, 3, 1, 10, 0, 0, 945958000, time.UTC)
+	if !entry.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entry.Timestamp, want)
+	}
+	if entry.Message != "GET http://www.example.com:80/ HTTP/1.1 200" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	wantFields := map[string]string{
+		"elb":                     "my-loadbalancer",
+		"backend_ip":              "10.0.0.1",
+		"backend_processing_time": "0.001048",
+		"backend_status_code":     "200",
+		"sent_bytes":              "29",
+		"user_agent":              "curl/7.38.0",
+	}
+	for k, v := range wantFields {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, entry.Fields[k], v)
+		}
+	}
+	if _, ok := entry.Fields["ssl_cipher"]; ok {
+		t.Error("ssl_cipher should be omitted for \"-\"")
+	}
+}
+
+func TestELBParser_TCPListener(t *testing.T) {
+	line := `2024-03-01T10:00:00.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.001069 0.000028 0.000041 - - 82 305 "- - - " "-" - -`
+	entry := (&ELBParser{}).ParseLine(line, 1, "/logs/elb.log")
+
+	if entry.Message != line {
+		t.Errorf("Message = %q, want the line", entry.Message)
+	}
+	if _, ok := entry.Fields["request_method"]; ok {
+		t.Error("request_method should be omitted for TCP listeners")
+	}
+	if entry.Fields["received_bytes"] != "82" {
+		t.Errorf("received_bytes = %q", entry.Fields["received_bytes"])
+	}
+}
+
+func TestLoadBalancerParser_Fallback(t *testing.T) {
+	for _, p := range []Parser{&ALBParser{}, &ELBParser{}} {
+		if entry := p.ParseLine("", 1, "/logs/lb.log"); entry != nil {
+			t.Errorf("%T: empty line should be skipped", p)
+		}
+		entry := p.ParseLine("not an access log", 1, "/logs/lb.log")
+		if entry == nil || entry.Message != "not an access log" || !entry.Timestamp.IsZero() {
+			t.Errorf("%T: fallback entry = %+v", p, entry)
+		}
+	}
+}
+
+func TestSplitQuoted(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`a b  c`, []string{"a", "b", "c"}},
+		{`a "b c" d`, []string{"a", "b c", "d"}},
+		{`"" x`, []string{"", "x"}},
+		{`"say \"hi\"" \z`, []string{`say "hi"`, `\z`}},
+	}
+
+	for _, tt := range tests {
+		got := splitQuoted(tt.line)
+		if len(got) != len(tt.want) {
+			t.Errorf("splitQuoted(%q) = %q, want %q", tt.line, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("splitQuoted(%q) = %q, want %q", tt.line, got, tt.want)
+				break
+			}
+		}
+	}
+}
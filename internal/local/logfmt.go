@@ -0,0 +1,183 @@
+package local
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// LogfmtParser handles logfmt lines of key=value pairs, such as
+// `ts=2024-03-01T10:00:00Z level=error msg="payment failed" user=42`.
+// The timestamp and message keys are taken out; every other pair is a field.
+type LogfmtParser struct{}
+
+// Timestamp keys in logfmt logs, in order of preference
+var logfmtTimestampKeys = []string{"ts", "time", "timestamp"}
+
+// Message keys in logfmt logs, in order of preference
+var logfmtMessageKeys = []string{"msg", "message"}
+
+// logfmtPair is a key and value of a logfmt line.
+type logfmtPair struct {
+	key   string
+	value string
+	flag  bool // key without a value
+}
+
+// parseLogfmt splits a logfmt line into its pairs, unquoting quoted values.
+// A key without a value, such as "debug" in "msg=x debug", is a flag and
+// has the value "true". ok is false if the line is not logfmt.
+func parseLogfmt(line string) (pairs []logfmtPair, ok bool) {
+	i := 0
+	for {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i == len(line) {
+			return pairs, len(pairs) > 0
+		}
+
+		start := i
+		for i < len(line) && line[i] != ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" || (i < len(line) && line[i] == '"') {
+			return nil, false
+		}
+		if i == len(line) || line[i] == ' ' {
+			pairs = append(pairs, logfmtPair{key: key, value: "true", flag: true})
+			continue
+		}
+
+		// Skip '='
+		i++
+		if i < len(line) && line[i] == '"' {
+			end := closingQuote(line, i)
+			if end < 0 || (end+1 < len(line) && line[end+1] != ' ') {
+				return nil, false
+			}
+			pairs = append(pairs, logfmtPair{key: key, value: unescapeLogfmt(line[i+1 : end])})
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		pairs = append(pairs, logfmtPair{key: key, value: line[start:i]})
+	}
+}
+
+// closingQuote returns the index of the quote ending the quoted value that
+// starts at open, or -1 if it is not closed.
+func closingQuote(line string, open int) int {
+	for i := open + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeLogfmt replaces the escape sequences of a quoted value, such as \"
+// and \n. Invalid sequences are kept as they are.
+func unescapeLogfmt(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		r, _, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			b.WriteByte(s[0])
+			s = s[1:]
+			continue
+		}
+		b.WriteRune(r)
+		s = tail
+	}
+	return b.String()
+}
+
+// isLogfmtLine reports whether a line is made of logfmt pairs, starting with
+// a key=value pair, with at least two values and a timestamp, level or
+// message key.
+func isLogfmtLine(line string) bool {
+	pairs, ok := parseLogfmt(line)
+	if !ok || pairs[0].flag {
+		return false
+	}
+	values, known := 0, false
+	for _, p := range pairs {
+		if !p.flag {
+			values++
+		}
+		switch p.key {
+		case "ts", "time", "timestamp", "level", "lvl", "msg", "message":
+			known = true
+		}
+	}
+	return values >= 2 && known
+}
+
+func (p *LogfmtParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+	}
+
+	pairs, ok := parseLogfmt(line)
+	if !ok {
+		// Not logfmt, treat as plain text
+		return entry
+	}
+
+	fields := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		fields[pair.key] = pair.value
+	}
+
+	for _, key := range logfmtTimestampKeys {
+		if ts := parseTimestampString(fields[key]); !ts.IsZero() {
+			entry.Timestamp = ts
+			delete(fields, key)
+			break
+		}
+	}
+
+	for _, key := range logfmtMessageKeys {
+		if msg, ok := fields[key]; ok {
+			entry.Message = msg
+			delete(fields, key)
+			break
+		}
+	}
+
+	// log15 and its descendants write the level as lvl
+	if lvl, ok := fields["lvl"]; ok {
+		if _, ok := fields["level"]; !ok {
+			fields["level"] = lvl
+			delete(fields, "lvl")
+		}
+	}
+
+	entry.Fields = fields
+	return entry
+}
+
+func (p *LogfmtParser) IsMultiline() bool        { return false }
+func (p *LogfmtParser) ShouldJoin(string) bool { return false }
@@ -0,0 +1,143 @@
+package local
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   []logfmtPair
+		wantOK bool
+	}{
+		{
+			name:   "simple pairs",
+			line:   "level=info user=42",
+			want:   []logfmtPair{{key: "level", value: "info"}, {key: "user", value: "42"}},
+			wantOK: true,
+		},
+		{
+			name:   "quoted value with escapes",
+			line:   `msg="said \"hi\"\n" path="C:\\tmp"`,
+			want:   []logfmtPair{{key: "msg", value: "said \"hi\"\n"}, {key: "path", value: `C:\tmp`}},
+			wantOK: true,
+		},
+		{
+			name:   "empty value and flag",
+			line:   "err= debug  id=7",
+			want:   []logfmtPair{{key: "err", value: ""}, {key: "debug", value: "true", flag: true}, {key: "id", value: "7"}},
+			wantOK: true,
+		},
+		{
+			name:   "value with equals sign",
+			line:   "query=a=b",
+			want:   []logfmtPair{{key: "query", value: "a=b"}},
+			wantOK: true,
+		},
+		{"unterminated quote", `msg="oops level=error`, nil, false},
+		{"text after quote", `msg="a"b level=error`, nil, false},
+		{"missing key", `=value`, nil, false},
+		{"empty line", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLogfmt(tt.line)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLogfmt(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsLogfmtLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{`ts=2024-03-01T10:00:00Z level=error msg="payment failed" user=42`, true},
+		{`time="2024-03-01T10:00:00Z" level=info msg="started"`, true},
+		{`lvl=warn msg=slow`, true},
+		{`user=42 order=17`, false},                       // no timestamp, level or message key
+		{`2024-03-01 10:00:00 INFO level=x msg=y`, false}, // text before the pairs
+		{`msg=started`, false},                            // a single pair
+		{`Some random log line`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isLogfmtLine(tt.line); got != tt.want {
+			t.Errorf("isLogfmtLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLogfmtParser_ParseLine(t *testing.T) {
+	p := &LogfmtParser{}
+
+	entry := p.ParseLine(`ts=2024-03-01T10:00:00.250Z level=error msg="payment failed: card \"declined\"" user=42 retry`, 3, "/logs/api.log")
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 250e6, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != `payment failed: card "declined"` {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	wantFields := map[string]string{"level": "error", "user": "42", "retry": "true"}
+	if !reflect.DeepEqual(entry.Fields, wantFields) {
+		t.Errorf("Fields = %v, want %v", entry.Fields, wantFields)
+	}
+	if entry.Ptr != source.MakeLocalPtr("/logs/api.log", 3) {
+		t.Errorf("Ptr = %q", entry.Ptr)
+	}
+
+	// Unix times, lvl and lines without a message
+	entry = p.ParseLine("time=1709287200 lvl=info component=db", 4, "/logs/api.log")
+	if !entry.Timestamp.Equal(time.Unix(1709287200, 0)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != "time=1709287200 lvl=info component=db" {
+		t.Errorf("Message = %q, want the whole line", entry.Message)
+	}
+	if entry.Fields["level"] != "info" || entry.Fields["component"] != "db" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+
+	// Lines that are not logfmt are kept as plain text
+	entry = p.ParseLine(`panic: "unterminated`, 5, "/logs/api.log")
+	if entry.Message != `panic: "unterminated` || entry.Fields != nil {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestSource_Logfmt(t *testing.T) {
+	content := `ts=2024-03-01T10:00:00Z level=info msg="server started" addr=:8080
+ts=2024-03-01T10:00:05Z level=error msg="payment failed" user=42
+ts=2024-03-01T10:00:09Z level=info msg="request done" status=200
+`
+	path := createTempFile(t, t.TempDir(), "api.log", content)
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	if src.format != FormatLogfmt {
+		t.Fatalf("format = %v, want logfmt", src.format)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Message != "payment failed" || entries[1].Fields["user"] != "42" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,127 @@
+package local
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// multilineProfiles are the built-in rules for joining the stack traces of
+// common runtimes to the line that logged them.
+var multilineProfiles = map[string]source.MultilineConfig{
+	// "Traceback (most recent call last):", indented frames and source lines,
+	// the exception, and chained tracebacks separated by blank lines
+	"python": {Continue: `^(?:\s*$|\s+\S|Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|(?:[A-Za-z_]\w*\.)*(?:\w*(?:Error|Exception|Warning|Exit|Interrupt|Iteration)|[A-Z]\w*[a-z]\w*)(?::\s|$))`},
+	// Panics and goroutine dumps: "goroutine 1 [running]:", function calls,
+	// tab-indented file lines, "created by" and the exit status
+	"go": {Continue: `^(?:\s*$|\t|goroutine \d+ \[|created by |\[signal |exit status \d+$|panic: .*\[recovered\]|[\w\-./]+\.(?:\(\*?\w+\)\.)?[\w$.\[\]]+\(.*\)$)`},
+	// The error, "    at fn (file:line:col)" frames, causes and the
+	// properties of logged error objects
+	"node": {Continue: `^(?:\s+\S|\}$|[A-Z]\w*(?:Error|Exception)(?: \[\w+\])?(?::|$))`},
+	// "   at Namespace.Type.Method() in file:line N", inner exceptions, end
+	// of stack trace markers, and the indented lines of console loggers
+	"dotnet": {Continue: `^(?:\s+at |\s*--- End of |\s*---> |\s{2,}\S|[A-Z]\w*(?:\.\w+)+Exception\b)`},
+	// Frames, elided frames, "Caused by:" and "Suppressed:" chains
+	"java": {Continue: `^(?:\s*$|\s+at |\s+\.\.\. \d+ (?:more|common frames omitted)|\s*Caused by:|\s*Suppressed:|[a-z][a-z0-9_]*(?:\.[a-z][a-z0-9_]*)*\.[A-Z]\w*(?:Exception|Error|Throwable))`},
+}
+
+// multilineRules decide which lines are joined into an entry: a line
+// matching start begins a new entry; otherwise a line matching continue, or
+// any line if there is no continue pattern, joins the entry, until a line
+// matching end closes it.
+type multilineRules struct {
+	start *regexp.Regexp
+	cont  *regexp.Regexp
+	end   *regexp.Regexp
+}
+
+// newMultilineRules compiles the multiline rules of a config, starting from
+// its profile, if any, whose patterns are replaced by those set in the
+// config. It returns nil if the config sets no rules.
+func newMultilineRules(cfg source.MultilineConfig) (*multilineRules, error) {
+	if cfg.Profile != "" {
+		profile, ok := multilineProfiles[strings.ToLower(cfg.Profile)]
+		if !ok {
+			return nil, fmt.Errorf("unknown multiline profile %q (available: %s)", cfg.Profile, strings.Join(multilineProfileNames(), ", "))
+		}
+		if cfg.Start == "" {
+			cfg.Start = profile.Start
+		}
+		if cfg.Continue == "" {
+			cfg.Continue = profile.Continue
+		}
+		if cfg.End == "" {
+			cfg.End = profile.End
+		}
+	}
+	if cfg.Start == "" && cfg.Continue == "" && cfg.End == "" {
+		return nil, nil
+	}
+
+	r := &multilineRules{}
+	for _, p := range []struct {
+		kind, pattern string
+		dst           **regexp.Regexp
+	}{
+		{"start", cfg.Start, &r.start},
+		{"continue", cfg.Continue, &r.cont},
+		{"end", cfg.End, &r.end},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("multiline %s: %w", p.kind, err)
+		}
+		*p.dst = re
+	}
+	return r, nil
+}
+
+// multilineProfileNames returns the names of the built-in profiles, sorted.
+func multilineProfileNames() []string {
+	names := make([]string, 0, len(multilineProfiles))
+	for name := range multilineProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *multilineRules) shouldJoin(line string) bool {
+	if r.start != nil && r.start.MatchString(line) {
+		return false
+	}
+	if r.cont != nil {
+		return r.cont.MatchString(line)
+	}
+	return true
+}
+
+func (r *multilineRules) endsEntry(line string) bool {
+	return r != nil && r.end != nil && r.end.MatchString(line)
+}
+
+// multilineParser joins lines into entries by multiline rules for a parser
+// of single lines, such as JSON or logfmt. The first line of each entry is
+// parsed; the lines joined to it are added to its message.
+type multilineParser struct {
+	Parser
+	rules *multilineRules
+}
+
+// withMultiline returns p assembling entries by rules, or p if rules is nil.
+func withMultiline(p Parser, rules *multilineRules) Parser {
+	if rules == nil {
+		return p
+	}
+	return &multilineParser{Parser: p, rules: rules}
+}
+
+func (p *multilineParser) IsMultiline() bool           { return true }
+func (p *multilineParser) ShouldJoin(line string) bool { return p.rules.shouldJoin(line) }
+func (p *multilineParser) EndsEntry(line string) bool  { return p.rules.endsEntry(line) }
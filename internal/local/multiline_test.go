@@ -0,0 +1,308 @@
+package local
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+func TestMultilineProfiles(t *testing.T) {
+	tests := []struct {
+		profile string
+		lines   []string // lines of one entry
+	}{
+		{
+			profile: "python",
+			lines: []string{
+				"2024-03-01 10:00:01 ERROR charge failed",
+				"Traceback (most recent call last):",
+				`  File "/app/gateway.py", line 7, in charge`,
+				"    raise CardDeclined(card)",
+				"gateway.CardDeclined: card 4242 declined",
+				"",
+				"During handling of the above exception, another exception occurred:",
+				"",
+				"Traceback (most recent call last):",
+				`  File "/app/views.py", line 44, in charge`,
+				`    raise PaymentError("declined") from e`,
+				"app.PaymentError: declined",
+			},
+		},
+		{
+			profile: "go",
+			// Panics are written to stderr on their own
+			lines: []string{
+				"panic: runtime error: invalid memory address or nil pointer dereference",
+				"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a5b2c]",
+				"",
+				"goroutine 1 [running]:",
+				"main.(*Server).handle(0x0, {0x5d1e80, 0xc000012345})",
+				"\t/app/server.go:42 +0x2c",
+				"net/http.HandlerFunc.ServeHTTP(0xc0000a2000?, {0x6d0a48?, 0xc0000e0000?}, 0x0?)",
+				"\t/usr/local/go/src/net/http/server.go:2136 +0x29",
+				"created by net/http.(*Server).Serve in goroutine 1",
+				"\t/usr/local/go/src/net/http/server.go:3285 +0x4b4",
+				"exit status 2",
+			},
+		},
+		{
+			profile: "node",
+			lines: []string{
+				"2024-03-01 10:00:01 ERROR charge failed",
+				"TypeError: Cannot read properties of undefined (reading 'id')",
+				"    at charge (/app/billing.js:42:17)",
+				"    at process.processTicksAndRejections (node:internal/process/task_queues:95:5) {",
+				"  [cause]: Error: connect ECONNREFUSED 127.0.0.1:5432",
+				"      at TCPConnectWrap.afterConnect [as oncomplete] (node:net:1555:16)",
+				"}",
+			},
+		},
+		{
+			profile: "dotnet",
+			lines: []string{
+				"2024-03-01 10:00:01 ERROR charge failed",
+				"System.InvalidOperationException: Card declined",
+				" ---> System.Net.Http.HttpRequestException: Connection refused",
+				"   at System.Net.Http.HttpConnectionPool.ConnectAsync()",
+				"   --- End of inner exception stack trace ---",
+				"   at Billing.Gateway.ChargeAsync(Card card) in /app/Gateway.cs:line 42",
+			},
+		},
+		{
+			profile: "java",
+			lines: []string{
+				"2024-03-01 10:00:01 ERROR charge failed",
+				"java.lang.IllegalStateException: card declined",
+				"\tat billing.Charge.run(Charge.java:42)",
+				"\t... 3 more",
+				"Caused by: java.net.ConnectException: Connection refused",
+				"\tat billing.Gateway.connect(Gateway.java:17)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			content := "2024-03-01 10:00:00 INFO charge started\n" +
+				strings.Join(tt.lines, "\n") + "\n" +
+				"\n" +
+				"2024-03-01 10:00:02 INFO charge retried\n"
+			path := createTempFile(t, t.TempDir(), "app.log", content)
+
+			src, err := NewSource(path, "plain+"+tt.profile)
+			if err != nil {
+				t.Fatalf("NewSource failed: %v", err)
+			}
+			entries, err := src.Query(context.Background(), source.QueryParams{})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(entries) != 3 {
+				t.Fatalf("got %d entries, want 3: %q", len(entries), entries)
+			}
+
+			// The trace keeps the pointer of its first line
+			byPtr := make(map[string]string)
+			for _, e := range entries {
+				byPtr[e.Ptr] = e.Message
+			}
+			want := strings.TrimPrefix(strings.Join(tt.lines, "\n"), "2024-03-01 10:00:01 ")
+			if got := byPtr[source.MakeLocalPtr(path, 2)]; got != want {
+				t.Errorf("Message = %q, want %q", got, want)
+			}
+			next := source.MakeLocalPtr(path, len(tt.lines)+3)
+			if got := byPtr[next]; got != "INFO charge retried" {
+				t.Errorf("next entry = %q", got)
+			}
+		})
+	}
+}
+
+func TestMultilineRules_StartEnd(t *testing.T) {
+	err := RegisterParser("events", source.ParserConfig{
+		Multiline: source.MultilineConfig{Start: `^<event>`, End: `^</event>`},
+	})
+	if err != nil {
+		t.Fatalf("RegisterParser failed: %v", err)
+	}
+
+	content := "<event>\n  <id>1</id>\n</event>\nheartbeat\n<event>\n  <id>2</id>\n</event>\n"
+	path := createTempFile(t, t.TempDir(), "events.log", content)
+	src, err := NewSource(path, "events")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	want := []string{"<event>\n  <id>1</id>\n</event>", "heartbeat", "<event>\n  <id>2</id>\n</event>"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %q", len(entries), len(want), entries)
+	}
+	for i, e := range entries {
+		if e.Message != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, e.Message, want[i])
+		}
+	}
+}
+
+func TestRegisterParser_Format(t *testing.T) {
+	err := RegisterParser("api", source.ParserConfig{
+		Format:    "json",
+		Multiline: source.MultilineConfig{Profile: "Python"},
+	})
+	if err != nil {
+		t.Fatalf("RegisterParser failed: %v", err)
+	}
+
+	content := `{"time":"2024-03-01T10:00:00Z","level":"info","msg":"charge started"}
+{"time":"2024-03-01T10:00:01Z","level":"error","msg":"charge failed"}
+Traceback (most recent call last):
+  File "/app/gateway.py", line 7, in charge
+KeyError: 'card'
+{"time":"2024-03-01T10:00:02Z","level":"info","msg":"charge retried"}
+`
+	path := createTempFile(t, t.TempDir(), "api.log", content)
+	src, err := NewSource(path, "api")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	failed := entries[1]
+	if !strings.HasSuffix(failed.Message, "\nKeyError: 'card'") || failed.Fields["level"] != "error" {
+		t.Errorf("entry = %+v", failed)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     source.ParserConfig
+		wantErr string
+	}{
+		{"pattern and format", source.ParserConfig{Pattern: ".*", Format: "json"}, "pattern and format cannot both be set"},
+		{"unknown format", source.ParserConfig{Format: "yaml"}, `unknown format "yaml"`},
+		{"record format", source.ParserConfig{Format: "cloudtrail"}, "reads whole records"},
+		{"unknown profile", source.ParserConfig{Multiline: source.MultilineConfig{Profile: "ruby"}}, `unknown multiline profile "ruby" (available: dotnet, go, java, node, python)`},
+		{"end", source.ParserConfig{Multiline: source.MultilineConfig{End: "("}}, "multiline end: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterParser("broken-"+strings.ReplaceAll(tt.name, " ", "-"), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFormat_Profile(t *testing.T) {
+	f := ParseFormat("logfmt+go")
+	if f == FormatAuto || f.String() != "logfmt+go" {
+		t.Fatalf("ParseFormat(logfmt+go) = %v", f)
+	}
+	// As decoded from ?format=logfmt+go
+	if ParseFormat("logfmt go") != f {
+		t.Error("a space should select the same format")
+	}
+
+	p := NewParser(f)
+	if !p.IsMultiline() || !p.ShouldJoin("goroutine 1 [running]:") || p.ShouldJoin("level=info msg=ok") {
+		t.Error("logfmt+go should join goroutine dumps")
+	}
+	if entry := p.ParseLine("level=error msg=crashed", 1, "/logs/app.log"); entry.Fields["level"] != "error" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+
+	if err := CheckFormat("logfmt+ruby"); err == nil || !strings.Contains(err.Error(), `unknown multiline profile "ruby"`) {
+		t.Errorf("CheckFormat = %v", err)
+	}
+	if err := CheckFormat("yaml+go"); err == nil || !strings.Contains(err.Error(), `unknown format "yaml"`) {
+		t.Errorf("CheckFormat = %v", err)
+	}
+}
+
+func TestSource_FetchContext_Multiline(t *testing.T) {
+	content := "2024-03-01 10:00:00 INFO one\n" +
+		"2024-03-01 10:00:01 ERROR two\n" +
+		"Traceback (most recent call last):\n" +
+		`  File "/app/main.py", line 3, in <module>` + "\n" +
+		"ValueError: bad\n" +
+		"2024-03-01 10:00:02 INFO three\n" +
+		"2024-03-01 10:00:03 INFO four\n"
+	path := createTempFile(t, t.TempDir(), "app.log", content)
+	src, err := NewSource(path, "plain+python")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	ctx := context.Background()
+
+	before, after, err := src.FetchContext(ctx, source.Entry{Ptr: source.MakeLocalPtr(path, 6)}, 1, 1)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || !strings.HasPrefix(before[0].Message, "ERROR two\nTraceback") || !strings.HasSuffix(before[0].Message, "\nValueError: bad") {
+		t.Errorf("before = %q", before)
+	}
+	if len(after) != 1 || after[0].Message != "INFO four" {
+		t.Errorf("after = %q", after)
+	}
+
+	if _, _, err := src.FetchContext(ctx, source.Entry{Ptr: source.MakeLocalPtr(path, 4)}, 1, 1); err == nil || !strings.Contains(err.Error(), "no log entry starts at line 4") {
+		t.Errorf("error = %v", err)
+	}
+	if _, _, err := src.FetchContext(ctx, source.Entry{Ptr: source.MakeLocalPtr(path, 20)}, 1, 1); err == nil || !strings.Contains(err.Error(), "line 20 out of range") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestSource_GetRecord_FromPtr(t *testing.T) {
+	content := "ts=2024-03-01T10:00:01Z level=error msg=boom\n" +
+		"Traceback (most recent call last):\n" +
+		`  File "/app/main.py", line 3, in <module>` + "\n" +
+		"ValueError: bad\n" +
+		"ts=2024-03-01T10:00:02Z level=info msg=ok\n"
+	path := createTempFile(t, t.TempDir(), "lf.log", content)
+	src, err := NewSource(path, "logfmt+python")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var failed source.Entry
+	for _, e := range entries {
+		if e.Fields["level"] == "error" {
+			failed = e
+		}
+	}
+	if !strings.HasSuffix(failed.Message, "\nValueError: bad") {
+		t.Fatalf("Message = %q", failed.Message)
+	}
+
+	// As with `clew get` in a later invocation, the cached metadata keeps the format
+	meta := src.Metadata()
+	if want := path + "?format=logfmt%2Bpython"; meta.URI != want {
+		t.Errorf("URI = %q, want %q", meta.URI, want)
+	}
+	ptrSrc, err := source.OpenFromPtr(failed.Ptr, &meta)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	got, err := ptrSrc.GetRecord(context.Background(), failed.Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if got.Message != failed.Message {
+		t.Errorf("Message = %q, want %q", got.Message, failed.Message)
+	}
+}
@@ -32,6 +32,22 @@ func NewParser(format Format) Parser {
 		return &JSONParser{}
 	case FormatSyslog:
 		return &SyslogParser{}
+	case FormatALB:
+		return &ALBParser{}
+	case FormatELB:
+		return &ELBParser{}
+	case FormatCloudFront:
+		return &CloudFrontParser{}
+	case FormatVPCFlow:
+		return &VPCFlowParser{}
+	case FormatLogfmt:
+		return &LogfmtParser{}
+	case FormatCommon:
+		return &AccessLogParser{format: commonLogFormat}
+	case FormatCombined:
+		return &AccessLogParser{format: combinedLogFormat}
+	case FormatNginxError:
+		return &NginxErrorParser{}
 	case FormatJava:
 		// Initialize with today's date as default reference for time-only entries
 		now := time.Now()
@@ -39,6 +55,9 @@ func NewParser(format Format) Parser {
 			referenceDate: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
 		}
 	default:
+		if c := format.custom(); c != nil {
+			return c.newParser()
+		}
 		return &PlainParser{}
 	}
 }
@@ -300,11 +319,34 @@ func parseJSONTimestamp(val interface{}) time.Time {
 	return time.Time{}
 }
 
+// parseTimestampString parses an RFC3339 or similar timestamp, or a Unix
+// time in seconds or milliseconds.
+func parseTimestampString(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	if ts := parseISO8601(s); !ts.IsZero() {
+		return ts
+	}
+	if ts := parseJSONTimestamp(s); !ts.IsZero() {
+		return ts
+	}
+	if unix, err := strconv.ParseFloat(s, 64); err == nil && unix > 0 {
+		if unix > 1e12 {
+			return time.UnixMilli(int64(unix))
+		}
+		sec := int64(unix)
+		return time.Unix(sec, int64((unix-float64(sec))*1e9))
+	}
+	return time.Time{}
+}
+
 // SyslogParser handles RFC3164/5424 syslog format.
 type SyslogParser struct{}
 
-// RFC3164 pattern: "Jan  2 15:04:05 hostname program[pid]: message"
-var syslog3164Pattern = regexp.MustCompile(`^([A-Z][a-z]{2})\s+(\d{1,2})\s+(\d{2}):(\d{2}):(\d{2})\s+(\S+)\s+(.*)$`)
+// RFC3164 pattern: "Jan  2 15:04:05 hostname program[pid]: message", with the
+// "<pri>" prefix messages carry on the network
+var syslog3164Pattern = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2})\s+(\d{1,2})\s+(\d{2}):(\d{2}):(\d{2})\s+(\S+)\s+(.*)$`)
 
 // RFC5424 pattern: "<pri>1 2006-01-02T15:04:05.000000Z hostname app proc msgid structured msg"
 var syslog5424Pattern = regexp.MustCompile(`^<(\d+)>1\s+(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s+(.*)$`)
@@ -354,18 +396,23 @@ func (p *SyslogParser) ParseLine(line string, lineNum int, filePath string) *sou
 
 	// Try RFC3164
 	if matches := syslog3164Pattern.FindStringSubmatch(line); matches != nil {
-		month := months[matches[1]]
-		day, _ := strconv.Atoi(matches[2])
-		hour, _ := strconv.Atoi(matches[3])
-		min, _ := strconv.Atoi(matches[4])
-		sec, _ := strconv.Atoi(matches[5])
+		if pri, err := strconv.Atoi(matches[1]); err == nil {
+			entry.Fields["facility"] = strconv.Itoa(pri / 8)
+			entry.Fields["severity"] = strconv.Itoa(pri % 8)
+		}
+
+		month := months[matches[2]]
+		day, _ := strconv.Atoi(matches[3])
+		hour, _ := strconv.Atoi(matches[4])
+		min, _ := strconv.Atoi(matches[5])
+		sec, _ := strconv.Atoi(matches[6])
 
 		// RFC3164 doesn't include year, assume current year
 		year := time.Now().Year()
 		entry.Timestamp = time.Date(year, month, day, hour, min, sec, 0, time.Local)
 
-		entry.Fields["hostname"] = matches[6]
-		entry.Message = matches[7]
+		entry.Fields["hostname"] = matches[7]
+		entry.Message = matches[8]
 
 		// Try to extract program name from message
 		if idx := strings.Index(entry.Message, ":"); idx > 0 {
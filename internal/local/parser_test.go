@@ -16,6 +16,14 @@ func TestNewParser(t *testing.T) {
 		{"json", FormatJSON, "*local.JSONParser"},
 		{"syslog", FormatSyslog, "*local.SyslogParser"},
 		{"java", FormatJava, "*local.JavaParser"},
+		{"alb", FormatALB, "*local.ALBParser"},
+		{"elb", FormatELB, "*local.ELBParser"},
+		{"cloudfront", FormatCloudFront, "*local.CloudFrontParser"},
+		{"vpcflow", FormatVPCFlow, "*local.VPCFlowParser"},
+		{"logfmt", FormatLogfmt, "*local.LogfmtParser"},
+		{"common", FormatCommon, "*local.AccessLogParser"},
+		{"combined", FormatCombined, "*local.AccessLogParser"},
+		{"nginx-error", FormatNginxError, "*local.NginxErrorParser"},
 		{"unknown", Format(99), "*local.PlainParser"}, // unknown format defaults to plain
 	}
 
@@ -40,6 +48,20 @@ func getTypeName(p Parser) string {
 		return "*local.SyslogParser"
 	case *JavaParser:
 		return "*local.JavaParser"
+	case *ALBParser:
+		return "*local.ALBParser"
+	case *ELBParser:
+		return "*local.ELBParser"
+	case *CloudFrontParser:
+		return "*local.CloudFrontParser"
+	case *VPCFlowParser:
+		return "*local.VPCFlowParser"
+	case *LogfmtParser:
+		return "*local.LogfmtParser"
+	case *AccessLogParser:
+		return "*local.AccessLogParser"
+	case *NginxErrorParser:
+		return "*local.NginxErrorParser"
 	default:
 		return "unknown"
 	}
@@ -535,6 +557,30 @@ func TestSyslogParser_RFC3164(t *testing.T) {
 	}
 }
 
+func TestSyslogParser_RFC3164_Priority(t *testing.T) {
+	p := &SyslogParser{}
+
+	// As sent over the network
+	line := "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"
+	entry := p.ParseLine(line, 1, "/var/log/remote.log")
+
+	if entry == nil {
+		t.Fatal("expected non-nil entry")
+	}
+	if entry.Message != "'su root' failed for lonvick on /dev/pts/8" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	if entry.Timestamp.Month() != time.October || entry.Timestamp.Day() != 11 {
+		t.Errorf("timestamp date wrong: got %v", entry.Timestamp)
+	}
+	if entry.Fields["hostname"] != "mymachine" || entry.Fields["program"] != "su" {
+		t.Errorf("unexpected fields %v", entry.Fields)
+	}
+	if entry.Fields["facility"] != "4" || entry.Fields["severity"] != "2" { // 34 = auth.crit
+		t.Errorf("facility/severity = %q/%q, want 4/2", entry.Fields["facility"], entry.Fields["severity"])
+	}
+}
+
 func TestSyslogParser_RFC3164_NoPID(t *testing.T) {
 	p := &SyslogParser{}
 
@@ -0,0 +1,94 @@
+package local
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// recordReader calls fn with the entries of a file whose entries are records
+// of a structured format rather than lines, until fn returns false. The
+// entries' pointers hold record numbers in place of line numbers.
+type recordReader func(ctx context.Context, path string, fn func(source.Entry) bool) error
+
+// recordReader returns the reader for formats read as whole files, or nil
+// for line-based formats.
+func (f Format) recordReader() recordReader {
+	switch f {
+	case FormatEVTX:
+		return readEVTXEntries
+	case FormatCloudTrail:
+		return readCloudTrailEntries
+	}
+	return nil
+}
+
+// recordNumber returns the record number an entry's pointer holds.
+func recordNumber(entry source.Entry) int {
+	info, _ := source.ParseLocalPtr(entry.Ptr)
+	return info.LineNum
+}
+
+// queryRecords returns the records of a file matching the parameters.
+func queryRecords(ctx context.Context, read recordReader, path string, params source.QueryParams) ([]source.Entry, error) {
+	var results []source.Entry
+	err := read(ctx, path, func(entry source.Entry) bool {
+		if MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+		return true
+	})
+	return results, err
+}
+
+// getRecord returns the record with a record number from a file.
+func getRecord(ctx context.Context, read recordReader, path string, num int) (*source.Entry, error) {
+	var found *source.Entry
+	err := read(ctx, path, func(entry source.Entry) bool {
+		if recordNumber(entry) == num {
+			found = &entry
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("record %d not found in %s", num, path)
+	}
+	return found, nil
+}
+
+// fetchRecordContext returns the records before and after the record with a
+// record number in a file.
+func fetchRecordContext(ctx context.Context, read recordReader, path string, num, before, after int) ([]source.Event, []source.Event, error) {
+	var beforeEvents, afterEvents []source.Event
+	found := false
+	err := read(ctx, path, func(entry source.Entry) bool {
+		event := source.Event{Timestamp: entry.Timestamp, Message: entry.Message, Stream: entry.Stream}
+		switch {
+		case found:
+			afterEvents = append(afterEvents, event)
+			return len(afterEvents) < after
+		case recordNumber(entry) == num:
+			found = true
+			return after > 0
+		case before > 0:
+			// Keep only the last N records before the target
+			if len(beforeEvents) == before {
+				beforeEvents = beforeEvents[1:]
+			}
+			beforeEvents = append(beforeEvents, event)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("record %d not found in %s", num, path)
+	}
+	return beforeEvents, afterEvents, nil
+}
@@ -0,0 +1,173 @@
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// RegexParser handles lines described by a regular expression, which may
+// use grok pattern references, from a config parser definition or a grok
+// format hint. Named groups become fields, except the timestamp and message
+// groups, which set the entry's timestamp and message.
+type RegexParser struct {
+	expr       *grokExpr
+	timestamp  string
+	timeLayout string
+	location   *time.Location
+	message    string
+	level      string
+	multiline  *multilineRules
+}
+
+// newRegexParser compiles a parser definition. Groups named explicitly in the
+// definition must exist in the pattern.
+func newRegexParser(cfg source.ParserConfig) (*RegexParser, error) {
+	if cfg.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	expr, err := compileGrok(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern: %w", err)
+	}
+
+	p := &RegexParser{
+		expr:       expr,
+		timestamp:  "timestamp",
+		timeLayout: cfg.TimeLayout,
+		location:   time.Local,
+		message:    "message",
+		level:      "level",
+	}
+
+	for _, group := range []struct {
+		kind, name string
+		dst        *string
+	}{
+		{"timestamp", cfg.Timestamp, &p.timestamp},
+		{"message", cfg.Message, &p.message},
+		{"level", cfg.Level, &p.level},
+	} {
+		if group.name == "" {
+			continue
+		}
+		if !slices.Contains(expr.fields, group.name) {
+			return nil, fmt.Errorf("%s group %q not in pattern", group.kind, group.name)
+		}
+		*group.dst = group.name
+	}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone %q: %w", cfg.Timezone, err)
+		}
+		p.location = loc
+	}
+
+	if p.multiline, err = newMultilineRules(cfg.Multiline); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *RegexParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	if line == "" {
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+	}
+
+	matches := p.expr.pattern.FindStringSubmatch(line)
+	if matches == nil {
+		// Not in the pattern, treat as plain text
+		return entry
+	}
+
+	entry.Fields = make(map[string]string)
+	for i, name := range p.expr.fields {
+		value := matches[i]
+		if name == "" || value == "" {
+			continue
+		}
+		// A field captured by several groups takes the first value
+		if _, ok := entry.Fields[name]; ok {
+			continue
+		}
+		value = convertGrokValue(value, p.expr.types[i])
+
+		switch name {
+		case p.timestamp:
+			if ts := p.parseTimestamp(value); !ts.IsZero() {
+				entry.Timestamp = ts
+				continue
+			}
+		case p.message:
+			entry.Message = value
+			continue
+		case p.level:
+			name = "level"
+		}
+		entry.Fields[name] = value
+	}
+
+	return entry
+}
+
+// Layouts of the timestamps of grok patterns such as HTTPDATE, SYSLOGTIMESTAMP
+// and CATALINA_DATESTAMP, tried when a parser has no layout and the timestamp
+// is not ISO 8601 or Unix time
+var regexTimestampLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	"Jan _2 15:04:05",
+	"Jan 2, 2006 3:04:05 PM",
+	"Mon Jan _2 15:04:05 2006",
+	time.UnixDate,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseTimestamp parses a timestamp with the configured layout, in the
+// configured zone unless the timestamp has one. Without a layout, common
+// formats are detected.
+func (p *RegexParser) parseTimestamp(s string) time.Time {
+	if p.timeLayout != "" {
+		ts, err := time.ParseInLocation(p.timeLayout, s, p.location)
+		if err != nil {
+			return time.Time{}
+		}
+		return ts
+	}
+
+	if ts := parseTimestampString(s); !ts.IsZero() {
+		return ts
+	}
+	for _, layout := range regexTimestampLayouts {
+		ts, err := time.ParseInLocation(layout, s, p.location)
+		if err != nil {
+			continue
+		}
+		// Syslog timestamps have no year, assume the current year
+		if ts.Year() == 0 {
+			ts = ts.AddDate(time.Now().Year(), 0, 0)
+		}
+		return ts
+	}
+	return time.Time{}
+}
+
+func (p *RegexParser) IsMultiline() bool           { return p.multiline != nil }
+func (p *RegexParser) ShouldJoin(line string) bool { return p.multiline.shouldJoin(line) }
+func (p *RegexParser) EndsEntry(line string) bool  { return p.multiline.endsEntry(line) }
@@ -0,0 +1,188 @@
+package local
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// billingParser is a parser definition for lines such as
+// "2024-03-01 10:00:00.250 ERROR [worker-3] charge failed".
+var billingParser = source.ParserConfig{
+	Pattern:    `^(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}) (?P<lvl>[A-Z]+) +\[(?P<thread>[^\]]+)\] (?P<msg>.*)$`,
+	Timestamp:  "ts",
+	TimeLayout: "2006-01-02 15:04:05.000",
+	Timezone:   "Europe/Berlin",
+	Message:    "msg",
+	Level:      "lvl",
+	Multiline:  source.MultilineConfig{Start: `^\d{4}-\d{2}-\d{2} `},
+}
+
+func TestRegexParser_ParseLine(t *testing.T) {
+	p, err := newRegexParser(billingParser)
+	if err != nil {
+		t.Fatalf("newRegexParser failed: %v", err)
+	}
+
+	entry := p.ParseLine("2024-03-01 10:00:00.250 ERROR [worker-3] charge failed", 7, "/logs/billing.log")
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+	// Timestamps without a zone are in the configured timezone (CET in March)
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 9, 0, 0, 250e6, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != "charge failed" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	if len(entry.Fields) != 2 || entry.Fields["level"] != "ERROR" || entry.Fields["thread"] != "worker-3" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+	if entry.Ptr != source.MakeLocalPtr("/logs/billing.log", 7) {
+		t.Errorf("Ptr = %q", entry.Ptr)
+	}
+
+	plain := p.ParseLine("not a billing line", 8, "/logs/billing.log")
+	if plain.Message != "not a billing line" || plain.Fields != nil {
+		t.Errorf("unmatched line = %+v", plain)
+	}
+
+	// Default group names, with the timestamp format detected
+	p, err = newRegexParser(source.ParserConfig{Pattern: `^(?P<timestamp>\S+) (?P<level>\w+) (?P<message>.*)$`})
+	if err != nil {
+		t.Fatalf("newRegexParser failed: %v", err)
+	}
+	entry = p.ParseLine("2024-03-01T10:00:00Z warn disk almost full", 1, "/logs/app.log")
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || entry.Message != "disk almost full" || entry.Fields["level"] != "warn" {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestRegexParser_Multiline(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiline source.MultilineConfig
+		line      string
+		want      bool
+	}{
+		{"start matches", source.MultilineConfig{Start: `^\d`}, "2024-03-01 next", false},
+		{"start does not match", source.MultilineConfig{Start: `^\d`}, "  at Foo.bar", true},
+		{"continue matches", source.MultilineConfig{Continue: `^\s`}, "  at Foo.bar", true},
+		{"continue does not match", source.MultilineConfig{Continue: `^\s`}, "next", false},
+		{"start wins over continue", source.MultilineConfig{Start: `^\d`, Continue: `.`}, "2024-03-01 next", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newRegexParser(source.ParserConfig{Pattern: `(?P<message>.*)`, Multiline: tt.multiline})
+			if err != nil {
+				t.Fatalf("newRegexParser failed: %v", err)
+			}
+			if !p.IsMultiline() {
+				t.Fatal("IsMultiline = false")
+			}
+			if got := p.ShouldJoin(tt.line); got != tt.want {
+				t.Errorf("ShouldJoin(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	p, _ := newRegexParser(source.ParserConfig{Pattern: `(?P<message>.*)`})
+	if p.IsMultiline() {
+		t.Error("parsers without multiline patterns should not be multiline")
+	}
+}
+
+func TestNewRegexParser_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     source.ParserConfig
+		wantErr string
+	}{
+		{"no pattern", source.ParserConfig{}, "pattern is required"},
+		{"bad pattern", source.ParserConfig{Pattern: `(?P<ts>\d+`}, "pattern: "},
+		{"missing group", source.ParserConfig{Pattern: `(?P<ts>\d+)`, Message: "msg"}, `message group "msg" not in pattern`},
+		{"bad timezone", source.ParserConfig{Pattern: `.*`, Timezone: "Mars/Olympus"}, `timezone "Mars/Olympus"`},
+		{"bad start", source.ParserConfig{Pattern: `.*`, Multiline: source.MultilineConfig{Start: `[`}}, "multiline start: "},
+		{"bad continue", source.ParserConfig{Pattern: `.*`, Multiline: source.MultilineConfig{Continue: `(`}}, "multiline continue: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRegexParser(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterParser(t *testing.T) {
+	if err := RegisterParser("Billing", billingParser); err != nil {
+		t.Fatalf("RegisterParser failed: %v", err)
+	}
+	if f := ParseFormat("billing"); f == FormatAuto || f.String() != "billing" {
+		t.Fatalf("ParseFormat(billing) = %v", f)
+	}
+	if err := CheckFormat("billing"); err != nil {
+		t.Errorf("CheckFormat(billing) = %v", err)
+	}
+
+	if err := RegisterParser("syslog", billingParser); err == nil {
+		t.Error("built-in format names should be rejected")
+	}
+
+	// Broken parsers are reported when selected rather than detected over
+	if err := RegisterParser("broken", source.ParserConfig{Pattern: `(`}); err == nil {
+		t.Error("patterns that do not compile should be rejected")
+	}
+	if ParseFormat("broken") != FormatAuto {
+		t.Error("broken parsers should not be selectable")
+	}
+	if err := CheckFormat("Broken"); err == nil || !strings.Contains(err.Error(), `format "broken": pattern: `) {
+		t.Errorf("CheckFormat(broken) = %v", err)
+	}
+	if _, err := NewSource(createTempFile(t, t.TempDir(), "app.log", "x\n"), "broken"); err == nil {
+		t.Error("NewSource should fail for a broken parser")
+	}
+	if err := CheckFormat("json"); err != nil {
+		t.Errorf("CheckFormat(json) = %v", err)
+	}
+}
+
+func TestSource_RegexParser(t *testing.T) {
+	if err := RegisterParser("billing", billingParser); err != nil {
+		t.Fatalf("RegisterParser failed: %v", err)
+	}
+
+	content := `2024-03-01 10:00:00.250 INFO  [worker-1] charge started
+2024-03-01 10:00:01.500 ERROR [worker-3] charge failed
+java.lang.IllegalStateException: card declined
+	at billing.Charge.run(Charge.java:42)
+2024-03-01 10:00:02.000 INFO  [worker-1] charge retried
+`
+	path := createTempFile(t, t.TempDir(), "billing.log", content)
+	src, err := NewSource(path, "billing")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	failed := entries[1]
+	if failed.Message != "charge failed\njava.lang.IllegalStateException: card declined\n\tat billing.Charge.run(Charge.java:42)" {
+		t.Errorf("Message = %q", failed.Message)
+	}
+	if failed.Fields["level"] != "ERROR" || failed.Ptr != source.MakeLocalPtr(path, 2) {
+		t.Errorf("entry = %+v", failed)
+	}
+}
@@ -0,0 +1,227 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// LineReader reads newline-delimited lines from a stream, tracking line numbers
+// and byte offsets. Lines longer than MaxScanTokenSize are rejected with
+// bufio.ErrTooLong, matching the limit bufio.Scanner used previously.
+type LineReader struct {
+	r       *bufio.Reader
+	num     int   // number of lines read so far (line number of the last line)
+	offset  int64 // byte offset of the next line
+	partial []byte
+
+	// follow keeps a trailing line without a newline buffered at EOF instead of
+	// returning it, so a file that is still being written can be read again later.
+	follow bool
+}
+
+// NewLineReader creates a LineReader that reads from r.
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next line without its line terminator, along with the byte
+// offset where the line starts. It returns io.EOF at the end of input.
+func (lr *LineReader) Next() (string, int64, error) {
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		if len(lr.partial)+len(chunk) > MaxScanTokenSize {
+			return "", 0, bufio.ErrTooLong
+		}
+		lr.partial = append(lr.partial, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			if len(lr.partial) == 0 || lr.follow {
+				return "", 0, io.EOF
+			}
+			break // Final line without a trailing newline
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		break
+	}
+
+	start := lr.offset
+	lr.offset += int64(len(lr.partial))
+	lr.num++
+
+	line := bytes.TrimSuffix(lr.partial, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	text := string(line)
+	lr.partial = lr.partial[:0]
+
+	return text, start, nil
+}
+
+// countLines counts the newline-terminated lines in r.
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	count := 0
+	for {
+		n, err := r.Read(buf)
+		count += bytes.Count(buf[:n], []byte("\n"))
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// scannedEntry is a parsed entry together with the position of its first line.
+type scannedEntry struct {
+	entry  source.Entry
+	line   int
+	offset int64
+	closed bool // ended by its last line; no more lines are joined
+}
+
+// entryEnder is implemented by multiline parsers whose entries can end at a
+// line, rather than only when the next entry starts.
+type entryEnder interface {
+	// EndsEntry returns true if the line is the last line of its entry.
+	EndsEntry(line string) bool
+}
+
+// EntryScanner reads log entries from a stream using a Parser, joining
+// continuation lines for multiline parsers. Usage mirrors bufio.Scanner:
+// call scan until it returns false, then check err.
+type EntryScanner struct {
+	lines   *LineReader
+	parser  Parser
+	name    string
+	pending *scannedEntry
+	current scannedEntry
+	err     error
+	done    bool
+}
+
+// NewEntryScanner creates a scanner that parses entries from r.
+// The name is passed to the parser as the file path (used for Stream/Source/Ptr).
+func NewEntryScanner(r io.Reader, parser Parser, name string) *EntryScanner {
+	return &EntryScanner{
+		lines:  NewLineReader(r),
+		parser: parser,
+		name:   name,
+	}
+}
+
+// Scan advances to the next entry. It returns false at the end of input,
+// when ctx is cancelled, or on a read error (reported by Err).
+// In follow mode, reaching the end of input returns false without flushing a
+// pending multiline entry; scan may be called again once more data is written.
+func (s *EntryScanner) Scan(ctx context.Context) bool {
+	for !s.done {
+		if err := ctx.Err(); err != nil {
+			s.err = err
+			s.done = true
+			return false
+		}
+
+		line, offset, err := s.lines.Next()
+		if err == io.EOF {
+			if s.lines.follow {
+				return false
+			}
+			s.done = true
+			break
+		}
+		if err != nil {
+			s.err = err
+			s.done = true
+			return false
+		}
+
+		// Handle multiline entries
+		if s.parser.IsMultiline() && s.pending != nil && !s.pending.closed && s.parser.ShouldJoin(line) {
+			s.pending.entry.Message += "\n" + line
+			s.pending.closed = s.endsEntry(line)
+			continue
+		}
+
+		var next *scannedEntry
+		if entry := s.parser.ParseLine(line, s.lines.num, s.name); entry != nil {
+			next = &scannedEntry{entry: *entry, line: s.lines.num, offset: offset, closed: s.endsEntry(line)}
+		}
+
+		// A new line ends the pending multiline entry
+		if s.pending != nil {
+			s.finish()
+			s.pending = next
+			return true
+		}
+
+		if next == nil {
+			continue
+		}
+
+		// For multiline parsers, start accumulating
+		if s.parser.IsMultiline() {
+			s.pending = next
+			continue
+		}
+
+		s.current = *next
+		return true
+	}
+
+	return s.flush()
+}
+
+// endsEntry reports whether a line is the last line of a multiline entry.
+func (s *EntryScanner) endsEntry(line string) bool {
+	ender, ok := s.parser.(entryEnder)
+	return ok && s.parser.IsMultiline() && ender.EndsEntry(line)
+}
+
+// flush makes a pending multiline entry the current entry.
+// Returns false if nothing was pending.
+func (s *EntryScanner) flush() bool {
+	if s.pending == nil {
+		return false
+	}
+	s.finish()
+	s.pending = nil
+	return true
+}
+
+// finish makes the pending multiline entry the current entry, without the
+// blank lines joined after its last line.
+func (s *EntryScanner) finish() {
+	s.current = *s.pending
+	s.current.entry.Message = strings.TrimRight(s.current.entry.Message, "\n")
+}
+
+// Entry returns the most recent entry produced by Scan.
+func (s *EntryScanner) Entry() source.Entry {
+	return s.current.entry
+}
+
+// Line returns the line number of the first line of the current entry.
+func (s *EntryScanner) Line() int {
+	return s.current.line
+}
+
+// Offset returns the byte offset of the first line of the current entry.
+func (s *EntryScanner) Offset() int64 {
+	return s.current.offset
+}
+
+// Err returns the first error encountered by Scan, including context cancellation.
+func (s *EntryScanner) Err() error {
+	return s.err
+}
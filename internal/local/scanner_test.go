@@ -0,0 +1,342 @@
+package local
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantLines   []string
+		wantOffsets []int64
+	}{
+		{
+			name:        "newline terminated",
+			input:       "a\nbb\nccc\n",
+			wantLines:   []string{"a", "bb", "ccc"},
+			wantOffsets: []int64{0, 2, 5},
+		},
+		{
+			name:        "final line without newline",
+			input:       "a\nbb",
+			wantLines:   []string{"a", "bb"},
+			wantOffsets: []int64{0, 2},
+		},
+		{
+			name:        "CRLF line endings",
+			input:       "a\r\nbb\r\n",
+			wantLines:   []string{"a", "bb"},
+			wantOffsets: []int64{0, 3},
+		},
+		{
+			name:        "blank lines",
+			input:       "a\n\nb\n",
+			wantLines:   []string{"a", "", "b"},
+			wantOffsets: []int64{0, 2, 3},
+		},
+		{
+			name:  "empty input",
+			input: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := NewLineReader(strings.NewReader(tt.input))
+			var lines []string
+			var offsets []int64
+			for {
+				line, offset, err := lr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("next failed: %v", err)
+				}
+				lines = append(lines, line)
+				offsets = append(offsets, offset)
+			}
+
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("expected %d lines, got %d (%q)", len(tt.wantLines), len(lines), lines)
+			}
+			for i := range lines {
+				if lines[i] != tt.wantLines[i] {
+					t.Errorf("line %d: expected %q, got %q", i+1, tt.wantLines[i], lines[i])
+				}
+				if offsets[i] != tt.wantOffsets[i] {
+					t.Errorf("line %d: expected offset %d, got %d", i+1, tt.wantOffsets[i], offsets[i])
+				}
+			}
+			if lr.num != len(tt.wantLines) {
+				t.Errorf("expected line count %d, got %d", len(tt.wantLines), lr.num)
+			}
+		})
+	}
+}
+
+func TestLineReader_TooLong(t *testing.T) {
+	input := "short\n" + strings.Repeat("x", MaxScanTokenSize+1) + "\nafter\n"
+	lr := NewLineReader(strings.NewReader(input))
+
+	if line, _, err := lr.Next(); err != nil || line != "short" {
+		t.Fatalf("expected first line 'short', got %q (err %v)", line, err)
+	}
+	if _, _, err := lr.Next(); err != bufio.ErrTooLong {
+		t.Errorf("expected bufio.ErrTooLong, got %v", err)
+	}
+}
+
+func TestLineReader_Follow(t *testing.T) {
+	r, w := io.Pipe()
+	lr := NewLineReader(r)
+	lr.follow = true
+
+	go func() {
+		_, _ = w.Write([]byte("complete\npart"))
+		_ = w.Close()
+	}()
+
+	if line, _, err := lr.Next(); err != nil || line != "complete" {
+		t.Fatalf("expected 'complete', got %q (err %v)", line, err)
+	}
+	// The partial line is held back in follow mode
+	if _, _, err := lr.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF for partial line, got %v", err)
+	}
+
+	// Completing the line makes it available with its original offset
+	lr.r.Reset(strings.NewReader("ial\n"))
+	line, offset, err := lr.Next()
+	if err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if line != "partial" || offset != 9 {
+		t.Errorf("expected 'partial' at offset 9, got %q at %d", line, offset)
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"a\n", 1},
+		{"a\nb\nc", 2},
+		{"\n\n\n", 3},
+	}
+
+	for _, tt := range tests {
+		got, err := countLines(strings.NewReader(tt.input))
+		if err != nil {
+			t.Fatalf("countLines(%q) failed: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("countLines(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// scanAll collects every entry with its first line number and byte offset.
+func scanAll(t *testing.T, input string, format Format) ([]scannedEntry, error) {
+	t.Helper()
+	scanner := NewEntryScanner(strings.NewReader(input), NewParser(format), "/logs/app.log")
+	var entries []scannedEntry
+	for scanner.Scan(context.Background()) {
+		entries = append(entries, scannedEntry{entry: scanner.Entry(), line: scanner.Line(), offset: scanner.Offset()})
+	}
+	return entries, scanner.Err()
+}
+
+func TestEntryScanner(t *testing.T) {
+	javaTrace := "2025-01-15 10:30:45,123 ERROR [main] com.example.App - boom\n" +
+		"java.lang.IllegalStateException: bad\n" +
+		"\tat com.example.App.run(App.java:10)\n" +
+		"2025-01-15 10:30:46,000 INFO [main] com.example.App - next\n"
+
+	tests := []struct {
+		name         string
+		input        string
+		format       Format
+		wantMessages []string
+		wantLines    []int
+		wantOffsets  []int64
+	}{
+		{
+			name:         "plain lines",
+			input:        "one\ntwo\n",
+			format:       FormatPlain,
+			wantMessages: []string{"one", "two"},
+			wantLines:    []int{1, 2},
+			wantOffsets:  []int64{0, 4},
+		},
+		{
+			name:         "final line without newline",
+			input:        "one\ntwo",
+			format:       FormatPlain,
+			wantMessages: []string{"one", "two"},
+			wantLines:    []int{1, 2},
+			wantOffsets:  []int64{0, 4},
+		},
+		{
+			name:         "CRLF line endings",
+			input:        "one\r\ntwo\r\n",
+			format:       FormatPlain,
+			wantMessages: []string{"one", "two"},
+			wantLines:    []int{1, 2},
+			wantOffsets:  []int64{0, 5},
+		},
+		{
+			name:         "skipped lines keep numbering",
+			input:        "one\n\nthree\n",
+			format:       FormatPlain,
+			wantMessages: []string{"one", "three"},
+			wantLines:    []int{1, 3},
+			wantOffsets:  []int64{0, 5},
+		},
+		{
+			name:   "joined multiline entry",
+			input:  javaTrace,
+			format: FormatJava,
+			wantMessages: []string{
+				"boom\njava.lang.IllegalStateException: bad\n\tat com.example.App.run(App.java:10)",
+				"next",
+			},
+			wantLines:   []int{1, 4},
+			wantOffsets: []int64{0, int64(len(javaTrace) - len("2025-01-15 10:30:46,000 INFO [main] com.example.App - next\n"))},
+		},
+		{
+			name: "multiline entry flushed at EOF",
+			input: "2025-01-15 10:30:45,123 ERROR [main] com.example.App - boom\n" +
+				"\tat com.example.App.run(App.java:10)",
+			format:       FormatJava,
+			wantMessages: []string{"boom\n\tat com.example.App.run(App.java:10)"},
+			wantLines:    []int{1},
+			wantOffsets:  []int64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := scanAll(t, tt.input, tt.format)
+			if err != nil {
+				t.Fatalf("scan failed: %v", err)
+			}
+			if len(entries) != len(tt.wantMessages) {
+				t.Fatalf("expected %d entries, got %d", len(tt.wantMessages), len(entries))
+			}
+			for i, e := range entries {
+				if e.entry.Message != tt.wantMessages[i] {
+					t.Errorf("entry %d: expected message %q, got %q", i, tt.wantMessages[i], e.entry.Message)
+				}
+				if e.line != tt.wantLines[i] {
+					t.Errorf("entry %d: expected line %d, got %d", i, tt.wantLines[i], e.line)
+				}
+				if e.offset != tt.wantOffsets[i] {
+					t.Errorf("entry %d: expected offset %d, got %d", i, tt.wantOffsets[i], e.offset)
+				}
+				wantPtr := source.MakeLocalPtr("/logs/app.log", tt.wantLines[i])
+				if e.entry.Ptr != wantPtr {
+					t.Errorf("entry %d: expected ptr %q, got %q", i, wantPtr, e.entry.Ptr)
+				}
+			}
+		})
+	}
+}
+
+func TestEntryScanner_TooLong(t *testing.T) {
+	input := "ok\n" + strings.Repeat("x", MaxScanTokenSize+1)
+	entries, err := scanAll(t, input, FormatPlain)
+	if err != bufio.ErrTooLong {
+		t.Errorf("expected bufio.ErrTooLong, got %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 entry before the error, got %d", len(entries))
+	}
+}
+
+func TestEntryScanner_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Blank lines never produce entries, so cancellation must be seen per line
+	scanner := NewEntryScanner(strings.NewReader(strings.Repeat("\n", 1000)), NewParser(FormatPlain), "app.log")
+	if scanner.Scan(ctx) {
+		t.Fatal("expected scan to stop when context is cancelled")
+	}
+	if scanner.Err() != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", scanner.Err())
+	}
+}
+
+func TestEntryScanner_FollowKeepsPending(t *testing.T) {
+	ctx := context.Background()
+	first := "2025-01-15 10:30:45,123 ERROR [main] com.example.App - boom\n"
+
+	scanner := NewEntryScanner(strings.NewReader(first), NewParser(FormatJava), "app.log")
+	scanner.lines.follow = true
+
+	// The entry may still get continuation lines, so it is not emitted at EOF
+	if scanner.Scan(ctx) {
+		t.Fatal("expected no entry until the multiline entry is complete")
+	}
+
+	scanner.lines.r.Reset(strings.NewReader("\tat com.example.App.run(App.java:10)\n"))
+	if scanner.Scan(ctx) {
+		t.Fatal("expected continuation line to be joined, not emitted")
+	}
+
+	if !scanner.flush() {
+		t.Fatal("expected flush to return the pending entry")
+	}
+	if got := scanner.Entry().Message; got != "boom\n\tat com.example.App.run(App.java:10)" {
+		t.Errorf("unexpected message %q", got)
+	}
+	if scanner.flush() {
+		t.Error("expected nothing pending after flush")
+	}
+}
+
+func TestSource_GetRecord_Multiline(t *testing.T) {
+	dir := t.TempDir()
+	content := "2025-01-15 10:30:45,123 ERROR [main] com.example.App - boom\n" +
+		"\tat com.example.App.run(App.java:10)\n" +
+		"2025-01-15 10:30:46,000 INFO [main] com.example.App - next\n"
+	path := createTempFile(t, dir, "app.log", content)
+
+	src, err := NewSource(filepath.Join(dir, "app.log"), "java")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	entry, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 1))
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if !strings.Contains(entry.Message, "App.java:10") {
+		t.Errorf("expected stack frame joined into entry, got %q", entry.Message)
+	}
+
+	entry, err = src.GetRecord(ctx, source.MakeLocalPtr(path, 3))
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "next" {
+		t.Errorf("expected 'next', got %q", entry.Message)
+	}
+
+	if _, err := src.GetRecord(ctx, source.MakeLocalPtr(path, 2)); err == nil {
+		t.Error("expected error for pointer to a continuation line")
+	}
+}
@@ -35,6 +35,7 @@ const (
 
 func init() {
 	source.Register("file", openSource)
+	source.Register("stdin", openStdinSource)
 }
 
 // Source implements source.Source for local filesystem logs.
@@ -42,7 +43,6 @@ type Source struct {
 	pattern       string
 	files         []string
 	format        Format
-	parser        Parser
 	uri           string
 	droppedEvents int64 // atomic counter for dropped events during tail
 }
@@ -68,8 +68,14 @@ func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
 
 // NewSource creates a new local file source.
 // The pattern can be a specific file path or a glob pattern.
-// The formatHint specifies the log format (auto, plain, json, syslog, java).
+// The formatHint specifies the log format (auto, plain, json, syslog, java, evtx,
+// alb, elb, cloudfront, cloudtrail, vpcflow, logfmt, common, combined,
+// nginx-error, or a format registered from the config).
 func NewSource(pattern, formatHint string) (*Source, error) {
+	if err := CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
 	// Expand glob pattern
 	files, err := filepath.Glob(pattern)
 	if err != nil {
@@ -88,7 +94,7 @@ func NewSource(pattern, formatHint string) (*Source, error) {
 	sort.Strings(files)
 
 	// Determine format
-	format := parseFormat(formatHint)
+	format := ParseFormat(formatHint)
 	if format == FormatAuto && len(files) > 0 {
 		format = DetectFormat(files[0])
 	}
@@ -97,8 +103,7 @@ func NewSource(pattern, formatHint string) (*Source, error) {
 		pattern: pattern,
 		files:   files,
 		format:  format,
-		parser:  NewParser(format),
-		uri:     pattern,
+		uri:     formatURI(pattern, formatHint),
 	}, nil
 }
 
@@ -108,6 +113,9 @@ func NewSourceFromFiles(files []string, formatHint string) (*Source, error) {
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no files provided")
 	}
+	if err := CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
 
 	// Verify files exist
 	var validFiles []string
@@ -125,7 +133,7 @@ func NewSourceFromFiles(files []string, formatHint string) (*Source, error) {
 	sort.Strings(validFiles)
 
 	// Determine format
-	format := parseFormat(formatHint)
+	format := ParseFormat(formatHint)
 	if format == FormatAuto && len(validFiles) > 0 {
 		format = DetectFormat(validFiles[0])
 	}
@@ -140,8 +148,7 @@ func NewSourceFromFiles(files []string, formatHint string) (*Source, error) {
 		pattern: uri,
 		files:   validFiles,
 		format:  format,
-		parser:  NewParser(format),
-		uri:     uri,
+		uri:     formatURI(uri, formatHint),
 	}, nil
 }
 
@@ -158,6 +165,9 @@ func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source
 
 		entries, err := s.queryFile(ctx, file, params)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return nil, fmt.Errorf("error reading %s: %w", file, err)
 		}
 		results = append(results, entries...)
@@ -191,78 +201,36 @@ func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source
 
 // queryFile reads and filters a single file.
 func (s *Source) queryFile(ctx context.Context, filepath string, params source.QueryParams) ([]source.Entry, error) {
-	f, err := os.Open(filepath)
+	if read := s.format.recordReader(); read != nil {
+		return queryRecords(ctx, read, filepath, params)
+	}
+
+	f, err := OpenLogFile(filepath)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = f.Close() }()
 
+	// Parsers keep state from earlier lines, such as a flow log header, so
+	// each file is read with its own
 	var results []source.Entry
-	scanner := bufio.NewScanner(f)
-
-	// Handle large lines
-	buf := make([]byte, MaxScanTokenSize)
-	scanner.Buffer(buf, MaxScanTokenSize)
-
-	lineNum := 0
-	var currentEntry *source.Entry
-
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
-		lineNum++
-		line := scanner.Text()
-
-		// Handle multiline entries
-		if s.parser.IsMultiline() && currentEntry != nil && s.parser.ShouldJoin(line) {
-			currentEntry.Message += "\n" + line
-			continue
-		}
-
-		// If we had a multiline entry, finalize it
-		if currentEntry != nil {
-			if s.matchesParams(*currentEntry, params) {
-				results = append(results, *currentEntry)
-			}
-			currentEntry = nil
-		}
-
-		// Parse the new line
-		entry := s.parser.ParseLine(line, lineNum, filepath)
-		if entry == nil {
-			continue
-		}
+	scanner := NewEntryScanner(f, NewParser(s.format), filepath)
 
-		// For multiline parsers, start accumulating
-		if s.parser.IsMultiline() {
-			currentEntry = entry
-		} else {
-			if s.matchesParams(*entry, params) {
-				results = append(results, *entry)
-			}
+	for scanner.Scan(ctx) {
+		if entry := scanner.Entry(); MatchesParams(entry, params) {
+			results = append(results, entry)
 		}
 	}
 
-	// Don't forget the last entry for multiline
-	if currentEntry != nil {
-		if s.matchesParams(*currentEntry, params) {
-			results = append(results, *currentEntry)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
 	}
 
 	return results, nil
 }
 
-// matchesParams checks if an entry matches the query parameters.
-func (s *Source) matchesParams(entry source.Entry, params source.QueryParams) bool {
+// MatchesParams checks if an entry matches the query time range and filter.
+func MatchesParams(entry source.Entry, params source.QueryParams) bool {
 	// Time range filter - only apply if entry has a parsed timestamp
 	// Plain text files may not have parseable timestamps, so we skip time filtering for those
 	if !entry.Timestamp.IsZero() {
@@ -293,6 +261,12 @@ func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan sou
 		return nil, fmt.Errorf("tailing multiple files not yet supported; specify a single file")
 	}
 
+	// EVTX files are rewritten chunk by chunk, and CloudTrail files are
+	// written whole, not appended to
+	if s.format.recordReader() != nil {
+		return nil, fmt.Errorf("%s files cannot be followed", s.format)
+	}
+
 	filePath := s.files[0]
 
 	watcher, err := fsnotify.NewWatcher()
@@ -307,6 +281,15 @@ func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan sou
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
+	// Compressed files are rotated archives; new data is never appended in readable form
+	header := make([]byte, 4)
+	n, _ := f.Read(header)
+	if c := DetectCompression(header[:n]); c != CompressionNone {
+		_ = f.Close()
+		_ = watcher.Close()
+		return nil, fmt.Errorf("cannot tail %s-compressed file %s", c, filePath)
+	}
+
 	// Seek to end of file
 	offset, err := f.Seek(0, io.SeekEnd)
 	if err != nil {
@@ -335,27 +318,17 @@ func (s *Source) tailLoop(ctx context.Context, f *os.File, watcher *fsnotify.Wat
 	defer func() { _ = f.Close() }()
 	defer func() { _ = watcher.Close() }()
 
-	reader := bufio.NewReader(f)
-	lineNum := 0
-
 	// Count initial lines (for accurate line numbers)
-	initialOffset := offset
-	_, _ = f.Seek(0, io.SeekStart)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lineNum++
-	}
-	_, _ = f.Seek(initialOffset, io.SeekStart)
-	reader.Reset(f)
-
-	var currentEntry *source.Entry
+	lineNum, _ := countLines(io.NewSectionReader(f, 0, offset))
+	scanner := s.newFollowScanner(f, filePath, lineNum, offset)
 
 	for {
 		select {
 		case <-ctx.Done():
 			// Finalize any pending multiline entry
-			if currentEntry != nil {
-				s.emitEntry(currentEntry, params, events)
+			if scanner.flush() {
+				entry := scanner.Entry()
+				s.emitEntry(&entry, params, events)
 			}
 			return
 
@@ -365,44 +338,16 @@ func (s *Source) tailLoop(ctx context.Context, f *os.File, watcher *fsnotify.Wat
 			}
 
 			if event.Op&fsnotify.Write == fsnotify.Write {
-				// Read new content
-				for {
-					line, err := reader.ReadString('\n')
-					if err != nil {
-						if err == io.EOF {
-							// Partial line - we can't unread it with bufio.Reader,
-							// so we just discard and wait for more data
-							break
-						}
-						return
-					}
-
-					line = strings.TrimRight(line, "\n\r")
-					lineNum++
-
-					// Handle multiline entries
-					if s.parser.IsMultiline() && currentEntry != nil && s.parser.ShouldJoin(line) {
-						currentEntry.Message += "\n" + line
-						continue
-					}
-
-					// Emit previous entry if exists
-					if currentEntry != nil {
-						s.emitEntry(currentEntry, params, events)
-						currentEntry = nil
-					}
-
-					// Parse new line
-					entry := s.parser.ParseLine(line, lineNum, filePath)
-					if entry == nil {
-						continue
-					}
-
-					if s.parser.IsMultiline() {
-						currentEntry = entry
-					} else {
-						s.emitEntry(entry, params, events)
+				// Read new content; a partial trailing line stays buffered until completed
+				for scanner.Scan(ctx) {
+					entry := scanner.Entry()
+					s.emitEntry(&entry, params, events)
+				}
+				if scanner.Err() != nil {
+					if scanner.Err() != context.Canceled {
+						logging.Warn("Stopped tailing %s: %v", filePath, scanner.Err())
 					}
+					return
 				}
 			}
 
@@ -415,8 +360,7 @@ func (s *Source) tailLoop(ctx context.Context, f *os.File, watcher *fsnotify.Wat
 				if err == nil {
 					_ = f.Close()
 					f = newFile
-					reader.Reset(f)
-					lineNum = 0
+					scanner = s.newFollowScanner(f, filePath, 0, 0)
 					// Re-add to watcher
 					_ = watcher.Remove(filePath)
 					_ = watcher.Add(filePath)
@@ -433,6 +377,17 @@ func (s *Source) tailLoop(ctx context.Context, f *os.File, watcher *fsnotify.Wat
 	}
 }
 
+// newFollowScanner creates an entry scanner for a file that is still being written,
+// positioned at the given line number and byte offset.
+func (s *Source) newFollowScanner(f *os.File, filePath string, lineNum int, offset int64) *EntryScanner {
+	_, _ = f.Seek(offset, io.SeekStart)
+	scanner := NewEntryScanner(f, NewParser(s.format), filePath)
+	scanner.lines.follow = true
+	scanner.lines.num = lineNum
+	scanner.lines.offset = offset
+	return scanner
+}
+
 // emitEntry sends an entry to the events channel if it matches the filter.
 func (s *Source) emitEntry(entry *source.Entry, params source.TailParams, events chan<- source.Event) {
 	// Apply filter
@@ -465,95 +420,92 @@ func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, erro
 		return nil, fmt.Errorf("invalid local pointer: %s", ptr)
 	}
 
-	f, err := os.Open(info.FilePath)
+	// EVTX and CloudTrail pointers hold record numbers rather than line numbers
+	if read := s.format.recordReader(); read != nil {
+		return getRecord(ctx, read, info.FilePath, info.LineNum)
+	}
+
+	f, err := OpenLogFile(info.FilePath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
-	scanner := bufio.NewScanner(f)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		if lineNum == info.LineNum {
-			line := scanner.Text()
-			entry := s.parser.ParseLine(line, lineNum, info.FilePath)
-			if entry == nil {
-				// Return a basic entry if parser returns nil
-				return &source.Entry{
-					Message: line,
-					Stream:  filepath.Base(info.FilePath),
-					Source:  info.FilePath,
-					Ptr:     ptr,
-				}, nil
-			}
-			return entry, nil
+	// Scan entries from the start so multiline entries are assembled
+	// the same way Query produced them
+	scanner := NewEntryScanner(f, NewParser(s.format), info.FilePath)
+	for scanner.Scan(ctx) {
+		if scanner.current.line == info.LineNum {
+			entry := scanner.Entry()
+			return &entry, nil
+		}
+		if scanner.current.line > info.LineNum {
+			return nil, fmt.Errorf("no log entry starts at line %d in %s", info.LineNum, info.FilePath)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
 	}
 
 	return nil, fmt.Errorf("line %d not found in %s", info.LineNum, info.FilePath)
 }
 
-// FetchContext retrieves context lines around a log entry.
+// FetchContext retrieves the entries around a log entry. Entries are
+// assembled as Query assembles them, so the lines of a multiline entry are
+// never split across the context.
 func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
 	info, ok := source.ParseLocalPtr(entry.Ptr)
 	if !ok {
 		return nil, nil, fmt.Errorf("invalid local pointer: %s", entry.Ptr)
 	}
 
-	f, err := os.Open(info.FilePath)
-	if err != nil {
-		return nil, nil, err
+	if read := s.format.recordReader(); read != nil {
+		return fetchRecordContext(ctx, read, info.FilePath, info.LineNum, before, after)
 	}
-	defer func() { _ = f.Close() }()
 
-	// Read all lines into memory for context retrieval
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
+	f, err := OpenLogFile(info.FilePath)
+	if err != nil {
 		return nil, nil, err
 	}
+	defer func() { _ = f.Close() }()
 
-	targetIdx := info.LineNum - 1 // Convert to 0-indexed
-	if targetIdx < 0 || targetIdx >= len(lines) {
-		return nil, nil, fmt.Errorf("line %d out of range", info.LineNum)
-	}
+	var beforeEvents, afterEvents []source.Event
+	found := false
+
+	scanner := NewEntryScanner(f, NewParser(s.format), info.FilePath)
+	for scanner.Scan(ctx) {
+		e := scanner.Entry()
+		event := source.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream}
+
+		switch {
+		case found:
+			afterEvents = append(afterEvents, event)
+		case scanner.current.line == info.LineNum:
+			found = true
+		case scanner.current.line > info.LineNum:
+			return nil, nil, fmt.Errorf("no log entry starts at line %d in %s", info.LineNum, info.FilePath)
+		case before > 0:
+			// Keep only the last N entries before the target
+			if len(beforeEvents) == before {
+				beforeEvents = beforeEvents[1:]
+			}
+			beforeEvents = append(beforeEvents, event)
+		}
 
-	// Collect before context
-	var beforeLines []source.Event
-	startIdx := targetIdx - before
-	if startIdx < 0 {
-		startIdx = 0
-	}
-	for i := startIdx; i < targetIdx; i++ {
-		beforeLines = append(beforeLines, source.Event{
-			Message: lines[i],
-			Stream:  filepath.Base(info.FilePath),
-		})
+		if found && len(afterEvents) >= after {
+			break
+		}
 	}
 
-	// Collect after context
-	var afterLines []source.Event
-	endIdx := targetIdx + after + 1
-	if endIdx > len(lines) {
-		endIdx = len(lines)
+	if scanner.Err() != nil {
+		return nil, nil, scanner.Err()
 	}
-	for i := targetIdx + 1; i < endIdx; i++ {
-		afterLines = append(afterLines, source.Event{
-			Message: lines[i],
-			Stream:  filepath.Base(info.FilePath),
-		})
+	if !found {
+		return nil, nil, fmt.Errorf("line %d out of range", info.LineNum)
 	}
 
-	return beforeLines, afterLines, nil
+	return beforeEvents, afterEvents, nil
 }
 
 // ListStreams returns available log files within this source.
@@ -589,6 +541,16 @@ func (s *Source) Metadata() source.SourceMetadata {
 	}
 }
 
+// formatURI returns the URI of a source with its format hint as the format
+// parameter, so sources reopened from its pointers parse entries the same way.
+func formatURI(uri, formatHint string) string {
+	f := ParseFormat(formatHint)
+	if f == FormatAuto {
+		return uri
+	}
+	return uri + "?format=" + url.QueryEscape(f.String())
+}
+
 // Close releases any resources held by the source.
 func (s *Source) Close() error {
 	return nil
@@ -610,6 +572,16 @@ const (
 	FormatJSON
 	FormatSyslog
 	FormatJava
+	FormatEVTX
+	FormatALB
+	FormatELB
+	FormatCloudFront
+	FormatCloudTrail
+	FormatVPCFlow
+	FormatLogfmt
+	FormatCommon
+	FormatCombined
+	FormatNginxError
 )
 
 func (f Format) String() string {
@@ -622,13 +594,36 @@ func (f Format) String() string {
 		return "syslog"
 	case FormatJava:
 		return "java"
+	case FormatEVTX:
+		return "evtx"
+	case FormatALB:
+		return "alb"
+	case FormatELB:
+		return "elb"
+	case FormatCloudFront:
+		return "cloudfront"
+	case FormatCloudTrail:
+		return "cloudtrail"
+	case FormatVPCFlow:
+		return "vpcflow"
+	case FormatLogfmt:
+		return "logfmt"
+	case FormatCommon:
+		return "common"
+	case FormatCombined:
+		return "combined"
+	case FormatNginxError:
+		return "nginx-error"
 	default:
+		if c := f.custom(); c != nil {
+			return c.name
+		}
 		return "auto"
 	}
 }
 
-// parseFormat converts a format string to a Format constant.
-func parseFormat(s string) Format {
+// ParseFormat converts a format string to a Format constant.
+func ParseFormat(s string) Format {
 	switch strings.ToLower(s) {
 	case "json":
 		return FormatJSON
@@ -636,26 +631,64 @@ func parseFormat(s string) Format {
 		return FormatSyslog
 	case "java":
 		return FormatJava
+	case "evtx":
+		return FormatEVTX
+	case "alb":
+		return FormatALB
+	case "elb":
+		return FormatELB
+	case "cloudfront":
+		return FormatCloudFront
+	case "cloudtrail":
+		return FormatCloudTrail
+	case "vpcflow":
+		return FormatVPCFlow
+	case "logfmt":
+		return FormatLogfmt
+	case "common":
+		return FormatCommon
+	case "combined":
+		return FormatCombined
+	case "nginx-error":
+		return FormatNginxError
 	case "plain":
 		return FormatPlain
 	default:
-		return FormatAuto
+		return parseCustomFormat(s)
 	}
 }
 
-// DetectFormat attempts to detect the log format by reading the first few lines.
+// DetectFormat attempts to detect the log format by reading the first few lines
+// of the (decompressed) file. Windows event logs are recognized by their
+// file signature, and CloudTrail files by their Records array.
 func DetectFormat(filepath string) Format {
-	f, err := os.Open(filepath)
+	f, err := OpenLogFile(filepath)
 	if err != nil {
 		return FormatPlain
 	}
 	defer func() { _ = f.Close() }()
 
-	scanner := bufio.NewScanner(f)
+	r := bufio.NewReader(f)
+	if header, _ := r.Peek(len(evtxFileMagic)); isEVTX(header) {
+		return FormatEVTX
+	}
+	if header, _ := r.Peek(cloudTrailDetectSize); isCloudTrail(header) {
+		return FormatCloudTrail
+	}
+	return DetectFormatReader(r)
+}
+
+// DetectFormatReader detects the log format from the first few lines of r.
+func DetectFormatReader(r io.Reader) Format {
+	lines := NewLineReader(r)
 	linesChecked := 0
 
-	for scanner.Scan() && linesChecked < FormatDetectionSampleLines {
-		line := strings.TrimSpace(scanner.Text())
+	for linesChecked < FormatDetectionSampleLines {
+		text, _, err := lines.Next()
+		if err != nil {
+			break
+		}
+		line := strings.TrimSpace(text)
 		if line == "" {
 			continue
 		}
@@ -666,6 +699,33 @@ func DetectFormat(filepath string) Format {
 			return FormatJSON
 		}
 
+		// Check for AWS access logs, whose entries start with fixed fields
+		switch {
+		case isCloudFrontHeader(line) || cloudFrontLinePattern.MatchString(line):
+			return FormatCloudFront
+		case albLinePattern.MatchString(line):
+			return FormatALB
+		case elbLinePattern.MatchString(line):
+			return FormatELB
+		case isVPCFlowHeader(line) || vpcFlowLinePattern.MatchString(line):
+			return FormatVPCFlow
+		}
+
+		// Check for web server logs (e.g., `10.0.0.1 - - [10/Oct/2025:13:55:36 -0700] "GET / HTTP/1.1" 200 512`)
+		switch {
+		case nginxErrorPattern.MatchString(line):
+			return FormatNginxError
+		case combinedLogFormat.pattern.MatchString(line):
+			return FormatCombined
+		case commonLogFormat.pattern.MatchString(line):
+			return FormatCommon
+		}
+
+		// Check for logfmt (e.g., "ts=2025-01-15T10:30:45Z level=info msg=...")
+		if isLogfmtLine(line) {
+			return FormatLogfmt
+		}
+
 		// Check for Java log pattern (e.g., "2025-01-15 10:30:45,123 INFO")
 		if isJavaLogLine(line) {
 			return FormatJava
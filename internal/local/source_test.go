@@ -366,8 +366,9 @@ func TestSource_TypeAndMetadata(t *testing.T) {
 	if meta.Type != "local" {
 		t.Errorf("Metadata.Type = %q, want 'local'", meta.Type)
 	}
-	if meta.URI != path {
-		t.Errorf("Metadata.URI = %q, want %q", meta.URI, path)
+	// The format hint is kept for sources reopened from pointers
+	if want := path + "?format=plain"; meta.URI != want {
+		t.Errorf("Metadata.URI = %q, want %q", meta.URI, want)
 	}
 }
 
@@ -444,6 +445,60 @@ func TestDetectFormat(t *testing.T) {
 			content:  "Jan 15 10:30:45 myhost sshd[1234]: Connection from 10.0.0.1\n",
 			want:     FormatSyslog,
 		},
+		{
+			name:     "alb access log",
+			filename: "alb.log",
+			content:  albLine + "\n",
+			want:     FormatALB,
+		},
+		{
+			name:     "classic elb access log",
+			filename: "elb.log",
+			content:  elbLine + "\n",
+			want:     FormatELB,
+		},
+		{
+			name:     "cloudfront access log",
+			filename: "E2ABC.2024-03-01-10.a1b2c3d4",
+			content:  cloudFrontLog,
+			want:     FormatCloudFront,
+		},
+		{
+			name:     "vpc flow log with header",
+			filename: "flow.log",
+			content:  vpcFlowLog,
+			want:     FormatVPCFlow,
+		},
+		{
+			name:     "vpc flow log exported from CloudWatch",
+			filename: "flow.log",
+			content:  "2 123456789012 eni-0a1b2c3d 10.0.0.5 10.0.1.9 443 52000 6 10 840 1709287200 1709287260 ACCEPT OK\n",
+			want:     FormatVPCFlow,
+		},
+		{
+			name:     "logfmt",
+			filename: "api.log",
+			content:  "ts=2024-03-01T10:00:00Z level=info msg=\"server started\" addr=:8080\n",
+			want:     FormatLogfmt,
+		},
+		{
+			name:     "combined access log",
+			filename: "access.log",
+			content:  combinedLine + "\n",
+			want:     FormatCombined,
+		},
+		{
+			name:     "common access log",
+			filename: "access.log",
+			content:  `10.0.0.1 - - [10/Oct/2025:13:55:36 -0700] "GET / HTTP/1.1" 200 512` + "\n",
+			want:     FormatCommon,
+		},
+		{
+			name:     "nginx error log",
+			filename: "error.log",
+			content:  nginxErrorLine + "\n",
+			want:     FormatNginxError,
+		},
 		{
 			name:     "plain text",
 			filename: "app.log",
@@ -474,15 +529,25 @@ func TestParseFormat(t *testing.T) {
 		{"json", FormatJSON},
 		{"syslog", FormatSyslog},
 		{"java", FormatJava},
+		{"evtx", FormatEVTX},
+		{"alb", FormatALB},
+		{"elb", FormatELB},
+		{"cloudfront", FormatCloudFront},
+		{"cloudtrail", FormatCloudTrail},
+		{"vpcflow", FormatVPCFlow},
+		{"logfmt", FormatLogfmt},
+		{"common", FormatCommon},
+		{"combined", FormatCombined},
+		{"nginx-error", FormatNginxError},
 		{"JAVA", FormatJava},   // case insensitive
 		{"unknown", FormatAuto}, // unknown defaults to auto
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.hint, func(t *testing.T) {
-			got := parseFormat(tt.hint)
+			got := ParseFormat(tt.hint)
 			if got != tt.want {
-				t.Errorf("parseFormat(%q) = %v, want %v", tt.hint, got, tt.want)
+				t.Errorf("ParseFormat(%q) = %v, want %v", tt.hint, got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,216 @@
+package local
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// StdinSource implements source.Source for logs piped to clew on standard
+// input. Input is copied to a spool file as it is read and entries point into
+// that file, so pointers, context lines and `case keep` keep working after
+// the pipe is gone. Spool files are left in the temp directory.
+type StdinSource struct {
+	in         io.Reader
+	formatHint string
+	spool      *os.File
+	file       *Source // source for the spool file, once all input is read
+}
+
+// openStdinSource opens standard input as a source from a parsed URL.
+func openStdinSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return nil, fmt.Errorf("stdin is a terminal; pipe logs to clew instead (e.g., kubectl logs web | clew query -)")
+	}
+
+	return NewStdinSource(os.Stdin, u.Query().Get("format"))
+}
+
+// NewStdinSource creates a source that reads logs from r.
+// The formatHint specifies the log format (auto, plain, json, syslog, java, evtx,
+// alb, elb, cloudfront, vpcflow, logfmt, common, combined, nginx-error, or a
+// format registered from the config).
+func NewStdinSource(r io.Reader, formatHint string) (*StdinSource, error) {
+	if err := CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	spool, err := os.CreateTemp("", "clew-stdin-*.log")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create spool file: %w", err)
+	}
+
+	return &StdinSource{
+		in:         r,
+		formatHint: formatHint,
+		spool:      spool,
+	}, nil
+}
+
+// load reads the rest of the input into the spool file and returns the
+// local source for it.
+func (s *StdinSource) load() (*Source, error) {
+	if s.file != nil {
+		return s.file, nil
+	}
+
+	if _, err := io.Copy(s.spool, s.in); err != nil {
+		return nil, fmt.Errorf("error reading stdin: %w", err)
+	}
+
+	file, err := NewSource(s.spool.Name(), s.formatHint)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return file, nil
+}
+
+// Query reads all input, then returns log entries matching the given parameters.
+func (s *StdinSource) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	file, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Query(ctx, params)
+}
+
+// Tail streams entries as lines arrive on the input, starting with the first
+// line. The channel is closed when the input ends.
+func (s *StdinSource) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	if s.file != nil {
+		return nil, fmt.Errorf("stdin has already been read")
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	go s.tailLoop(ctx, params, events)
+
+	return events, nil
+}
+
+// tailLoop forwards entries read from the input until it ends or the
+// context is cancelled.
+func (s *StdinSource) tailLoop(ctx context.Context, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+
+	// Reads from the pipe block, so they happen in their own goroutine
+	// that is abandoned if the context is cancelled first
+	entries := make(chan source.Entry)
+	go s.scanInput(ctx, entries)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if params.Filter != nil && !params.Filter.MatchString(entry.Message) {
+				continue
+			}
+
+			event := source.Event{
+				Timestamp: entry.Timestamp,
+				Message:   entry.Message,
+				Stream:    entry.Stream,
+			}
+			// Lines without a timestamp are shown with the time they arrived
+			if event.Timestamp.IsZero() {
+				event.Timestamp = time.Now()
+			}
+
+			// Piped input waits for the reader rather than dropping events
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// scanInput parses the input as it is spooled and sends each entry.
+func (s *StdinSource) scanInput(ctx context.Context, entries chan<- source.Entry) {
+	defer close(entries)
+
+	input := bufio.NewReader(io.TeeReader(s.in, s.spool))
+
+	// Detect the format from the first non-blank line without waiting for
+	// more input, which may not arrive for a while
+	format := ParseFormat(s.formatHint)
+	var head strings.Builder
+	for format == FormatAuto {
+		line, err := input.ReadString('\n')
+		head.WriteString(line)
+		if strings.TrimSpace(line) != "" || err != nil {
+			format = DetectFormatReader(strings.NewReader(head.String()))
+		}
+	}
+
+	r := io.MultiReader(strings.NewReader(head.String()), input)
+	scanner := NewEntryScanner(r, NewParser(format), s.spool.Name())
+	for scanner.Scan(ctx) {
+		select {
+		case entries <- scanner.Entry():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *StdinSource) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	file, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.GetRecord(ctx, ptr)
+}
+
+// FetchContext retrieves context lines around a log entry.
+func (s *StdinSource) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	file, err := s.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	return file.FetchContext(ctx, entry, before, after)
+}
+
+// ListStreams returns the spool file holding the input.
+func (s *StdinSource) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	file, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.ListStreams(ctx)
+}
+
+// Type returns the source type identifier.
+func (s *StdinSource) Type() string {
+	return "stdin"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+// The URI is the spool file, where the input can be read again, with the
+// format hint.
+func (s *StdinSource) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "stdin",
+		URI:  formatURI(s.spool.Name(), s.formatHint),
+	}
+}
+
+// Close closes the spool file. The file itself is kept so pointers to its
+// entries stay valid.
+func (s *StdinSource) Close() error {
+	return s.spool.Close()
+}
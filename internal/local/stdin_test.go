@@ -0,0 +1,195 @@
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// newTestStdinSource creates a stdin source reading r, spooling to a test temp dir.
+func newTestStdinSource(t *testing.T, r io.Reader, formatHint string) *StdinSource {
+	t.Helper()
+	t.Setenv("TMPDIR", t.TempDir())
+	src, err := NewStdinSource(r, formatHint)
+	if err != nil {
+		t.Fatalf("NewStdinSource failed: %v", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+	return src
+}
+
+func TestStdinSource_Query(t *testing.T) {
+	input := `{"timestamp":"2025-01-15T10:00:00Z","level":"info","message":"started"}
+{"timestamp":"2025-01-15T10:00:01Z","level":"error","message":"connection refused"}
+{"timestamp":"2025-01-15T10:00:02Z","level":"info","message":"retrying"}
+`
+	src := newTestStdinSource(t, strings.NewReader(input), "")
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		Filter: regexp.MustCompile("refused"),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	// Format is detected from the input, and the entry points into the spool file
+	e := entries[0]
+	if e.Fields["level"] != "error" {
+		t.Errorf("expected level field from JSON, got %v", e.Fields)
+	}
+	spool := src.Metadata().URI
+	if e.Ptr != source.MakeLocalPtr(spool, 2) {
+		t.Errorf("Ptr = %q, want line 2 of %s", e.Ptr, spool)
+	}
+	if filepath.Dir(spool) != os.Getenv("TMPDIR") {
+		t.Errorf("spool file %s not in temp dir", spool)
+	}
+
+	// Querying again reads the spooled input
+	entries, err = src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("second Query failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 entries on second query, got %d", len(entries))
+	}
+}
+
+func TestStdinSource_PointersOutliveSource(t *testing.T) {
+	input := "2025-01-15 10:00:00 first\n2025-01-15 10:00:01 second\n2025-01-15 10:00:02 third\n"
+	src := newTestStdinSource(t, strings.NewReader(input), "plain")
+
+	entries, err := src.Query(context.Background(), source.QueryParams{Filter: regexp.MustCompile("second")})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	_ = src.Close()
+
+	// As with `clew get` in a later invocation, the pointer opens the spool file
+	ptrSrc, err := source.OpenFromPtr(entries[0].Ptr, nil)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	got, err := ptrSrc.GetRecord(context.Background(), entries[0].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if got.Message != entries[0].Message {
+		t.Errorf("Message = %q, want %q", got.Message, entries[0].Message)
+	}
+
+	before, after, err := ptrSrc.FetchContext(context.Background(), *got, 1, 1)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	if len(before) != 1 || !strings.Contains(before[0].Message, "first") {
+		t.Errorf("before = %+v", before)
+	}
+	if len(after) != 1 || !strings.Contains(after[0].Message, "third") {
+		t.Errorf("after = %+v", after)
+	}
+}
+
+func TestStdinSource_Tail(t *testing.T) {
+	pr, pw := io.Pipe()
+	src := newTestStdinSource(t, pr, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("Failed")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	// Entries are emitted as lines arrive, without waiting for the input to end
+	_, _ = pw.Write([]byte("2025-01-15 10:00:00,000 INFO [main] Started\n2025-01-15 10:00:01,000 ERROR [main] Failed\n"))
+	_, _ = pw.Write([]byte("java.lang.IllegalStateException: boom\n\tat com.example.App.main(App.java:10)\n2025-01-15 10:00:02,000 INFO [main] Next\n"))
+
+	select {
+	case event := <-events:
+		if !strings.Contains(event.Message, "Failed") || !strings.Contains(event.Message, "App.java:10") {
+			t.Errorf("Message = %q, want joined stack trace", event.Message)
+		}
+		if event.Timestamp.IsZero() {
+			t.Error("expected timestamp")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tail event")
+	}
+
+	// The channel closes when the input ends
+	_ = pw.Close()
+	select {
+	case event, ok := <-events:
+		if ok {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tail to end")
+	}
+
+	// Everything read while tailing is in the spool file
+	data, err := os.ReadFile(src.Metadata().URI)
+	if err != nil {
+		t.Fatalf("failed to read spool file: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "INFO [main] Next\n") {
+		t.Errorf("spool file = %q", data)
+	}
+}
+
+func TestStdinSource_TailCancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+	src := newTestStdinSource(t, pr, "plain")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := src.Tail(ctx, source.TailParams{})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	// Cancelling ends the tail even while a read is blocked
+	cancel()
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail did not stop after cancel")
+	}
+}
+
+func TestOpenStdinSource_Registered(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	src, err := source.Open("stdin://?format=json")
+	if err != nil {
+		// Under a terminal, stdin is rejected rather than blocking
+		if !strings.Contains(err.Error(), "terminal") {
+			t.Fatalf("Open failed: %v", err)
+		}
+		return
+	}
+	defer func() { _ = src.Close() }()
+
+	s, ok := src.(*StdinSource)
+	if !ok {
+		t.Fatalf("expected *StdinSource, got %T", src)
+	}
+	if s.formatHint != "json" || s.Type() != "stdin" {
+		t.Errorf("formatHint = %q, Type = %q", s.formatHint, s.Type())
+	}
+}
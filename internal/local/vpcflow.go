@@ -0,0 +1,151 @@
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// vpcFlowDefaultFields is the default (version 2) flow log layout, used until
+// a header line names the fields, as in flow logs exported from CloudWatch.
+var vpcFlowDefaultFields = []string{
+	"version", "account-id", "interface-id", "srcaddr", "dstaddr", "srcport", "dstport",
+	"protocol", "packets", "bytes", "start", "end", "action", "log-status",
+}
+
+// vpcFlowFieldNames are the fields custom flow log layouts (versions 2 to 8)
+// can include. A line of only these names is a header.
+var vpcFlowFieldNames = map[string]bool{
+	"version": true, "account-id": true, "interface-id": true, "srcaddr": true, "dstaddr": true,
+	"srcport": true, "dstport": true, "protocol": true, "packets": true, "bytes": true,
+	"start": true, "end": true, "action": true, "log-status": true,
+	// Version 3
+	"vpc-id": true, "subnet-id": true, "instance-id": true, "tcp-flags": true, "type": true,
+	"pkt-srcaddr": true, "pkt-dstaddr": true,
+	// Version 4
+	"region": true, "az-id": true, "sublocation-type": true, "sublocation-id": true,
+	// Version 5
+	"pkt-src-aws-service": true, "pkt-dst-aws-service": true, "flow-direction": true, "traffic-path": true,
+	// Versions 7 and 8
+	"ecs-cluster-arn": true, "ecs-cluster-name": true, "ecs-container-instance-arn": true,
+	"ecs-container-instance-id": true, "ecs-container-id": true, "ecs-second-container-id": true,
+	"ecs-service-name": true, "ecs-task-definition-arn": true, "ecs-task-arn": true, "ecs-task-id": true,
+	"reject-reason": true,
+}
+
+// Default layout entries: "2 123456789012 eni-0a1b2c3d 10.0.0.5 10.0.1.9 443 52000 6 10 840 1709287200 1709287260 ACCEPT OK"
+var vpcFlowLinePattern = regexp.MustCompile(`^2 (?:\d{12}|unknown) (?:eni-[0-9a-f]+|-) \S+ \S+ \S+ \S+ \S+ \S+ \S+ \d{10} \d{10} (?:ACCEPT|REJECT|-) (?:OK|NODATA|SKIPDATA)$`)
+
+// Protocol numbers shown by name in messages
+var ipProtocolNames = map[string]string{"1": "ICMP", "6": "TCP", "17": "UDP", "58": "ICMPv6"}
+
+// VPCFlowParser handles VPC Flow Logs. A header line names the fields of the
+// records that follow it, so custom layouts are read by name.
+type VPCFlowParser struct {
+	fields []string
+}
+
+// isVPCFlowHeader reports whether a line is a flow log header.
+func isVPCFlowHeader(line string) bool {
+	names := strings.Fields(line)
+	if len(names) < 2 {
+		return false
+	}
+	for _, name := range names {
+		if !vpcFlowFieldNames[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *VPCFlowParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	if isVPCFlowHeader(line) {
+		p.fields = strings.Fields(line)
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+		Fields:  make(map[string]string),
+	}
+
+	names := p.fields
+	if names == nil {
+		names = vpcFlowDefaultFields
+	}
+
+	for i, value := range strings.Fields(line) {
+		if i >= len(names) {
+			break
+		}
+		// "-" marks fields that do not apply, such as addresses of NODATA records
+		if value == "-" {
+			continue
+		}
+		entry.Fields[strings.ReplaceAll(names[i], "-", "_")] = value
+	}
+
+	// The start of the capture window is the entry time, or else its end
+	for _, name := range []string{"start", "end"} {
+		if secs, err := strconv.ParseInt(entry.Fields[name], 10, 64); err == nil {
+			entry.Timestamp = time.Unix(secs, 0).UTC()
+			break
+		}
+	}
+
+	if msg := vpcFlowMessage(entry.Fields); msg != "" {
+		entry.Message = msg
+	}
+
+	return entry
+}
+
+// vpcFlowMessage summarises a flow record, e.g.
+// "REJECT TCP 203.0.113.9:52000 -> 10.0.0.5:22 (3 packets, 180 bytes)",
+// or returns "" if the record has no addresses.
+func vpcFlowMessage(fields map[string]string) string {
+	src, dst := fields["srcaddr"], fields["dstaddr"]
+	if src == "" || dst == "" {
+		return ""
+	}
+	if port := fields["srcport"]; port != "" {
+		src += ":" + port
+	}
+	if port := fields["dstport"]; port != "" {
+		dst += ":" + port
+	}
+
+	var parts []string
+	if action := fields["action"]; action != "" {
+		parts = append(parts, action)
+	}
+	if proto := fields["protocol"]; proto != "" {
+		if name, ok := ipProtocolNames[proto]; ok {
+			proto = name
+		} else {
+			proto = "protocol " + proto
+		}
+		parts = append(parts, proto)
+	}
+	parts = append(parts, src, "->", dst)
+	if fields["packets"] != "" && fields["bytes"] != "" {
+		parts = append(parts, fmt.Sprintf("(%s packets, %s bytes)", fields["packets"], fields["bytes"]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p *VPCFlowParser) IsMultiline() bool        { return false }
+func (p *VPCFlowParser) ShouldJoin(string) bool { return false }
@@ -0,0 +1,143 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// vpcFlowLog is a custom version 5 layout as delivered to S3, with its header.
+const vpcFlowLog = "version vpc-id subnet-id interface-id srcaddr dstaddr srcport dstport protocol packets bytes start end action tcp-flags log-status flow-direction\n" +
+	"5 vpc-0a1b subnet-0c2d eni-0a1b2c3d 203.0.113.9 10.0.0.5 52000 22 6 3 180 1709287200 1709287260 REJECT 2 OK ingress\n" +
+	"5 vpc-0a1b subnet-0c2d eni-0a1b2c3d 10.0.0.5 10.0.1.9 443 52001 6 10 840 1709287210 1709287270 ACCEPT 19 OK egress\n" +
+	"5 vpc-0a1b subnet-0c2d eni-0a1b2c3d - - - - - - - 1709287220 1709287280 - - NODATA -\n"
+
+func TestVPCFlowParser_DefaultLayout(t *testing.T) {
+	p := &VPCFlowParser{}
+	entry := p.ParseLine("2 123456789012 eni-0a1b2c3d 10.0.0.5 10.0.1.9 443 52000 17 10 840 1709287200 1709287260 ACCEPT OK", 4, "/logs/flow.log")
+	if entry == nil {
+		t.Fatal("ParseLine returned nil")
+	}
+
+	if !entry.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != "ACCEPT UDP 10.0.0.5:443 -> 10.0.1.9:52000 (10 packets, 840 bytes)" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	wantFields := map[string]string{
+		"account_id":   "123456789012",
+		"interface_id": "eni-0a1b2c3d",
+		"srcaddr":      "10.0.0.5",
+		"dstport":      "52000",
+		"protocol":     "17",
+		"bytes":        "840",
+		"end":          "1709287260",
+		"action":       "ACCEPT",
+		"log_status":   "OK",
+	}
+	for k, v := range wantFields {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, entry.Fields[k], v)
+		}
+	}
+}
+
+func TestVPCFlowParser_Header(t *testing.T) {
+	p := &VPCFlowParser{}
+	if entry := p.ParseLine("version srcaddr dstaddr action start", 1, "/logs/flow.log"); entry != nil {
+		t.Fatal("header line should be skipped")
+	}
+
+	entry := p.ParseLine("4 10.0.0.5 10.0.1.9 REJECT 1709287200", 2, "/logs/flow.log")
+	if entry.Message != "REJECT 10.0.0.5 -> 10.0.1.9" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	if entry.Fields["action"] != "REJECT" || entry.Fields["version"] != "4" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+	if entry.Timestamp.IsZero() {
+		t.Error("Timestamp not set from start")
+	}
+}
+
+func TestSource_VPCFlow(t *testing.T) {
+	path := createTempFile(t, t.TempDir(), "flow.log", vpcFlowLog)
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	// Time filtering uses the start of the capture window
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: time.Date(2024, 3, 1, 10, 0, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	nodata := entries[0]
+	if nodata.Fields["log_status"] != "NODATA" || nodata.Message != "5 vpc-0a1b subnet-0c2d eni-0a1b2c3d - - - - - - - 1709287220 1709287280 - - NODATA -" {
+		t.Errorf("NODATA entry = %+v", nodata)
+	}
+	if _, ok := nodata.Fields["srcaddr"]; ok {
+		t.Error("srcaddr should be omitted for \"-\"")
+	}
+	if entries[1].Fields["flow_direction"] != "egress" || entries[1].Fields["tcp_flags"] != "19" {
+		t.Errorf("Fields = %v", entries[1].Fields)
+	}
+
+	rejected, err := src.Query(context.Background(), source.QueryParams{Filter: regexp.MustCompile(`REJECT`)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rejected) != 1 || rejected[0].Message != "REJECT TCP 203.0.113.9:52000 -> 10.0.0.5:22 (3 packets, 180 bytes)" {
+		t.Errorf("rejected = %+v", rejected)
+	}
+	if rejected[0].Ptr != source.MakeLocalPtr(path, 2) {
+		t.Errorf("Ptr = %q", rejected[0].Ptr)
+	}
+}
+
+func TestSource_VPCFlow_HeaderPerFile(t *testing.T) {
+	dir := t.TempDir()
+	createTempFile(t, dir, "a.log", "version srcaddr dstaddr action start\n"+
+		"4 10.0.0.5 10.0.1.9 REJECT 1709287200\n")
+	createTempFile(t, dir, "b.log", "version start action dstaddr srcaddr\n"+
+		"5 1709287210 ACCEPT 10.0.2.7 10.0.0.6\n")
+	// A file without a header has the default layout, whatever the file
+	// before it declared
+	createTempFile(t, dir, "c.log", "2 123456789012 eni-0a1b2c3d 10.0.0.7 10.0.1.9 443 52000 17 10 840 1709287220 1709287260 ACCEPT OK\n")
+
+	src, err := NewSource(filepath.Join(dir, "*.log"), "vpcflow")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want := []string{
+		"ACCEPT UDP 10.0.0.7:443 -> 10.0.1.9:52000 (10 packets, 840 bytes)",
+		"ACCEPT 10.0.0.6 -> 10.0.2.7",
+		"REJECT 10.0.0.5 -> 10.0.1.9",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Message != want[i] {
+			t.Errorf("entries[%d].Message = %q, want %q", i, e.Message, want[i])
+		}
+	}
+	if entries[0].Fields["account_id"] != "123456789012" || entries[1].Fields["version"] != "5" {
+		t.Errorf("Fields = %v, %v", entries[0].Fields, entries[1].Fields)
+	}
+}
@@ -0,0 +1,230 @@
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Built-in access log layouts, in nginx log_format syntax. Apache writes the
+// ident field that nginx fills with "-", so it is kept as a variable.
+const (
+	commonLogLayout   = `$remote_addr $ident $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	combinedLogLayout = commonLogLayout + ` "$http_referer" "$http_user_agent"`
+)
+
+var (
+	commonLogFormat   = mustCompileLogFormat(commonLogLayout)
+	combinedLogFormat = mustCompileLogFormat(combinedLogLayout)
+)
+
+// Variables of a log_format definition: $name or ${name}
+var logFormatVariable = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// logFormat is an access log layout compiled from an nginx log_format definition.
+type logFormat struct {
+	pattern *regexp.Regexp
+	names   []string // variable captured by each group
+}
+
+// compileLogFormat compiles an nginx log_format definition, such as
+// `$remote_addr [$time_local] "$request" $status $upstream_response_time`,
+// into a pattern matching the lines it writes. Each value runs up to the
+// character that follows its variable in the layout, so quoted and
+// bracketed values may contain spaces. Text after the layout is ignored.
+func compileLogFormat(layout string) (*logFormat, error) {
+	vars := logFormatVariable.FindAllStringSubmatchIndex(layout, -1)
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("no $variables in %q", layout)
+	}
+
+	f := &logFormat{}
+	var b strings.Builder
+	b.WriteString("^")
+	prev := 0
+	for _, loc := range vars {
+		b.WriteString(regexp.QuoteMeta(layout[prev:loc[0]]))
+		prev = loc[1]
+
+		var name string
+		if loc[2] >= 0 {
+			name = layout[loc[2]:loc[3]]
+		} else {
+			name = layout[loc[4]:loc[5]]
+		}
+		f.names = append(f.names, name)
+
+		switch next, _ := utf8.DecodeRuneInString(layout[loc[1]:]); {
+		case loc[1] == len(layout):
+			b.WriteString(`(\S*)`)
+		case next == '$':
+			// Adjacent variables have nothing to separate them
+			b.WriteString(`(.*?)`)
+		default:
+			b.WriteString(`([^` + regexp.QuoteMeta(string(next)) + `]*)`)
+		}
+	}
+	b.WriteString(regexp.QuoteMeta(layout[prev:]))
+
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", layout, err)
+	}
+	f.pattern = pattern
+	return f, nil
+}
+
+// mustCompileLogFormat compiles a built-in layout.
+func mustCompileLogFormat(layout string) *logFormat {
+	f, err := compileLogFormat(layout)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// AccessLogParser handles web server access logs written in the layout of an
+// nginx log_format definition, which includes Apache's common and combined
+// formats. Variables become fields by name, such as remote_addr, status and
+// upstream_response_time.
+type AccessLogParser struct {
+	format *logFormat
+}
+
+func (p *AccessLogParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	if line == "" {
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+	}
+
+	matches := p.format.pattern.FindStringSubmatch(line)
+	if matches == nil {
+		// Not in the layout, treat as plain text
+		return entry
+	}
+
+	entry.Fields = make(map[string]string, len(p.format.names)+3)
+	for i, name := range p.format.names {
+		value := matches[i+1]
+		// Servers write "-" for values that are not set
+		if value == "-" || value == "" {
+			continue
+		}
+
+		switch name {
+		case "time_local":
+			if ts, err := time.Parse("02/Jan/2006:15:04:05 -0700", value); err == nil {
+				entry.Timestamp = ts
+				continue
+			}
+		case "time_iso8601":
+			if ts, err := time.Parse(time.RFC3339, value); err == nil {
+				entry.Timestamp = ts
+				continue
+			}
+		case "msec":
+			if secs, err := strconv.ParseFloat(value, 64); err == nil {
+				entry.Timestamp = time.UnixMilli(int64(secs * 1000))
+				continue
+			}
+		case "request":
+			addRequestFields(value, entry.Fields)
+		}
+		entry.Fields[name] = value
+	}
+
+	if request := entry.Fields["request"]; request != "" {
+		entry.Message = request
+		if status := entry.Fields["status"]; status != "" {
+			entry.Message += " " + status
+		}
+	}
+
+	return entry
+}
+
+// addRequestFields splits a request line such as "GET /index.html HTTP/1.1"
+// into the request_method, request_uri and server_protocol fields.
+func addRequestFields(request string, fields map[string]string) {
+	if parts := strings.SplitN(request, " ", 3); len(parts) == 3 {
+		fields["request_method"] = parts[0]
+		fields["request_uri"] = parts[1]
+		fields["server_protocol"] = parts[2]
+	}
+}
+
+func (p *AccessLogParser) IsMultiline() bool        { return false }
+func (p *AccessLogParser) ShouldJoin(string) bool { return false }
+
+// NginxErrorParser handles nginx error logs.
+type NginxErrorParser struct{}
+
+// "2025/10/10 13:55:36 [error] 1234#5678: *99 open() "/srv/x" failed (2: No such file or directory), client: 10.0.0.1, ..."
+var nginxErrorPattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`)
+
+// Details nginx appends to error messages: ", client: 10.0.0.1, server: example.com, request: "GET / HTTP/1.1""
+var nginxErrorDetail = regexp.MustCompile(`, (client|server|subrequest|request|upstream|host|referrer): ("(?:[^"\\]|\\.)*"|[^,]*)`)
+
+func (p *NginxErrorParser) ParseLine(line string, lineNum int, filePath string) *source.Entry {
+	if line == "" {
+		return nil
+	}
+
+	entry := &source.Entry{
+		Message: line,
+		Stream:  filepath.Base(filePath),
+		Source:  filePath,
+		Ptr:     source.MakeLocalPtr(filePath, lineNum),
+		Fields:  make(map[string]string),
+	}
+
+	matches := nginxErrorPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return entry
+	}
+
+	// nginx writes error log times in the server's local time
+	if ts, err := time.ParseInLocation("2006/01/02 15:04:05", matches[1], time.Local); err == nil {
+		entry.Timestamp = ts
+	}
+	entry.Fields["level"] = matches[2]
+	entry.Fields["pid"] = matches[3]
+	entry.Fields["tid"] = matches[4]
+	if matches[5] != "" {
+		entry.Fields["connection"] = matches[5]
+	}
+
+	message := matches[6]
+	if details := nginxErrorDetail.FindAllStringSubmatchIndex(message, -1); details != nil {
+		for _, loc := range details {
+			name := message[loc[2]:loc[3]]
+			value := message[loc[4]:loc[5]]
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			if name == "request" {
+				addRequestFields(value, entry.Fields)
+			}
+			entry.Fields[name] = value
+		}
+		message = message[:details[0][0]]
+	}
+	entry.Message = message
+
+	return entry
+}
+
+func (p *NginxErrorParser) IsMultiline() bool        { return false }
+func (p *NginxErrorParser) ShouldJoin(string) bool { return false }
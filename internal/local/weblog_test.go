@@ -0,0 +1,187 @@
+package local
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+const (
+	combinedLine   = `203.0.113.9 - frank [10/Oct/2025:13:55:36 -0700] "GET /apache_pb.gif?x=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`
+	nginxErrorLine = `2025/10/10 13:55:36 [error] 1234#5678: *99 open() "/srv/www/favicon.ico" failed (2: No such file or directory), client: 203.0.113.9, server: example.com, request: "GET /favicon.ico HTTP/1.1", host: "example.com"`
+)
+
+func TestCompileLogFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  string
+		line    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "quoted and bracketed values",
+			layout: `$remote_addr [$time_local] "$request" $status`,
+			line:   `10.0.0.1 [10/Oct/2025:13:55:36 -0700] "GET / HTTP/1.1" 404`,
+			want:   map[string]string{"remote_addr": "10.0.0.1", "time_local": "10/Oct/2025:13:55:36 -0700", "request": "GET / HTTP/1.1", "status": "404"},
+		},
+		{
+			name:   "braced variables and literal text",
+			layout: `${host}:${server_port} rt=$request_time uct="$upstream_connect_time"`,
+			line:   `example.com:443 rt=0.052 uct="0.004"`,
+			want:   map[string]string{"host": "example.com", "server_port": "443", "request_time": "0.052", "upstream_connect_time": "0.004"},
+		},
+		{
+			name:    "no variables",
+			layout:  `static text`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := compileLogFormat(tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileLogFormat error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			matches := f.pattern.FindStringSubmatch(tt.line)
+			if matches == nil {
+				t.Fatalf("pattern %s does not match %q", f.pattern, tt.line)
+			}
+			for i, name := range f.names {
+				if matches[i+1] != tt.want[name] {
+					t.Errorf("%s = %q, want %q", name, matches[i+1], tt.want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLogParser_Combined(t *testing.T) {
+	p := NewParser(FormatCombined)
+	entry := p.ParseLine(combinedLine, 1, "/var/log/apache2/access.log")
+
+	if !entry.Timestamp.Equal(time.Date(2025, 10, 10, 20, 55, 36, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != "GET /apache_pb.gif?x=1 HTTP/1.0 200" {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	wantFields := map[string]string{
+		"remote_addr":     "203.0.113.9",
+		"remote_user":     "frank",
+		"request_method":  "GET",
+		"request_uri":     "/apache_pb.gif?x=1",
+		"server_protocol": "HTTP/1.0",
+		"status":          "200",
+		"body_bytes_sent": "2326",
+		"http_referer":    "http://www.example.com/start.html",
+		"http_user_agent": "Mozilla/4.08 [en] (Win98; I ;Nav)",
+	}
+	for k, v := range wantFields {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, entry.Fields[k], v)
+		}
+	}
+	for _, k := range []string{"ident", "time_local"} {
+		if _, ok := entry.Fields[k]; ok {
+			t.Errorf("Fields[%q] should be omitted", k)
+		}
+	}
+
+	// Lines in another layout are kept as plain text
+	entry = p.ParseLine("not an access log line", 2, "/var/log/apache2/access.log")
+	if entry.Message != "not an access log line" || !entry.Timestamp.IsZero() {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestNginxErrorParser(t *testing.T) {
+	p := &NginxErrorParser{}
+	entry := p.ParseLine(nginxErrorLine, 1, "/var/log/nginx/error.log")
+
+	if !entry.Timestamp.Equal(time.Date(2025, 10, 10, 13, 55, 36, 0, time.Local)) {
+		t.Errorf("Timestamp = %v", entry.Timestamp)
+	}
+	if entry.Message != `open() "/srv/www/favicon.ico" failed (2: No such file or directory)` {
+		t.Errorf("Message = %q", entry.Message)
+	}
+	wantFields := map[string]string{
+		"level":          "error",
+		"pid":            "1234",
+		"tid":            "5678",
+		"connection":     "99",
+		"client":         "203.0.113.9",
+		"server":         "example.com",
+		"request":        "GET /favicon.ico HTTP/1.1",
+		"request_method": "GET",
+		"request_uri":    "/favicon.ico",
+		"host":           "example.com",
+	}
+	for k, v := range wantFields {
+		if entry.Fields[k] != v {
+			t.Errorf("Fields[%q] = %q, want %q", k, entry.Fields[k], v)
+		}
+	}
+
+	entry = p.ParseLine("2025/10/10 13:55:37 [notice] 1#1: signal process started", 2, "/var/log/nginx/error.log")
+	if entry.Message != "signal process started" || entry.Fields["level"] != "notice" {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestRegisterLogFormat(t *testing.T) {
+	layout := `$remote_addr [$time_local] "$request" $status $request_time $upstream_response_time`
+	if err := RegisterLogFormat("Upstream", layout); err != nil {
+		t.Fatalf("RegisterLogFormat failed: %v", err)
+	}
+
+	f := ParseFormat("upstream")
+	if f == FormatAuto || f.String() != "upstream" {
+		t.Fatalf("ParseFormat(upstream) = %v", f)
+	}
+	entry := NewParser(f).ParseLine(`10.0.0.1 [10/Oct/2025:13:55:36 +0000] "POST /api HTTP/2.0" 502 1.204 1.201`, 1, "/logs/upstream.log")
+	if entry.Fields["upstream_response_time"] != "1.201" || entry.Fields["request_time"] != "1.204" || entry.Message != "POST /api HTTP/2.0 502" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	if err := RegisterLogFormat("combined", layout); err == nil {
+		t.Error("built-in format names should be rejected")
+	}
+	if err := RegisterLogFormat("broken", "no variables"); err == nil {
+		t.Error("layouts without variables should be rejected")
+	}
+	if ParseFormat("broken") != FormatAuto {
+		t.Error("failed formats should not be registered")
+	}
+}
+
+func TestSource_Combined(t *testing.T) {
+	content := `10.0.0.1 - - [10/Oct/2025:13:55:30 -0700] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"
+10.0.0.2 - - [10/Oct/2025:13:55:36 -0700] "GET /missing HTTP/1.1" 404 0 "-" "curl/8.0"
+10.0.0.1 - - [10/Oct/2025:13:55:40 -0700] "POST /login HTTP/1.1" 500 42 "https://example.com/" "Mozilla/5.0"
+`
+	path := createTempFile(t, t.TempDir(), "access.log", content)
+	src, err := NewSource(path, "")
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+
+	// Time ranges apply now that timestamps are parsed
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: time.Date(2025, 10, 10, 20, 55, 35, 0, time.UTC),
+		Filter:    regexp.MustCompile(`GET`),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Fields["status"] != "404" || entries[0].Fields["remote_addr"] != "10.0.0.2" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
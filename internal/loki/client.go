@@ -0,0 +1,234 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Client configuration values
+const (
+	// RequestTimeout is the maximum time to wait for a Loki HTTP API request
+	RequestTimeout = 60 * time.Second
+
+	// MaxTailMessageSize is the largest tail websocket message accepted
+	MaxTailMessageSize = 16 * 1024 * 1024
+
+	// maxErrorBodySize is how much of an error response body is included in errors
+	maxErrorBodySize = 1024
+)
+
+// Stream is a set of log lines sharing the same labels.
+type Stream struct {
+	Labels map[string]string
+	Values []Value
+}
+
+// Value is a single log line with its nanosecond timestamp.
+type Value struct {
+	Timestamp int64 // Unix nanoseconds, as Loki reports them
+	Line      string
+}
+
+// Client calls the Loki HTTP API. Credentials are read from the same
+// environment variables as logcli: LOKI_USERNAME and LOKI_PASSWORD for basic
+// auth, or LOKI_BEARER_TOKEN.
+type Client struct {
+	baseURL     string // e.g. http://localhost:3100
+	orgID       string // X-Scope-OrgID tenant, for multi-tenant Loki
+	username    string
+	password    string
+	bearerToken string
+	http        *http.Client
+}
+
+// NewClient creates a client for the Loki server at baseURL.
+func NewClient(baseURL, orgID string) *Client {
+	return &Client{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		orgID:       orgID,
+		username:    os.Getenv("LOKI_USERNAME"),
+		password:    os.Getenv("LOKI_PASSWORD"),
+		bearerToken: os.Getenv("LOKI_BEARER_TOKEN"),
+		http:        &http.Client{Timeout: RequestTimeout},
+	}
+}
+
+// QueryRange runs a LogQL log query over [start, end) and returns the
+// matching streams. Direction is "backward" (newest first) or "forward".
+func (c *Client) QueryRange(ctx context.Context, query string, start, end time.Time, limit int, direction string) ([]Stream, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+
+	var resp struct {
+		Data struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, "/loki/api/v1/query_range", params, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("loki returned %q results; only log queries are supported", resp.Data.ResultType)
+	}
+
+	return decodeStreams(resp.Data.Result)
+}
+
+// Series returns the label sets of streams matching a selector within [start, end).
+func (c *Client) Series(ctx context.Context, match string, start, end time.Time) ([]map[string]string, error) {
+	params := url.Values{}
+	params.Set("match[]", match)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+
+	var resp struct {
+		Data []map[string]string `json:"data"`
+	}
+	if err := c.get(ctx, "/loki/api/v1/series", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// get performs a GET request against the API and decodes the JSON response.
+func (c *Client) get(ctx context.Context, path string, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header = c.header()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("loki returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid loki response: %w", err)
+	}
+	return nil
+}
+
+// header returns the tenant and authentication headers for a request.
+func (c *Client) header() http.Header {
+	h := http.Header{}
+	if c.orgID != "" {
+		h.Set("X-Scope-OrgID", c.orgID)
+	}
+	if c.bearerToken != "" {
+		h.Set("Authorization", "Bearer "+c.bearerToken)
+	} else if c.username != "" {
+		req := &http.Request{Header: h}
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return h
+}
+
+// TailConn is an open tail websocket.
+type TailConn struct {
+	conn *websocket.Conn
+}
+
+// Tail opens a tail websocket for a LogQL query, starting at start.
+func (c *Client) Tail(ctx context.Context, query string, start time.Time) (*TailConn, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+
+	wsURL := c.baseURL + "/loki/api/v1/tail?" + params.Encode()
+	if strings.HasPrefix(wsURL, "https://") {
+		wsURL = "wss://" + strings.TrimPrefix(wsURL, "https://")
+	} else {
+		wsURL = "ws://" + strings.TrimPrefix(wsURL, "http://")
+	}
+
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{HTTPHeader: c.header()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open loki tail: %w", err)
+	}
+	conn.SetReadLimit(MaxTailMessageSize)
+
+	return &TailConn{conn: conn}, nil
+}
+
+// Next waits for the next batch of tailed streams.
+func (t *TailConn) Next(ctx context.Context) ([]Stream, error) {
+	_, data, err := t.conn.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg struct {
+		Streams json.RawMessage `json:"streams"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid loki tail message: %w", err)
+	}
+	if len(msg.Streams) == 0 {
+		return nil, nil
+	}
+	return decodeStreams(msg.Streams)
+}
+
+// Close closes the websocket.
+func (t *TailConn) Close() error {
+	return t.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// decodeStreams decodes a Loki streams result. Each value is an array of
+// timestamp and line, optionally followed by structured metadata.
+func decodeStreams(data json.RawMessage) ([]Stream, error) {
+	var raw []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid loki streams: %w", err)
+	}
+
+	streams := make([]Stream, 0, len(raw))
+	for _, r := range raw {
+		stream := Stream{Labels: r.Stream}
+		for _, v := range r.Values {
+			if len(v) < 2 {
+				return nil, fmt.Errorf("invalid loki value: %d elements", len(v))
+			}
+			var ts, line string
+			if err := json.Unmarshal(v[0], &ts); err != nil {
+				return nil, fmt.Errorf("invalid loki timestamp: %w", err)
+			}
+			if err := json.Unmarshal(v[1], &line); err != nil {
+				return nil, fmt.Errorf("invalid loki line: %w", err)
+			}
+			ns, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid loki timestamp %q: %w", ts, err)
+			}
+			stream.Values = append(stream.Values, Value{Timestamp: ns, Line: line})
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
@@ -0,0 +1,475 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// DefaultQueryWindow is how far back queries without a start time search,
+	// matching Loki's own default
+	DefaultQueryWindow = time.Hour
+
+	// QueryPageSize is the number of lines requested per query_range call
+	QueryPageSize = 1000
+
+	// ContextLookbackWindow bounds how far before or after an entry context lines are searched
+	ContextLookbackWindow = time.Hour
+
+	// StreamListWindow is how far back ListStreams looks for active streams
+	StreamListWindow = 24 * time.Hour
+
+	// TailReconnectInterval is how long to wait before reopening a dropped tail connection
+	TailReconnectInterval = 5 * time.Second
+)
+
+func init() {
+	source.Register("loki", openSource)
+}
+
+// Source implements source.Source for Grafana Loki. The URI path is a LogQL
+// stream selector, and pointers name a stream by its full label set and a
+// line by its nanosecond timestamp.
+type Source struct {
+	client        *Client
+	host          string
+	selector      string // LogQL stream selector, e.g. {app="api"}
+	uri           string
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// openSource opens a Loki source from a parsed URL:
+// loki://host:3100/{app="api"}?tls=true&org=tenant
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf(`loki URI needs a host, e.g. loki://localhost:3100/{app="api"}`)
+	}
+
+	selector := strings.TrimPrefix(u.Path, "/")
+	if selector == "" {
+		return nil, fmt.Errorf(`loki URI needs a stream selector, e.g. loki://%s/{app="api"}`, u.Host)
+	}
+
+	query := u.Query()
+	useTLS := false
+	if v := query.Get("tls"); v != "" {
+		var err error
+		if useTLS, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid loki tls value %q", v)
+		}
+	}
+
+	return NewSource(u.Host, selector, useTLS, query.Get("org"))
+}
+
+// NewSource creates a Loki source for the server at host and a LogQL stream
+// selector. OrgID sets the tenant for multi-tenant Loki.
+func NewSource(host, selector string, useTLS bool, orgID string) (*Source, error) {
+	selector = strings.TrimSpace(selector)
+	if !strings.HasPrefix(selector, "{") || !strings.HasSuffix(selector, "}") {
+		return nil, fmt.Errorf("invalid loki stream selector %q: expected {label=\"value\", ...}", selector)
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+
+	return &Source{
+		client:   NewClient(scheme+"://"+host, orgID),
+		host:     host,
+		selector: selector,
+		uri:      buildURI(host, selector, useTLS, orgID),
+	}, nil
+}
+
+// uriEscaper escapes the characters of a selector that would otherwise end
+// the URI path. The rest is kept readable for aliases and case files.
+var uriEscaper = strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23")
+
+// buildURI builds the canonical URI for a source, keeping the TLS and tenant
+// settings so sources reopened from cached pointers reach the same server.
+func buildURI(host, selector string, useTLS bool, orgID string) string {
+	uri := "loki://" + host + "/" + uriEscaper.Replace(selector)
+
+	query := url.Values{}
+	if orgID != "" {
+		query.Set("org", orgID)
+	}
+	if useTLS {
+		query.Set("tls", "true")
+	}
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return uri
+}
+
+// buildQuery builds the LogQL query for the query parameters. A source-specific
+// query starting with { replaces the selector; otherwise it is appended to it
+// as pipeline stages (e.g. `| json | status >= 500`).
+func (s *Source) buildQuery(params source.QueryParams) string {
+	query := s.selector
+	if q := strings.TrimSpace(params.Query); q != "" {
+		if strings.HasPrefix(q, "{") {
+			query = q
+		} else {
+			query += " " + q
+		}
+	}
+	if params.Filter != nil {
+		// LogQL regexes use RE2 syntax, like Go's regexp package
+		query += " |~ " + quoteLogQL(params.Filter.String())
+	}
+	return query
+}
+
+// quoteLogQL quotes a string for LogQL, preferring backticks so regexes need
+// no extra escaping.
+func quoteLogQL(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// formatLabels formats a label set as a stream selector with sorted labels,
+// as Loki prints stream labels.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Query returns log entries matching the given parameters, newest first.
+// Results are paged backwards through the time range until the limit is
+// reached or the range is exhausted.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	end := params.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := params.StartTime
+	if start.IsZero() {
+		start = end.Add(-DefaultQueryWindow)
+	}
+	// query_range treats end as exclusive; include lines logged at the end time
+	end = end.Add(time.Nanosecond)
+
+	// Pages are not shrunk to the lines still needed, as each page after the
+	// first starts with lines already seen
+	pageSize := QueryPageSize
+	if params.Limit > 0 && params.Limit < pageSize {
+		pageSize = params.Limit
+	}
+
+	query := s.buildQuery(params)
+	seen := make(map[string]bool)
+	var results []source.Entry
+
+	for start.Before(end) {
+		streams, err := s.client.QueryRange(ctx, query, start, end, pageSize, "backward")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("error reading %s: %w", s.uri, err)
+		}
+
+		page := s.convertStreams(streams)
+		sortNewestFirst(page)
+
+		added := 0
+		for _, entry := range page {
+			// Pages overlap at the oldest timestamp of the previous page
+			key := entry.Ptr + "\x00" + entry.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			results = append(results, entry)
+			added++
+		}
+
+		if len(page) < pageSize || added == 0 || (params.Limit > 0 && len(results) >= params.Limit) {
+			break
+		}
+
+		// Continue from the oldest line, including it in case other lines share its timestamp
+		end = page[len(page)-1].Timestamp.Add(time.Nanosecond)
+	}
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// convertStreams flattens Loki streams into entries. Stream labels become fields.
+func (s *Source) convertStreams(streams []Stream) []source.Entry {
+	var entries []source.Entry
+	for _, stream := range streams {
+		selector := formatLabels(stream.Labels)
+		for _, v := range stream.Values {
+			fields := make(map[string]string, len(stream.Labels))
+			for name, value := range stream.Labels {
+				fields[name] = value
+			}
+			entries = append(entries, source.Entry{
+				Timestamp: time.Unix(0, v.Timestamp),
+				Message:   v.Line,
+				Stream:    selector,
+				Source:    "loki://" + s.host,
+				Ptr:       source.MakeLokiPtr(s.host, selector, v.Timestamp),
+				Fields:    fields,
+			})
+		}
+	}
+	return entries
+}
+
+// sortNewestFirst sorts entries newest first, keeping Loki's order for equal timestamps.
+func sortNewestFirst(entries []source.Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+}
+
+// Tail streams new log lines over Loki's tail websocket. A dropped connection
+// is reopened from the last line received.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	start := time.Now()
+	conn, err := s.client.Tail(ctx, s.selector, start)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	go s.tailLoop(ctx, conn, start, params, events)
+
+	return events, nil
+}
+
+// tailLoop reads tail messages until the context is cancelled.
+func (s *Source) tailLoop(ctx context.Context, conn *TailConn, start time.Time, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	for {
+		if conn == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(TailReconnectInterval):
+			}
+
+			var err error
+			if conn, err = s.client.Tail(ctx, s.selector, start); err != nil {
+				logging.Debug("Failed to reopen loki tail: %v", err)
+				continue
+			}
+		}
+
+		streams, err := conn.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logging.Warn("Loki tail connection lost, reconnecting: %v", err)
+			_ = conn.Close()
+			conn = nil
+			continue
+		}
+
+		entries := s.convertStreams(streams)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		})
+		for i := range entries {
+			s.emitEntry(&entries[i], params, events)
+			if next := entries[i].Timestamp.Add(time.Nanosecond); next.After(start) {
+				start = next
+			}
+		}
+	}
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, params source.TailParams, events chan<- source.Event) {
+	// Apply filter
+	if params.Filter != nil && !params.Filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseLokiPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid Loki pointer: %s", ptr)
+	}
+
+	ts := time.Unix(0, info.Timestamp)
+	streams, err := s.client.QueryRange(ctx, info.Selector, ts, ts.Add(time.Nanosecond), QueryPageSize, "forward")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range s.convertStreams(streams) {
+		if entry.Ptr == ptr {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("log line not found in %s at %s (it may have passed the retention period)", info.Selector, ts.UTC().Format(time.RFC3339Nano))
+}
+
+// FetchContext retrieves the lines logged before and after an entry in the
+// same stream, searching up to ContextLookbackWindow either side.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseLokiPtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid Loki pointer: %s", entry.Ptr)
+	}
+	ts := time.Unix(0, info.Timestamp)
+
+	var beforeLines, afterLines []source.Event
+
+	if before > 0 {
+		streams, err := s.client.QueryRange(ctx, info.Selector, ts.Add(-ContextLookbackWindow), ts, before, "backward")
+		if err != nil {
+			return nil, nil, err
+		}
+		entries := s.convertStreams(streams)
+		sortNewestFirst(entries)
+		for _, e := range entries {
+			if len(beforeLines) == before {
+				break
+			}
+			beforeLines = append([]source.Event{toEvent(e)}, beforeLines...)
+		}
+	}
+
+	if after > 0 {
+		from := ts.Add(time.Nanosecond)
+		streams, err := s.client.QueryRange(ctx, info.Selector, from, from.Add(ContextLookbackWindow), after, "forward")
+		if err != nil {
+			return nil, nil, err
+		}
+		entries := s.convertStreams(streams)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		})
+		for _, e := range entries {
+			if len(afterLines) == after {
+				break
+			}
+			afterLines = append(afterLines, toEvent(e))
+		}
+	}
+
+	return beforeLines, afterLines, nil
+}
+
+// toEvent converts an entry to a context event.
+func toEvent(e source.Entry) source.Event {
+	return source.Event{
+		Timestamp: e.Timestamp,
+		Message:   e.Message,
+		Stream:    e.Stream,
+	}
+}
+
+// ListStreams returns the streams matching the selector that received logs
+// within StreamListWindow.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	end := time.Now()
+	series, err := s.client.Series(ctx, s.selector, end.Add(-StreamListWindow), end)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make([]source.StreamInfo, len(series))
+	for i, labels := range series {
+		streams[i] = source.StreamInfo{Name: formatLabels(labels)}
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Name < streams[j].Name
+	})
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "loki"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "loki",
+		URI:  s.uri,
+	}
+}
+
+// Close releases resources. Tail connections are closed when their context ends.
+func (s *Source) Close() error {
+	return nil
+}
@@ -0,0 +1,437 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// testLine is a log line stored by the fake Loki server.
+type testLine struct {
+	labels map[string]string
+	ts     int64
+	line   string
+}
+
+// fakeLoki serves the query_range, series and tail endpoints over a fixed set
+// of lines. Selectors support equality matchers and a single |~ line filter.
+type fakeLoki struct {
+	lines []testLine
+
+	mu      sync.Mutex
+	queries []url.Values
+	headers []http.Header
+}
+
+var (
+	matcherPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	filterPattern  = regexp.MustCompile("\\|~ `([^`]*)`")
+)
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.queries = append(f.queries, r.URL.Query())
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/loki/api/v1/query_range":
+		f.queryRange(w, r)
+	case "/loki/api/v1/series":
+		f.series(w, r)
+	case "/loki/api/v1/tail":
+		f.tail(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// matching returns the lines matching a query within [start, end).
+func (f *fakeLoki) matching(query string, start, end int64) []testLine {
+	selector, _, _ := strings.Cut(query, "}")
+	var filter *regexp.Regexp
+	if m := filterPattern.FindStringSubmatch(query); m != nil {
+		filter = regexp.MustCompile(m[1])
+	}
+
+	var result []testLine
+	for _, l := range f.lines {
+		if l.ts < start || l.ts >= end {
+			continue
+		}
+		ok := true
+		for _, m := range matcherPattern.FindAllStringSubmatch(selector, -1) {
+			if l.labels[m[1]] != m[2] {
+				ok = false
+			}
+		}
+		if ok && (filter == nil || filter.MatchString(l.line)) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+func (f *fakeLoki) queryRange(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+	end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	lines := f.matching(q.Get("query"), start, end)
+	sort.SliceStable(lines, func(i, j int) bool {
+		if q.Get("direction") == "forward" {
+			return lines[i].ts < lines[j].ts
+		}
+		return lines[i].ts > lines[j].ts
+	})
+	if len(lines) > limit {
+		lines = lines[:limit]
+	}
+
+	writeJSON(w, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"resultType": "streams",
+			"result":     toStreams(lines),
+		},
+	})
+}
+
+func (f *fakeLoki) series(w http.ResponseWriter, r *http.Request) {
+	seen := make(map[string]bool)
+	var data []map[string]string
+	for _, l := range f.matching(r.URL.Query().Get("match[]"), 0, 1<<62) {
+		if key := formatLabels(l.labels); !seen[key] {
+			seen[key] = true
+			data = append(data, l.labels)
+		}
+	}
+	writeJSON(w, map[string]any{"status": "success", "data": data})
+}
+
+// tail sends every stored line in one message, then waits for the client to close.
+func (f *fakeLoki) tail(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	data, _ := json.Marshal(map[string]any{"streams": toStreams(f.lines)})
+	if err := conn.Write(r.Context(), websocket.MessageText, data); err != nil {
+		return
+	}
+	_, _, _ = conn.Read(r.Context())
+}
+
+// toStreams groups lines into Loki's streams result format.
+func toStreams(lines []testLine) []map[string]any {
+	var streams []map[string]any
+	index := make(map[string]int)
+	for _, l := range lines {
+		key := formatLabels(l.labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, map[string]any{"stream": l.labels, "values": [][]string{}})
+		}
+		streams[i]["values"] = append(streams[i]["values"].([][]string), []string{strconv.FormatInt(l.ts, 10), l.line})
+	}
+	return streams
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// base is the time of the first test line.
+var base = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// newTestSource starts a fake Loki server and opens a source for selector against it.
+func newTestSource(t *testing.T, f *fakeLoki, selector string) *Source {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	src, err := source.Open("loki://" + host + "/" + selector + "?org=acme")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return src.(*Source)
+}
+
+// apiLines returns n lines of the api stream, one second apart, and a line of
+// another stream between each.
+func apiLines(n int) []testLine {
+	var lines []testLine
+	for i := 0; i < n; i++ {
+		ts := base.Add(time.Duration(i) * time.Second).UnixNano()
+		lines = append(lines,
+			testLine{labels: map[string]string{"app": "api", "env": "prod"}, ts: ts, line: "api line " + strconv.Itoa(i)},
+			testLine{labels: map[string]string{"app": "web", "env": "prod"}, ts: ts + 1, line: "web line " + strconv.Itoa(i)},
+		)
+	}
+	return lines
+}
+
+func TestSource_QueryPaging(t *testing.T) {
+	lines := apiLines(QueryPageSize + 500)
+	// A second line sharing the timestamp at the page boundary must not be lost or repeated
+	boundary := lines[2*500]
+	lines = append(lines, testLine{labels: boundary.labels, ts: boundary.ts, line: "api line at boundary"})
+
+	f := &fakeLoki{lines: lines}
+	src := newTestSource(t, f, `{app="api"}`)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: base,
+		EndTime:   base.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(entries) != QueryPageSize+501 {
+		t.Fatalf("expected %d entries, got %d", QueryPageSize+501, len(entries))
+	}
+	if len(f.queries) < 2 {
+		t.Errorf("expected results to be paged, got %d request(s)", len(f.queries))
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		if i > 0 && e.Timestamp.After(entries[i-1].Timestamp) {
+			t.Fatalf("entries not sorted newest first at %d", i)
+		}
+		if seen[e.Message] {
+			t.Fatalf("duplicate entry %q", e.Message)
+		}
+		seen[e.Message] = true
+	}
+	if !seen["api line at boundary"] || !seen["api line 0"] {
+		t.Error("expected every line of the range")
+	}
+
+	// The tenant is sent with every request
+	if got := f.headers[0].Get("X-Scope-OrgID"); got != "acme" {
+		t.Errorf("X-Scope-OrgID = %q, want acme", got)
+	}
+}
+
+func TestSource_QueryFilterAndLabels(t *testing.T) {
+	f := &fakeLoki{lines: []testLine{
+		{labels: map[string]string{"app": "api", "pod": "api-1"}, ts: base.UnixNano(), line: "started"},
+		{labels: map[string]string{"app": "api", "pod": "api-2"}, ts: base.Add(time.Second).UnixNano(), line: "connection refused"},
+		{labels: map[string]string{"app": "api", "pod": "api-1"}, ts: base.Add(2 * time.Second).UnixNano(), line: "retrying"},
+	}}
+	src := newTestSource(t, f, `{app="api"}`)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: base,
+		EndTime:   base.Add(time.Minute),
+		Filter:    regexp.MustCompile("refused|timeout"),
+		Limit:     10,
+		Context:   1,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// The filter is pushed down to Loki as a line filter
+	if got := f.queries[0].Get("query"); got != "{app=\"api\"} |~ `refused|timeout`" {
+		t.Errorf("query = %q", got)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Fields["pod"] != "api-2" || e.Fields["app"] != "api" {
+		t.Errorf("expected labels as fields, got %v", e.Fields)
+	}
+	if e.Stream != `{app="api", pod="api-2"}` {
+		t.Errorf("Stream = %q", e.Stream)
+	}
+	if !e.Timestamp.Equal(base.Add(time.Second)) {
+		t.Errorf("Timestamp = %v", e.Timestamp)
+	}
+
+	// Context comes from the entry's own stream, not the other pod
+	if len(e.Context.Before) != 0 || len(e.Context.After) != 0 {
+		t.Errorf("expected no context from other streams, got %+v", e.Context)
+	}
+}
+
+func TestSource_GetRecordAndContext(t *testing.T) {
+	f := &fakeLoki{lines: apiLines(5)}
+	src := newTestSource(t, f, `{env="prod"}`)
+
+	ptr := source.MakeLokiPtr(src.host, `{app="api", env="prod"}`, base.Add(2*time.Second).UnixNano())
+	entry, err := src.GetRecord(context.Background(), ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "api line 2" || entry.Ptr != ptr {
+		t.Errorf("GetRecord = %q (%s)", entry.Message, entry.Ptr)
+	}
+
+	before, after, err := src.FetchContext(context.Background(), *entry, 2, 5)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	var got []string
+	for _, e := range append(before, after...) {
+		got = append(got, e.Message)
+	}
+	want := "api line 0,api line 1,api line 3,api line 4"
+	if strings.Join(got, ",") != want {
+		t.Errorf("context = %v, want %s", got, want)
+	}
+
+	if _, err := src.GetRecord(context.Background(), source.MakeLokiPtr(src.host, `{app="api", env="prod"}`, base.Add(-time.Hour).UnixNano())); err == nil {
+		t.Error("expected error for a line that does not exist")
+	}
+}
+
+func TestSource_PointerReopens(t *testing.T) {
+	f := &fakeLoki{lines: apiLines(3)}
+	src := newTestSource(t, f, `{app="api"}`)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{StartTime: base, EndTime: base.Add(time.Minute), Limit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query = %d entries, %v", len(entries), err)
+	}
+
+	// As with `clew get` in a later invocation, the pointer and cached metadata reopen the source
+	meta := src.Metadata()
+	reopened, err := source.OpenFromPtr(entries[0].Ptr, &meta)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	got, err := reopened.GetRecord(context.Background(), entries[0].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if got.Message != "api line 2" {
+		t.Errorf("Message = %q, want newest line", got.Message)
+	}
+	if reopened.Metadata().URI != `loki://`+src.host+`/{app="api", env="prod"}?org=acme` {
+		t.Errorf("URI = %q", reopened.Metadata().URI)
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	f := &fakeLoki{lines: apiLines(2)}
+	src := newTestSource(t, f, `{env="prod"}`)
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 2 || streams[0].Name != `{app="api", env="prod"}` || streams[1].Name != `{app="web", env="prod"}` {
+		t.Errorf("ListStreams = %+v", streams)
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	f := &fakeLoki{lines: apiLines(2)}
+	src := newTestSource(t, f, `{app="api"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("^web")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			got = append(got, event.Message)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for tail events, got %v", got)
+		}
+	}
+	if got[0] != "web line 0" || got[1] != "web line 1" {
+		t.Errorf("tail events = %v, want filtered lines oldest first", got)
+	}
+
+	cancel()
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail did not stop after cancel")
+	}
+}
+
+func TestOpenSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr bool
+		wantURI string
+	}{
+		{
+			name:    "selector with regex matcher",
+			uri:     `loki://localhost:3100/{app=~"api|web", env!="dev"}`,
+			wantURI: `loki://localhost:3100/{app=~"api|web", env!="dev"}`,
+		},
+		{
+			name:    "escaped question mark in selector",
+			uri:     `loki://loki.example.com/{path=~"/v1%3F.*"}?tls=true&org=acme`,
+			wantURI: `loki://loki.example.com/{path=~"/v1%3F.*"}?org=acme&tls=true`,
+		},
+		{
+			name:    "no selector",
+			uri:     "loki://localhost:3100",
+			wantErr: true,
+		},
+		{
+			name:    "not a selector",
+			uri:     "loki://localhost:3100/app",
+			wantErr: true,
+		},
+		{
+			name:    "invalid tls value",
+			uri:     `loki://localhost:3100/{app="api"}?tls=maybe`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.Open(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s", tt.uri)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if got := src.Metadata().URI; got != tt.wantURI {
+				t.Errorf("URI = %q, want %q", got, tt.wantURI)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of OTLP/HTTP requests and responses
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// defaultService is the stream of log records without a service.name
+// resource attribute, as OpenTelemetry SDKs name an unnamed service.
+const defaultService = "unknown_service"
+
+// handleLogs receives an OTLP/HTTP log export request.
+func (s *Source) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	data, err := readBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if contentType == contentTypeJSON {
+		err = unmarshalJSON(data, &req)
+	} else {
+		err = proto.Unmarshal(data, &req)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid export request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.store(entries(&req, time.Now()))
+
+	var resp []byte
+	if contentType == contentTypeJSON {
+		resp, err = protojson.Marshal(&collogspb.ExportLogsServiceResponse{})
+	} else {
+		resp, err = proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(resp)
+}
+
+// readBody reads a request body, decompressing it if it is gzip encoded.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var body io.Reader = http.MaxBytesReader(w, r.Body, MaxRequestSize)
+
+	switch encoding := r.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		body = io.LimitReader(gz, MaxRequestSize+1)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request: %w", err)
+	}
+	if len(data) > MaxRequestSize {
+		return nil, fmt.Errorf("request exceeds the %d byte limit", MaxRequestSize)
+	}
+	return data, nil
+}
+
+// unmarshalJSON decodes an OTLP/JSON export request. OTLP/JSON differs from
+// the protobuf JSON mapping in encoding trace and span IDs as hex rather than
+// base64, so those are converted before decoding.
+func unmarshalJSON(data []byte, req *collogspb.ExportLogsServiceRequest) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	for _, rl := range objects(doc, "resourceLogs", "resource_logs") {
+		for _, sl := range objects(rl, "scopeLogs", "scope_logs") {
+			for _, lr := range objects(sl, "logRecords", "log_records") {
+				for _, key := range []string{"traceId", "trace_id", "spanId", "span_id"} {
+					id, ok := lr[key].(string)
+					if !ok {
+						continue
+					}
+					raw, err := hex.DecodeString(id)
+					if err != nil {
+						return fmt.Errorf("invalid %s %q: %w", key, id, err)
+					}
+					lr[key] = base64.StdEncoding.EncodeToString(raw)
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, req)
+}
+
+// objects returns the objects in the array under a key of doc, given in its
+// lowerCamelCase and original protobuf spellings.
+func objects(doc map[string]any, keys ...string) []map[string]any {
+	var objs []map[string]any
+	for _, key := range keys {
+		items, _ := doc[key].([]any)
+		for _, item := range items {
+			if obj, ok := item.(map[string]any); ok {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs
+}
+
+// entries converts the log records of an export request into entries.
+// Records without a timestamp get the time they were received.
+func entries(req *collogspb.ExportLogsServiceRequest, now time.Time) []source.Entry {
+	var result []source.Entry
+	for _, rl := range req.GetResourceLogs() {
+		resource := make(map[string]string)
+		flattenAttributes(resource, "", rl.GetResource().GetAttributes())
+		service := resource["service.name"]
+		if service == "" {
+			service = defaultService
+		}
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				fields := make(map[string]string, len(resource))
+				for k, v := range resource {
+					fields[k] = v
+				}
+				flattenAttributes(fields, "", lr.GetAttributes())
+
+				if name := sl.GetScope().GetName(); name != "" {
+					fields["scope"] = name
+				}
+				if severity := severityText(lr); severity != "" {
+					fields["severity"] = severity
+				}
+				if lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+					fields["severity_number"] = strconv.Itoa(int(lr.GetSeverityNumber()))
+				}
+				if id := lr.GetTraceId(); len(id) > 0 {
+					fields["trace_id"] = hex.EncodeToString(id)
+				}
+				if id := lr.GetSpanId(); len(id) > 0 {
+					fields["span_id"] = hex.EncodeToString(id)
+				}
+				if name := lr.GetEventName(); name != "" {
+					fields["event_name"] = name
+				}
+
+				result = append(result, source.Entry{
+					Timestamp: recordTime(lr, now),
+					Message:   valueString(lr.GetBody()),
+					Stream:    service,
+					Fields:    fields,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// recordTime returns when a log record's event happened, or else when the
+// collector observed it, or else now.
+func recordTime(lr *logspb.LogRecord, now time.Time) time.Time {
+	if ns := lr.GetTimeUnixNano(); ns != 0 {
+		return time.Unix(0, int64(ns))
+	}
+	if ns := lr.GetObservedTimeUnixNano(); ns != 0 {
+		return time.Unix(0, int64(ns))
+	}
+	return now
+}
+
+// severityText returns a log record's severity text, or else the short name
+// of its severity number (e.g., "WARN" for SEVERITY_NUMBER_WARN2).
+func severityText(lr *logspb.LogRecord) string {
+	if text := lr.GetSeverityText(); text != "" {
+		return text
+	}
+	n := int(lr.GetSeverityNumber())
+	if n < 1 || n > 24 {
+		return ""
+	}
+	return []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}[(n-1)/4]
+}
+
+// flattenAttributes adds attributes to fields, naming nested key-value lists
+// with dotted keys.
+func flattenAttributes(fields map[string]string, prefix string, attrs []*commonpb.KeyValue) {
+	for _, kv := range attrs {
+		name := kv.GetKey()
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if kvlist, ok := kv.GetValue().GetValue().(*commonpb.AnyValue_KvlistValue); ok {
+			flattenAttributes(fields, name, kvlist.KvlistValue.GetValues())
+			continue
+		}
+		fields[name] = valueString(kv.GetValue())
+	}
+}
+
+// valueString renders a value as text: strings as they are, arrays and
+// key-value lists as JSON.
+func valueString(v *commonpb.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(nativeValue(v))
+	return string(data)
+}
+
+// nativeValue converts a value to the Go value it encodes as in JSON.
+func nativeValue(v *commonpb.AnyValue) any {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return x.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return x.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return x.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return x.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(x.ArrayValue.GetValues()))
+		for _, item := range x.ArrayValue.GetValues() {
+			values = append(values, nativeValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(x.KvlistValue.GetValues()))
+		for _, kv := range x.KvlistValue.GetValues() {
+			values[kv.GetKey()] = nativeValue(kv.GetValue())
+		}
+		return values
+	}
+	return nil
+}
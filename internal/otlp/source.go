@@ -0,0 +1,370 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultBufferSize is how many log records the receiver keeps in memory
+	DefaultBufferSize = 10000
+
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// DefaultPort is the standard OTLP/HTTP port, used when the URI has none
+	DefaultPort = "4318"
+
+	// LogsPath is the path OTLP/HTTP exporters send logs to
+	LogsPath = "/v1/logs"
+
+	// MaxRequestSize is the largest export request the receiver accepts,
+	// after decompression (16MB)
+	MaxRequestSize = 16 * 1024 * 1024
+)
+
+func init() {
+	source.Register("otlp", openSource)
+}
+
+// Source implements source.Source for an OTLP/HTTP log receiver. Received
+// log records are kept in a ring buffer of the last bufferSize records.
+type Source struct {
+	addr       string
+	uri        string
+	bufferSize int
+	server     *http.Server
+	serveErr   chan error
+
+	mu            sync.Mutex
+	ring          []source.Entry
+	received      int64 // records received, the sequence number of the newest
+	tails         map[chan source.Event]*regexp.Regexp
+	closed        bool
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// openSource opens an OTLP receiver from a parsed URL.
+// URL format: otlp://host:port?buffer=10000 (an empty host listens on all interfaces)
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	port := u.Port()
+	if port == "" {
+		port = DefaultPort
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	bufferSize := DefaultBufferSize
+	if v := u.Query().Get("buffer"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid buffer size %q: must be a positive number of log records", v)
+		}
+		bufferSize = n
+	}
+
+	return NewSource(addr, bufferSize)
+}
+
+// NewSource starts an OTLP/HTTP receiver listening on addr that keeps the
+// last bufferSize log records.
+func NewSource(addr string, bufferSize int) (*Source, error) {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %w", addr, err)
+	}
+
+	s := &Source{
+		addr:       ln.Addr().String(),
+		bufferSize: bufferSize,
+		ring:       make([]source.Entry, bufferSize),
+		tails:      make(map[chan source.Event]*regexp.Regexp),
+		serveErr:   make(chan error, 1),
+	}
+	s.uri = s.buildURI()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(LogsPath, s.handleLogs)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		s.serveErr <- s.server.Serve(ln)
+	}()
+
+	logging.Debug("OTLP receiver listening on http://%s%s", s.addr, LogsPath)
+	return s, nil
+}
+
+// buildURI returns the URI of the source, with the address it listens on.
+func (s *Source) buildURI() string {
+	u := url.URL{Scheme: "otlp", Host: s.addr}
+	if s.bufferSize != DefaultBufferSize {
+		u.RawQuery = url.Values{"buffer": {strconv.Itoa(s.bufferSize)}}.Encode()
+	}
+	return u.String()
+}
+
+// store buffers received log records and sends them to the running tails.
+func (s *Source) store(entries []source.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	for i := range entries {
+		s.received++
+		entries[i].Ptr = source.MakeOTLPPtr(s.addr, s.received)
+		entries[i].Source = s.uri
+		s.ring[(s.received-1)%int64(s.bufferSize)] = entries[i]
+
+		for events, filter := range s.tails {
+			s.emitEntry(&entries[i], filter, events)
+		}
+	}
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, filter *regexp.Regexp, events chan<- source.Event) {
+	// Apply filter
+	if filter != nil && !filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// buffered returns the log records in the ring buffer, oldest first.
+func (s *Source) buffered() []source.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.received - int64(s.bufferSize) + 1
+	if first < 1 {
+		first = 1
+	}
+	entries := make([]source.Entry, 0, s.received-first+1)
+	for seq := first; seq <= s.received; seq++ {
+		entries = append(entries, s.ring[(seq-1)%int64(s.bufferSize)])
+	}
+	return entries
+}
+
+// lookup returns the buffered log record with a sequence number, and the
+// buffered records from the same service around it.
+func (s *Source) lookup(seq int64, before, after int) (*source.Entry, []source.Event, []source.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.received - int64(s.bufferSize) + 1
+	if seq < first || seq > s.received {
+		return nil, nil, nil, fmt.Errorf("log record %d is no longer buffered (the receiver keeps the last %d records)", seq, s.bufferSize)
+	}
+	if first < 1 {
+		first = 1
+	}
+
+	at := func(seq int64) source.Entry {
+		return s.ring[(seq-1)%int64(s.bufferSize)]
+	}
+	entry := at(seq)
+
+	var beforeEvents, afterEvents []source.Event
+	for n := seq - 1; n >= first && len(beforeEvents) < before; n-- {
+		if e := at(n); e.Stream == entry.Stream {
+			beforeEvents = append([]source.Event{{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream}}, beforeEvents...)
+		}
+	}
+	for n := seq + 1; n <= s.received && len(afterEvents) < after; n++ {
+		if e := at(n); e.Stream == entry.Stream {
+			afterEvents = append(afterEvents, source.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream})
+		}
+	}
+
+	return &entry, beforeEvents, afterEvents, nil
+}
+
+// Query returns buffered log records matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	var results []source.Entry
+	for _, entry := range s.buffered() {
+		if local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Tail streams log records as they are received.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("OTLP receiver is closed")
+	}
+	s.tails[events] = params.Filter
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.tails[events]; ok {
+			delete(s.tails, events)
+			close(events)
+		}
+	}()
+
+	return events, nil
+}
+
+// GetRecord retrieves a single log record by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseOTLPPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid OTLP pointer: %s", ptr)
+	}
+	if info.Addr != s.addr {
+		return nil, fmt.Errorf("pointer %s is from another receiver than %s", ptr, s.uri)
+	}
+
+	entry, _, _, err := s.lookup(info.Seq, 0, 0)
+	return entry, err
+}
+
+// FetchContext retrieves the buffered log records from the same service
+// around a log record.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseOTLPPtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid OTLP pointer: %s", entry.Ptr)
+	}
+
+	_, beforeEvents, afterEvents, err := s.lookup(info.Seq, before, after)
+	return beforeEvents, afterEvents, err
+}
+
+// ListStreams returns the services log records were received from.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	byService := make(map[string]int)
+	var streams []source.StreamInfo
+	for _, e := range s.buffered() {
+		i, ok := byService[e.Stream]
+		if !ok {
+			i = len(streams)
+			byService[e.Stream] = i
+			streams = append(streams, source.StreamInfo{Name: e.Stream})
+		}
+		info := &streams[i]
+		info.Size += int64(len(e.Message))
+		if info.FirstTime.IsZero() || e.Timestamp.Before(info.FirstTime) {
+			info.FirstTime = e.Timestamp
+		}
+		if e.Timestamp.After(info.LastTime) {
+			info.LastTime = e.Timestamp
+		}
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Name < streams[j].Name
+	})
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "otlp"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "otlp",
+		URI:  s.uri,
+	}
+}
+
+// Close stops the receiver and ends any running tails.
+func (s *Source) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for events := range s.tails {
+		delete(s.tails, events)
+		close(events)
+	}
+	s.mu.Unlock()
+
+	if err := s.server.Close(); err != nil {
+		return err
+	}
+	if err := <-s.serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// openReceiver starts a receiver on a free local port.
+func openReceiver(t *testing.T, bufferSize int) *Source {
+	t.Helper()
+	src, err := NewSource("127.0.0.1:0", bufferSize)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := src.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return src
+}
+
+// post sends an export request body and returns the response status.
+func post(t *testing.T, src *Source, contentType, encoding string, body []byte) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://"+src.addr+LogsPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// testRequest returns an export request with records from the checkout service.
+func testRequest() *collogspb.ExportLogsServiceRequest {
+	ts := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: stringValue("checkout")},
+				{Key: "host.name", Value: stringValue("web1")},
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope: &commonpb.InstrumentationScope{Name: "checkout.payments"},
+				LogRecords: []*logspb.LogRecord{
+					{
+						TimeUnixNano:   uint64(ts.UnixNano()),
+						SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+						Body:           stringValue("order placed"),
+					},
+					{
+						TimeUnixNano:   uint64(ts.Add(time.Second).UnixNano()),
+						SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2,
+						SeverityText:   "Error",
+						Body:           stringValue("card declined"),
+						TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+						SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+						Attributes: []*commonpb.KeyValue{
+							{Key: "http.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 402}}},
+							{Key: "card", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+								Values: []*commonpb.KeyValue{{Key: "brand", Value: stringValue("visa")}},
+							}}}},
+						},
+					},
+				},
+			}},
+		}},
+	}
+}
+
+// testJSON is an OTLP/JSON export request from the inventory service, with
+// hex trace and span IDs and a record without a timestamp.
+const testJSON = `{
+  "resourceLogs": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "inventory"}}]},
+    "scopeLogs": [{
+      "logRecords": [
+        {
+          "timeUnixNano": "1736935205000000000",
+          "severityNumber": 13,
+          "body": {"stringValue": "stock low"},
+          "traceId": "5b8efff798038103d269b633813fc60c",
+          "spanId": "eee19b7ec3c1b174",
+          "attributes": [{"key": "sku", "value": {"stringValue": "A-42"}}]
+        },
+        {"body": {"kvlistValue": {"values": [{"key": "count", "value": {"intValue": "3"}}]}}}
+      ]
+    }]
+  }]
+}`
+
+func TestSource_Receive(t *testing.T) {
+	src := openReceiver(t, DefaultBufferSize)
+	ctx := context.Background()
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("declined|stock")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	data, err := proto.Marshal(testRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, _ = gz.Write(data)
+	_ = gz.Close()
+
+	if status := post(t, src, "application/x-protobuf", "gzip", gzipped.Bytes()); status != http.StatusOK {
+		t.Fatalf("protobuf export: status %d", status)
+	}
+	if status := post(t, src, "application/json", "", []byte(testJSON)); status != http.StatusOK {
+		t.Fatalf("JSON export: status %d", status)
+	}
+
+	for _, want := range []string{"card declined", "stock low"} {
+		select {
+		case ev := <-events:
+			if ev.Message != want {
+				t.Errorf("expected tail event %q, got %+v", want, ev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	results, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("declined|stock")})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Stream != "inventory" || results[1].Stream != "checkout" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	wantFields := map[string]string{
+		"service.name":     "checkout",
+		"host.name":        "web1",
+		"scope":            "checkout.payments",
+		"severity":         "Error",
+		"severity_number":  "18",
+		"trace_id":         "5b8efff798038103d269b633813fc60c",
+		"span_id":          "eee19b7ec3c1b174",
+		"http.status_code": "402",
+		"card.brand":       "visa",
+	}
+	for k, v := range wantFields {
+		if got := results[1].Fields[k]; got != v {
+			t.Errorf("field %s: expected %q, got %q", k, v, got)
+		}
+	}
+	if results[0].Fields["trace_id"] != "5b8efff798038103d269b633813fc60c" || results[0].Fields["severity"] != "WARN" {
+		t.Errorf("unexpected JSON record fields %v", results[0].Fields)
+	}
+	if !results[0].Timestamp.Equal(time.Unix(0, 1736935205000000000)) {
+		t.Errorf("unexpected timestamp %v", results[0].Timestamp)
+	}
+
+	entry, err := src.GetRecord(ctx, results[1].Ptr)
+	if err != nil || entry.Message != "card declined" {
+		t.Errorf("GetRecord: got %+v, %v", entry, err)
+	}
+
+	// A record without a timestamp gets the time it was received
+	all, err := src.Query(ctx, source.QueryParams{Limit: 1, Context: 1})
+	if err != nil || len(all) != 1 || all[0].Message != `{"count":3}` {
+		t.Fatalf("expected the newest record first, got %+v, %v", all, err)
+	}
+	if len(all[0].Context.Before) != 1 || all[0].Context.Before[0].Message != "stock low" {
+		t.Errorf("unexpected context %+v", all[0].Context)
+	}
+
+	streams, err := src.ListStreams(ctx)
+	if err != nil || len(streams) != 2 || streams[0].Name != "checkout" {
+		t.Errorf("ListStreams: got %+v, %v", streams, err)
+	}
+}
+
+func TestSource_BufferSize(t *testing.T) {
+	src := openReceiver(t, 1)
+	ctx := context.Background()
+
+	data, _ := proto.Marshal(testRequest())
+	if status := post(t, src, "application/x-protobuf", "", data); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+
+	results, err := src.Query(ctx, source.QueryParams{})
+	if err != nil || len(results) != 1 || results[0].Message != "card declined" {
+		t.Fatalf("expected only the newest record, got %+v, %v", results, err)
+	}
+	if _, err := src.GetRecord(ctx, source.MakeOTLPPtr(src.addr, 1)); err == nil {
+		t.Error("expected an error for a record no longer buffered")
+	}
+	if !strings.HasSuffix(src.Metadata().URI, "?buffer=1") {
+		t.Errorf("unexpected URI %s", src.Metadata().URI)
+	}
+}
+
+func TestSource_BadRequests(t *testing.T) {
+	src := openReceiver(t, DefaultBufferSize)
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        string
+		want        int
+	}{
+		{name: "unsupported content type", contentType: "text/plain", body: "hello", want: http.StatusUnsupportedMediaType},
+		{name: "invalid JSON", contentType: "application/json", body: "{", want: http.StatusBadRequest},
+		{name: "invalid trace ID", contentType: "application/json", body: `{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`, want: http.StatusBadRequest},
+		{name: "invalid protobuf", contentType: "application/x-protobuf", body: "\xff\xff", want: http.StatusBadRequest},
+		{name: "unsupported encoding", contentType: "application/json", encoding: "br", body: "{}", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(t, src, tt.contentType, tt.encoding, []byte(tt.body)); got != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	resp, err := http.Get("http://" + src.addr + LogsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", resp.StatusCode)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,9 +35,7 @@ func (f *Formatter) formatEntriesText(entries []source.Entry) error {
 	}
 
 	// Check if this is a stats/aggregation result (no standard log fields)
-	isStatsResult := len(entries) > 0 && entries[0].Timestamp.IsZero() && entries[0].Message == ""
-
-	if isStatsResult {
+	if isStatsResult(entries) {
 		return f.formatEntriesStatsText(entries)
 	}
 
@@ -59,17 +59,45 @@ func (f *Formatter) formatEntriesText(entries []source.Entry) error {
 		}
 		_, _ = fmt.Fprint(f.writer, ui.TimestampStyle.Render(entry.Timestamp.Format("2006-01-02 15:04:05.000")))
 		_, _ = fmt.Fprint(f.writer, " | ")
+		if f.sources && entry.Source != "" {
+			_, _ = fmt.Fprint(f.writer, ui.LogStreamStyle.Render(entry.Source))
+			_, _ = fmt.Fprint(f.writer, " | ")
+		}
 		_, _ = fmt.Fprint(f.writer, ui.LogStreamStyle.Render(entry.Stream))
 
 		// Show shortened pointer suffix (unique chars are at the end for CloudWatch)
 		if entry.Ptr != "" {
 			suffix := entry.Ptr
-			// For file:// URIs, show a shortened version
-			if strings.HasPrefix(entry.Ptr, "file://") {
-				// Show last part of path + line number
+			// For file://, s3://, http(s):// and archive:// URIs, show a shortened version
+			if strings.HasPrefix(entry.Ptr, "file://") || strings.HasPrefix(entry.Ptr, "s3://") ||
+				strings.HasPrefix(entry.Ptr, "http://") || strings.HasPrefix(entry.Ptr, "https://") ||
+				strings.HasPrefix(entry.Ptr, "archive://") {
+				// Show last part of path + line number (or byte offset)
 				if idx := strings.LastIndex(entry.Ptr, "/"); idx >= 0 {
 					suffix = entry.Ptr[idx+1:]
 				}
+			} else if info, ok := source.ParseJournalPtr(entry.Ptr); ok {
+				// Journal cursors are long; the seqnum identifies the entry
+				for _, part := range strings.Split(info.Cursor, ";") {
+					if strings.HasPrefix(part, "i=") {
+						suffix = part
+					}
+				}
+			} else if info, ok := source.ParseContainerPtr(entry.Ptr); ok {
+				// Docker names log files after the container ID, which is long
+				// and already identified by the stream
+				name := path.Base(info.FilePath)
+				if _, rest, found := strings.Cut(name, "-json.log"); found {
+					name = "json.log" + rest
+				}
+				suffix = fmt.Sprintf("%s#%d", name, info.LineNum)
+			} else if info, ok := source.ParseSQLitePtr(entry.Ptr); ok {
+				// The stream is the table; primary keys end with their last value
+				key := strconv.FormatInt(info.RowID, 10)
+				if info.Key != "" {
+					key = "key=" + info.Key[max(len(info.Key)-12, 0):]
+				}
+				suffix = path.Base(info.Path) + "#" + key
 			} else if len(suffix) > 12 {
 				// CloudWatch @ptr - show suffix
 				suffix = suffix[len(suffix)-12:]
@@ -110,12 +138,60 @@ func (f *Formatter) formatEntriesText(entries []source.Entry) error {
 	return nil
 }
 
+// FormatStats outputs stats/aggregation entries as a table of their fields
+// in the configured format.
+func (f *Formatter) FormatStats(entries []source.Entry) error {
+	if len(entries) == 0 {
+		return f.FormatEntries(entries)
+	}
+
+	switch f.format {
+	case FormatJSON:
+		headers, rows := statsTable(entries)
+		objects := make([]map[string]string, len(rows))
+		for i, row := range rows {
+			objects[i] = make(map[string]string, len(headers))
+			for j, name := range headers {
+				objects[i][name] = row[j]
+			}
+		}
+		encoder := json.NewEncoder(f.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(objects)
+	case FormatCSV:
+		headers, rows := statsTable(entries)
+		writer := csv.NewWriter(f.writer)
+		defer writer.Flush()
+		if err := writer.Write(headers); err != nil {
+			return err
+		}
+		return writer.WriteAll(rows)
+	default:
+		return f.formatEntriesStatsText(entries)
+	}
+}
+
 // formatEntriesStatsText outputs stats/aggregation entries in a table format.
 func (f *Formatter) formatEntriesStatsText(entries []source.Entry) error {
 	if len(entries) == 0 {
 		return nil
 	}
 
+	headers, rows := statsTable(entries)
+
+	// Use renderer's Table method for formatted output
+	f.renderer.Table(headers, rows)
+	return nil
+}
+
+// isStatsResult reports whether entries are stats/aggregation rows, which
+// carry only fields and no standard log fields.
+func isStatsResult(entries []source.Entry) bool {
+	return len(entries) > 0 && entries[0].Timestamp.IsZero() && entries[0].Message == ""
+}
+
+// statsTable returns the column names and rows of stats entries.
+func statsTable(entries []source.Entry) ([]string, [][]string) {
 	// Collect all field names from the first entry (excluding internal @ptr)
 	var headers []string
 	for name := range entries[0].Fields {
@@ -137,9 +213,7 @@ func (f *Formatter) formatEntriesStatsText(entries []source.Entry) error {
 		rows = append(rows, row)
 	}
 
-	// Use renderer's Table method for formatted output
-	f.renderer.Table(headers, rows)
-	return nil
+	return headers, rows
 }
 
 // formatEntriesJSON outputs entries as a JSON array.
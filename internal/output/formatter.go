@@ -24,6 +24,7 @@ type Formatter struct {
 	format    Format
 	writer    io.Writer
 	highlight *regexp.Regexp
+	sources   bool
 	renderer  *ui.Renderer
 }
 
@@ -36,6 +37,13 @@ func NewFormatter(format string, writer io.Writer) *Formatter {
 	}
 }
 
+// WithSources shows each entry's source in text output, for results merged
+// from several sources.
+func (f *Formatter) WithSources() *Formatter {
+	f.sources = true
+	return f
+}
+
 // WithHighlight sets a pattern to highlight in text output.
 // The pattern is treated as a regular expression.
 func (f *Formatter) WithHighlight(pattern string) *Formatter {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jmurray2011/clew/internal/cloudwatch"
+	"github.com/jmurray2011/clew/internal/source"
 )
 
 func TestNewFormatter(t *testing.T) {
@@ -54,3 +55,88 @@ func TestFormatLogGroups(t *testing.T) {
 		t.Errorf("expected output to contain log group name, got: %s", output)
 	}
 }
+
+func TestFormatStats(t *testing.T) {
+	entries := []source.Entry{
+		{Fields: map[string]string{"time_bucket": "2025-01-15 10:05:00.000", "count": "2"}},
+		{Fields: map[string]string{"time_bucket": "2025-01-15 10:00:00.000", "count": "1", "@ptr": "x"}},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"csv", []string{"time_bucket,count\n2025-01-15 10:05:00.000,2\n2025-01-15 10:00:00.000,1\n"}},
+		{"json", []string{`"time_bucket": "2025-01-15 10:05:00.000"`, `"count": "1"`}},
+		{"text", []string{"time_bucket", "2025-01-15 10:00:00.000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewFormatter(tt.format, &buf).FormatStats(entries); err != nil {
+				t.Fatalf("FormatStats failed: %v", err)
+			}
+
+			output := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got: %s", want, output)
+				}
+			}
+			if strings.Contains(output, "0001-01-01") || strings.Contains(output, "@ptr") {
+				t.Errorf("stats output should only contain stats fields, got: %s", output)
+			}
+		})
+	}
+}
+
+func TestFormatEntries_CloudWatchStats(t *testing.T) {
+	// CloudWatch stats rows keep the entry shape in JSON and CSV
+	entries := []source.Entry{
+		{Fields: map[string]string{"bin(5m)": "2025-01-15 10:05:00.000", "count()": "2"}},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"csv", []string{"timestamp,stream,source,message,ptr\n"}},
+		{"json", []string{`"timestamp": "0001-01-01T00:00:00Z"`, `"count()": "2"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewFormatter(tt.format, &buf).FormatEntries(entries); err != nil {
+				t.Fatalf("FormatEntries failed: %v", err)
+			}
+
+			output := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got: %s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatEntries_SQLitePtr(t *testing.T) {
+	entries := []source.Entry{
+		{Message: "upstream timeout", Stream: "logs", Ptr: source.MakeSQLitePtr("/var/lib/app/app.db", "logs", 2997)},
+		{Message: "permission denied", Stream: "audit", Ptr: source.MakeSQLiteKeyPtr("/var/lib/app/app.db", "audit", "0317013a30300000000000000004")},
+	}
+
+	var buf bytes.Buffer
+	if err := NewFormatter("text", &buf).FormatEntries(entries); err != nil {
+		t.Fatalf("FormatEntries failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"@app.db#2997", "@app.db#key=000000000004"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got: %s", want, output)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/jmurray2011/clew/pkg/plugin"
+)
+
+// schemePattern matches valid URI schemes (RFC 3986), lowercase as clew
+// compares them.
+var schemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// Plugin is a discovered source plugin executable.
+type Plugin struct {
+	Scheme     string
+	Path       string
+	Configured bool // From the config file rather than PATH
+	Registered bool // False if a built-in source already serves the scheme
+}
+
+// discover finds source plugins: the executables configured by scheme (the
+// plugins section of the config file), then clew-source-<scheme> executables
+// on PATH. A configured plugin takes precedence over one on PATH, and an
+// earlier PATH directory over a later one. Plugins are sorted by scheme.
+func discover(configured map[string]string) []Plugin {
+	found := make(map[string]Plugin)
+
+	for scheme, path := range configured {
+		if !schemePattern.MatchString(scheme) {
+			logging.Warn("Ignoring plugin for invalid scheme %q in config", scheme)
+			continue
+		}
+		resolved, err := resolveConfigured(path)
+		if err != nil {
+			logging.Warn("Ignoring %s plugin: %v", scheme, err)
+			continue
+		}
+		found[scheme] = Plugin{Scheme: scheme, Path: resolved, Configured: true}
+	}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			scheme, ok := pluginScheme(e.Name())
+			if !ok {
+				continue
+			}
+			if _, seen := found[scheme]; seen {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			found[scheme] = Plugin{Scheme: scheme, Path: path}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Scheme < plugins[j].Scheme
+	})
+	return plugins
+}
+
+// Register discovers plugins and registers a source for each scheme clew has
+// no built-in source for. It returns the discovered plugins.
+func Register(configured map[string]string) []Plugin {
+	plugins := discover(configured)
+	for i, p := range plugins {
+		plugins[i].Registered = source.RegisterPlugin(p.Scheme, opener(p.Path))
+		if !plugins[i].Registered {
+			logging.Debug("Plugin %s is shadowed by the built-in %s:// source", p.Path, p.Scheme)
+		}
+	}
+	return plugins
+}
+
+// pluginScheme returns the scheme served by a plugin executable name.
+func pluginScheme(name string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	}
+	scheme, ok := strings.CutPrefix(name, plugin.ExecutablePrefix)
+	if !ok || !schemePattern.MatchString(scheme) {
+		return "", false
+	}
+	return scheme, true
+}
+
+// resolveConfigured expands ~ and $VARS in a configured plugin path, and
+// looks up bare names on PATH.
+func resolveConfigured(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return exec.LookPath(path)
+}
+
+// isExecutable reports whether path is a regular file that can be executed.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeExecutable writes a file with the given mode to dir.
+func writeExecutable(t *testing.T, dir, name string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin names and permissions differ on Windows")
+	}
+
+	first, second, configDir := t.TempDir(), t.TempDir(), t.TempDir()
+	mydb := writeExecutable(t, first, "clew-source-mydb", 0755)
+	writeExecutable(t, second, "clew-source-mydb", 0755)      // shadowed by the first PATH entry
+	writeExecutable(t, first, "clew-source-notexec", 0644)    // not executable
+	writeExecutable(t, first, "clew-source-Bad_Scheme", 0755) // invalid scheme
+	writeExecutable(t, first, "clew-sourcery", 0755)          // not a plugin
+	pathOnly := writeExecutable(t, second, "clew-source-other", 0755)
+	configured := writeExecutable(t, configDir, "my-clickhouse", 0755)
+	writeExecutable(t, first, "clew-source-clickhouse", 0755) // config takes precedence
+
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+	t.Setenv("PLUGIN_DIR", configDir)
+
+	plugins := discover(map[string]string{
+		"clickhouse": "$PLUGIN_DIR/my-clickhouse",
+		"missing":    "/no/such/plugin",
+		"Bad_Scheme": configured,
+	})
+
+	want := []Plugin{
+		{Scheme: "clickhouse", Path: configured, Configured: true},
+		{Scheme: "mydb", Path: mydb},
+		{Scheme: "other", Path: pathOnly},
+	}
+	if len(plugins) != len(want) {
+		t.Fatalf("expected %d plugins, got %+v", len(want), plugins)
+	}
+	for i, w := range want {
+		if plugins[i] != w {
+			t.Errorf("plugin %d: expected %+v, got %+v", i, w, plugins[i])
+		}
+	}
+}
+
+func TestPluginScheme(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		ok     bool
+	}{
+		{"clew-source-mydb", "mydb", true},
+		{"clew-source-open-search.v2", "open-search.v2", true},
+		{"clew-source-", "", false},
+		{"clew-source-2fast", "", false},
+		{"clew-source-My_DB", "", false},
+		{"clew", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, ok := pluginScheme(tt.name)
+			if scheme != tt.scheme || ok != tt.ok {
+				t.Errorf("pluginScheme(%q) = %q, %v; want %q, %v", tt.name, scheme, ok, tt.scheme, tt.ok)
+			}
+		})
+	}
+}
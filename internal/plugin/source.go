@@ -0,0 +1,238 @@
+// Package plugin registers source plugins found on PATH or in the config as
+// clew sources. The protocol is implemented in pkg/plugin.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/jmurray2011/clew/pkg/plugin"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+)
+
+// Source implements source.Source by forwarding requests to a plugin process.
+// Optional methods the plugin does not declare return errors; tail then falls
+// back to polling with Query.
+type Source struct {
+	client        *plugin.Client
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// opener returns the source opener for a plugin executable.
+func opener(path string) source.SourceOpener {
+	return func(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+		client, err := plugin.Start(path, u.String())
+		if err != nil {
+			return nil, err
+		}
+		return &Source{client: client}, nil
+	}
+}
+
+// Query returns log entries matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	p := plugin.QueryParams{
+		Query: params.Query,
+		Limit: params.Limit,
+	}
+	if !params.StartTime.IsZero() {
+		p.StartTime = &params.StartTime
+	}
+	if !params.EndTime.IsZero() {
+		p.EndTime = &params.EndTime
+	}
+	if params.Filter != nil {
+		p.Filter = params.Filter.String()
+	}
+
+	entries, err := s.client.Query(ctx, p)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error reading %s: %w", s.Metadata().URI, err)
+	}
+
+	results := make([]source.Entry, len(entries))
+	for i, e := range entries {
+		results[i] = convertEntry(e)
+	}
+
+	// Plugins should already return entries newest first within the limit
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested and supported
+	if params.Context > 0 && s.client.Supports(plugin.MethodFetchContext) {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Tail streams log events from plugins that support tail.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	if !s.client.Supports(plugin.MethodTail) {
+		return nil, fmt.Errorf("streaming tail not supported by the %s plugin", s.Type())
+	}
+
+	var p plugin.TailParams
+	if params.Filter != nil {
+		p.Filter = params.Filter.String()
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+	go func() {
+		defer close(events)
+		err := s.client.Tail(ctx, p, func(ev plugin.Event) {
+			s.emitEvent(convertEvent(ev), params, events)
+		})
+		if err != nil && ctx.Err() == nil {
+			logging.Warn("Tail of %s ended: %v", s.Metadata().URI, err)
+		}
+	}()
+
+	return events, nil
+}
+
+// emitEvent sends an event to the events channel if it matches the filter.
+func (s *Source) emitEvent(event source.Event, params source.TailParams, events chan<- source.Event) {
+	// Apply filter, in case the plugin does not
+	if params.Filter != nil && !params.Filter.MatchString(event.Message) {
+		return
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	e, err := s.client.GetRecord(ctx, ptr)
+	if err != nil {
+		return nil, err
+	}
+	entry := convertEntry(*e)
+	return &entry, nil
+}
+
+// FetchContext retrieves context lines around a log entry.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	e := plugin.Entry{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+		Source:    entry.Source,
+		Ptr:       entry.Ptr,
+		Fields:    entry.Fields,
+	}
+	beforeEvents, afterEvents, err := s.client.FetchContext(ctx, e, before, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	return convertEvents(beforeEvents), convertEvents(afterEvents), nil
+}
+
+// ListStreams returns the streams the plugin reports.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	streams, err := s.client.ListStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]source.StreamInfo, len(streams))
+	for i, st := range streams {
+		result[i] = source.StreamInfo{
+			Name:      st.Name,
+			Size:      st.Size,
+			FirstTime: timeValue(st.FirstTime),
+			LastTime:  timeValue(st.LastTime),
+		}
+	}
+	return result, nil
+}
+
+// Type returns the source type reported by the plugin.
+func (s *Source) Type() string {
+	return s.client.Info().Type
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	metadata := s.client.Info().Metadata
+	return source.SourceMetadata{
+		Type: metadata.Type,
+		URI:  metadata.URI,
+	}
+}
+
+// Close stops the plugin process.
+func (s *Source) Close() error {
+	return s.client.Close()
+}
+
+func convertEntry(e plugin.Entry) source.Entry {
+	return source.Entry{
+		Timestamp: e.Timestamp,
+		Message:   e.Message,
+		Stream:    e.Stream,
+		Source:    e.Source,
+		Ptr:       e.Ptr,
+		Fields:    e.Fields,
+	}
+}
+
+func convertEvent(e plugin.Event) source.Event {
+	return source.Event{
+		Timestamp: e.Timestamp,
+		Message:   e.Message,
+		Stream:    e.Stream,
+	}
+}
+
+func convertEvents(events []plugin.Event) []source.Event {
+	result := make([]source.Event, len(events))
+	for i, e := range events {
+		result[i] = convertEvent(e)
+	}
+	return result
+}
+
+// timeValue returns the time t points to, or the zero time.
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/jmurray2011/clew/pkg/plugin"
+)
+
+// serveEnv makes the test binary serve testSource, so it can be registered
+// as a plugin executable.
+const serveEnv = "CLEW_PLUGIN_ADAPTER_TEST_SERVE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(serveEnv) == "1" {
+		if err := plugin.Serve(func(uri string) (plugin.Source, error) {
+			return &testSource{uri: uri}, nil
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+var testEntries = []plugin.Entry{
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), Message: "starting", Stream: "events", Ptr: "adaptertest://db/events#1"},
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 5, 0, time.UTC), Message: "connection refused", Stream: "events", Ptr: "adaptertest://db/events#2"},
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 10, 0, time.UTC), Message: "retrying", Stream: "events", Ptr: "adaptertest://db/events#3"},
+}
+
+// testSource returns entries oldest first and ignores the limit, which the
+// adapter corrects. It supports tail but not list_streams.
+type testSource struct {
+	uri string
+}
+
+func (s *testSource) Metadata() plugin.Metadata {
+	return plugin.Metadata{Type: "adaptertest", URI: s.uri}
+}
+
+func (s *testSource) Query(ctx context.Context, params plugin.QueryParams) ([]plugin.Entry, error) {
+	filter := regexp.MustCompile(params.Filter)
+	var entries []plugin.Entry
+	for _, e := range testEntries {
+		if filter.MatchString(e.Message) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (s *testSource) Tail(ctx context.Context, params plugin.TailParams, emit func(plugin.Event)) error {
+	for _, e := range testEntries {
+		emit(plugin.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream})
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *testSource) GetRecord(ctx context.Context, ptr string) (*plugin.Entry, error) {
+	for _, e := range testEntries {
+		if e.Ptr == ptr {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no record %s", ptr)
+}
+
+func (s *testSource) FetchContext(ctx context.Context, entry plugin.Entry, before, after int) ([]plugin.Event, []plugin.Event, error) {
+	return []plugin.Event{{Message: "before " + entry.Message}}, []plugin.Event{{Message: "after " + entry.Message}}, nil
+}
+
+// openTestSource registers the test binary as the adaptertest:// plugin and opens it.
+func openTestSource(t *testing.T) source.Source {
+	t.Helper()
+	t.Setenv(serveEnv, "1")
+	source.RegisterPlugin("adaptertest", opener(os.Args[0]))
+
+	src, err := source.Open("adaptertest://db/events")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := src.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return src
+}
+
+func TestSource_Query(t *testing.T) {
+	src := openTestSource(t)
+	ctx := context.Background()
+
+	if src.Type() != "adaptertest" || src.Metadata().URI != "adaptertest://db/events" {
+		t.Errorf("unexpected type %q and metadata %+v", src.Type(), src.Metadata())
+	}
+
+	results, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("r"), Limit: 2, Context: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Message != "retrying" || results[1].Message != "connection refused" {
+		t.Fatalf("expected the 2 newest matches, newest first, got %+v", results)
+	}
+	if len(results[0].Context.Before) != 1 || results[0].Context.After[0].Message != "after retrying" {
+		t.Errorf("unexpected context %+v", results[0].Context)
+	}
+
+	// Plugin pointers reopen the source they came from
+	ptrSrc, err := source.OpenFromPtr(results[1].Ptr, &source.SourceMetadata{Type: "adaptertest", URI: src.Metadata().URI})
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	defer func() { _ = ptrSrc.Close() }()
+	entry, err := ptrSrc.GetRecord(ctx, results[1].Ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "connection refused" {
+		t.Errorf("unexpected record %+v", entry)
+	}
+
+	if _, err := src.ListStreams(ctx); err == nil {
+		t.Error("expected an error for an undeclared method")
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	src := openTestSource(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("refused|retry")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	var got []string
+	for ev := range events {
+		got = append(got, ev.Message)
+		if len(got) == 2 {
+			cancel()
+		}
+	}
+	if len(got) != 2 || got[0] != "connection refused" || got[1] != "retrying" {
+		t.Errorf("unexpected events %v", got)
+	}
+}
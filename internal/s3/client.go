@@ -0,0 +1,122 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectInfo describes an S3 object returned by a listing.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ObjectClient defines the interface for S3 object operations.
+// This interface enables mocking for testing.
+type ObjectClient interface {
+	// ListObjects returns all objects in a bucket whose keys start with prefix.
+	ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error)
+
+	// GetObject opens an object for reading, starting at the given byte offset.
+	GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error)
+}
+
+// Ensure Client implements ObjectClient interface
+var _ ObjectClient = (*Client)(nil)
+
+// Client wraps the S3 client with convenience methods.
+type Client struct {
+	client *awss3.Client
+}
+
+// NewClient creates a new Client wrapper from an SDK client.
+func NewClient(client *awss3.Client) *Client {
+	return &Client{client: client}
+}
+
+// NewS3Client creates a new S3 client with the specified profile and region.
+// A non-empty endpoint selects an S3-compatible service (e.g., MinIO) using path-style addressing.
+func NewS3Client(profile, region, endpoint string) (*awss3.Client, error) {
+	cfg, err := loadAWSConfig(profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return awss3.NewFromConfig(cfg, func(o *awss3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+// loadAWSConfig loads the AWS configuration with optional profile and region.
+func loadAWSConfig(profile, region string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// ListObjects returns all objects under a prefix, following pagination.
+func (c *Client) ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	paginator := awss3.NewListObjectsV2Paginator(c.client, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in s3://%s/%s: %w", bucket, prefix, err)
+		}
+
+		for _, obj := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// GetObject opens an object for reading. A non-zero offset issues a ranged request.
+func (c *Client) GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error) {
+	input := &awss3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	result, err := c.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return result.Body, nil
+}
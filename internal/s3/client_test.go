@@ -0,0 +1,243 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// fakeS3 is a minimal S3-compatible HTTP server supporting path-style
+// ListObjectsV2 (paginated) and GetObject (with Range) for one bucket.
+type fakeS3 struct {
+	bucket   string
+	objects  map[string][]byte
+	modified time.Time
+	pageSize int
+
+	mu     sync.Mutex
+	ranges []string // Range header of every GetObject request
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	KeyCount              int            `xml:"KeyCount"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	Contents              []listContents `xml:"Contents"`
+}
+
+type listContents struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != f.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if key == "" && r.URL.Query().Get("list-type") == "2" {
+		f.list(w, r)
+		return
+	}
+
+	data, ok := f.objects[key]
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	f.mu.Lock()
+	f.ranges = append(f.ranges, rangeHeader)
+	f.mu.Unlock()
+
+	w.Header().Set("Last-Modified", f.modified.Format(http.TimeFormat))
+	if rangeHeader == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+		return
+	}
+
+	start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+	if err != nil || start >= len(data) {
+		writeS3Error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+	w.WriteHeader(http.StatusPartialContent)
+	_, _ = w.Write(data[start:])
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	end := min(start+f.pageSize, len(keys))
+
+	result := listBucketResult{Name: f.bucket, Prefix: prefix, KeyCount: end - start}
+	for _, k := range keys[start:end] {
+		result.Contents = append(result.Contents, listContents{
+			Key:          k,
+			Size:         int64(len(f.objects[k])),
+			LastModified: f.modified.Format(time.RFC3339),
+		})
+	}
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// setStaticCredentials isolates the AWS SDK from the user's environment.
+func setStaticCredentials(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatalf("failed to write empty config: %v", err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", empty)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", empty)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestSource_S3Compatible(t *testing.T) {
+	setStaticCredentials(t)
+
+	fake := &fakeS3{
+		bucket: "my-bucket",
+		objects: map[string][]byte{
+			"logs/app.log":            []byte(appLog),
+			"logs/archive/app.log.gz": gzipData(t, archivedLog),
+			"logs/empty.log":          nil,
+			"other/ignored.log":       []byte("2025-01-15T10:00:00Z ERROR ignored\n"),
+		},
+		modified: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		pageSize: 2,
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	uri := "s3://my-bucket/logs/?format=plain&endpoint=" + server.URL
+	src, err := source.Open(uri)
+	if err != nil {
+		t.Fatalf("Open(%s) failed: %v", uri, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	ctx := context.Background()
+
+	// ListStreams follows pagination
+	streams, err := src.ListStreams(ctx)
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 3 {
+		t.Fatalf("expected 3 streams, got %d", len(streams))
+	}
+
+	entries, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	// Every pointer resolves back to the same entry
+	for _, want := range entries {
+		got, err := src.GetRecord(ctx, want.Ptr)
+		if err != nil {
+			t.Fatalf("GetRecord(%s) failed: %v", want.Ptr, err)
+		}
+		if got.Message != want.Message {
+			t.Errorf("GetRecord(%s): expected %q, got %q", want.Ptr, want.Message, got.Message)
+		}
+	}
+
+	// Uncompressed objects are fetched with a ranged request
+	fake.mu.Lock()
+	ranged := false
+	for _, r := range fake.ranges {
+		if strings.HasPrefix(r, "bytes=") && r != "bytes=0-" {
+			ranged = true
+		}
+	}
+	fake.mu.Unlock()
+	if !ranged {
+		t.Error("expected GetRecord to issue a ranged GetObject request")
+	}
+
+	// Pointers reopen through OpenFromPtr using the cached metadata
+	ptr := entries[0].Ptr
+	meta := src.Metadata()
+	reopened, err := source.OpenFromPtr(ptr, &meta)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	entry, err := reopened.GetRecord(ctx, ptr)
+	if err != nil {
+		t.Fatalf("GetRecord after OpenFromPtr failed: %v", err)
+	}
+	if entry.Message != entries[0].Message {
+		t.Errorf("expected %q, got %q", entries[0].Message, entry.Message)
+	}
+
+	// Context is read from the decompressed stream
+	for _, e := range entries {
+		if e.Message != "ERROR disk full" {
+			continue
+		}
+		before, after, err := src.FetchContext(ctx, e, 1, 1)
+		if err != nil {
+			t.Fatalf("FetchContext failed: %v", err)
+		}
+		if len(before) != 0 || len(after) != 1 || !strings.Contains(after[0].Message, "recovered") {
+			t.Errorf("unexpected context: before=%v after=%v", before, after)
+		}
+	}
+}
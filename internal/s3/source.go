@@ -0,0 +1,450 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// FormatDetectionSampleSize is how many bytes of an object are sampled to detect its log format
+	FormatDetectionSampleSize = 64 * 1024
+)
+
+func init() {
+	// Register the s3 scheme with the source registry
+	source.Register("s3", openSource)
+}
+
+// Source implements source.Source for log objects stored in Amazon S3.
+// Every object under the prefix is treated as a log file; gzip, zstd and
+// bzip2 objects are decompressed transparently. Pointers use the byte offset of
+// an entry within the (decompressed) object.
+type Source struct {
+	bucket  string
+	prefix  string
+	client  ObjectClient
+	format  local.Format
+	uri     string
+	profile string
+	region  string
+}
+
+// NewSource creates a new S3 log source for all objects under bucket/prefix.
+// A non-empty endpoint selects an S3-compatible service instead of AWS.
+func NewSource(bucket, prefix, formatHint, profile, region, endpoint string) (*Source, error) {
+	if err := local.CheckFormat(formatHint); err != nil {
+		return nil, err
+	}
+
+	s3Client, err := NewS3Client(profile, region, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	s := NewSourceWithClient(bucket, prefix, formatHint, NewClient(s3Client))
+	s.profile = profile
+	s.region = region
+	s.uri = buildURI(bucket, prefix, formatHint, region, endpoint)
+
+	return s, nil
+}
+
+// NewSourceWithClient creates a new S3 log source with a custom client.
+// This is primarily used for testing with mock clients.
+func NewSourceWithClient(bucket, prefix, formatHint string, client ObjectClient) *Source {
+	return &Source{
+		bucket: bucket,
+		prefix: prefix,
+		client: client,
+		format: local.ParseFormat(formatHint),
+		uri:    buildURI(bucket, prefix, formatHint, "", ""),
+	}
+}
+
+// openSource is the SourceOpener for the s3 scheme.
+func openSource(u *url.URL, opts source.OpenOptions) (source.Source, error) {
+	bucket := u.Host
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 URI requires a bucket (e.g., s3://bucket/prefix)")
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+
+	// Use options as defaults, URI query params override
+	query := u.Query()
+
+	profile := opts.Profile
+	if p := query.Get("profile"); p != "" {
+		profile = p
+	}
+
+	region := opts.Region
+	if r := query.Get("region"); r != "" {
+		region = r
+	}
+
+	return NewSource(bucket, prefix, query.Get("format"), profile, region, query.Get("endpoint"))
+}
+
+// buildURI builds the canonical URI for a source. Settings that are needed to
+// reopen the source from a cached pointer are kept as query parameters.
+func buildURI(bucket, prefix, formatHint, region, endpoint string) string {
+	uri := fmt.Sprintf("s3://%s/%s", bucket, prefix)
+
+	query := url.Values{}
+	if endpoint != "" {
+		query.Set("endpoint", endpoint)
+	}
+	if region != "" {
+		query.Set("region", region)
+	}
+	if f := local.ParseFormat(formatHint); f != local.FormatAuto {
+		query.Set("format", f.String())
+	}
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+
+	return uri
+}
+
+// Query returns log entries matching the given parameters.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	objects, err := s.listLogObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []source.Entry
+
+	for _, obj := range objects {
+		// Objects last written before the start time cannot contain newer entries
+		if !params.StartTime.IsZero() && !obj.LastModified.IsZero() && obj.LastModified.Before(params.StartTime) {
+			continue
+		}
+
+		entries, err := s.queryObject(ctx, obj.Key, params)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("error reading s3://%s/%s: %w", s.bucket, obj.Key, err)
+		}
+		results = append(results, entries...)
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// queryObject reads and filters a single object.
+func (s *Source) queryObject(ctx context.Context, key string, params source.QueryParams) ([]source.Entry, error) {
+	obj, err := s.openObject(ctx, s.bucket, key, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = obj.Close() }()
+
+	var results []source.Entry
+	scanner := local.NewEntryScanner(obj, obj.parser, s.bucket+"/"+key)
+
+	for scanner.Scan(ctx) {
+		entry := s.convertEntry(scanner.Entry(), s.bucket, key, scanner.Offset())
+		if local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+	}
+
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	return results, nil
+}
+
+// convertEntry replaces the file-based source and pointer set by the parser
+// with their S3 equivalents.
+func (s *Source) convertEntry(entry source.Entry, bucket, key string, offset int64) source.Entry {
+	entry.Source = fmt.Sprintf("s3://%s/%s", bucket, key)
+	entry.Ptr = source.MakeS3Ptr(bucket, key, offset)
+	return entry
+}
+
+// Tail is not supported for S3; callers fall back to polling with Query.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	return nil, fmt.Errorf("streaming tail not supported for S3 sources")
+}
+
+// GetRecord retrieves a single log entry by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	info, ok := source.ParseS3Ptr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid S3 pointer: %s", ptr)
+	}
+
+	obj, err := s.openObject(ctx, info.Bucket, info.Key, info.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = obj.Close() }()
+
+	// The reader starts at the pointer offset, so the entry must be the first one read
+	scanner := local.NewEntryScanner(obj, obj.parser, info.Bucket+"/"+info.Key)
+	if !scanner.Scan(ctx) {
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+		return nil, fmt.Errorf("offset %d not found in s3://%s/%s", info.Offset, info.Bucket, info.Key)
+	}
+	if scanner.Offset() != 0 {
+		return nil, fmt.Errorf("no log entry starts at offset %d in s3://%s/%s", info.Offset, info.Bucket, info.Key)
+	}
+
+	entry := s.convertEntry(scanner.Entry(), info.Bucket, info.Key, info.Offset)
+	return &entry, nil
+}
+
+// FetchContext retrieves context lines around a log entry.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	info, ok := source.ParseS3Ptr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid S3 pointer: %s", entry.Ptr)
+	}
+
+	obj, err := s.openObject(ctx, info.Bucket, info.Key, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = obj.Close() }()
+
+	stream := path.Base(info.Key)
+	lines := local.NewLineReader(obj)
+
+	var beforeLines, afterLines []source.Event
+	found := false
+
+	for len(afterLines) < after || !found {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		line, offset, err := lines.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case found:
+			afterLines = append(afterLines, source.Event{Message: line, Stream: stream})
+		case offset == info.Offset:
+			found = true
+		case offset > info.Offset:
+			return nil, nil, fmt.Errorf("offset %d does not start a line", info.Offset)
+		case before > 0:
+			// Keep only the last N lines before the target
+			if len(beforeLines) == before {
+				beforeLines = beforeLines[1:]
+			}
+			beforeLines = append(beforeLines, source.Event{Message: line, Stream: stream})
+		}
+	}
+
+	if !found {
+		return nil, nil, fmt.Errorf("offset %d out of range", info.Offset)
+	}
+
+	return beforeLines, afterLines, nil
+}
+
+// ListStreams returns the log objects under the source prefix.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	objects, err := s.listLogObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []source.StreamInfo
+	for _, obj := range objects {
+		streams = append(streams, source.StreamInfo{
+			Name:     obj.Key,
+			Size:     obj.Size,
+			LastTime: obj.LastModified,
+		})
+	}
+
+	return streams, nil
+}
+
+// listLogObjects lists objects under the prefix, skipping folder placeholders.
+func (s *Source) listLogObjects(ctx context.Context) ([]ObjectInfo, error) {
+	objects, err := s.client.ListObjects(ctx, s.bucket, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ObjectInfo
+	for _, obj := range objects {
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		result = append(result, obj)
+	}
+
+	// Sort keys for consistent ordering
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "s3"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type:    "s3",
+		URI:     s.uri,
+		Profile: s.profile,
+		Region:  s.region,
+	}
+}
+
+// Close releases any resources held by the source.
+func (s *Source) Close() error {
+	return nil
+}
+
+// Bucket returns the S3 bucket name.
+func (s *Source) Bucket() string {
+	return s.bucket
+}
+
+// Prefix returns the key prefix.
+func (s *Source) Prefix() string {
+	return s.prefix
+}
+
+// object is an open S3 object positioned at a requested offset of its
+// decompressed content, along with the parser for its detected format.
+type object struct {
+	io.Reader
+	parser  local.Parser
+	closers []io.Closer
+}
+
+// Close closes the decompressor (if any) and the response body.
+func (o *object) Close() error {
+	var firstErr error
+	for i := len(o.closers) - 1; i >= 0; i-- {
+		if err := o.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// openObject opens an object and positions it at offset within its decompressed
+// content. Uncompressed objects are read with a ranged request; compressed objects
+// must be decompressed from the start and skipped forward. A non-zero offset
+// must start a line.
+func (s *Source) openObject(ctx context.Context, bucket, key string, offset int64) (*object, error) {
+	body, err := s.client.GetObject(ctx, bucket, key, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &object{closers: []io.Closer{body}}
+
+	r, compression, err := local.NewDecompressReader(body)
+	if err != nil {
+		_ = obj.Close()
+		return nil, fmt.Errorf("failed to decompress s3://%s/%s: %w", bucket, key, err)
+	}
+	obj.closers = append(obj.closers, r)
+	compressed := compression != local.CompressionNone
+	br := bufio.NewReaderSize(r, FormatDetectionSampleSize)
+
+	// Detect the format from the start of the object so every reader agrees
+	obj.parser = local.NewParser(s.detectFormat(br))
+
+	if offset == 0 {
+		obj.Reader = br
+		return obj, nil
+	}
+
+	// Position one byte early so we can check that offset starts a line
+	if compressed {
+		if _, err := io.CopyN(io.Discard, br, offset-1); err != nil {
+			_ = obj.Close()
+			return nil, fmt.Errorf("offset %d out of range for s3://%s/%s: %w", offset, bucket, key, err)
+		}
+		obj.Reader = br
+	} else {
+		// Reopen at the offset so only the requested part is downloaded
+		_ = body.Close()
+		body, err = s.client.GetObject(ctx, bucket, key, offset-1)
+		if err != nil {
+			return nil, err
+		}
+		obj.closers = []io.Closer{body}
+		obj.Reader = body
+	}
+
+	var prev [1]byte
+	if _, err := io.ReadFull(obj.Reader, prev[:]); err != nil || prev[0] != '\n' {
+		_ = obj.Close()
+		return nil, fmt.Errorf("no log entry starts at offset %d in s3://%s/%s", offset, bucket, key)
+	}
+
+	return obj, nil
+}
+
+// detectFormat returns the configured format, or detects it from the buffered
+// start of an object when the format is auto.
+func (s *Source) detectFormat(br *bufio.Reader) local.Format {
+	if s.format != local.FormatAuto {
+		return s.format
+	}
+	// Peek returns what is available when the object is smaller than the sample
+	sample, _ := br.Peek(FormatDetectionSampleSize)
+	return local.DetectFormatReader(bytes.NewReader(sample))
+}
@@ -0,0 +1,548 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+	"github.com/klauspost/compress/zstd"
+)
+
+// mockObjectClient implements ObjectClient for testing.
+type mockObjectClient struct {
+	objects map[string]mockObject
+	gets    []string // "key@offset" for every GetObject call
+	err     error
+}
+
+type mockObject struct {
+	data     []byte
+	modified time.Time
+}
+
+func (m *mockObjectClient) ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var objects []ObjectInfo
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: int64(len(obj.data)), LastModified: obj.modified})
+		}
+	}
+	return objects, nil
+}
+
+func (m *mockObjectClient) GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error) {
+	m.gets = append(m.gets, fmt.Sprintf("%s@%d", key, offset))
+	if m.err != nil {
+		return nil, m.err
+	}
+	obj, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey: %s", key)
+	}
+	if offset > int64(len(obj.data)) {
+		return nil, fmt.Errorf("InvalidRange: %d", offset)
+	}
+	return io.NopCloser(bytes.NewReader(obj.data[offset:])), nil
+}
+
+func gzipData(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip write failed: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, s string) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd writer failed: %v", err)
+	}
+	defer func() { _ = enc.Close() }()
+	return enc.EncodeAll([]byte(s), nil)
+}
+
+const (
+	appLog = "2025-01-15T10:00:00Z INFO starting\n" +
+		"2025-01-15T10:01:00Z ERROR connection refused\n" +
+		"2025-01-15T10:02:00Z INFO retrying\n"
+
+	archivedLog = "2025-01-14T09:00:00Z ERROR disk full\n" +
+		"2025-01-14T09:05:00Z INFO recovered\n"
+)
+
+func newTestSource(t *testing.T) (*Source, *mockObjectClient) {
+	t.Helper()
+	modified := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	mock := &mockObjectClient{
+		objects: map[string]mockObject{
+			"logs/":                   {modified: modified},
+			"logs/app.log":            {data: []byte(appLog), modified: modified},
+			"logs/archive/app.log.gz": {data: gzipData(t, archivedLog), modified: modified.Add(-24 * time.Hour)},
+			"other/ignored.log":       {data: []byte("2025-01-15T10:00:00Z ERROR ignored\n"), modified: modified},
+		},
+	}
+	return NewSourceWithClient("my-bucket", "logs/", "plain", mock), mock
+}
+
+func TestOpenSource(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantBucket string
+		wantPrefix string
+		wantURI    string
+		wantErr    bool
+	}{
+		{
+			name:       "bucket and prefix",
+			uri:        "s3://my-bucket/logs/app/",
+			wantBucket: "my-bucket",
+			wantPrefix: "logs/app/",
+			wantURI:    "s3://my-bucket/logs/app/",
+		},
+		{
+			name:       "bucket only",
+			uri:        "s3://my-bucket",
+			wantBucket: "my-bucket",
+			wantPrefix: "",
+			wantURI:    "s3://my-bucket/",
+		},
+		{
+			name:       "endpoint, region and format are kept in the URI",
+			uri:        "s3://my-bucket/logs?endpoint=http://localhost:9000&region=eu-west-1&format=json",
+			wantBucket: "my-bucket",
+			wantPrefix: "logs",
+			wantURI:    "s3://my-bucket/logs?endpoint=http%3A%2F%2Flocalhost%3A9000&format=json&region=eu-west-1",
+		},
+		{
+			name:    "missing bucket",
+			uri:     "s3:///logs",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatalf("url.Parse failed: %v", err)
+			}
+
+			src, err := openSource(u, source.OpenOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			s := src.(*Source)
+			if s.Bucket() != tt.wantBucket {
+				t.Errorf("expected bucket %q, got %q", tt.wantBucket, s.Bucket())
+			}
+			if s.Prefix() != tt.wantPrefix {
+				t.Errorf("expected prefix %q, got %q", tt.wantPrefix, s.Prefix())
+			}
+			if s.Metadata().URI != tt.wantURI {
+				t.Errorf("expected URI %q, got %q", tt.wantURI, s.Metadata().URI)
+			}
+		})
+	}
+}
+
+func TestSource_Query(t *testing.T) {
+	src, _ := newTestSource(t)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	// Newest first across objects
+	if entries[0].Message != "INFO retrying" {
+		t.Errorf("expected newest entry first, got %q", entries[0].Message)
+	}
+	if entries[4].Message != "ERROR disk full" {
+		t.Errorf("expected oldest entry last, got %q", entries[4].Message)
+	}
+
+	// Pointers are byte offsets within the decompressed object
+	wantPtrs := map[string]string{
+		"INFO retrying":   "s3://my-bucket/logs/app.log#81",
+		"INFO recovered":  "s3://my-bucket/logs/archive/app.log.gz#37",
+		"ERROR disk full": "s3://my-bucket/logs/archive/app.log.gz#0",
+	}
+	for _, e := range entries {
+		if want, ok := wantPtrs[e.Message]; ok && e.Ptr != want {
+			t.Errorf("entry %q: expected ptr %q, got %q", e.Message, want, e.Ptr)
+		}
+	}
+
+	if entries[0].Stream != "app.log" {
+		t.Errorf("expected stream 'app.log', got %q", entries[0].Stream)
+	}
+	if entries[0].Source != "s3://my-bucket/logs/app.log" {
+		t.Errorf("expected source 's3://my-bucket/logs/app.log', got %q", entries[0].Source)
+	}
+}
+
+func TestSource_Query_WithParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		params       source.QueryParams
+		wantMessages []string
+		wantGets     int
+	}{
+		{
+			name:         "filter",
+			params:       source.QueryParams{Filter: regexp.MustCompile("ERROR")},
+			wantMessages: []string{"ERROR connection refused", "ERROR disk full"},
+			wantGets:     2,
+		},
+		{
+			name:         "limit",
+			params:       source.QueryParams{Limit: 2},
+			wantMessages: []string{"INFO retrying", "ERROR connection refused"},
+			wantGets:     2,
+		},
+		{
+			name: "start time skips objects last modified before it",
+			params: source.QueryParams{
+				StartTime: time.Date(2025, 1, 15, 10, 0, 30, 0, time.UTC),
+			},
+			wantMessages: []string{"INFO retrying", "ERROR connection refused"},
+			wantGets:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, mock := newTestSource(t)
+
+			entries, err := src.Query(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+
+			if len(entries) != len(tt.wantMessages) {
+				t.Fatalf("expected %d entries, got %d", len(tt.wantMessages), len(entries))
+			}
+			for i, e := range entries {
+				if e.Message != tt.wantMessages[i] {
+					t.Errorf("entry %d: expected %q, got %q", i, tt.wantMessages[i], e.Message)
+				}
+			}
+			if len(mock.gets) != tt.wantGets {
+				t.Errorf("expected %d object reads, got %d (%v)", tt.wantGets, len(mock.gets), mock.gets)
+			}
+		})
+	}
+}
+
+func TestSource_Query_Error(t *testing.T) {
+	mock := &mockObjectClient{err: fmt.Errorf("AccessDenied")}
+	src := NewSourceWithClient("my-bucket", "logs/", "", mock)
+
+	if _, err := src.Query(context.Background(), source.QueryParams{}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestSource_Query_WithContext(t *testing.T) {
+	src, _ := newTestSource(t)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		Filter:  regexp.MustCompile("connection refused"),
+		Context: 1,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	ctx := entries[0].Context
+	if len(ctx.Before) != 1 || !strings.Contains(ctx.Before[0].Message, "starting") {
+		t.Errorf("expected 'starting' before, got %v", ctx.Before)
+	}
+	if len(ctx.After) != 1 || !strings.Contains(ctx.After[0].Message, "retrying") {
+		t.Errorf("expected 'retrying' after, got %v", ctx.After)
+	}
+}
+
+func TestSource_GetRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		ptr         string
+		wantMessage string
+		wantGets    []string
+		wantErr     bool
+	}{
+		{
+			name:        "uncompressed object uses ranged read",
+			ptr:         "s3://my-bucket/logs/app.log#35",
+			wantMessage: "ERROR connection refused",
+			wantGets:    []string{"logs/app.log@0", "logs/app.log@34"},
+		},
+		{
+			name:        "first entry",
+			ptr:         "s3://my-bucket/logs/app.log#0",
+			wantMessage: "INFO starting",
+			wantGets:    []string{"logs/app.log@0"},
+		},
+		{
+			name:        "gzip object is decompressed from the start",
+			ptr:         "s3://my-bucket/logs/archive/app.log.gz#37",
+			wantMessage: "INFO recovered",
+			wantGets:    []string{"logs/archive/app.log.gz@0"},
+		},
+		{
+			name:    "offset inside a line",
+			ptr:     "s3://my-bucket/logs/app.log#40",
+			wantErr: true,
+		},
+		{
+			name:    "offset inside a line of gzip object",
+			ptr:     "s3://my-bucket/logs/archive/app.log.gz#38",
+			wantErr: true,
+		},
+		{
+			name:    "offset past end of gzip object",
+			ptr:     "s3://my-bucket/logs/archive/app.log.gz#500",
+			wantErr: true,
+		},
+		{
+			name:    "missing object",
+			ptr:     "s3://my-bucket/logs/missing.log#0",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pointer",
+			ptr:     "file:///var/log/app.log#1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, mock := newTestSource(t)
+
+			entry, err := src.GetRecord(context.Background(), tt.ptr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if entry.Message != tt.wantMessage {
+				t.Errorf("expected %q, got %q", tt.wantMessage, entry.Message)
+			}
+			if entry.Ptr != tt.ptr {
+				t.Errorf("expected ptr %q, got %q", tt.ptr, entry.Ptr)
+			}
+			if strings.Join(mock.gets, ",") != strings.Join(tt.wantGets, ",") {
+				t.Errorf("expected reads %v, got %v", tt.wantGets, mock.gets)
+			}
+		})
+	}
+}
+
+func TestSource_GetRecord_Multiline(t *testing.T) {
+	content := "2025-01-15 10:30:45,123 ERROR [main] com.example.App - boom\n" +
+		"\tat com.example.App.run(App.java:10)\n" +
+		"2025-01-15 10:30:46,000 INFO [main] com.example.App - next\n"
+
+	mock := &mockObjectClient{
+		objects: map[string]mockObject{
+			"app.log.gz": {data: gzipData(t, content)},
+		},
+	}
+	src := NewSourceWithClient("my-bucket", "", "", mock)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries (format auto-detected as java), got %d", len(entries))
+	}
+
+	for _, want := range entries {
+		got, err := src.GetRecord(context.Background(), want.Ptr)
+		if err != nil {
+			t.Fatalf("GetRecord(%s) failed: %v", want.Ptr, err)
+		}
+		if got.Message != want.Message {
+			t.Errorf("GetRecord(%s): expected %q, got %q", want.Ptr, want.Message, got.Message)
+		}
+	}
+}
+
+func TestSource_FetchContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		ptr        string
+		before     int
+		after      int
+		wantBefore []string
+		wantAfter  []string
+		wantErr    bool
+	}{
+		{
+			name:       "middle line",
+			ptr:        "s3://my-bucket/logs/app.log#35",
+			before:     5,
+			after:      5,
+			wantBefore: []string{"2025-01-15T10:00:00Z INFO starting"},
+			wantAfter:  []string{"2025-01-15T10:02:00Z INFO retrying"},
+		},
+		{
+			name:      "gzip object",
+			ptr:       "s3://my-bucket/logs/archive/app.log.gz#0",
+			before:    1,
+			after:     1,
+			wantAfter: []string{"2025-01-14T09:05:00Z INFO recovered"},
+		},
+		{
+			name:    "offset inside a line",
+			ptr:     "s3://my-bucket/logs/app.log#3",
+			before:  1,
+			after:   1,
+			wantErr: true,
+		},
+		{
+			name:    "offset out of range",
+			ptr:     "s3://my-bucket/logs/app.log#9999",
+			before:  1,
+			after:   1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, _ := newTestSource(t)
+
+			before, after, err := src.FetchContext(context.Background(), source.Entry{Ptr: tt.ptr}, tt.before, tt.after)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(before) != len(tt.wantBefore) {
+				t.Fatalf("expected %d before lines, got %d", len(tt.wantBefore), len(before))
+			}
+			for i := range before {
+				if before[i].Message != tt.wantBefore[i] {
+					t.Errorf("before[%d]: expected %q, got %q", i, tt.wantBefore[i], before[i].Message)
+				}
+			}
+			if len(after) != len(tt.wantAfter) {
+				t.Fatalf("expected %d after lines, got %d", len(tt.wantAfter), len(after))
+			}
+			for i := range after {
+				if after[i].Message != tt.wantAfter[i] {
+					t.Errorf("after[%d]: expected %q, got %q", i, tt.wantAfter[i], after[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	src, _ := newTestSource(t)
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+
+	// Folder placeholders and objects outside the prefix are skipped
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 streams, got %d", len(streams))
+	}
+	if streams[0].Name != "logs/app.log" || streams[1].Name != "logs/archive/app.log.gz" {
+		t.Errorf("unexpected streams: %s, %s", streams[0].Name, streams[1].Name)
+	}
+	if streams[0].Size != int64(len(appLog)) {
+		t.Errorf("expected size %d, got %d", len(appLog), streams[0].Size)
+	}
+}
+
+func TestSource_Tail(t *testing.T) {
+	src, _ := newTestSource(t)
+
+	if _, err := src.Tail(context.Background(), source.TailParams{}); err == nil {
+		t.Error("expected Tail to be unsupported")
+	}
+}
+
+func TestSource_Metadata(t *testing.T) {
+	src, _ := newTestSource(t)
+
+	meta := src.Metadata()
+	if meta.Type != "s3" {
+		t.Errorf("expected type 's3', got %q", meta.Type)
+	}
+	if meta.URI != "s3://my-bucket/logs/?format=plain" {
+		t.Errorf("expected URI 's3://my-bucket/logs/?format=plain', got %q", meta.URI)
+	}
+	if src.Type() != "s3" {
+		t.Errorf("expected Type() 's3', got %q", src.Type())
+	}
+}
+
+func TestSource_Query_Zstd(t *testing.T) {
+	mock := &mockObjectClient{
+		objects: map[string]mockObject{
+			"app.log.zst": {data: zstdData(t, archivedLog)},
+		},
+	}
+	src := NewSourceWithClient("my-bucket", "", "plain", mock)
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	entry, err := src.GetRecord(context.Background(), "s3://my-bucket/app.log.zst#37")
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "INFO recovered" {
+		t.Errorf("expected 'INFO recovered', got %q", entry.Message)
+	}
+}
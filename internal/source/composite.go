@@ -0,0 +1,228 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Composite implements Source over several sources of any type. Queries fan
+// out to every member concurrently and the results are merged newest first,
+// with Entry.Source set to the member the entry came from.
+type Composite struct {
+	labels  []string
+	members []Source
+}
+
+// PartialError is returned by Composite.Query when some members failed but
+// others returned results. The results are still returned alongside it.
+type PartialError struct {
+	Errors []error // One error per failed member, prefixed with its label
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d source(s) failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// NewComposite creates an empty composite source. Members are added with Add.
+func NewComposite() *Composite {
+	return &Composite{}
+}
+
+// Add adds a member source. The label identifies the member in Entry.Source
+// and error messages, and is typically the URI or alias it was opened from.
+func (c *Composite) Add(label string, src Source) {
+	c.labels = append(c.labels, label)
+	c.members = append(c.members, src)
+}
+
+// Labels returns the member labels in the order they were added.
+func (c *Composite) Labels() []string {
+	return c.labels
+}
+
+// Query runs the query against every member concurrently and returns the
+// merged entries, newest first. If some members fail, the other members'
+// entries are returned with a *PartialError; if all fail, only an error is
+// returned.
+func (c *Composite) Query(ctx context.Context, params QueryParams) ([]Entry, error) {
+	results := make([][]Entry, len(c.members))
+	errs := make([]error, len(c.members))
+
+	var wg sync.WaitGroup
+	for i, src := range c.members {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			results[i], errs[i] = src.Query(ctx, params)
+		}(i, src)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var merged []Entry
+	var failed []error
+	for i, entries := range results {
+		if errs[i] != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", c.labels[i], errs[i]))
+			continue
+		}
+		for _, e := range entries {
+			e.Source = c.labels[i]
+			merged = append(merged, e)
+		}
+	}
+
+	if len(failed) == len(c.members) {
+		return nil, errors.Join(failed...)
+	}
+
+	// Members return their own entries sorted; merge them across members
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.After(merged[j].Timestamp)
+	})
+
+	if params.Limit > 0 && len(merged) > params.Limit {
+		merged = merged[:params.Limit]
+	}
+
+	if len(failed) > 0 {
+		return merged, &PartialError{Errors: failed}
+	}
+	return merged, nil
+}
+
+// Tail streams events from every member on a single channel. The channel is
+// closed once all members' channels are closed.
+func (c *Composite) Tail(ctx context.Context, params TailParams) (<-chan Event, error) {
+	// Members are tailed under a context of their own, so those already
+	// started stop if a later one fails
+	ctx, cancel := context.WithCancel(ctx)
+	var channels []<-chan Event
+	for i, src := range c.members {
+		ch, err := src.Tail(ctx, params)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("%s: %w", c.labels[i], err)
+		}
+		channels = append(channels, ch)
+	}
+
+	events := make(chan Event)
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		wg.Add(1)
+		go func(ch <-chan Event) {
+			defer wg.Done()
+			for event := range ch {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// GetRecord retrieves a single log entry from the first member that can
+// resolve the pointer.
+func (c *Composite) GetRecord(ctx context.Context, ptr string) (*Entry, error) {
+	var lastErr error
+	for i, src := range c.members {
+		entry, err := src.GetRecord(ctx, ptr)
+		if err == nil {
+			entry.Source = c.labels[i]
+			return entry, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no source could retrieve pointer %s: %w", ptr, lastErr)
+}
+
+// FetchContext retrieves context lines from the member the entry came from.
+func (c *Composite) FetchContext(ctx context.Context, entry Entry, before, after int) ([]Event, []Event, error) {
+	i := c.member(entry)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("entry source %q is not part of this query", entry.Source)
+	}
+	return c.members[i].FetchContext(ctx, entry, before, after)
+}
+
+// ListStreams returns the streams of every member, prefixed with the member's label.
+func (c *Composite) ListStreams(ctx context.Context) ([]StreamInfo, error) {
+	var streams []StreamInfo
+	for i, src := range c.members {
+		memberStreams, err := src.ListStreams(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.labels[i], err)
+		}
+		for _, s := range memberStreams {
+			s.Name = c.labels[i] + ": " + s.Name
+			streams = append(streams, s)
+		}
+	}
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (c *Composite) Type() string {
+	return "composite"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+// Use EntryMetadata for the metadata of the member an entry came from.
+func (c *Composite) Metadata() SourceMetadata {
+	return SourceMetadata{
+		Type: "composite",
+		URI:  strings.Join(c.labels, " "),
+	}
+}
+
+// EntryMetadata returns the metadata of the member the entry came from, so
+// pointers can be cached with the source that can resolve them.
+func (c *Composite) EntryMetadata(entry Entry) SourceMetadata {
+	if i := c.member(entry); i >= 0 {
+		return c.members[i].Metadata()
+	}
+	return c.Metadata()
+}
+
+// member returns the index of the member an entry came from, or -1.
+func (c *Composite) member(entry Entry) int {
+	for i, label := range c.labels {
+		if label == entry.Source {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close closes every member and returns the first error.
+func (c *Composite) Close() error {
+	var firstErr error
+	for _, src := range c.members {
+		if err := src.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,208 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource is a Source returning fixed entries or an error.
+type fakeSource struct {
+	typ     string
+	entries []Entry
+	err     error
+	tailErr error
+	tailCtx context.Context // context of the last Tail call
+	closed  bool
+}
+
+func (f *fakeSource) Query(ctx context.Context, params QueryParams) ([]Entry, error) {
+	return f.entries, f.err
+}
+
+func (f *fakeSource) Tail(ctx context.Context, params TailParams) (<-chan Event, error) {
+	f.tailCtx = ctx
+	if f.tailErr != nil {
+		return nil, f.tailErr
+	}
+	events := make(chan Event, len(f.entries))
+	for _, e := range f.entries {
+		events <- Event{Timestamp: e.Timestamp, Message: e.Message}
+	}
+	close(events)
+	return events, nil
+}
+
+func (f *fakeSource) GetRecord(ctx context.Context, ptr string) (*Entry, error) {
+	for _, e := range f.entries {
+		if e.Ptr == ptr {
+			return &e, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeSource) FetchContext(ctx context.Context, entry Entry, before, after int) ([]Event, []Event, error) {
+	return []Event{{Message: f.typ}}, nil, nil
+}
+
+func (f *fakeSource) ListStreams(ctx context.Context) ([]StreamInfo, error) {
+	return []StreamInfo{{Name: "main"}}, nil
+}
+
+func (f *fakeSource) Type() string { return f.typ }
+
+func (f *fakeSource) Metadata() SourceMetadata {
+	return SourceMetadata{Type: f.typ, URI: f.typ + "-uri", Profile: f.typ + "-profile"}
+}
+
+func (f *fakeSource) Close() error {
+	f.closed = true
+	return nil
+}
+
+func at(min int) time.Time {
+	return time.Date(2025, 1, 15, 10, min, 0, 0, time.UTC)
+}
+
+func TestComposite_Query(t *testing.T) {
+	cw := &fakeSource{typ: "cloudwatch", entries: []Entry{
+		{Timestamp: at(4), Message: "cw newest", Ptr: "cw-2"},
+		{Timestamp: at(1), Message: "cw oldest", Ptr: "cw-1"},
+	}}
+	file := &fakeSource{typ: "local", entries: []Entry{
+		{Timestamp: at(3), Message: "file middle", Ptr: "file:///app.log#2", Source: "/app.log"},
+		{Timestamp: at(2), Message: "file early", Ptr: "file:///app.log#1"},
+	}}
+
+	c := NewComposite()
+	c.Add("@prod-api", cw)
+	c.Add("/var/log/app.log", file)
+
+	entries, err := c.Query(context.Background(), QueryParams{Limit: 3})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// Merged newest first, then limited
+	want := []struct {
+		message string
+		source  string
+	}{
+		{"cw newest", "@prod-api"},
+		{"file middle", "/var/log/app.log"},
+		{"file early", "/var/log/app.log"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		if entries[i].Message != w.message || entries[i].Source != w.source {
+			t.Errorf("entry %d = %q from %q, want %q from %q", i, entries[i].Message, entries[i].Source, w.message, w.source)
+		}
+	}
+
+	// Each entry keeps the metadata of its own source
+	if meta := c.EntryMetadata(entries[0]); meta.Type != "cloudwatch" || meta.Profile != "cloudwatch-profile" {
+		t.Errorf("EntryMetadata = %+v, want cloudwatch member metadata", meta)
+	}
+	if meta := c.EntryMetadata(entries[1]); meta.Type != "local" {
+		t.Errorf("EntryMetadata = %+v, want local member metadata", meta)
+	}
+
+	// Context comes from the entry's own source
+	before, _, err := c.FetchContext(context.Background(), entries[1], 1, 0)
+	if err != nil || len(before) != 1 || before[0].Message != "local" {
+		t.Errorf("FetchContext = %+v, %v; want context from local member", before, err)
+	}
+}
+
+func TestComposite_QueryPartialFailure(t *testing.T) {
+	c := NewComposite()
+	c.Add("@prod-api", &fakeSource{typ: "cloudwatch", err: errors.New("expired token")})
+	c.Add("/var/log/app.log", &fakeSource{typ: "local", entries: []Entry{{Timestamp: at(1), Message: "ok"}}})
+
+	entries, err := c.Query(context.Background(), QueryParams{})
+
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected *PartialError, got %v", err)
+	}
+	if len(partial.Errors) != 1 || !strings.Contains(partial.Errors[0].Error(), "@prod-api: expired token") {
+		t.Errorf("Errors = %v", partial.Errors)
+	}
+	if len(entries) != 1 || entries[0].Message != "ok" {
+		t.Errorf("expected results of the working source, got %+v", entries)
+	}
+}
+
+func TestComposite_QueryAllFail(t *testing.T) {
+	c := NewComposite()
+	c.Add("@a", &fakeSource{err: errors.New("first")})
+	c.Add("@b", &fakeSource{err: errors.New("second")})
+
+	entries, err := c.Query(context.Background(), QueryParams{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		t.Error("expected a plain error when every source fails")
+	}
+	if entries != nil {
+		t.Errorf("expected no entries, got %+v", entries)
+	}
+	if !strings.Contains(err.Error(), "@a: first") || !strings.Contains(err.Error(), "@b: second") {
+		t.Errorf("error = %v, want both failures", err)
+	}
+}
+
+func TestComposite_TailAndClose(t *testing.T) {
+	a := &fakeSource{entries: []Entry{{Message: "a1"}, {Message: "a2"}}}
+	b := &fakeSource{entries: []Entry{{Message: "b1"}}}
+	c := NewComposite()
+	c.Add("a", a)
+	c.Add("b", b)
+
+	events, err := c.Tail(context.Background(), TailParams{})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+	var count int
+	for range events {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("expected 3 events, got %d", count)
+	}
+
+	streams, err := c.ListStreams(context.Background())
+	if err != nil || len(streams) != 2 || streams[1].Name != "b: main" {
+		t.Errorf("ListStreams = %+v, %v", streams, err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !a.closed || !b.closed {
+		t.Error("expected every member to be closed")
+	}
+}
+
+func TestComposite_TailMemberFails(t *testing.T) {
+	a := &fakeSource{entries: []Entry{{Message: "a1"}}}
+	b := &fakeSource{tailErr: errors.New("connection refused")}
+	c := NewComposite()
+	c.Add("a", a)
+	c.Add("b", b)
+
+	_, err := c.Tail(context.Background(), TailParams{})
+	if err == nil || !strings.Contains(err.Error(), "b: connection refused") {
+		t.Fatalf("Tail error = %v, want the failing member's error", err)
+	}
+	if a.tailCtx == nil || a.tailCtx.Err() == nil {
+		t.Error("members already tailing should be stopped when a later one fails")
+	}
+}
@@ -9,15 +9,42 @@ import (
 
 // Config represents the clew configuration file.
 type Config struct {
-	Sources       map[string]SourceAlias `yaml:"sources"`
-	DefaultSource string                 `yaml:"default_source"`
-	Output        OutputConfig           `yaml:"output"`
+	Sources       map[string]SourceAlias  `yaml:"sources"`
+	DefaultSource string                  `yaml:"default_source"`
+	Output        OutputConfig            `yaml:"output"`
+	Plugins       map[string]string       `yaml:"plugins,omitempty"`       // Source plugin executables by URI scheme
+	LogFormats    map[string]string       `yaml:"log_formats,omitempty"`   // Access log layouts in nginx log_format syntax, by format name
+	Parsers       map[string]ParserConfig `yaml:"parsers,omitempty"`       // Regex line parsers, by format name
+	GrokPatterns  []string                `yaml:"grok_patterns,omitempty"` // Grok pattern files or directories, in Logstash format
 }
 
 // SourceAlias defines a named source alias.
 type SourceAlias struct {
-	URI    string `yaml:"uri"`
-	Format string `yaml:"format,omitempty"` // Optional format hint for local files
+	URI     string            `yaml:"uri"`
+	Format  string            `yaml:"format,omitempty"`  // Format hint for file-based sources: a built-in format, <format>+<multiline profile>, or a config log_formats or parsers name
+	Headers map[string]string `yaml:"headers,omitempty"` // HTTP request headers for http(s) sources; $VARS are expanded
+}
+
+// ParserConfig defines a log format parsed by a regular expression, or a
+// format with multiline rules. Named groups of the pattern become fields,
+// except those used for the timestamp and message.
+type ParserConfig struct {
+	Pattern    string          `yaml:"pattern,omitempty"`     // Line regex with named groups, in Go RE2 syntax; may use grok %{SYNTAX:semantic}
+	Format     string          `yaml:"format,omitempty"`      // Format of the lines instead of a pattern, for multiline rules over it (default plain)
+	Timestamp  string          `yaml:"timestamp,omitempty"`   // Group holding the timestamp (default "timestamp")
+	TimeLayout string          `yaml:"time_layout,omitempty"` // Go time layout of the timestamp; detected if empty
+	Timezone   string          `yaml:"timezone,omitempty"`    // Zone of timestamps without one: Local (default), UTC, or an IANA name
+	Message    string          `yaml:"message,omitempty"`     // Group holding the message (default "message"; the whole line if absent)
+	Level      string          `yaml:"level,omitempty"`       // Group stored as the level field (default "level")
+	Multiline  MultilineConfig `yaml:"multiline,omitempty"`
+}
+
+// MultilineConfig defines how lines are joined into multiline entries.
+type MultilineConfig struct {
+	Profile  string `yaml:"profile,omitempty"`  // Built-in rules: python, go, node, dotnet, java
+	Start    string `yaml:"start,omitempty"`    // Regex matching the first line of an entry
+	Continue string `yaml:"continue,omitempty"` // Regex matching lines joined to the previous entry
+	End      string `yaml:"end,omitempty"`      // Regex matching the last line of an entry
 }
 
 // OutputConfig defines output preferences.
@@ -11,15 +11,39 @@ import (
 // - CloudWatch: raw @ptr string (base64-like, e.g., "CmAKJgo...")
 // - Local: "file:///path/to/file#linenum"
 // - S3: "s3://bucket/key#offset"
+// - Journal: "journal:///var/log/journal#<cursor>"
+// - Container: "docker:///var/lib/docker/containers/<id>/<id>-json.log#linenum"
+//   or "k8s-node:///var/log/pods/<namespace>_<pod>_<uid>/<container>/0.log#linenum"
+// - Loki: "loki://host:3100/{app="api",env="prod"}#<unix nanoseconds>"
+// - Elasticsearch: "es://host:9200/<index>/<document id>"
+// - HTTP: "https://host/path/app.log#offset" (byte offset, as for S3)
+// - Archive: "archive:///path/bundle.tar.gz?member=var/log/app.log#linenum"
+// - Syslog receiver: "syslog+udp://0.0.0.0:5514#<sequence number>" (valid while
+//   the receiver runs; spooled messages get local pointers into the spool file)
+// - OTLP receiver: "otlp://0.0.0.0:4318#<sequence number>" (valid while the
+//   receiver runs)
+// - SQLite: "sqlite:///path/app.db?table=logs#<rowid>", or for WITHOUT ROWID
+//   tables "sqlite:///path/app.db?table=logs#key=<primary key>"
+// - Plugin: any URI with a plugin's scheme, opaque to clew
 
 // PtrType represents the type of a log pointer.
 type PtrType string
 
 const (
-	PtrTypeCloudWatch PtrType = "cloudwatch"
-	PtrTypeLocal      PtrType = "local"
-	PtrTypeS3         PtrType = "s3"
-	PtrTypeUnknown    PtrType = "unknown"
+	PtrTypeCloudWatch    PtrType = "cloudwatch"
+	PtrTypeLocal         PtrType = "local"
+	PtrTypeS3            PtrType = "s3"
+	PtrTypeJournal       PtrType = "journal"
+	PtrTypeContainer     PtrType = "container"
+	PtrTypeLoki          PtrType = "loki"
+	PtrTypeElasticsearch PtrType = "elasticsearch"
+	PtrTypeHTTP          PtrType = "http"
+	PtrTypeArchive       PtrType = "archive"
+	PtrTypeSyslog        PtrType = "syslog"
+	PtrTypeOTLP          PtrType = "otlp"
+	PtrTypeSQLite        PtrType = "sqlite"
+	PtrTypePlugin        PtrType = "plugin"
+	PtrTypeUnknown       PtrType = "unknown"
 )
 
 // ParsePtrType determines the source type from a pointer string.
@@ -30,6 +54,36 @@ func ParsePtrType(ptr string) PtrType {
 	if strings.HasPrefix(ptr, "s3://") {
 		return PtrTypeS3
 	}
+	if strings.HasPrefix(ptr, "journal://") {
+		return PtrTypeJournal
+	}
+	if strings.HasPrefix(ptr, "docker://") || strings.HasPrefix(ptr, "k8s-node://") {
+		return PtrTypeContainer
+	}
+	if strings.HasPrefix(ptr, "loki://") {
+		return PtrTypeLoki
+	}
+	if strings.HasPrefix(ptr, "es://") {
+		return PtrTypeElasticsearch
+	}
+	if strings.HasPrefix(ptr, "http://") || strings.HasPrefix(ptr, "https://") {
+		return PtrTypeHTTP
+	}
+	if strings.HasPrefix(ptr, "archive://") {
+		return PtrTypeArchive
+	}
+	if strings.HasPrefix(ptr, "syslog+udp://") || strings.HasPrefix(ptr, "syslog+tcp://") {
+		return PtrTypeSyslog
+	}
+	if strings.HasPrefix(ptr, "otlp://") {
+		return PtrTypeOTLP
+	}
+	if strings.HasPrefix(ptr, "sqlite://") {
+		return PtrTypeSQLite
+	}
+	if scheme, _, ok := strings.Cut(ptr, "://"); ok && pluginSchemes[scheme] {
+		return PtrTypePlugin
+	}
 	// CloudWatch @ptr values are base64-like strings without a scheme
 	// They typically start with uppercase letters and contain alphanumeric chars
 	if len(ptr) > 0 && !strings.Contains(ptr, "://") {
@@ -126,3 +180,395 @@ func ParseS3Ptr(ptr string) (S3PtrInfo, bool) {
 		Offset: offset,
 	}, true
 }
+
+// JournalPtrInfo contains parsed information from a journal pointer.
+type JournalPtrInfo struct {
+	Path   string // Journal directory or file
+	Cursor string // journalctl-style cursor
+}
+
+// MakeJournalPtr creates a journal pointer from a journal path and entry cursor.
+func MakeJournalPtr(path, cursor string) string {
+	return fmt.Sprintf("journal://%s#%s", path, cursor)
+}
+
+// ParseJournalPtr extracts the journal path and cursor from a journal pointer.
+func ParseJournalPtr(ptr string) (JournalPtrInfo, bool) {
+	if !strings.HasPrefix(ptr, "journal://") {
+		return JournalPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil {
+		return JournalPtrInfo{}, false
+	}
+
+	if u.Path == "" || u.Fragment == "" {
+		return JournalPtrInfo{}, false
+	}
+
+	return JournalPtrInfo{
+		Path:   u.Path,
+		Cursor: u.Fragment,
+	}, true
+}
+
+// ContainerPtrInfo contains parsed information from a container log pointer.
+type ContainerPtrInfo struct {
+	Scheme   string // docker or k8s-node
+	FilePath string
+	LineNum  int // line of the first record of the message
+}
+
+// MakeContainerPtr creates a container log pointer from a source scheme,
+// log file path and line number.
+func MakeContainerPtr(scheme, filePath string, lineNum int) string {
+	return fmt.Sprintf("%s://%s#%d", scheme, filePath, lineNum)
+}
+
+// ParseContainerPtr extracts the scheme, file path and line number from a
+// container log pointer.
+func ParseContainerPtr(ptr string) (ContainerPtrInfo, bool) {
+	if ParsePtrType(ptr) != PtrTypeContainer {
+		return ContainerPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil {
+		return ContainerPtrInfo{}, false
+	}
+
+	// Pointers always name a log file; docker://<name> is a source URI
+	if u.Host != "" || u.Path == "" {
+		return ContainerPtrInfo{}, false
+	}
+
+	lineNum, err := strconv.Atoi(u.Fragment)
+	if err != nil || lineNum < 1 {
+		return ContainerPtrInfo{}, false
+	}
+
+	return ContainerPtrInfo{
+		Scheme:   u.Scheme,
+		FilePath: u.Path,
+		LineNum:  lineNum,
+	}, true
+}
+
+// LokiPtrInfo contains parsed information from a Loki pointer.
+type LokiPtrInfo struct {
+	Host      string // Loki server host:port
+	Selector  string // Stream selector with every label of the stream, e.g. {app="api"}
+	Timestamp int64  // Unix nanoseconds
+}
+
+// MakeLokiPtr creates a Loki pointer from a server host, the full label
+// selector of a stream and the nanosecond timestamp of a line in it.
+func MakeLokiPtr(host, selector string, timestamp int64) string {
+	u := url.URL{
+		Scheme:   "loki",
+		Host:     host,
+		Path:     "/" + selector,
+		Fragment: strconv.FormatInt(timestamp, 10),
+	}
+	return u.String()
+}
+
+// ParseLokiPtr extracts the host, stream selector and timestamp from a Loki pointer.
+func ParseLokiPtr(ptr string) (LokiPtrInfo, bool) {
+	if !strings.HasPrefix(ptr, "loki://") {
+		return LokiPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil {
+		return LokiPtrInfo{}, false
+	}
+
+	selector := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || !strings.HasPrefix(selector, "{") {
+		return LokiPtrInfo{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(u.Fragment, 10, 64)
+	if err != nil {
+		return LokiPtrInfo{}, false
+	}
+
+	return LokiPtrInfo{
+		Host:      u.Host,
+		Selector:  selector,
+		Timestamp: timestamp,
+	}, true
+}
+
+// ElasticsearchPtrInfo contains parsed information from an Elasticsearch pointer.
+type ElasticsearchPtrInfo struct {
+	Host  string // Cluster host:port
+	Index string // Concrete index the document is in
+	ID    string // Document _id
+}
+
+// MakeElasticsearchPtr creates an Elasticsearch pointer from a cluster host,
+// index and document ID.
+func MakeElasticsearchPtr(host, index, id string) string {
+	u := url.URL{
+		Scheme: "es",
+		Host:   host,
+		Path:   "/" + index + "/" + id,
+	}
+	return u.String()
+}
+
+// ParseElasticsearchPtr extracts the host, index and document ID from an
+// Elasticsearch pointer. Index names cannot contain "/", so the ID is
+// everything after the first path segment.
+func ParseElasticsearchPtr(ptr string) (ElasticsearchPtrInfo, bool) {
+	if !strings.HasPrefix(ptr, "es://") {
+		return ElasticsearchPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil || u.Host == "" {
+		return ElasticsearchPtrInfo{}, false
+	}
+
+	index, id, ok := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	// Source URIs name an index pattern without a document
+	if !ok || index == "" || id == "" || strings.ContainsAny(index, "*,") {
+		return ElasticsearchPtrInfo{}, false
+	}
+
+	return ElasticsearchPtrInfo{
+		Host:  u.Host,
+		Index: index,
+		ID:    id,
+	}, true
+}
+
+// HTTPPtrInfo contains parsed information from an HTTP pointer.
+type HTTPPtrInfo struct {
+	URL    string // URL of the log, without the fragment
+	Offset int64  // Byte offset of the entry within the (decompressed) content
+}
+
+// MakeHTTPPtr creates an HTTP pointer from a log URL and byte offset.
+func MakeHTTPPtr(rawURL string, offset int64) string {
+	return fmt.Sprintf("%s#%d", rawURL, offset)
+}
+
+// ParseHTTPPtr extracts the URL and byte offset from an HTTP pointer.
+func ParseHTTPPtr(ptr string) (HTTPPtrInfo, bool) {
+	if ParsePtrType(ptr) != PtrTypeHTTP {
+		return HTTPPtrInfo{}, false
+	}
+
+	// The offset follows the last #, so URLs are kept exactly as given
+	i := strings.LastIndex(ptr, "#")
+	if i < 0 {
+		return HTTPPtrInfo{}, false
+	}
+	offset, err := strconv.ParseInt(ptr[i+1:], 10, 64)
+	if err != nil || offset < 0 {
+		return HTTPPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr[:i])
+	if err != nil || u.Host == "" {
+		return HTTPPtrInfo{}, false
+	}
+
+	return HTTPPtrInfo{
+		URL:    ptr[:i],
+		Offset: offset,
+	}, true
+}
+
+// ArchivePtrInfo contains parsed information from an archive pointer.
+type ArchivePtrInfo struct {
+	ArchivePath string // tar or zip file
+	Member      string // path of the log file within the archive
+	LineNum     int
+}
+
+// MakeArchivePtr creates an archive pointer from an archive path, the path
+// of a member within it and a line number in the member.
+func MakeArchivePtr(archivePath, member string, lineNum int) string {
+	u := url.URL{
+		Scheme: "archive",
+		Path:   archivePath,
+		// Keep slashes readable; url.Parse accepts them in queries
+		RawQuery: "member=" + strings.ReplaceAll(url.QueryEscape(member), "%2F", "/"),
+		Fragment: strconv.Itoa(lineNum),
+	}
+	return u.String()
+}
+
+// ParseArchivePtr extracts the archive path, member and line number from an
+// archive pointer.
+func ParseArchivePtr(ptr string) (ArchivePtrInfo, bool) {
+	if !strings.HasPrefix(ptr, "archive://") {
+		return ArchivePtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil {
+		return ArchivePtrInfo{}, false
+	}
+
+	member := u.Query().Get("member")
+	if u.Path == "" || member == "" {
+		return ArchivePtrInfo{}, false
+	}
+
+	lineNum, err := strconv.Atoi(u.Fragment)
+	if err != nil || lineNum < 1 {
+		return ArchivePtrInfo{}, false
+	}
+
+	return ArchivePtrInfo{
+		ArchivePath: u.Path,
+		Member:      member,
+		LineNum:     lineNum,
+	}, true
+}
+
+// SyslogPtrInfo contains parsed information from a syslog receiver pointer.
+type SyslogPtrInfo struct {
+	Scheme string // syslog+udp or syslog+tcp
+	Addr   string // listen address
+	Seq    int64  // sequence number of the message since the receiver started
+}
+
+// MakeSyslogPtr creates a syslog receiver pointer from the receiver's scheme,
+// listen address and a message sequence number.
+func MakeSyslogPtr(scheme, addr string, seq int64) string {
+	return fmt.Sprintf("%s://%s#%d", scheme, addr, seq)
+}
+
+// ParseSyslogPtr extracts the scheme, listen address and sequence number from
+// a syslog receiver pointer.
+func ParseSyslogPtr(ptr string) (SyslogPtrInfo, bool) {
+	if ParsePtrType(ptr) != PtrTypeSyslog {
+		return SyslogPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil || u.Host == "" {
+		return SyslogPtrInfo{}, false
+	}
+
+	seq, err := strconv.ParseInt(u.Fragment, 10, 64)
+	if err != nil || seq < 1 {
+		return SyslogPtrInfo{}, false
+	}
+
+	return SyslogPtrInfo{
+		Scheme: u.Scheme,
+		Addr:   u.Host,
+		Seq:    seq,
+	}, true
+}
+
+// OTLPPtrInfo contains parsed information from an OTLP receiver pointer.
+type OTLPPtrInfo struct {
+	Addr string // listen address
+	Seq  int64  // sequence number of the log record since the receiver started
+}
+
+// MakeOTLPPtr creates an OTLP receiver pointer from the receiver's listen
+// address and a log record sequence number.
+func MakeOTLPPtr(addr string, seq int64) string {
+	return fmt.Sprintf("otlp://%s#%d", addr, seq)
+}
+
+// ParseOTLPPtr extracts the listen address and sequence number from an OTLP
+// receiver pointer.
+func ParseOTLPPtr(ptr string) (OTLPPtrInfo, bool) {
+	if ParsePtrType(ptr) != PtrTypeOTLP {
+		return OTLPPtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil || u.Host == "" {
+		return OTLPPtrInfo{}, false
+	}
+
+	seq, err := strconv.ParseInt(u.Fragment, 10, 64)
+	if err != nil || seq < 1 {
+		return OTLPPtrInfo{}, false
+	}
+
+	return OTLPPtrInfo{
+		Addr: u.Host,
+		Seq:  seq,
+	}, true
+}
+
+// SQLitePtrInfo contains parsed information from a SQLite pointer.
+type SQLitePtrInfo struct {
+	Path  string // database file
+	Table string
+	RowID int64
+	Key   string // primary key of a row of a WITHOUT ROWID table, instead of RowID
+}
+
+// MakeSQLitePtr creates a SQLite pointer from a database path, a table name
+// and the rowid of a row in the table.
+func MakeSQLitePtr(dbPath, table string, rowid int64) string {
+	return makeSQLitePtr(dbPath, table, strconv.FormatInt(rowid, 10))
+}
+
+// MakeSQLiteKeyPtr creates a SQLite pointer from a database path, a table
+// name and the primary key of a row in a WITHOUT ROWID table, as encoded by
+// the SQLite source.
+func MakeSQLiteKeyPtr(dbPath, table, key string) string {
+	return makeSQLitePtr(dbPath, table, "key="+key)
+}
+
+func makeSQLitePtr(dbPath, table, fragment string) string {
+	u := url.URL{
+		Scheme:   "sqlite",
+		Path:     dbPath,
+		RawQuery: "table=" + url.QueryEscape(table),
+		Fragment: fragment,
+	}
+	return u.String()
+}
+
+// ParseSQLitePtr extracts the database path, table and rowid or primary key
+// from a SQLite pointer. Rowids may be any 64-bit integer, including
+// negative ones.
+func ParseSQLitePtr(ptr string) (SQLitePtrInfo, bool) {
+	if !strings.HasPrefix(ptr, "sqlite://") {
+		return SQLitePtrInfo{}, false
+	}
+
+	u, err := url.Parse(ptr)
+	if err != nil {
+		return SQLitePtrInfo{}, false
+	}
+
+	table := u.Query().Get("table")
+	if u.Path == "" || table == "" {
+		return SQLitePtrInfo{}, false
+	}
+
+	if key, ok := strings.CutPrefix(u.Fragment, "key="); ok {
+		if key == "" {
+			return SQLitePtrInfo{}, false
+		}
+		return SQLitePtrInfo{Path: u.Path, Table: table, Key: key}, true
+	}
+
+	rowid, err := strconv.ParseInt(u.Fragment, 10, 64)
+	if err != nil {
+		return SQLitePtrInfo{}, false
+	}
+
+	return SQLitePtrInfo{
+		Path:  u.Path,
+		Table: table,
+		RowID: rowid,
+	}, true
+}
@@ -30,6 +30,66 @@ func TestParsePtrType(t *testing.T) {
 			ptr:  "s3://mybucket/logs/app.log",
 			want: PtrTypeS3,
 		},
+		{
+			name: "journal pointer",
+			ptr:  "journal:///var/log/journal#s=0123;i=1a",
+			want: PtrTypeJournal,
+		},
+		{
+			name: "docker pointer",
+			ptr:  "docker:///var/lib/docker/containers/abc/abc-json.log#12",
+			want: PtrTypeContainer,
+		},
+		{
+			name: "k8s-node pointer",
+			ptr:  "k8s-node:///var/log/pods/default_web_uid/nginx/0.log#3",
+			want: PtrTypeContainer,
+		},
+		{
+			name: "loki pointer",
+			ptr:  "loki://localhost:3100/%7Bapp=%22api%22%7D#1736935200000000000",
+			want: PtrTypeLoki,
+		},
+		{
+			name: "elasticsearch pointer",
+			ptr:  "es://localhost:9200/logs-2025.01.15/Xk3mPZQBa1b2c3d4e5f6",
+			want: PtrTypeElasticsearch,
+		},
+		{
+			name: "https pointer",
+			ptr:  "https://ci.example.com/job/42/log.txt#1024",
+			want: PtrTypeHTTP,
+		},
+		{
+			name: "http pointer",
+			ptr:  "http://localhost:8080/app.log#0",
+			want: PtrTypeHTTP,
+		},
+		{
+			name: "archive pointer",
+			ptr:  "archive:///tmp/bundle.tar.gz?member=var/log/app.log#12",
+			want: PtrTypeArchive,
+		},
+		{
+			name: "syslog udp pointer",
+			ptr:  "syslog+udp://0.0.0.0:5514#7",
+			want: PtrTypeSyslog,
+		},
+		{
+			name: "syslog tcp pointer",
+			ptr:  "syslog+tcp://[::]:6514#7",
+			want: PtrTypeSyslog,
+		},
+		{
+			name: "otlp pointer",
+			ptr:  "otlp://[::]:4318#12",
+			want: PtrTypeOTLP,
+		},
+		{
+			name: "sqlite pointer",
+			ptr:  "sqlite:///var/lib/app/app.db?table=logs#42",
+			want: PtrTypeSQLite,
+		},
 		{
 			name: "cloudwatch pointer (base64-like)",
 			ptr:  "CmAKJgoiMzIxMDk4NzY1NDMyOi9hd3MvbGFtYmRhL215LWZ1bmN0aW9u",
@@ -47,7 +107,7 @@ func TestParsePtrType(t *testing.T) {
 		},
 		{
 			name: "unknown scheme",
-			ptr:  "http://example.com",
+			ptr:  "ftp://example.com",
 			want: PtrTypeUnknown,
 		},
 	}
@@ -308,3 +368,579 @@ func TestS3PtrRoundTrip(t *testing.T) {
 		t.Errorf("Offset = %d, want %d", info.Offset, offset)
 	}
 }
+
+func TestParseJournalPtr(t *testing.T) {
+	cursor := "s=6e3a1f0c9b2d4e8fa1b2c3d4e5f60718;i=2a;b=0f1e2d3c4b5a69788796a5b4c3d2e1f0;m=1e8480;t=5f3c2b1a09876;x=9a8b7c6d5e4f3a2b"
+
+	tests := []struct {
+		name       string
+		ptr        string
+		wantOK     bool
+		wantPath   string
+		wantCursor string
+	}{
+		{
+			name:       "valid pointer",
+			ptr:        "journal:///var/log/journal#" + cursor,
+			wantOK:     true,
+			wantPath:   "/var/log/journal",
+			wantCursor: cursor,
+		},
+		{
+			name:       "single journal file",
+			ptr:        "journal:///tmp/export/system.journal#" + cursor,
+			wantOK:     true,
+			wantPath:   "/tmp/export/system.journal",
+			wantCursor: cursor,
+		},
+		{
+			name:   "no cursor",
+			ptr:    "journal:///var/log/journal",
+			wantOK: false,
+		},
+		{
+			name:   "no path",
+			ptr:    "journal://#" + cursor,
+			wantOK: false,
+		},
+		{
+			name:   "not a journal pointer",
+			ptr:    "file:///var/log/app.log#1",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseJournalPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseJournalPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", info.Path, tt.wantPath)
+			}
+			if info.Cursor != tt.wantCursor {
+				t.Errorf("Cursor = %q, want %q", info.Cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestJournalPtrRoundTrip(t *testing.T) {
+	path := "/var/log/journal"
+	cursor := "s=6e3a1f0c9b2d4e8fa1b2c3d4e5f60718;i=2a;b=0f1e2d3c4b5a69788796a5b4c3d2e1f0;m=1e8480;t=5f3c2b1a09876;x=9a8b7c6d5e4f3a2b"
+
+	ptr := MakeJournalPtr(path, cursor)
+	info, ok := ParseJournalPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseJournalPtr failed on pointer created by MakeJournalPtr: %s", ptr)
+	}
+	if info.Path != path {
+		t.Errorf("Path = %q, want %q", info.Path, path)
+	}
+	if info.Cursor != cursor {
+		t.Errorf("Cursor = %q, want %q", info.Cursor, cursor)
+	}
+}
+
+func TestParseContainerPtr(t *testing.T) {
+	tests := []struct {
+		name       string
+		ptr        string
+		wantOK     bool
+		wantScheme string
+		wantPath   string
+		wantLine   int
+	}{
+		{
+			name:       "docker pointer",
+			ptr:        "docker:///var/lib/docker/containers/abc/abc-json.log#12",
+			wantOK:     true,
+			wantScheme: "docker",
+			wantPath:   "/var/lib/docker/containers/abc/abc-json.log",
+			wantLine:   12,
+		},
+		{
+			name:       "k8s-node pointer",
+			ptr:        "k8s-node:///var/log/pods/default_web_uid/nginx/0.log.20250115-100000.gz#3",
+			wantOK:     true,
+			wantScheme: "k8s-node",
+			wantPath:   "/var/log/pods/default_web_uid/nginx/0.log.20250115-100000.gz",
+			wantLine:   3,
+		},
+		{
+			name:   "container name instead of file",
+			ptr:    "docker://web#12",
+			wantOK: false,
+		},
+		{
+			name:   "no line number",
+			ptr:    "docker:///var/lib/docker/containers/abc/abc-json.log",
+			wantOK: false,
+		},
+		{
+			name:   "invalid line number",
+			ptr:    "k8s-node:///var/log/pods/default_web_uid/nginx/0.log#abc",
+			wantOK: false,
+		},
+		{
+			name:   "not a container pointer",
+			ptr:    "file:///var/log/app.log#1",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseContainerPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseContainerPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Scheme != tt.wantScheme {
+				t.Errorf("Scheme = %q, want %q", info.Scheme, tt.wantScheme)
+			}
+			if info.FilePath != tt.wantPath {
+				t.Errorf("FilePath = %q, want %q", info.FilePath, tt.wantPath)
+			}
+			if info.LineNum != tt.wantLine {
+				t.Errorf("LineNum = %d, want %d", info.LineNum, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestContainerPtrRoundTrip(t *testing.T) {
+	path := "/var/log/pods/kube-system_coredns-5d78c9869d-abcde_0123/coredns/1.log"
+
+	ptr := MakeContainerPtr("k8s-node", path, 42)
+	info, ok := ParseContainerPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseContainerPtr failed on pointer created by MakeContainerPtr: %s", ptr)
+	}
+	if info.Scheme != "k8s-node" {
+		t.Errorf("Scheme = %q, want %q", info.Scheme, "k8s-node")
+	}
+	if info.FilePath != path {
+		t.Errorf("FilePath = %q, want %q", info.FilePath, path)
+	}
+	if info.LineNum != 42 {
+		t.Errorf("LineNum = %d, want 42", info.LineNum)
+	}
+}
+
+func TestParseLokiPtr(t *testing.T) {
+	tests := []struct {
+		name         string
+		ptr          string
+		wantOK       bool
+		wantHost     string
+		wantSelector string
+		wantTS       int64
+	}{
+		{
+			name:         "escaped selector",
+			ptr:          "loki://localhost:3100/%7Bapp=%22api%22,env=%22prod%22%7D#1736935200000000001",
+			wantOK:       true,
+			wantHost:     "localhost:3100",
+			wantSelector: `{app="api",env="prod"}`,
+			wantTS:       1736935200000000001,
+		},
+		{
+			name:   "no timestamp",
+			ptr:    "loki://localhost:3100/%7Bapp=%22api%22%7D",
+			wantOK: false,
+		},
+		{
+			name:   "no selector",
+			ptr:    "loki://localhost:3100/#1736935200000000000",
+			wantOK: false,
+		},
+		{
+			name:   "no host",
+			ptr:    "loki:///%7Bapp=%22api%22%7D#1736935200000000000",
+			wantOK: false,
+		},
+		{
+			name:   "not a loki pointer",
+			ptr:    "file:///var/log/app.log#1",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseLokiPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseLokiPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", info.Host, tt.wantHost)
+			}
+			if info.Selector != tt.wantSelector {
+				t.Errorf("Selector = %q, want %q", info.Selector, tt.wantSelector)
+			}
+			if info.Timestamp != tt.wantTS {
+				t.Errorf("Timestamp = %d, want %d", info.Timestamp, tt.wantTS)
+			}
+		})
+	}
+}
+
+func TestLokiPtrRoundTrip(t *testing.T) {
+	selector := `{app="api", path="/v1/users?id=1#top"}`
+
+	ptr := MakeLokiPtr("loki.example.com", selector, 1736935200123456789)
+	info, ok := ParseLokiPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseLokiPtr failed on pointer created by MakeLokiPtr: %s", ptr)
+	}
+	if info.Host != "loki.example.com" {
+		t.Errorf("Host = %q, want %q", info.Host, "loki.example.com")
+	}
+	if info.Selector != selector {
+		t.Errorf("Selector = %q, want %q", info.Selector, selector)
+	}
+	if info.Timestamp != 1736935200123456789 {
+		t.Errorf("Timestamp = %d, want 1736935200123456789", info.Timestamp)
+	}
+}
+
+func TestParseElasticsearchPtr(t *testing.T) {
+	tests := []struct {
+		name      string
+		ptr       string
+		wantOK    bool
+		wantHost  string
+		wantIndex string
+		wantID    string
+	}{
+		{
+			name:      "document pointer",
+			ptr:       "es://localhost:9200/logs-2025.01.15/Xk3mPZQBa1b2c3d4e5f6",
+			wantOK:    true,
+			wantHost:  "localhost:9200",
+			wantIndex: "logs-2025.01.15",
+			wantID:    "Xk3mPZQBa1b2c3d4e5f6",
+		},
+		{
+			name:      "escaped ID",
+			ptr:       "es://search.example.com/audit/user%2F42%3Fx%23y",
+			wantOK:    true,
+			wantHost:  "search.example.com",
+			wantIndex: "audit",
+			wantID:    "user/42?x#y",
+		},
+		{
+			name:   "index pattern source URI",
+			ptr:    "es://localhost:9200/logs-*",
+			wantOK: false,
+		},
+		{
+			name:   "pattern with ID",
+			ptr:    "es://localhost:9200/logs-*/abc",
+			wantOK: false,
+		},
+		{
+			name:   "no host",
+			ptr:    "es:///logs/abc",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseElasticsearchPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseElasticsearchPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", info.Host, tt.wantHost)
+			}
+			if info.Index != tt.wantIndex {
+				t.Errorf("Index = %q, want %q", info.Index, tt.wantIndex)
+			}
+			if info.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", info.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestElasticsearchPtrRoundTrip(t *testing.T) {
+	id := "user/42?x#y"
+
+	ptr := MakeElasticsearchPtr("localhost:9200", "audit-2025.01", id)
+	info, ok := ParseElasticsearchPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseElasticsearchPtr failed on pointer created by MakeElasticsearchPtr: %s", ptr)
+	}
+	if info.Index != "audit-2025.01" || info.ID != id {
+		t.Errorf("Index = %q, ID = %q", info.Index, info.ID)
+	}
+}
+
+func TestParseHTTPPtr(t *testing.T) {
+	tests := []struct {
+		name       string
+		ptr        string
+		wantOK     bool
+		wantURL    string
+		wantOffset int64
+	}{
+		{
+			name:       "https pointer",
+			ptr:        "https://ci.example.com/job/42/log.txt#1024",
+			wantOK:     true,
+			wantURL:    "https://ci.example.com/job/42/log.txt",
+			wantOffset: 1024,
+		},
+		{
+			name:       "URL with query",
+			ptr:        "http://localhost:8080/raw?file=app.log#0",
+			wantOK:     true,
+			wantURL:    "http://localhost:8080/raw?file=app.log",
+			wantOffset: 0,
+		},
+		{
+			name:       "URL with fragment",
+			ptr:        "https://host/app.log#top#77",
+			wantOK:     true,
+			wantURL:    "https://host/app.log#top",
+			wantOffset: 77,
+		},
+		{
+			name:   "no offset",
+			ptr:    "https://host/app.log",
+			wantOK: false,
+		},
+		{
+			name:   "invalid offset",
+			ptr:    "https://host/app.log#abc",
+			wantOK: false,
+		},
+		{
+			name:   "no host",
+			ptr:    "https:///app.log#10",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseHTTPPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseHTTPPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.URL != tt.wantURL {
+				t.Errorf("URL = %q, want %q", info.URL, tt.wantURL)
+			}
+			if info.Offset != tt.wantOffset {
+				t.Errorf("Offset = %d, want %d", info.Offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestHTTPPtrRoundTrip(t *testing.T) {
+	rawURL := "https://ci.example.com/api/jobs/42/trace?token=x"
+
+	ptr := MakeHTTPPtr(rawURL, 4096)
+	info, ok := ParseHTTPPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseHTTPPtr failed on pointer created by MakeHTTPPtr: %s", ptr)
+	}
+	if info.URL != rawURL || info.Offset != 4096 {
+		t.Errorf("URL = %q, Offset = %d", info.URL, info.Offset)
+	}
+}
+
+func TestParseArchivePtr(t *testing.T) {
+	tests := []struct {
+		name        string
+		ptr         string
+		wantOK      bool
+		wantArchive string
+		wantMember  string
+		wantLine    int
+	}{
+		{
+			name:        "member pointer",
+			ptr:         "archive:///tmp/bundle.tar.gz?member=var/log/app.log#12",
+			wantOK:      true,
+			wantArchive: "/tmp/bundle.tar.gz",
+			wantMember:  "var/log/app.log",
+			wantLine:    12,
+		},
+		{
+			name:        "escaped member",
+			ptr:         "archive:///tmp/bundle.zip?member=logs/a+b%26c%23d.log#1",
+			wantOK:      true,
+			wantArchive: "/tmp/bundle.zip",
+			wantMember:  "logs/a b&c#d.log",
+			wantLine:    1,
+		},
+		{
+			name:   "source URI",
+			ptr:    "archive:///tmp/bundle.tar.gz?glob=var/log/*.log",
+			wantOK: false,
+		},
+		{
+			name:   "no line",
+			ptr:    "archive:///tmp/bundle.tar.gz?member=var/log/app.log",
+			wantOK: false,
+		},
+		{
+			name:   "no archive path",
+			ptr:    "archive://?member=app.log#1",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseArchivePtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseArchivePtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if info.ArchivePath != tt.wantArchive {
+				t.Errorf("ArchivePath = %q, want %q", info.ArchivePath, tt.wantArchive)
+			}
+			if info.Member != tt.wantMember {
+				t.Errorf("Member = %q, want %q", info.Member, tt.wantMember)
+			}
+			if info.LineNum != tt.wantLine {
+				t.Errorf("LineNum = %d, want %d", info.LineNum, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestArchivePtrRoundTrip(t *testing.T) {
+	archivePath := "/cases/INC-42/support bundle#1.tar.gz"
+	member := "node-1/var/log/app?1.log"
+
+	ptr := MakeArchivePtr(archivePath, member, 99)
+	info, ok := ParseArchivePtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseArchivePtr failed on pointer created by MakeArchivePtr: %s", ptr)
+	}
+	if info.ArchivePath != archivePath || info.Member != member || info.LineNum != 99 {
+		t.Errorf("ArchivePath = %q, Member = %q, LineNum = %d", info.ArchivePath, info.Member, info.LineNum)
+	}
+}
+
+func TestParseSyslogPtr(t *testing.T) {
+	tests := []struct {
+		name     string
+		ptr      string
+		wantOK   bool
+		wantAddr string
+		wantSeq  int64
+	}{
+		{name: "udp", ptr: "syslog+udp://0.0.0.0:5514#42", wantOK: true, wantAddr: "0.0.0.0:5514", wantSeq: 42},
+		{name: "tcp ipv6", ptr: "syslog+tcp://[::1]:6514#1", wantOK: true, wantAddr: "[::1]:6514", wantSeq: 1},
+		{name: "no sequence", ptr: "syslog+udp://0.0.0.0:5514", wantOK: false},
+		{name: "zero sequence", ptr: "syslog+udp://0.0.0.0:5514#0", wantOK: false},
+		{name: "no address", ptr: "syslog+udp://#3", wantOK: false},
+		{name: "not syslog", ptr: "file:///var/log/syslog#3", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := ParseSyslogPtr(tt.ptr)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseSyslogPtr(%q) ok = %v, want %v", tt.ptr, ok, tt.wantOK)
+			}
+			if ok && (info.Addr != tt.wantAddr || info.Seq != tt.wantSeq) {
+				t.Errorf("Addr = %q, Seq = %d; want %q, %d", info.Addr, info.Seq, tt.wantAddr, tt.wantSeq)
+			}
+		})
+	}
+}
+
+func TestSyslogPtrRoundTrip(t *testing.T) {
+	ptr := MakeSyslogPtr("syslog+tcp", "127.0.0.1:6514", 1234)
+	info, ok := ParseSyslogPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseSyslogPtr failed on pointer created by MakeSyslogPtr: %s", ptr)
+	}
+	if info.Scheme != "syslog+tcp" || info.Addr != "127.0.0.1:6514" || info.Seq != 1234 {
+		t.Errorf("Scheme = %q, Addr = %q, Seq = %d", info.Scheme, info.Addr, info.Seq)
+	}
+}
+
+func TestOTLPPtrRoundTrip(t *testing.T) {
+	ptr := MakeOTLPPtr("[::]:4318", 77)
+	info, ok := ParseOTLPPtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseOTLPPtr failed on pointer created by MakeOTLPPtr: %s", ptr)
+	}
+	if info.Addr != "[::]:4318" || info.Seq != 77 {
+		t.Errorf("Addr = %q, Seq = %d", info.Addr, info.Seq)
+	}
+
+	for _, bad := range []string{"otlp://:4318", "otlp://#3", "syslog+udp://0.0.0.0:514#3"} {
+		if _, ok := ParseOTLPPtr(bad); ok {
+			t.Errorf("ParseOTLPPtr(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSQLitePtrRoundTrip(t *testing.T) {
+	dbPath := "/var/lib/agent/logs #1.db"
+	table := "event log"
+
+	ptr := MakeSQLitePtr(dbPath, table, -7)
+	info, ok := ParseSQLitePtr(ptr)
+
+	if !ok {
+		t.Fatalf("ParseSQLitePtr failed on pointer created by MakeSQLitePtr: %s", ptr)
+	}
+	if info.Path != dbPath || info.Table != table || info.RowID != -7 {
+		t.Errorf("Path = %q, Table = %q, RowID = %d", info.Path, info.Table, info.RowID)
+	}
+
+	ptr = MakeSQLiteKeyPtr(dbPath, table, "0317")
+	info, ok = ParseSQLitePtr(ptr)
+	if !ok || info.Path != dbPath || info.Table != table || info.Key != "0317" {
+		t.Errorf("ParseSQLitePtr(%q) = %+v, %v", ptr, info, ok)
+	}
+
+	for _, bad := range []string{
+		"sqlite:///app.db?table=logs",
+		"sqlite:///app.db?table=logs#key=",
+		"sqlite:///app.db#3",
+		"sqlite://?table=logs#3",
+		"file:///app.db?table=logs#3",
+	} {
+		if _, ok := ParseSQLitePtr(bad); ok {
+			t.Errorf("ParseSQLitePtr(%q) should fail", bad)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	clerrors "github.com/jmurray2011/clew/internal/errors"
@@ -16,19 +17,35 @@ type SourceOpener func(u *url.URL, opts OpenOptions) (Source, error)
 // OpenOptions provides default values for source configuration.
 // These can be overridden by URI query parameters or alias config.
 type OpenOptions struct {
-	Profile string // Default AWS profile
-	Region  string // Default AWS region
+	Profile string            // Default AWS profile
+	Region  string            // Default AWS region
+	Headers map[string]string // HTTP request headers, from the alias config
 }
 
 // registry holds registered source openers by scheme.
 var registry = make(map[string]SourceOpener)
 
+// pluginSchemes holds the schemes served by source plugins.
+var pluginSchemes = make(map[string]bool)
+
 // Register adds a source opener for the given URI scheme.
 // This should be called during init() by each source implementation.
 func Register(scheme string, opener SourceOpener) {
 	registry[scheme] = opener
 }
 
+// RegisterPlugin adds a source opener for a scheme served by a source plugin,
+// whose pointers are URIs with that scheme. Built-in sources take precedence:
+// it returns false, and registers nothing, if the scheme is already registered.
+func RegisterPlugin(scheme string, opener SourceOpener) bool {
+	if _, ok := registry[scheme]; ok {
+		return false
+	}
+	registry[scheme] = opener
+	pluginSchemes[scheme] = true
+	return true
+}
+
 // Open parses a URI and returns the appropriate Source.
 // For CloudWatch sources, use OpenWithOptions to specify default profile/region.
 func Open(uri string) (Source, error) {
@@ -40,8 +57,24 @@ func Open(uri string) (Source, error) {
 //   - cloudwatch:///log-group (uses -p profile flag)
 //   - file:///path/to/file (or bare paths like /var/log/app.log)
 //   - s3://bucket/prefix
+//   - journal:///var/log/journal
+//   - loki://host:3100/{app="api"}
+//   - es://host:9200/logs-*
+//   - https://host/path/app.log
+//   - archive:///path/bundle.tar.gz?glob=var/log/*.log
+//   - syslog+udp://0.0.0.0:5514 (or syslog+tcp://)
+//   - otlp://:4318
+//   - sqlite:///path/app.db?table=logs
+//   - case://case-id (or case:///path/export.zip)
+//   - <scheme>://... served by a clew-source-<scheme> plugin
+//   - stdin:// (or - as shorthand)
 //   - @alias (resolved from config)
 func OpenWithOptions(uri string, opts OpenOptions) (Source, error) {
+	// Handle - as standard input
+	if uri == "-" {
+		uri = "stdin://"
+	}
+
 	// Handle bare paths as file://
 	if strings.HasPrefix(uri, "/") || strings.HasPrefix(uri, "./") || strings.HasPrefix(uri, "../") || strings.HasPrefix(uri, "~") {
 		// Resolve to absolute path to avoid url.Parse misinterpreting relative paths
@@ -117,7 +150,58 @@ func OpenAliasWithOptions(name string, opts OpenOptions) (Source, error) {
 		return nil, clerrors.SourceNotFoundError("@"+name, available)
 	}
 
-	return OpenWithOptions(alias.URI, opts)
+	if len(alias.Headers) > 0 {
+		opts.Headers = alias.Headers
+	}
+	return OpenWithOptions(aliasURI(alias), opts)
+}
+
+// aliasURI returns the URI of an alias with the alias format as its format
+// hint, unless the URI sets one. CloudWatch log groups and other aliases
+// take no format and are returned unchanged.
+func aliasURI(alias SourceAlias) string {
+	uri := alias.URI
+	if alias.Format == "" || strings.HasPrefix(uri, "@") || strings.HasPrefix(uri, "cloudwatch://") {
+		return uri
+	}
+	if uri == "-" {
+		uri = "stdin://"
+	}
+	if u, err := url.Parse(uri); err == nil && u.Query().Has("format") {
+		return uri
+	}
+	return withQueryParam(uri, "format", alias.Format)
+}
+
+// aliasHeaders returns the HTTP headers configured for the alias of a URL's
+// server, so sources reopened from pointers authenticate like the alias did.
+// Aliases are checked in name order; the first with the same scheme and host wins.
+func aliasHeaders(rawURL string) map[string]string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Sources))
+	for name := range cfg.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		alias := cfg.Sources[name]
+		if len(alias.Headers) == 0 {
+			continue
+		}
+		if a, err := url.Parse(alias.URI); err == nil && a.Scheme == u.Scheme && a.Host == u.Host {
+			return alias.Headers
+		}
+	}
+	return nil
 }
 
 // OpenFromPtr opens a source capable of retrieving the given pointer.
@@ -131,7 +215,16 @@ func OpenFromPtr(ptr string, metadata *SourceMetadata) (Source, error) {
 		if !ok {
 			return nil, fmt.Errorf("invalid local pointer: %s", ptr)
 		}
-		return Open("file://" + info.FilePath)
+		uri := "file://" + info.FilePath
+		// Keep the format of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil {
+				if format := u.Query().Get("format"); format != "" {
+					uri = withQueryParam(uri, "format", format)
+				}
+			}
+		}
+		return Open(uri)
 
 	case PtrTypeCloudWatch:
 		// CloudWatch pointers need metadata to know profile/region
@@ -151,13 +244,161 @@ func OpenFromPtr(ptr string, metadata *SourceMetadata) (Source, error) {
 		if !ok {
 			return nil, fmt.Errorf("invalid S3 pointer: %s", ptr)
 		}
-		return Open(fmt.Sprintf("s3://%s/%s", info.Bucket, info.Key))
+		uri := fmt.Sprintf("s3://%s/%s", info.Bucket, info.Key)
+		if metadata == nil {
+			return Open(uri)
+		}
+		// Keep endpoint/region/format settings from the source that produced the pointer
+		if u, err := url.Parse(metadata.URI); err == nil && u.RawQuery != "" {
+			uri += "?" + u.RawQuery
+		}
+		opts := OpenOptions{
+			Profile: metadata.Profile,
+			Region:  metadata.Region,
+		}
+		return OpenWithOptions(uri, opts)
+
+	case PtrTypeJournal:
+		info, ok := ParseJournalPtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid journal pointer: %s", ptr)
+		}
+		uri := "journal://" + info.Path
+		// Keep unit filters so context lines come from the same units
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil && u.RawQuery != "" {
+				uri += "?" + u.RawQuery
+			}
+		}
+		return Open(uri)
+
+	case PtrTypeContainer:
+		info, ok := ParseContainerPtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid container pointer: %s", ptr)
+		}
+		uri := info.Scheme + "://" + info.FilePath
+		// Keep the message format of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil && u.RawQuery != "" {
+				uri += "?" + u.RawQuery
+			}
+		}
+		return Open(uri)
+
+	case PtrTypeLoki:
+		info, ok := ParseLokiPtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid Loki pointer: %s", ptr)
+		}
+		uri := (&url.URL{Scheme: "loki", Host: info.Host, Path: "/" + info.Selector}).String()
+		// Keep the TLS and tenant settings of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil && u.RawQuery != "" {
+				uri += "?" + u.RawQuery
+			}
+		}
+		return Open(uri)
+
+	case PtrTypeHTTP:
+		info, ok := ParseHTTPPtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid HTTP pointer: %s", ptr)
+		}
+		uri := info.URL
+		// Keep the format of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil {
+				if format := u.Query().Get("format"); format != "" {
+					uri = withQueryParam(uri, "format", format)
+				}
+			}
+		}
+		return OpenWithOptions(uri, OpenOptions{Headers: aliasHeaders(info.URL)})
+
+	case PtrTypeArchive:
+		info, ok := ParseArchivePtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid archive pointer: %s", ptr)
+		}
+		uri := (&url.URL{Scheme: "archive", Path: info.ArchivePath}).String()
+		// Keep the member glob and format of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil && u.RawQuery != "" {
+				uri += "?" + u.RawQuery
+			}
+		}
+		return Open(uri)
+
+	case PtrTypeSQLite:
+		info, ok := ParseSQLitePtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid SQLite pointer: %s", ptr)
+		}
+		query := url.Values{}
+		// Keep the columns of the source that produced the pointer
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil {
+				query = u.Query()
+			}
+		}
+		query.Set("table", info.Table)
+		return Open((&url.URL{Scheme: "sqlite", Path: info.Path}).String() + "?" + query.Encode())
+
+	case PtrTypeElasticsearch:
+		info, ok := ParseElasticsearchPtr(ptr)
+		if !ok {
+			return nil, fmt.Errorf("invalid Elasticsearch pointer: %s", ptr)
+		}
+		uri := "es://" + info.Host + "/" + info.Index
+		if metadata != nil {
+			if u, err := url.Parse(metadata.URI); err == nil {
+				// Reopen the index pattern the pointer came from, so context
+				// can span indices, with its field and TLS settings
+				if u.Host == info.Host && u.Path != "" {
+					uri = "es://" + u.Host + u.EscapedPath()
+				}
+				if u.RawQuery != "" {
+					uri += "?" + u.RawQuery
+				}
+			}
+		}
+		return Open(uri)
+
+	case PtrTypeSyslog:
+		// Unspooled messages live only in the memory of the receiver that got them
+		return nil, fmt.Errorf("syslog receiver pointers are only valid while the receiver runs; add ?spool=<file> to the source URI to keep messages on disk")
+
+	case PtrTypeOTLP:
+		// Log records live only in the memory of the receiver that got them
+		return nil, fmt.Errorf("OTLP receiver pointers are only valid while the receiver runs")
+
+	case PtrTypePlugin:
+		// Plugin pointers are opaque; reopen the source they came from, so
+		// its settings apply, or else the pointer itself
+		uri := ptr
+		if metadata != nil {
+			scheme, _, _ := strings.Cut(ptr, "://")
+			if u, err := url.Parse(metadata.URI); err == nil && u.Scheme == scheme {
+				uri = metadata.URI
+			}
+		}
+		return Open(uri)
 
 	default:
 		return nil, fmt.Errorf("unknown pointer type: %s", ptr)
 	}
 }
 
+// withQueryParam adds a query parameter to a URI that may already have a query.
+func withQueryParam(uri, key, value string) string {
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	return uri + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
 func availableSchemes() string {
 	schemes := make([]string, 0, len(registry))
 	for s := range registry {
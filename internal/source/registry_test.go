@@ -1,8 +1,10 @@
 package source
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -161,3 +163,414 @@ func TestExpandPath_PreservesGlobs(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenFromPtr_S3(t *testing.T) {
+	var gotURL string
+	var gotOpts OpenOptions
+	Register("s3", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		gotOpts = opts
+		return nil, nil
+	})
+	defer delete(registry, "s3")
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+		wantOpts OpenOptions
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "s3://bucket/logs/app.log",
+		},
+		{
+			name: "with metadata",
+			metadata: &SourceMetadata{
+				Type:    "s3",
+				URI:     "s3://bucket/logs/?endpoint=http%3A%2F%2Flocalhost%3A9000&format=json",
+				Profile: "prod",
+				Region:  "eu-west-1",
+			},
+			wantURL:  "s3://bucket/logs/app.log?endpoint=http%3A%2F%2Flocalhost%3A9000&format=json",
+			wantOpts: OpenOptions{Profile: "prod", Region: "eu-west-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("s3://bucket/logs/app.log#42", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+			if !reflect.DeepEqual(gotOpts, tt.wantOpts) {
+				t.Errorf("expected options %+v, got %+v", tt.wantOpts, gotOpts)
+			}
+		})
+	}
+}
+
+func TestOpenFromPtr_Journal(t *testing.T) {
+	var gotURL string
+	Register("journal", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	})
+	defer delete(registry, "journal")
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "journal:///var/log/journal",
+		},
+		{
+			name: "with unit filter",
+			metadata: &SourceMetadata{
+				Type: "journal",
+				URI:  "journal:///var/log/journal?unit=nginx.service",
+			},
+			wantURL: "journal:///var/log/journal?unit=nginx.service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("journal:///var/log/journal#s=00;i=1", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
+
+func TestOpenFromPtr_Container(t *testing.T) {
+	var gotURL string
+	Register("docker", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	})
+	defer delete(registry, "docker")
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "docker:///var/lib/docker/containers/abc/abc-json.log",
+		},
+		{
+			name: "with format",
+			metadata: &SourceMetadata{
+				Type: "docker",
+				URI:  "docker://web?format=json",
+			},
+			wantURL: "docker:///var/lib/docker/containers/abc/abc-json.log?format=json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("docker:///var/lib/docker/containers/abc/abc-json.log#7", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
+
+func TestOpenFromPtr_Loki(t *testing.T) {
+	var gotHost, gotPath, gotQuery string
+	Register("loki", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotHost, gotPath, gotQuery = u.Host, u.Path, u.RawQuery
+		return nil, nil
+	})
+	defer delete(registry, "loki")
+
+	ptr := MakeLokiPtr("loki:3100", `{app="api",env="prod"}`, 1736935200000000000)
+	metadata := &SourceMetadata{Type: "loki", URI: `loki://loki:3100/{app="api"}?org=acme&tls=true`}
+	if _, err := OpenFromPtr(ptr, metadata); err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+
+	// The pointer's stream is reopened with the tenant and TLS settings of its source
+	if gotHost != "loki:3100" || gotPath != `/{app="api",env="prod"}` || gotQuery != "org=acme&tls=true" {
+		t.Errorf("opened host %q path %q query %q", gotHost, gotPath, gotQuery)
+	}
+}
+
+func TestOpenFromPtr_Elasticsearch(t *testing.T) {
+	var gotURL string
+	Register("es", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	})
+	defer delete(registry, "es")
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "es://localhost:9200/logs-2025.01.15",
+		},
+		{
+			name: "with index pattern and fields",
+			metadata: &SourceMetadata{
+				Type: "elasticsearch",
+				URI:  "es://localhost:9200/logs-*?stream=agent.name",
+			},
+			wantURL: "es://localhost:9200/logs-*?stream=agent.name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("es://localhost:9200/logs-2025.01.15/abc", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
+
+func TestOpenFromPtr_HTTP(t *testing.T) {
+	var gotURL string
+	var gotHeaders map[string]string
+	Register("https", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		gotHeaders = opts.Headers
+		return nil, nil
+	})
+	defer delete(registry, "https")
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := `sources:
+  ci:
+    uri: https://ci.example.com/job/1/log.txt
+    headers:
+      Authorization: Bearer ${CI_TOKEN}
+  other:
+    uri: https://other.example.com/app.log
+`
+	if err := os.MkdirAll(filepath.Join(home, ".clew"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".clew", "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ptr         string
+		metadata    *SourceMetadata
+		wantURL     string
+		wantHeaders bool
+	}{
+		{
+			name:        "alias host gets headers",
+			ptr:         "https://ci.example.com/job/42/log.txt#100",
+			wantURL:     "https://ci.example.com/job/42/log.txt",
+			wantHeaders: true,
+		},
+		{
+			name:     "format kept from metadata",
+			ptr:      "https://other.example.com/app.log#0",
+			metadata: &SourceMetadata{Type: "http", URI: "https://other.example.com/app.log?format=java"},
+			wantURL:  "https://other.example.com/app.log?format=java",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr(tt.ptr, tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+			if got := gotHeaders["Authorization"] != ""; got != tt.wantHeaders {
+				t.Errorf("expected headers %v, got %v", tt.wantHeaders, gotHeaders)
+			}
+		})
+	}
+}
+
+func TestOpenFromPtr_Archive(t *testing.T) {
+	var gotURL string
+	Register("archive", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	})
+	defer delete(registry, "archive")
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "archive:///tmp/bundle.tar.gz",
+		},
+		{
+			name: "with glob and format",
+			metadata: &SourceMetadata{
+				Type: "archive",
+				URI:  "archive:///tmp/bundle.tar.gz?glob=var/log/*.log&format=java",
+			},
+			wantURL: "archive:///tmp/bundle.tar.gz?glob=var/log/*.log&format=java",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("archive:///tmp/bundle.tar.gz?member=var/log/app.log#3", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	var gotURL string
+	opener := func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	}
+	Register("builtin", opener)
+	defer delete(registry, "builtin")
+	defer delete(registry, "mydb")
+	defer delete(pluginSchemes, "mydb")
+
+	if RegisterPlugin("builtin", opener) {
+		t.Error("expected a plugin not to replace a built-in source")
+	}
+	if !RegisterPlugin("mydb", opener) {
+		t.Fatal("expected the plugin to be registered")
+	}
+	if got := ParsePtrType("mydb://db1/events#42"); got != PtrTypePlugin {
+		t.Errorf("expected plugin pointer type, got %s", got)
+	}
+	if got := ParsePtrType("builtin://x#1"); got != PtrTypeUnknown {
+		t.Errorf("expected unknown pointer type for a non-plugin scheme, got %s", got)
+	}
+
+	tests := []struct {
+		name     string
+		metadata *SourceMetadata
+		wantURL  string
+	}{
+		{
+			name:    "without metadata",
+			wantURL: "mydb://db1/events#42",
+		},
+		{
+			name:     "source URI from metadata",
+			metadata: &SourceMetadata{Type: "mydb", URI: "mydb://db1/?table=events"},
+			wantURL:  "mydb://db1/?table=events",
+		},
+		{
+			name:     "metadata for another scheme",
+			metadata: &SourceMetadata{Type: "file", URI: "file:///var/log/app.log"},
+			wantURL:  "mydb://db1/events#42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFromPtr("mydb://db1/events#42", tt.metadata); err != nil {
+				t.Fatalf("OpenFromPtr failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
+
+func TestOpen_Stdin(t *testing.T) {
+	var gotScheme string
+	Register("stdin", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotScheme = u.Scheme
+		return nil, nil
+	})
+	defer delete(registry, "stdin")
+
+	for _, uri := range []string{"-", "stdin://"} {
+		gotScheme = ""
+		if _, err := Open(uri); err != nil {
+			t.Fatalf("Open(%q) failed: %v", uri, err)
+		}
+		if gotScheme != "stdin" {
+			t.Errorf("Open(%q) opened scheme %q, want stdin", uri, gotScheme)
+		}
+	}
+}
+
+func TestOpenAlias_Format(t *testing.T) {
+	var gotURL string
+	Register("file", func(u *url.URL, opts OpenOptions) (Source, error) {
+		gotURL = u.String()
+		return nil, nil
+	})
+	defer delete(registry, "file")
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := `sources:
+  app:
+    uri: /var/log/app.log
+    format: myapp
+  pinned:
+    uri: file:///var/log/app.log?format=json
+    format: myapp
+  plain:
+    uri: file:///var/log/app.log
+`
+	if err := os.MkdirAll(filepath.Join(home, ".clew"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".clew", "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alias   string
+		wantURL string
+	}{
+		{"@app", "file:///var/log/app.log?format=myapp"},
+		{"@pinned", "file:///var/log/app.log?format=json"},
+		{"@plain", "file:///var/log/app.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			if _, err := Open(tt.alias); err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("expected URL %q, got %q", tt.wantURL, gotURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,806 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"unicode/utf16"
+
+	"github.com/jmurray2011/clew/internal/logging"
+)
+
+// The SQLite file format is documented at https://www.sqlite.org/fileformat2.html.
+// Only what is needed to read tables and their indexes is implemented;
+// no SQL is run and the database is never written or locked. Frames committed
+// to the write-ahead log (<database>-wal) replace the pages they hold, so rows
+// a running application has not checkpointed yet are read too.
+
+// headerString starts every database file.
+const headerString = "SQLite format 3\x00"
+
+const (
+	dbHeaderSize       = 100
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+
+	// WAL magic numbers; the low bit selects big-endian checksums
+	walMagicLE = 0x377f0682
+	walMagicBE = 0x377f0683
+
+	// maxTreeDepth guards against cycles in corrupt b-trees
+	maxTreeDepth = 64
+
+	// maxPayloadSize guards against corrupt sizes causing huge allocations
+	maxPayloadSize = 256 * 1024 * 1024
+
+	// minRowid and maxRowid bound the rowids of a table
+	minRowid = math.MinInt64
+	maxRowid = math.MaxInt64
+
+	// pageCacheSize bounds the number of pages kept per open database.
+	// Interior pages are read again for every rowid looked up.
+	pageCacheSize = 1024
+)
+
+// B-tree page types
+const (
+	pageIndexInterior = 0x02
+	pageTableInterior = 0x05
+	pageIndexLeaf     = 0x0a
+	pageTableLeaf     = 0x0d
+)
+
+// Database text encodings
+const (
+	encodingUTF8    = 1
+	encodingUTF16LE = 2
+	encodingUTF16BE = 3
+)
+
+// database is an open SQLite database file.
+type database struct {
+	path      string
+	f         *os.File
+	wal       *os.File
+	walPages  map[uint32]int64 // page number to offset of its newest committed frame
+	pageSize  int
+	usable    int // page size less the bytes reserved at the end of each page
+	pageCount uint32
+	encoding  uint32
+	cache     map[uint32][]byte
+}
+
+// openDatabase opens a database file and its write-ahead log, if any.
+func openDatabase(path string) (*database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &database{path: path, f: f, cache: make(map[uint32][]byte)}
+	if err := db.init(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// init reads the database header and the committed frames of the WAL.
+func (db *database) init() error {
+	header := make([]byte, dbHeaderSize)
+	n, err := db.f.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	// A database in WAL mode can be empty until its first checkpoint
+	if n > 0 {
+		if n < dbHeaderSize || string(header[:len(headerString)]) != headerString {
+			return fmt.Errorf("%s is not a SQLite database", db.path)
+		}
+		db.pageSize = pageSizeOf(uint32(binary.BigEndian.Uint16(header[16:])))
+		if db.pageSize == 0 {
+			return fmt.Errorf("%s has an invalid page size", db.path)
+		}
+	}
+
+	walPageCount, err := db.readWAL()
+	if err != nil {
+		return err
+	}
+	if db.pageSize == 0 {
+		return fmt.Errorf("%s is an empty database", db.path)
+	}
+
+	// Page 1 holds the header as of the last commit, which may be in the WAL
+	db.usable = db.pageSize
+	db.pageCount = math.MaxUint32
+	page1, err := db.page(1)
+	if err != nil {
+		return err
+	}
+	db.usable = db.pageSize - int(page1[20])
+	db.encoding = binary.BigEndian.Uint32(page1[56:])
+	if db.encoding == 0 {
+		db.encoding = encodingUTF8
+	}
+
+	switch {
+	case walPageCount > 0:
+		db.pageCount = walPageCount
+	case binary.BigEndian.Uint32(page1[92:]) == binary.BigEndian.Uint32(page1[24:]) && binary.BigEndian.Uint32(page1[28:]) > 0:
+		// The header's page count is valid when written by the last commit
+		db.pageCount = binary.BigEndian.Uint32(page1[28:])
+	default:
+		info, err := db.f.Stat()
+		if err != nil {
+			return err
+		}
+		db.pageCount = uint32(info.Size() / int64(db.pageSize))
+	}
+
+	if db.usable < 480 {
+		return fmt.Errorf("%s has an invalid page size", db.path)
+	}
+	return nil
+}
+
+// pageSizeOf decodes a page size field, in which 1 means 65536.
+func pageSizeOf(v uint32) int {
+	if v == 1 {
+		return 65536
+	}
+	if v < 512 || v > 32768 || v&(v-1) != 0 {
+		return 0
+	}
+	return int(v)
+}
+
+// readWAL indexes the frames of committed transactions in the write-ahead
+// log and returns the database size in pages after the last commit, or 0 if
+// there is no WAL. Frames after the last commit, from an earlier generation
+// of the log (with other salts) or with a bad checksum are ignored, as
+// SQLite ignores them.
+func (db *database) readWAL() (uint32, error) {
+	wal, err := os.Open(db.path + "-wal")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	db.wal = wal
+
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(io.NewSectionReader(wal, 0, walHeaderSize), header); err != nil {
+		return 0, nil
+	}
+
+	var order binary.ByteOrder
+	switch binary.BigEndian.Uint32(header) {
+	case walMagicLE:
+		order = binary.LittleEndian
+	case walMagicBE:
+		order = binary.BigEndian
+	default:
+		return 0, nil
+	}
+	pageSize := pageSizeOf(binary.BigEndian.Uint32(header[8:]))
+	if pageSize == 0 || (db.pageSize != 0 && pageSize != db.pageSize) {
+		logging.Debug("Ignoring %s-wal: page size does not match the database", db.path)
+		return 0, nil
+	}
+	s0, s1 := walChecksum(order, header[:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(header[24:]) || s1 != binary.BigEndian.Uint32(header[28:]) {
+		return 0, nil
+	}
+	db.pageSize = pageSize
+
+	salts := header[16:24]
+	frame := make([]byte, walFrameHeaderSize+pageSize)
+	pending := make(map[uint32]int64)
+	db.walPages = make(map[uint32]int64)
+	var pageCount uint32
+
+	for offset := int64(walHeaderSize); ; offset += int64(len(frame)) {
+		if _, err := wal.ReadAt(frame, offset); err != nil {
+			break
+		}
+		if string(frame[8:16]) != string(salts) {
+			break
+		}
+		s0, s1 = walChecksum(order, frame[:8], s0, s1)
+		s0, s1 = walChecksum(order, frame[walFrameHeaderSize:], s0, s1)
+		if s0 != binary.BigEndian.Uint32(frame[16:]) || s1 != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+
+		pending[binary.BigEndian.Uint32(frame)] = offset + walFrameHeaderSize
+		// Commit frames record the database size after the transaction
+		if size := binary.BigEndian.Uint32(frame[4:]); size != 0 {
+			for page, off := range pending {
+				db.walPages[page] = off
+			}
+			clear(pending)
+			pageCount = size
+		}
+	}
+
+	return pageCount, nil
+}
+
+// walChecksum continues a WAL checksum over data.
+func walChecksum(order binary.ByteOrder, data []byte, s0, s1 uint32) (uint32, uint32) {
+	for i := 0; i+8 <= len(data); i += 8 {
+		s0 += order.Uint32(data[i:]) + s1
+		s1 += order.Uint32(data[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// Close closes the database and WAL files.
+func (db *database) Close() error {
+	if db.wal != nil {
+		_ = db.wal.Close()
+	}
+	return db.f.Close()
+}
+
+// page returns the content of a page. The returned slice must not be modified.
+func (db *database) page(n uint32) ([]byte, error) {
+	if n < 1 || n > db.pageCount {
+		return nil, fmt.Errorf("page %d out of range (database has %d pages)", n, db.pageCount)
+	}
+	if data, ok := db.cache[n]; ok {
+		return data, nil
+	}
+
+	data := make([]byte, db.pageSize)
+	var err error
+	if offset, ok := db.walPages[n]; ok {
+		_, err = db.wal.ReadAt(data, offset)
+	} else {
+		_, err = db.f.ReadAt(data, int64(n-1)*int64(db.pageSize))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read page %d: %w", n, err)
+	}
+
+	if len(db.cache) >= pageCacheSize {
+		clear(db.cache)
+	}
+	db.cache[n] = data
+	return data, nil
+}
+
+// btreePage is a parsed b-tree page.
+type btreePage struct {
+	number uint32
+	typ    byte
+	data   []byte
+	cells  []int  // offsets of the cells, in key order
+	right  uint32 // right-most child of interior pages
+}
+
+// btreePage reads a b-tree page.
+func (db *database) btreePage(n uint32) (*btreePage, error) {
+	data, err := db.page(n)
+	if err != nil {
+		return nil, err
+	}
+
+	// The database header precedes the b-tree header on page 1
+	hdr := 0
+	if n == 1 {
+		hdr = dbHeaderSize
+	}
+	p := &btreePage{number: n, typ: data[hdr], data: data}
+
+	ptrs := hdr + 8
+	switch p.typ {
+	case pageIndexInterior, pageTableInterior:
+		p.right = binary.BigEndian.Uint32(data[hdr+8:])
+		ptrs = hdr + 12
+	case pageIndexLeaf, pageTableLeaf:
+	default:
+		return nil, fmt.Errorf("page %d is not a b-tree page", n)
+	}
+
+	count := int(binary.BigEndian.Uint16(data[hdr+3:]))
+	if ptrs+2*count > db.usable {
+		return nil, fmt.Errorf("page %d has an invalid cell count", n)
+	}
+	p.cells = make([]int, count)
+	for i := range p.cells {
+		p.cells[i] = int(binary.BigEndian.Uint16(data[ptrs+2*i:]))
+		if p.cells[i] < ptrs || p.cells[i] >= db.usable {
+			return nil, fmt.Errorf("page %d has an invalid cell offset", n)
+		}
+	}
+	return p, nil
+}
+
+// varint decodes a SQLite variable-length integer, returning its length or
+// 0 if b is too short.
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
+
+// appendVarint appends a SQLite variable-length integer: big-endian groups
+// of 7 bits, except for a ninth byte of 8.
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [9]byte
+	if v > 1<<56-1 {
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	i := 7
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:8]...)
+}
+
+// cellVarint decodes a varint at an offset of a page.
+func (p *btreePage) cellVarint(off int) (uint64, int, error) {
+	if off >= len(p.data) {
+		return 0, 0, fmt.Errorf("page %d has a truncated cell", p.number)
+	}
+	v, n := varint(p.data[off:])
+	if n == 0 {
+		return 0, 0, fmt.Errorf("page %d has a truncated cell", p.number)
+	}
+	return v, off + n, nil
+}
+
+// payload returns the payload of a cell starting at an offset of a page,
+// following its overflow pages.
+func (db *database) payload(p *btreePage, off int, size uint64) ([]byte, error) {
+	if size > maxPayloadSize {
+		return nil, fmt.Errorf("page %d has a cell of %d bytes", p.number, size)
+	}
+
+	local := db.localPayloadSize(int(size), p.typ == pageTableLeaf)
+	if off+local > db.usable {
+		return nil, fmt.Errorf("page %d has a truncated cell", p.number)
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, p.data[off:off+local]...)
+	if local == int(size) {
+		return payload, nil
+	}
+
+	if off+local+4 > db.usable {
+		return nil, fmt.Errorf("page %d has a truncated cell", p.number)
+	}
+	next := binary.BigEndian.Uint32(p.data[off+local:])
+	for len(payload) < int(size) {
+		if next == 0 {
+			return nil, fmt.Errorf("overflow chain of page %d ends early", p.number)
+		}
+		data, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		n := min(int(size)-len(payload), db.usable-4)
+		payload = append(payload, data[4:4+n]...)
+		next = binary.BigEndian.Uint32(data)
+	}
+	return payload, nil
+}
+
+// localPayloadSize returns how much of a payload is stored in its cell, the
+// rest going to overflow pages.
+func (db *database) localPayloadSize(size int, tableLeaf bool) int {
+	u := db.usable
+	maxLocal := (u-12)*64/255 - 23
+	if tableLeaf {
+		maxLocal = u - 35
+	}
+	if size <= maxLocal {
+		return size
+	}
+	minLocal := (u-12)*32/255 - 23
+	k := minLocal + (size-minLocal)%(u-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// rowFunc is called with the rowid and record of table rows. It returns
+// false to stop.
+type rowFunc func(rowid int64, record []byte) (bool, error)
+
+// walkTable calls fn for the rows of a table b-tree with rowids from lo to
+// hi, in ascending rowid order or, if desc, descending.
+func (db *database) walkTable(root uint32, lo, hi int64, desc bool, fn rowFunc) error {
+	_, err := db.walkTablePage(root, lo, hi, desc, fn, 0)
+	return err
+}
+
+func (db *database) walkTablePage(n uint32, lo, hi int64, desc bool, fn rowFunc, depth int) (bool, error) {
+	if depth > maxTreeDepth {
+		return false, fmt.Errorf("b-tree at page %d is too deep", n)
+	}
+	p, err := db.btreePage(n)
+	if err != nil {
+		return false, err
+	}
+
+	switch p.typ {
+	case pageTableLeaf:
+		for _, i := range cellOrder(len(p.cells), desc) {
+			size, off, err := p.cellVarint(p.cells[i])
+			if err != nil {
+				return false, err
+			}
+			key, off, err := p.cellVarint(off)
+			if err != nil {
+				return false, err
+			}
+			if rowid := int64(key); rowid >= lo && rowid <= hi {
+				record, err := db.payload(p, off, size)
+				if err != nil {
+					return false, err
+				}
+				if more, err := fn(rowid, record); !more || err != nil {
+					return false, err
+				}
+			}
+		}
+		return true, nil
+
+	case pageTableInterior:
+		// Child i holds the rowids after key i-1 up to key i, and the
+		// right-most child those after the last key
+		children := make([]uint32, len(p.cells)+1)
+		keys := make([]int64, len(p.cells))
+		for i, off := range p.cells {
+			if off+4 > len(p.data) {
+				return false, fmt.Errorf("page %d has a truncated cell", n)
+			}
+			children[i] = binary.BigEndian.Uint32(p.data[off:])
+			key, _, err := p.cellVarint(off + 4)
+			if err != nil {
+				return false, err
+			}
+			keys[i] = int64(key)
+		}
+		children[len(p.cells)] = p.right
+
+		for _, i := range cellOrder(len(children), desc) {
+			if i < len(keys) && keys[i] < lo {
+				continue
+			}
+			if i > 0 && keys[i-1] >= hi {
+				continue
+			}
+			if more, err := db.walkTablePage(children[i], lo, hi, desc, fn, depth+1); !more || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	return false, fmt.Errorf("page %d is not a table b-tree page", n)
+}
+
+// row returns the record of the row with a rowid, or nil if there is none.
+func (db *database) row(root uint32, rowid int64) ([]byte, error) {
+	var found []byte
+	err := db.walkTable(root, rowid, rowid, false, func(_ int64, record []byte) (bool, error) {
+		found = record
+		return false, nil
+	})
+	return found, err
+}
+
+// keyFunc is called with the keys of index entries, and the records they
+// were decoded from. The last value of an index key is the rowid of the row
+// the entry is for; the entries of WITHOUT ROWID tables are their rows. It
+// returns false to stop.
+type keyFunc func(key []value, record []byte) (bool, error)
+
+// walkIndex calls fn for the entries of an index b-tree in key order or, if
+// desc, in reverse. position tells where a key is relative to the keys
+// wanted: negative before them, positive after them, and 0 among them or
+// unknown. Entries outside the wanted keys are skipped, as are subtrees
+// whose keys all are.
+func (db *database) walkIndex(root uint32, desc bool, position func(key []value) int, fn keyFunc) error {
+	_, err := db.walkIndexPage(root, desc, position, fn, 0)
+	return err
+}
+
+func (db *database) walkIndexPage(n uint32, desc bool, position func([]value) int, fn keyFunc, depth int) (bool, error) {
+	if depth > maxTreeDepth {
+		return false, fmt.Errorf("b-tree at page %d is too deep", n)
+	}
+	p, err := db.btreePage(n)
+	if err != nil {
+		return false, err
+	}
+	if p.typ != pageIndexLeaf && p.typ != pageIndexInterior {
+		return false, fmt.Errorf("page %d is not an index b-tree page", n)
+	}
+	interior := p.typ == pageIndexInterior
+
+	// Interior cells are entries too, between their left child and the next child
+	keys := make([][]value, len(p.cells))
+	payloads := make([][]byte, len(p.cells))
+	positions := make([]int, len(p.cells))
+	children := make([]uint32, len(p.cells)+1)
+	for i, off := range p.cells {
+		if interior {
+			if off+4 > len(p.data) {
+				return false, fmt.Errorf("page %d has a truncated cell", n)
+			}
+			children[i] = binary.BigEndian.Uint32(p.data[off:])
+			off += 4
+		}
+		size, off, err := p.cellVarint(off)
+		if err != nil {
+			return false, err
+		}
+		if payloads[i], err = db.payload(p, off, size); err != nil {
+			return false, err
+		}
+		if keys[i], err = db.decodeRecord(payloads[i]); err != nil {
+			return false, fmt.Errorf("page %d: %w", n, err)
+		}
+		positions[i] = position(keys[i])
+	}
+	children[len(p.cells)] = p.right
+
+	visit := func(child int) (bool, error) {
+		if !interior {
+			return true, nil
+		}
+		// The child holds the keys between the entries either side of it
+		if child > 0 && positions[child-1] > 0 {
+			return true, nil
+		}
+		if child < len(keys) && positions[child] < 0 {
+			return true, nil
+		}
+		return db.walkIndexPage(children[child], desc, position, fn, depth+1)
+	}
+	entry := func(i int) (bool, error) {
+		if positions[i] != 0 {
+			// Past the wanted keys in the direction of the walk
+			return desc == (positions[i] > 0), nil
+		}
+		return fn(keys[i], payloads[i])
+	}
+
+	if desc {
+		if more, err := visit(len(keys)); !more || err != nil {
+			return false, err
+		}
+		for i := len(keys) - 1; i >= 0; i-- {
+			if more, err := entry(i); !more || err != nil {
+				return false, err
+			}
+			if more, err := visit(i); !more || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	for i := range keys {
+		if more, err := visit(i); !more || err != nil {
+			return false, err
+		}
+		if more, err := entry(i); !more || err != nil {
+			return false, err
+		}
+	}
+	return visit(len(keys))
+}
+
+// cellOrder returns the indexes 0..n-1, reversed if desc.
+func cellOrder(n int, desc bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		if desc {
+			order[i] = n - 1 - i
+		}
+	}
+	return order
+}
+
+// Storage classes of values
+const (
+	kindNull = iota
+	kindInteger
+	kindReal
+	kindText
+	kindBlob
+)
+
+// value is a column value of a record.
+type value struct {
+	kind int
+	i    int64
+	f    float64
+	s    string // text, or the bytes of a blob
+}
+
+// decodeRecord decodes a record: a header of serial types followed by the values.
+func (db *database) decodeRecord(record []byte) ([]value, error) {
+	headerSize, pos := varint(record)
+	if pos == 0 || headerSize > uint64(len(record)) {
+		return nil, fmt.Errorf("invalid record header")
+	}
+
+	var values []value
+	body := int(headerSize)
+	for pos < int(headerSize) {
+		serialType, n := varint(record[pos:headerSize])
+		if n == 0 {
+			return nil, fmt.Errorf("invalid record header")
+		}
+		pos += n
+
+		size := serialSize(serialType)
+		if size < 0 || body+size > len(record) {
+			return nil, fmt.Errorf("invalid record value")
+		}
+		values = append(values, db.decodeValue(serialType, record[body:body+size]))
+		body += size
+	}
+	return values, nil
+}
+
+// recordField returns the serial type and bytes of the value at a position
+// of a record without decoding the others, or false if the record is too
+// short to hold it.
+func recordField(record []byte, position int) (uint64, []byte, bool) {
+	headerSize, pos := varint(record)
+	if pos == 0 || headerSize > uint64(len(record)) {
+		return 0, nil, false
+	}
+
+	body := int(headerSize)
+	for i := 0; pos < int(headerSize); i++ {
+		serialType, n := varint(record[pos:headerSize])
+		if n == 0 {
+			return 0, nil, false
+		}
+		pos += n
+
+		size := serialSize(serialType)
+		if size < 0 || body+size > len(record) {
+			return 0, nil, false
+		}
+		if i == position {
+			return serialType, record[body : body+size], true
+		}
+		body += size
+	}
+	return 0, nil, false
+}
+
+// encodeRecord encodes values as a record, with text in UTF-8. It is the
+// inverse of decodeRecord for databases in UTF-8.
+func encodeRecord(values []value) []byte {
+	var header, body []byte
+	for _, v := range values {
+		switch v.kind {
+		case kindNull:
+			header = appendVarint(header, 0)
+		case kindInteger:
+			// Serial types 1 to 6 hold integers of 1, 2, 3, 4, 6 and 8 bytes
+			serialType, size := uint64(6), 8
+			for i, n := range []int{1, 2, 3, 4, 6} {
+				if v.i >= -1<<(8*n-1) && v.i < 1<<(8*n-1) {
+					serialType, size = uint64(i+1), n
+					break
+				}
+			}
+			header = appendVarint(header, serialType)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v.i>>(8*i)))
+			}
+		case kindReal:
+			header = appendVarint(header, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v.f))
+		case kindText:
+			header = appendVarint(header, uint64(len(v.s))*2+13)
+			body = append(body, v.s...)
+		case kindBlob:
+			header = appendVarint(header, uint64(len(v.s))*2+12)
+			body = append(body, v.s...)
+		}
+	}
+	// The header size counts the varint holding it
+	n := 1
+	for len(appendVarint(nil, uint64(len(header)+n))) != n {
+		n++
+	}
+	record := appendVarint(nil, uint64(len(header)+n))
+	return append(append(record, header...), body...)
+}
+
+// serialSize returns the size of a value of a serial type, or -1 for the
+// reserved types.
+func serialSize(serialType uint64) int {
+	switch {
+	case serialType <= 4:
+		return int(serialType)
+	case serialType == 5:
+		return 6
+	case serialType == 6, serialType == 7:
+		return 8
+	case serialType == 8, serialType == 9:
+		return 0
+	case serialType >= 12 && serialType < 2*maxPayloadSize:
+		return int(serialType-12) / 2
+	}
+	return -1
+}
+
+func (db *database) decodeValue(serialType uint64, data []byte) value {
+	switch {
+	case serialType == 0:
+		return value{kind: kindNull}
+	case serialType <= 6:
+		// Big-endian two's complement integers of 1 to 8 bytes
+		var v int64
+		for i, b := range data {
+			if i == 0 {
+				v = int64(int8(b))
+			} else {
+				v = v<<8 | int64(b)
+			}
+		}
+		return value{kind: kindInteger, i: v}
+	case serialType == 7:
+		return value{kind: kindReal, f: math.Float64frombits(binary.BigEndian.Uint64(data))}
+	case serialType == 8, serialType == 9:
+		return value{kind: kindInteger, i: int64(serialType - 8)}
+	case serialType%2 == 0:
+		return value{kind: kindBlob, s: string(data)}
+	}
+	return value{kind: kindText, s: db.decodeText(data)}
+}
+
+// decodeText decodes text in the database encoding.
+func (db *database) decodeText(data []byte) string {
+	if db.encoding == encodingUTF8 {
+		return string(data)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if db.encoding == encodingUTF16BE {
+		order = binary.BigEndian
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestDatabase(t *testing.T, name string) *database {
+	t.Helper()
+	db, err := openDatabase(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("openDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestDatabase_WalkTable(t *testing.T) {
+	db := openTestDatabase(t, "logs.db")
+	tbl, err := db.findTable("logs")
+	if err != nil {
+		t.Fatalf("findTable failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		lo, hi int64
+		desc   bool
+		want   []int64
+	}{
+		{name: "range", lo: 120, hi: 123, want: []int64{120, 121, 122, 123}},
+		{name: "range descending", lo: 120, hi: 123, desc: true, want: []int64{123, 122, 121, 120}},
+		{name: "first", lo: minRowid, hi: 1, want: []int64{1}},
+		{name: "past the end", lo: 300, hi: maxRowid, want: []int64{300}},
+		{name: "empty", lo: 301, hi: maxRowid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			err := db.walkTable(tbl.root, tt.lo, tt.hi, tt.desc, func(rowid int64, _ []byte) (bool, error) {
+				got = append(got, rowid)
+				return true, nil
+			})
+			if err != nil {
+				t.Fatalf("walkTable failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("rowids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("rowids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDatabase_WalkIndex(t *testing.T) {
+	db := openTestDatabase(t, "logs.db")
+	tbl, err := db.findTable("logs")
+	if err != nil {
+		t.Fatalf("findTable failed: %v", err)
+	}
+	idx := tbl.indexOn("created_at")
+	if idx == nil {
+		t.Fatalf("no index on created_at in %+v", tbl.indexes)
+	}
+
+	// Keys between 10:01:40 and 10:01:42, counting the keys compared
+	compared := 0
+	position := func(key []value) int {
+		compared++
+		switch {
+		case key[0].s < "2024-03-01 10:01:40":
+			return -1
+		case key[0].s > "2024-03-01 10:01:42":
+			return 1
+		}
+		return 0
+	}
+
+	var got []int64
+	err = db.walkIndex(idx.root, true, position, func(key []value, _ []byte) (bool, error) {
+		got = append(got, key[len(key)-1].i)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("walkIndex failed: %v", err)
+	}
+	if len(got) != 3 || got[0] != 102 || got[2] != 100 {
+		t.Errorf("rowids = %v, want [102 101 100]", got)
+	}
+	// Pages outside the range are skipped
+	if compared >= 300 {
+		t.Errorf("compared %d keys, want fewer than the 300 rows", compared)
+	}
+}
+
+func TestOpenDatabase_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	valid, err := os.ReadFile(filepath.Join("testdata", "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	badPageSize := append([]byte(nil), valid...)
+	badPageSize[16], badPageSize[17] = 0, 100
+
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{name: "text file", content: []byte("2024-03-01 10:00:00 INFO started\n"), wantErr: "not a SQLite database"},
+		{name: "empty", content: nil, wantErr: "empty database"},
+		{name: "truncated header", content: valid[:50], wantErr: "not a SQLite database"},
+		{name: "invalid page size", content: badPageSize, wantErr: "page size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".db")
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			db, err := openDatabase(path)
+			if err == nil {
+				_ = db.Close()
+				t.Fatalf("openDatabase succeeded, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenDatabase_UncommittedWAL(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"wal.db", "wal.db-wal"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Cut the WAL in the middle of the last commit's frame
+		if name == "wal.db-wal" {
+			data = data[:len(data)-100]
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := NewSource(filepath.Join(dir, "wal.db"), "app_log", Options{})
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	last, err := src.lastRow()
+	if err != nil || last == nil || last.rowid != 2 {
+		t.Errorf("lastRow = %v, %v; want 2 committed rows", last, err)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		sql       string
+		want      string
+		wantRowid string
+	}{
+		{
+			sql:       "CREATE TABLE logs (id INTEGER PRIMARY KEY, ts TEXT, msg TEXT)",
+			want:      "id,ts,msg",
+			wantRowid: "id",
+		},
+		{
+			sql:       `CREATE TABLE "log entries" ("the id" integer, [msg] TEXT DEFAULT 'a,b', ts, PRIMARY KEY ("the id"))`,
+			want:      "the id,msg,ts",
+			wantRowid: "the id",
+		},
+		{
+			sql:  "CREATE TABLE t (id TEXT PRIMARY KEY, -- comment, with comma\n body TEXT CHECK (length(body) > 0), UNIQUE (body))",
+			want: "id,body",
+		},
+		{
+			sql:  "CREATE TABLE t (a INTEGER, b INTEGER, PRIMARY KEY (a, b))",
+			want: "a,b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			tbl, err := parseTable(tt.sql)
+			if err != nil {
+				t.Fatalf("parseTable failed: %v", err)
+			}
+			var names []string
+			rowid := ""
+			for _, c := range tbl.columns {
+				names = append(names, c.name)
+				if c.rowid {
+					rowid = c.name
+				}
+			}
+			if strings.Join(names, ",") != tt.want || rowid != tt.wantRowid {
+				t.Errorf("columns = %v, rowid %q; want %s, rowid %q", names, rowid, tt.want, tt.wantRowid)
+			}
+		})
+	}
+
+}
+
+func TestParseTable_WithoutRowid(t *testing.T) {
+	tests := []struct {
+		sql        string
+		wantKey    []int
+		wantRecord []int
+	}{
+		{"CREATE TABLE t (k TEXT PRIMARY KEY, v) WITHOUT ROWID", []int{0}, []int{0, 1}},
+		{"CREATE TABLE t (id INTEGER PRIMARY KEY, v) WITHOUT ROWID", []int{0}, []int{0, 1}},
+		{"CREATE TABLE t (a, b, c, CONSTRAINT pk PRIMARY KEY (c, a)) WITHOUT ROWID", []int{2, 0}, []int{2, 0, 1}},
+		{"CREATE TABLE t (a, b AS (a + 1), c, PRIMARY KEY (c ASC)) WITHOUT ROWID", []int{2}, []int{2, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			tbl, err := parseTable(tt.sql)
+			if err != nil {
+				t.Fatalf("parseTable failed: %v", err)
+			}
+			if !tbl.withoutRowid || fmt.Sprint(tbl.primaryKey) != fmt.Sprint(tt.wantKey) ||
+				fmt.Sprint(tbl.record) != fmt.Sprint(tt.wantRecord) {
+				t.Errorf("primary key = %v, record = %v; want %v, %v", tbl.primaryKey, tbl.record, tt.wantKey, tt.wantRecord)
+			}
+			for _, c := range tbl.columns {
+				if c.rowid {
+					t.Errorf("column %s is a rowid alias in a WITHOUT ROWID table", c.name)
+				}
+			}
+		})
+	}
+
+	for _, sql := range []string{
+		"CREATE TABLE t (k TEXT, v) WITHOUT ROWID",
+		"CREATE TABLE t (k TEXT PRIMARY KEY DESC, v) WITHOUT ROWID",
+		"CREATE TABLE t (k TEXT COLLATE NOCASE PRIMARY KEY, v) WITHOUT ROWID",
+		"CREATE TABLE t (a, b, PRIMARY KEY (a, b DESC)) WITHOUT ROWID",
+	} {
+		if _, err := parseTable(sql); err == nil {
+			t.Errorf("parseTable(%q) should fail", sql)
+		}
+	}
+}
+
+func TestRecordField(t *testing.T) {
+	values := []value{
+		{kind: kindInteger, i: -3},
+		{kind: kindInteger, i: 1 << 40},
+		{kind: kindNull},
+		{kind: kindText, s: strings.Repeat("x", 200)},
+		{kind: kindBlob, s: "\x00\x01"},
+		{kind: kindReal, f: 1.5},
+	}
+	record := encodeRecord(values)
+
+	decoded, err := (&database{encoding: encodingUTF8}).decodeRecord(record)
+	if err != nil || fmt.Sprint(decoded) != fmt.Sprint(values) {
+		t.Fatalf("decodeRecord(encodeRecord) = %v, %v; want %v", decoded, err, values)
+	}
+
+	serialType, data, ok := recordField(record, 3)
+	if !ok || serialType != 200*2+13 || string(data) != values[3].s {
+		t.Errorf("recordField(3) = %d, %q, %v", serialType, data, ok)
+	}
+	if _, _, ok := recordField(record, 6); ok {
+		t.Error("recordField should fail past the last value")
+	}
+}
+
+func TestParseIndexColumn(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		wantOK bool
+	}{
+		{"CREATE INDEX i ON logs (created_at)", "created_at", true},
+		{"CREATE INDEX i ON logs(ts ASC, level)", "ts", true},
+		{`CREATE INDEX "i" ON "logs" ("ts" COLLATE BINARY)`, "ts", true},
+		{"CREATE INDEX i ON logs (ts DESC)", "", false},
+		{"CREATE INDEX i ON logs (ts COLLATE NOCASE)", "", false},
+		{"CREATE INDEX i ON logs (ts) WHERE level = 'ERROR'", "", false},
+		{"CREATE INDEX i ON logs (datetime(ts))", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			got, ok := parseIndexColumn(tt.sql)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseIndexColumn = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// rowKey identifies a row of a table: by its rowid, or by its primary key
+// in WITHOUT ROWID tables.
+type rowKey struct {
+	rowid int64
+	pk    []value
+}
+
+// String renders a key as pointers hold it: the rowid, or the primary key
+// encoded as a record in hex.
+func (k rowKey) String() string {
+	if k.pk == nil {
+		return fmt.Sprint(k.rowid)
+	}
+	return hex.EncodeToString(encodeRecord(k.pk))
+}
+
+// parseKey parses the primary key of a row of a WITHOUT ROWID table, as
+// rendered by rowKey.String.
+func parseKey(s string) (rowKey, error) {
+	record, err := hex.DecodeString(s)
+	if err == nil {
+		var pk []value
+		// Pointers hold text in UTF-8 whatever the database encoding
+		if pk, err = (&database{encoding: encodingUTF8}).decodeRecord(record); err == nil && len(pk) > 0 {
+			return rowKey{pk: pk}, nil
+		}
+	}
+	return rowKey{}, fmt.Errorf("invalid primary key %q", s)
+}
+
+// compareValues orders values as SQLite does with the BINARY collation:
+// NULL first, then numbers, text and blobs.
+func compareValues(a, b value) int {
+	class := func(v value) int {
+		switch v.kind {
+		case kindInteger, kindReal:
+			return 1
+		case kindText:
+			return 2
+		case kindBlob:
+			return 3
+		}
+		return 0
+	}
+	if c := cmp.Compare(class(a), class(b)); c != 0 {
+		return c
+	}
+
+	switch a.kind {
+	case kindNull:
+		return 0
+	case kindText, kindBlob:
+		return strings.Compare(a.s, b.s)
+	}
+	if a.kind == kindInteger && b.kind == kindInteger {
+		return cmp.Compare(a.i, b.i)
+	}
+	number := func(v value) float64 {
+		if v.kind == kindInteger {
+			return float64(v.i)
+		}
+		return v.f
+	}
+	return cmp.Compare(number(a), number(b))
+}
+
+// compareKeys orders primary keys, comparing as many values as both have.
+func compareKeys(a, b []value) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// rowKeyFunc is called with the key and record of table rows. It returns
+// false to stop.
+type rowKeyFunc func(k rowKey, record []byte) (bool, error)
+
+// walkRows calls fn for the rows of a table with keys strictly between after
+// and before, either of which may be nil, in key order or, if desc, in
+// reverse.
+func (db *database) walkRows(t *table, after, before *rowKey, desc bool, fn rowKeyFunc) error {
+	if !t.withoutRowid {
+		lo, hi := int64(minRowid), int64(maxRowid)
+		if after != nil {
+			if after.rowid == maxRowid {
+				return nil
+			}
+			lo = after.rowid + 1
+		}
+		if before != nil {
+			if before.rowid == minRowid {
+				return nil
+			}
+			hi = before.rowid - 1
+		}
+		return db.walkTable(t.root, lo, hi, desc, func(rowid int64, record []byte) (bool, error) {
+			return fn(rowKey{rowid: rowid}, record)
+		})
+	}
+
+	n := len(t.primaryKey)
+	return db.walkIndex(t.root, desc, func(key []value) int {
+		switch {
+		case after != nil && compareKeys(key[:min(n, len(key))], after.pk) <= 0:
+			return -1
+		case before != nil && compareKeys(key[:min(n, len(key))], before.pk) >= 0:
+			return 1
+		}
+		return 0
+	}, func(key []value, record []byte) (bool, error) {
+		return fn(rowKey{pk: key[:min(n, len(key))]}, record)
+	})
+}
+
+// lookupRow returns the record of the row with a key, or nil if there is none.
+func (db *database) lookupRow(t *table, k rowKey) ([]byte, error) {
+	if !t.withoutRowid {
+		return db.row(t.root, k.rowid)
+	}
+
+	var found []byte
+	n := len(t.primaryKey)
+	err := db.walkIndex(t.root, false, func(key []value) int {
+		return compareKeys(key[:min(n, len(key))], k.pk)
+	}, func(_ []value, record []byte) (bool, error) {
+		found = record
+		return false, nil
+	})
+	return found, err
+}
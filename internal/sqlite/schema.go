@@ -0,0 +1,396 @@
+package sqlite
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// schemaRoot is the root page of the sqlite_schema table, which lists the
+// tables and indexes with the SQL that created them.
+const schemaRoot = 1
+
+// table is a table of the database. Rowid tables are table b-trees keyed
+// by rowid; WITHOUT ROWID tables are index b-trees keyed by primary key.
+type table struct {
+	name         string
+	root         uint32
+	columns      []column
+	indexes      []index
+	withoutRowid bool
+	primaryKey   []int // positions of the primary key columns of WITHOUT ROWID tables
+	record       []int // positions of the columns stored in records, in record order
+}
+
+// column is a column of a table.
+type column struct {
+	name    string
+	rowid   bool // INTEGER PRIMARY KEY, stored as the rowid
+	virtual bool // generated column, not stored in records
+}
+
+// index is an index usable to read a table in the order of a column.
+type index struct {
+	name   string
+	root   uint32
+	column string // first indexed column
+}
+
+var (
+	integerPrimaryKey = regexp.MustCompile(`(?i)^INTEGER\s+PRIMARY\s+KEY\b`)
+	integerType       = regexp.MustCompile(`(?i)^INTEGER\b`)
+	generatedColumn   = regexp.MustCompile(`(?i)\bAS\s*\(`)
+	storedColumn      = regexp.MustCompile(`(?i)\)\s*STORED\b`)
+	withoutRowid      = regexp.MustCompile(`(?i)\bWITHOUT\s+ROWID\b`)
+	primaryKeyColumn  = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b`)
+	descendingKey     = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\s+DESC\b`)
+	collation         = regexp.MustCompile(`(?i)\bCOLLATE\s+"?(\w+)`)
+	ascendingBinary   = regexp.MustCompile(`(?i)^(COLLATE\s+BINARY\s*)?(ASC)?$`)
+)
+
+// findTable reads the schema of a table, with the indexes whose first
+// column can be used to read it in order.
+func (db *database) findTable(name string) (*table, error) {
+	var t *table
+	var indexes []index
+	var tables []string
+
+	err := db.walkTable(schemaRoot, minRowid, maxRowid, false, func(_ int64, record []byte) (bool, error) {
+		values, err := db.decodeRecord(record)
+		if err != nil {
+			return false, fmt.Errorf("invalid schema: %w", err)
+		}
+		if len(values) < 5 {
+			return true, nil
+		}
+		typ, objName, tblName, sql := values[0].s, values[1].s, values[2].s, values[4].s
+		root := uint32(values[3].i)
+
+		switch typ {
+		case "table":
+			if strings.HasPrefix(objName, "sqlite_") {
+				return true, nil
+			}
+			tables = append(tables, objName)
+			if !strings.EqualFold(objName, name) {
+				return true, nil
+			}
+			if t, err = parseTable(sql); err != nil {
+				return false, fmt.Errorf("table %s: %w", objName, err)
+			}
+			t.name, t.root = objName, root
+		case "index":
+			// Automatic indexes for UNIQUE constraints have no SQL
+			if !strings.EqualFold(tblName, name) || sql == "" {
+				return true, nil
+			}
+			if col, ok := parseIndexColumn(sql); ok {
+				indexes = append(indexes, index{name: objName, root: root, column: col})
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if t == nil {
+		if len(tables) == 0 {
+			return nil, fmt.Errorf("table %q not found: %s has no tables", name, db.path)
+		}
+		return nil, fmt.Errorf("table %q not found in %s (tables: %s)", name, db.path, strings.Join(tables, ", "))
+	}
+	if t.root == 0 {
+		return nil, fmt.Errorf("table %s is a virtual table, which cannot be read", t.name)
+	}
+	// The entries of indexes on WITHOUT ROWID tables end with the primary
+	// key rather than a rowid, so such tables are read in key order instead
+	if !t.withoutRowid {
+		t.indexes = indexes
+	}
+	return t, nil
+}
+
+// column returns the position of a column by name, or -1.
+func (t *table) column(name string) int {
+	for i, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnNames returns the names of the table's columns.
+func (t *table) columnNames() []string {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// indexOn returns an index whose first column is the given column, or nil.
+func (t *table) indexOn(name string) *index {
+	for i := range t.indexes {
+		if strings.EqualFold(t.indexes[i].column, name) {
+			return &t.indexes[i]
+		}
+	}
+	return nil
+}
+
+// rowValues returns the values of a row's columns from its record. Columns
+// added by ALTER TABLE after the row was written are missing from its
+// record and read as NULL.
+func (t *table) rowValues(rowid int64, record []value) []value {
+	values := make([]value, len(t.columns))
+	for i, c := range t.record {
+		if i < len(record) {
+			values[c] = record[i]
+		}
+	}
+	for c, col := range t.columns {
+		// The record holds NULL in place of the rowid alias
+		if col.rowid {
+			values[c] = value{kind: kindInteger, i: rowid}
+		}
+	}
+	return values
+}
+
+// recordPosition returns the position of a column in the table's records,
+// or -1 if it is not stored in them.
+func (t *table) recordPosition(c int) int {
+	for i, rc := range t.record {
+		if rc == c && !t.columns[c].rowid {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTable returns the columns declared by a CREATE TABLE statement and
+// the layout of the table's records. The records of WITHOUT ROWID tables
+// hold the primary key columns first, then the others in declared order.
+func parseTable(sql string) (*table, error) {
+	open, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("cannot parse %q", sql)
+	}
+	t := &table{withoutRowid: withoutRowid.MatchString(sql[end:])}
+
+	var integer []bool // whether each column is declared INTEGER
+	var defs []string  // the definition of each column after its name
+	var primaryKey []string
+	for _, def := range splitDefinitions(sql[open+1 : end]) {
+		name, rest, quoted := identifier(def)
+		if !quoted && strings.EqualFold(name, "CONSTRAINT") {
+			// Skip the constraint's name
+			_, rest, _ = identifier(rest)
+			name, rest, quoted = identifier(rest)
+		}
+		if !quoted {
+			switch strings.ToUpper(name) {
+			case "PRIMARY":
+				// PRIMARY KEY (id) makes an INTEGER column the rowid, as the
+				// column constraint does
+				if open := strings.Index(rest, "("); open >= 0 {
+					primaryKey = splitDefinitions(strings.TrimSuffix(strings.TrimSpace(rest[open+1:]), ")"))
+				}
+				continue
+			case "CONSTRAINT", "UNIQUE", "CHECK", "FOREIGN":
+				continue
+			}
+		}
+
+		t.columns = append(t.columns, column{
+			name:    name,
+			rowid:   !t.withoutRowid && integerPrimaryKey.MatchString(rest),
+			virtual: generatedColumn.MatchString(rest) && !storedColumn.MatchString(rest),
+		})
+		integer = append(integer, integerType.MatchString(rest))
+		defs = append(defs, rest)
+	}
+	if len(t.columns) == 0 {
+		return nil, fmt.Errorf("no columns in %q", sql)
+	}
+
+	if t.withoutRowid {
+		if err := t.parsePrimaryKey(primaryKey, defs); err != nil {
+			return nil, err
+		}
+	} else if len(primaryKey) == 1 {
+		name, _, _ := identifier(primaryKey[0])
+		if c := t.column(name); c >= 0 && integer[c] {
+			t.columns[c].rowid = true
+		}
+	}
+
+	t.record = append(t.record, t.primaryKey...)
+	for c, col := range t.columns {
+		if !col.virtual && !slices.Contains(t.primaryKey, c) {
+			t.record = append(t.record, c)
+		}
+	}
+	return t, nil
+}
+
+// parsePrimaryKey sets the primary key columns of a WITHOUT ROWID table from
+// its PRIMARY KEY table constraint, or else the column declared PRIMARY KEY.
+// Keys are compared as stored, so descending keys and other collations than
+// BINARY are not supported.
+func (t *table) parsePrimaryKey(primaryKey, defs []string) error {
+	unsupported := fmt.Errorf("WITHOUT ROWID tables are only supported with a primary key in ascending BINARY order")
+
+	if len(primaryKey) == 0 {
+		for c, def := range defs {
+			if primaryKeyColumn.MatchString(def) {
+				if descendingKey.MatchString(def) {
+					return unsupported
+				}
+				primaryKey = append(primaryKey, t.columns[c].name)
+			}
+		}
+	}
+	for _, key := range primaryKey {
+		name, rest, _ := identifier(key)
+		c := t.column(name)
+		if c < 0 {
+			return fmt.Errorf("primary key column %q is not a column", name)
+		}
+		if !ascendingBinary.MatchString(rest) {
+			return unsupported
+		}
+		if m := collation.FindStringSubmatch(defs[c]); m != nil && !strings.EqualFold(m[1], "BINARY") {
+			return unsupported
+		}
+		if !slices.Contains(t.primaryKey, c) {
+			t.primaryKey = append(t.primaryKey, c)
+		}
+	}
+	if len(t.primaryKey) == 0 {
+		return fmt.Errorf("WITHOUT ROWID table has no PRIMARY KEY")
+	}
+	return nil
+}
+
+// parseIndexColumn returns the first column of a CREATE INDEX statement, if
+// the index keeps every row in ascending order of that column's values. For
+// partial indexes, expressions, descending order or other collations it
+// reports false.
+func parseIndexColumn(sql string) (string, bool) {
+	on := strings.Index(strings.ToUpper(sql), " ON ")
+	if on < 0 {
+		return "", false
+	}
+	open, end := strings.Index(sql[on:], "("), strings.LastIndex(sql, ")")
+	if open < 0 || on+open > end {
+		return "", false
+	}
+	open += on
+	if strings.Contains(strings.ToUpper(sql[end:]), "WHERE") {
+		return "", false
+	}
+
+	defs := splitDefinitions(sql[open+1 : end])
+	if len(defs) == 0 {
+		return "", false
+	}
+	name, rest, _ := identifier(defs[0])
+	if name == "" || !ascendingBinary.MatchString(rest) {
+		return "", false
+	}
+	return name, true
+}
+
+// splitDefinitions splits a list of column definitions at the commas outside
+// parentheses, quotes and comments, trimming each definition.
+func splitDefinitions(s string) []string {
+	var defs []string
+	var current strings.Builder
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for j < len(s) && s[j] != closing {
+				j++
+			}
+			current.WriteString(s[i:min(j+1, len(s))])
+			i = j
+			continue
+		case strings.HasPrefix(s[i:], "--"):
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(s)
+			}
+			current.WriteByte(' ')
+			continue
+		case strings.HasPrefix(s[i:], "/*"):
+			if j := strings.Index(s[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(s)
+			}
+			current.WriteByte(' ')
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			if def := strings.TrimSpace(current.String()); def != "" {
+				defs = append(defs, def)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if def := strings.TrimSpace(current.String()); def != "" {
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// identifier splits the leading identifier off a definition, unquoting it,
+// and returns the trimmed rest.
+func identifier(def string) (name, rest string, quoted bool) {
+	def = strings.TrimSpace(def)
+	if def == "" {
+		return "", "", false
+	}
+
+	if closing, ok := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[def[0]]; ok {
+		var b strings.Builder
+		i := 1
+		for ; i < len(def); i++ {
+			if def[i] == closing {
+				// Quotes are escaped by doubling them
+				if closing != ']' && i+1 < len(def) && def[i+1] == closing {
+					b.WriteByte(closing)
+					i++
+					continue
+				}
+				break
+			}
+			b.WriteByte(def[i])
+		}
+		return b.String(), strings.TrimSpace(def[min(i+1, len(def)):]), true
+	}
+
+	end := strings.IndexAny(def, " \t\r\n(")
+	if end < 0 {
+		return def, "", false
+	}
+	return def[:end], strings.TrimSpace(def[end:]), false
+}
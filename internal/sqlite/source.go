@@ -0,0 +1,654 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// TailPollInterval is how often the table is checked for new rows while tailing
+	TailPollInterval = time.Second
+)
+
+// Column names tried, in order, when the URI does not name the timestamp or
+// message column
+var (
+	timeColumnNames    = []string{"timestamp", "ts", "time", "created_at", "logged_at", "datetime", "date"}
+	messageColumnNames = []string{"message", "msg", "log", "text", "line"}
+)
+
+func init() {
+	source.Register("sqlite", openSource)
+}
+
+// Source implements source.Source for a table of log rows in a SQLite
+// database. The database file is read directly, so no SQLite library or cgo
+// is needed. Pointers are rowids, or primary keys in WITHOUT ROWID tables.
+type Source struct {
+	path          string
+	table         string
+	timeColumn    string
+	messageColumn string
+	uri           string
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// Options configures the columns a source reads.
+type Options struct {
+	TimeColumn    string // detected from common names when empty
+	MessageColumn string // detected from common names when empty
+}
+
+// openSource opens a SQLite source from a parsed URL:
+// sqlite:///path/app.db?table=logs&ts=created_at&msg=message
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	dbPath := u.Path
+	if u.Host != "" {
+		// sqlite://app.db names a relative path
+		dbPath = u.Host + u.Path
+	}
+	if dbPath == "" {
+		return nil, fmt.Errorf("sqlite:// URI requires a path (e.g., sqlite:///var/lib/app/app.db?table=logs)")
+	}
+
+	// Expand ~ to home directory
+	if strings.HasPrefix(dbPath, "/~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dbPath = filepath.Join(home, dbPath[3:])
+		}
+	}
+
+	query := u.Query()
+	table := query.Get("table")
+	if table == "" {
+		return nil, fmt.Errorf("sqlite:// URI requires a table (e.g., sqlite://%s?table=logs)", dbPath)
+	}
+
+	return NewSource(dbPath, table, Options{
+		TimeColumn:    query.Get("ts"),
+		MessageColumn: query.Get("msg"),
+	})
+}
+
+// NewSource creates a source for a table of the database at dbPath. The
+// table's schema is read to check the columns exist.
+func NewSource(dbPath, tableName string, opts Options) (*Source, error) {
+	db, err := openDatabase(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	t, err := db.findTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	timeColumn, err := resolveColumn(t, opts.TimeColumn, timeColumnNames, "timestamp", "ts")
+	if err != nil {
+		return nil, err
+	}
+	messageColumn, err := resolveColumn(t, opts.MessageColumn, messageColumnNames, "message", "msg")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		path:          dbPath,
+		table:         t.name,
+		timeColumn:    timeColumn,
+		messageColumn: messageColumn,
+	}
+	s.uri = s.buildURI()
+	return s, nil
+}
+
+// resolveColumn returns the table's name for a column given in the URI, or
+// else the first of the common names the table has.
+func resolveColumn(t *table, name string, candidates []string, what, param string) (string, error) {
+	if name != "" {
+		if i := t.column(name); i >= 0 {
+			return t.columns[i].name, nil
+		}
+		return "", fmt.Errorf("table %s has no column %q (columns: %s)", t.name, name, strings.Join(t.columnNames(), ", "))
+	}
+	for _, candidate := range candidates {
+		if i := t.column(candidate); i >= 0 {
+			return t.columns[i].name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot tell which column of table %s holds the %s; add ?%s=<column> (columns: %s)",
+		t.name, what, param, strings.Join(t.columnNames(), ", "))
+}
+
+// buildURI builds the canonical URI for a source, naming the columns read so
+// sources reopened from cached pointers read rows the same way.
+func (s *Source) buildURI() string {
+	query := url.Values{
+		"table": {s.table},
+		"ts":    {s.timeColumn},
+		"msg":   {s.messageColumn},
+	}
+	return (&url.URL{Scheme: "sqlite", Path: s.path}).String() + "?" + query.Encode()
+}
+
+// openTable opens the database and reads the table's schema. The caller
+// must close the database.
+func (s *Source) openTable() (*database, *table, error) {
+	db, err := openDatabase(s.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	t, err := db.findTable(s.table)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	return db, t, nil
+}
+
+// rowEntry converts a row into an entry. Rows whose message does not match
+// the filter are rejected before their other columns are converted, and
+// those whose message is UTF-8 text before any column is decoded.
+func (s *Source) rowEntry(db *database, t *table, k rowKey, record []byte, filter *regexp.Regexp) (source.Entry, bool, error) {
+	msgIndex, tsIndex := t.column(s.messageColumn), t.column(s.timeColumn)
+	if msgIndex < 0 || tsIndex < 0 {
+		return source.Entry{}, false, fmt.Errorf("table %s no longer has columns %s and %s", t.name, s.timeColumn, s.messageColumn)
+	}
+	if filter != nil && db.encoding == encodingUTF8 {
+		if pos := t.recordPosition(msgIndex); pos >= 0 {
+			// Text values have odd serial types from 13
+			serialType, text, ok := recordField(record, pos)
+			if ok && serialType >= 13 && serialType%2 == 1 && !filter.Match(text) {
+				return source.Entry{}, false, nil
+			}
+		}
+	}
+
+	decoded, err := db.decodeRecord(record)
+	if err != nil {
+		return source.Entry{}, false, fmt.Errorf("row %s: %w", k, err)
+	}
+	values := t.rowValues(k.rowid, decoded)
+
+	message := values[msgIndex].String()
+	if filter != nil && !filter.MatchString(message) {
+		return source.Entry{}, false, nil
+	}
+
+	fields := make(map[string]string, len(values))
+	for i, v := range values {
+		if i == msgIndex || i == tsIndex || v.kind == kindNull {
+			continue
+		}
+		fields[t.columns[i].name] = v.String()
+	}
+	ts, _ := parseTime(values[tsIndex])
+
+	return source.Entry{
+		Timestamp: ts,
+		Message:   message,
+		Stream:    t.name,
+		Source:    s.path,
+		Ptr:       rowPtr(s.path, t.name, k),
+		Fields:    fields,
+	}, true, nil
+}
+
+// rowPtr returns the pointer to a row of a table.
+func rowPtr(dbPath, tableName string, k rowKey) string {
+	if k.pk != nil {
+		return source.MakeSQLiteKeyPtr(dbPath, tableName, k.String())
+	}
+	return source.MakeSQLitePtr(dbPath, tableName, k.rowid)
+}
+
+// String renders a value as text: numbers in decimal, and blobs as they are
+// if they hold UTF-8 text, else in hex.
+func (v value) String() string {
+	switch v.kind {
+	case kindInteger:
+		return strconv.FormatInt(v.i, 10)
+	case kindReal:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	case kindText:
+		return v.s
+	case kindBlob:
+		if utf8.ValidString(v.s) {
+			return v.s
+		}
+		return hex.EncodeToString([]byte(v.s))
+	}
+	return ""
+}
+
+// parseTime parses a timestamp value: text dates as SQLite's date functions
+// write them (without a zone meaning UTC), Unix times in seconds,
+// milliseconds, microseconds or nanoseconds, or Julian day numbers.
+func parseTime(v value) (time.Time, bool) {
+	switch v.kind {
+	case kindInteger:
+		return unixTime(float64(v.i)), true
+	case kindReal:
+		// julianday('now') is around 2.46 million
+		if v.f > 2e6 && v.f < 3e6 {
+			return time.UnixMicro(int64((v.f - 2440587.5) * 86400e6)).UTC(), true
+		}
+		return unixTime(v.f), true
+	case kindText:
+		text := strings.TrimSpace(v.s)
+		for _, layout := range []string{
+			time.RFC3339Nano,
+			"2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999",
+			"2006-01-02T15:04:05.999999999",
+			"2006-01-02 15:04",
+			"2006-01-02",
+		} {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, true
+			}
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return parseTime(value{kind: kindReal, f: f})
+		}
+	}
+	return time.Time{}, false
+}
+
+// unixTime converts a Unix time to a time, telling its unit by its magnitude.
+func unixTime(n float64) time.Time {
+	switch {
+	case n > 1e17 || n < -1e17:
+		return time.Unix(0, int64(n)).UTC()
+	case n > 1e14 || n < -1e14:
+		return time.UnixMicro(int64(n)).UTC()
+	case n > 1e11 || n < -1e11:
+		return time.UnixMilli(int64(n)).UTC()
+	}
+	return time.UnixMicro(int64(n * 1e6)).UTC()
+}
+
+// Query returns rows matching the given parameters. When the timestamp
+// column is indexed, only the part of the index within the time range is
+// read, newest first, stopping once the limit is reached; otherwise every
+// row of the table is read. Indexes of WITHOUT ROWID tables are not used.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var results []source.Entry
+	collect := func(k rowKey, record []byte) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		entry, ok, err := s.rowEntry(db, t, k, record, params.Filter)
+		if err != nil {
+			return false, err
+		}
+		if ok && local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+		return true, nil
+	}
+
+	if idx := t.indexOn(s.timeColumn); idx != nil {
+		logging.Debug("Reading %s through index %s", t.name, idx.name)
+		err = db.walkIndex(idx.root, true, timeRangePosition(params.StartTime, params.EndTime), func(key []value, _ []byte) (bool, error) {
+			rowid := key[len(key)-1].i
+			record, err := db.row(t.root, rowid)
+			if err != nil || record == nil {
+				return false, err
+			}
+			if more, err := collect(rowKey{rowid: rowid}, record); !more || err != nil {
+				return false, err
+			}
+			return params.Limit <= 0 || len(results) < params.Limit, nil
+		})
+	} else {
+		err = db.walkRows(t, nil, nil, true, collect)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error reading %s: %w", s.path, err)
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// timeRangePosition returns where index keys on the timestamp column are
+// relative to a time range, for walkIndex. Keys that are not timestamps are
+// read, and left to the time filter. This assumes the column's values sort
+// in time order, as timestamps written in one format do.
+func timeRangePosition(start, end time.Time) func([]value) int {
+	return func(key []value) int {
+		if len(key) < 2 {
+			return 0
+		}
+		t, ok := parseTime(key[0])
+		switch {
+		case !ok:
+			return 0
+		case !start.IsZero() && t.Before(start):
+			return -1
+		case !end.IsZero() && t.After(end):
+			return 1
+		}
+		return 0
+	}
+}
+
+// Tail streams rows as they are inserted by polling the table for keys
+// above the largest seen: rowids, or the primary keys of WITHOUT ROWID
+// tables, which must then increase as rows are inserted. Rows updated in
+// place are not shown again.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	last, err := s.lastRow()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	go s.tailLoop(ctx, last, params, events)
+
+	return events, nil
+}
+
+// lastRow returns the key of the last row of the table, or nil if it is empty.
+func (s *Source) lastRow() (*rowKey, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var last *rowKey
+	err = db.walkRows(t, nil, nil, true, func(k rowKey, _ []byte) (bool, error) {
+		last = &k
+		return false, nil
+	})
+	return last, err
+}
+
+// tailLoop polls for new rows until the context is cancelled.
+func (s *Source) tailLoop(ctx context.Context, last *rowKey, params source.TailParams, events chan<- source.Event) {
+	defer close(events)
+
+	ticker := time.NewTicker(TailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		entries, newLast, err := s.rowsAfter(ctx, last)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The database may be mid-write; try again next poll
+			logging.Debug("Failed to read %s: %v", s.path, err)
+			continue
+		}
+		last = newLast
+
+		for i := range entries {
+			s.emitEntry(&entries[i], params, events)
+		}
+	}
+}
+
+// rowsAfter returns the rows with keys above last, or all rows if last is
+// nil, in key order, and the key of the last row read.
+func (s *Source) rowsAfter(ctx context.Context, last *rowKey) ([]source.Entry, *rowKey, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, last, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var entries []source.Entry
+	err = db.walkRows(t, last, nil, false, func(k rowKey, record []byte) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		entry, _, err := s.rowEntry(db, t, k, record, nil)
+		if err != nil {
+			return false, err
+		}
+		entries = append(entries, entry)
+		last = &k
+		return true, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, last, nil
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, params source.TailParams, events chan<- source.Event) {
+	// Apply filter
+	if params.Filter != nil && !params.Filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// parsePtr parses a pointer to a row of the source's table.
+func (s *Source) parsePtr(t *table, ptr string) (rowKey, error) {
+	info, ok := source.ParseSQLitePtr(ptr)
+	if !ok {
+		return rowKey{}, fmt.Errorf("invalid SQLite pointer: %s", ptr)
+	}
+	if info.Path != s.path || !strings.EqualFold(info.Table, s.table) || (info.Key != "") != t.withoutRowid {
+		return rowKey{}, fmt.Errorf("pointer %s is for another table than %s", ptr, s.uri)
+	}
+	if info.Key != "" {
+		return parseKey(info.Key)
+	}
+	return rowKey{rowid: info.RowID}, nil
+}
+
+// GetRecord retrieves a single row by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	k, err := s.parsePtr(t, ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := db.lookupRow(t, k)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("row %s not found in table %s of %s", k, t.name, s.path)
+	}
+
+	entry, _, err := s.rowEntry(db, t, k, record, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FetchContext retrieves the rows with the nearest keys before and after a row.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	k, err := s.parsePtr(t, entry.Ptr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// neighbours reads up to n rows either side of the row, nearest first
+	neighbours := func(desc bool, n int) ([]source.Event, error) {
+		var events []source.Event
+		if n <= 0 {
+			return nil, nil
+		}
+		lo, hi := &k, (*rowKey)(nil)
+		if desc {
+			lo, hi = nil, &k
+		}
+		err := db.walkRows(t, lo, hi, desc, func(k rowKey, record []byte) (bool, error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			e, _, err := s.rowEntry(db, t, k, record, nil)
+			if err != nil {
+				return false, err
+			}
+			events = append(events, source.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream})
+			return len(events) < n, nil
+		})
+		return events, err
+	}
+
+	beforeEvents, err := neighbours(true, before)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Oldest first
+	for i, j := 0, len(beforeEvents)-1; i < j; i, j = i+1, j-1 {
+		beforeEvents[i], beforeEvents[j] = beforeEvents[j], beforeEvents[i]
+	}
+	afterEvents, err := neighbours(false, after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeEvents, afterEvents, nil
+}
+
+// ListStreams returns the table, with its row count and time range.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	db, t, err := s.openTable()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	tsIndex := t.column(s.timeColumn)
+	stream := source.StreamInfo{Name: t.name}
+	err = db.walkRows(t, nil, nil, false, func(k rowKey, record []byte) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		stream.Size++
+
+		decoded, err := db.decodeRecord(record)
+		if err != nil || tsIndex < 0 {
+			return err == nil, err
+		}
+		if ts, ok := parseTime(t.rowValues(k.rowid, decoded)[tsIndex]); ok {
+			if stream.FirstTime.IsZero() || ts.Before(stream.FirstTime) {
+				stream.FirstTime = ts
+			}
+			if ts.After(stream.LastTime) {
+				stream.LastTime = ts
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []source.StreamInfo{stream}, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "sqlite"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "sqlite",
+		URI:  s.uri,
+	}
+}
+
+// Close releases any resources held by the source.
+func (s *Source) Close() error {
+	return nil
+}
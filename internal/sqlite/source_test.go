@@ -0,0 +1,534 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Fixtures in testdata/ were written by Python's sqlite3 module:
+//   - logs.db: 300 rows of logs(id INTEGER PRIMARY KEY, created_at TEXT,
+//     level, message, host, payload BLOB) one second apart from
+//     2024-03-01 10:00:01, with an index on created_at and 1024-byte pages.
+//     Every 50th row is an ERROR "upstream timeout", every 3rd has a NULL
+//     host, row 100 has a binary payload and row 200 overflows its page.
+//   - events.db: events(ts INTEGER, msg, source) in Unix milliseconds, with
+//     a user column added by ALTER TABLE before the sixth row.
+//   - wal.db: app_log(logged_at REAL, msg) in Julian days, whose three rows
+//     are only in wal.db-wal.
+//   - keyed.db: 200 rows of audit(ts TEXT, seq INTEGER, actor, message,
+//     PRIMARY KEY (ts, seq)) WITHOUT ROWID one second apart from
+//     2024-03-01 10:00:01, with 512-byte pages. Every 40th is a
+//     "permission denied".
+
+var testBase = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func testdataPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestSource(t *testing.T, name, table string, opts Options) *Source {
+	t.Helper()
+	src, err := NewSource(testdataPath(t, name), table, opts)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	return src
+}
+
+func messages(entries []source.Entry) []string {
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestOpenSource(t *testing.T) {
+	path := testdataPath(t, "logs.db")
+
+	tests := []struct {
+		name    string
+		uri     string
+		wantTS  string
+		wantMsg string
+		wantErr string
+	}{
+		{
+			name:    "detected columns",
+			uri:     "sqlite://" + path + "?table=logs",
+			wantTS:  "created_at",
+			wantMsg: "message",
+		},
+		{
+			name:    "named columns",
+			uri:     "sqlite://" + path + "?table=LOGS&ts=created_at&msg=level",
+			wantTS:  "created_at",
+			wantMsg: "level",
+		},
+		{
+			name:    "no table",
+			uri:     "sqlite://" + path,
+			wantErr: "requires a table",
+		},
+		{
+			name:    "unknown table",
+			uri:     "sqlite://" + path + "?table=audit",
+			wantErr: "tables: logs",
+		},
+		{
+			name:    "unknown column",
+			uri:     "sqlite://" + path + "?table=logs&msg=body",
+			wantErr: `no column "body"`,
+		},
+		{
+			name:    "not a database",
+			uri:     "sqlite://" + testdataPath(t, "wal.db-wal") + "?table=logs",
+			wantErr: "cannot open database",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := openSource(u, source.OpenOptions{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("openSource(%q) error = %v, want %q", tt.uri, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("openSource(%q) failed: %v", tt.uri, err)
+			}
+			s := src.(*Source)
+			if s.timeColumn != tt.wantTS || s.messageColumn != tt.wantMsg {
+				t.Errorf("columns = %s, %s; want %s, %s", s.timeColumn, s.messageColumn, tt.wantTS, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestNewSource_UndetectedColumns(t *testing.T) {
+	_, err := NewSource(testdataPath(t, "logs.db"), "logs", Options{MessageColumn: "level", TimeColumn: "host"})
+	if err != nil {
+		t.Fatalf("NewSource with named columns failed: %v", err)
+	}
+
+	_, err = NewSource(testdataPath(t, "events.db"), "events", Options{})
+	if err != nil {
+		t.Fatalf("NewSource failed to detect ts and msg: %v", err)
+	}
+
+	_, err = NewSource(testdataPath(t, "wal.db"), "app_log", Options{TimeColumn: "logged_at", MessageColumn: "message"})
+	if err == nil || !strings.Contains(err.Error(), "columns: logged_at, msg") {
+		t.Errorf("error = %v, want the column list", err)
+	}
+}
+
+func TestSource_Query(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params source.QueryParams
+		want   []string
+		count  int
+	}{
+		{
+			name:   "newest first with limit",
+			params: source.QueryParams{Limit: 3},
+			want:   []string{"upstream timeout after 30s (request 300)", "request 299 served in 10ms", "request 298 served in 9ms"},
+		},
+		{
+			name: "time range",
+			params: source.QueryParams{
+				StartTime: testBase.Add(10 * time.Second),
+				EndTime:   testBase.Add(12 * time.Second),
+			},
+			want: []string{"request 12 served in 12ms", "request 11 served in 11ms", "request 10 served in 10ms"},
+		},
+		{
+			name:   "filter",
+			params: source.QueryParams{Filter: regexp.MustCompile(`upstream timeout`), Limit: 2},
+			want:   []string{"upstream timeout after 30s (request 300)", "upstream timeout after 30s (request 250)"},
+		},
+		{
+			name: "filter in time range",
+			params: source.QueryParams{
+				Filter:  regexp.MustCompile(`timeout`),
+				EndTime: testBase.Add(120 * time.Second),
+			},
+			want: []string{"upstream timeout after 30s (request 100)", "upstream timeout after 30s (request 50)"},
+		},
+		{
+			name:   "all rows",
+			params: source.QueryParams{},
+			count:  300,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := src.Query(ctx, tt.params)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if tt.want != nil {
+				if got := messages(entries); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+					t.Errorf("messages = %q, want %q", got, tt.want)
+				}
+			} else if len(entries) != tt.count {
+				t.Errorf("got %d entries, want %d", len(entries), tt.count)
+			}
+		})
+	}
+}
+
+func TestSource_Query_Entry(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		StartTime: testBase.Add(100 * time.Second),
+		EndTime:   testBase.Add(100 * time.Second),
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	e := entries[0]
+	if !e.Timestamp.Equal(testBase.Add(100 * time.Second)) {
+		t.Errorf("Timestamp = %v", e.Timestamp)
+	}
+	if e.Stream != "logs" || e.Source != src.path {
+		t.Errorf("Stream = %q, Source = %q", e.Stream, e.Source)
+	}
+	if e.Ptr != source.MakeSQLitePtr(src.path, "logs", 100) {
+		t.Errorf("Ptr = %q", e.Ptr)
+	}
+	want := map[string]string{"id": "100", "level": "ERROR", "host": "edge-0", "payload": "ff0064"}
+	if fmt.Sprint(e.Fields) != fmt.Sprint(want) {
+		t.Errorf("Fields = %v, want %v", e.Fields, want)
+	}
+}
+
+func TestSource_Query_Overflow(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	entries, err := src.Query(context.Background(), source.QueryParams{Filter: regexp.MustCompile(`^stack dump`)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if want := "stack dump: " + strings.Repeat("frame ", 800); entries[0].Message != want {
+		t.Errorf("Message has %d bytes, want %d", len(entries[0].Message), len(want))
+	}
+}
+
+func TestSource_Query_Unindexed(t *testing.T) {
+	src := newTestSource(t, "events.db", "events", Options{})
+
+	entries, err := src.Query(context.Background(), source.QueryParams{StartTime: testBase.Add(4 * time.Second)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := messages(entries); strings.Join(got, "|") != "event 6|event 5|event 4" {
+		t.Errorf("messages = %q", got)
+	}
+	// The user column was added after the first five rows
+	if entries[0].Fields["user"] != "alice" || entries[1].Fields["user"] != "" {
+		t.Errorf("user fields = %q, %q", entries[0].Fields["user"], entries[1].Fields["user"])
+	}
+	if entries[2].Fields["source"] != "agent" || !entries[2].Timestamp.Equal(testBase.Add(4*time.Second)) {
+		t.Errorf("entry = %+v", entries[2])
+	}
+}
+
+func TestSource_Query_WAL(t *testing.T) {
+	src := newTestSource(t, "wal.db", "app_log", Options{})
+
+	entries, err := src.Query(context.Background(), source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := messages(entries); strings.Join(got, "|") != "wal row 3|wal row 2|wal row 1" {
+		t.Errorf("messages = %q", got)
+	}
+	want := time.Date(2024, 3, 1, 0, 0, 3, 0, time.UTC)
+	if len(entries) > 0 && entries[0].Timestamp.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, want)
+	}
+}
+
+func TestSource_Query_Cancelled(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := src.Query(ctx, source.QueryParams{}); err != context.Canceled {
+		t.Errorf("Query error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSource_GetRecord(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+	ctx := context.Background()
+
+	entry, err := src.GetRecord(ctx, source.MakeSQLitePtr(src.path, "logs", 43))
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "request 43 served in 9ms" || entry.Fields["host"] != "edge-3" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	for _, ptr := range []string{
+		source.MakeSQLitePtr(src.path, "logs", 301),
+		source.MakeSQLitePtr(src.path, "audit", 1),
+		source.MakeSQLitePtr("/other.db", "logs", 1),
+		"file:///var/log/app.log#1",
+	} {
+		if _, err := src.GetRecord(ctx, ptr); err == nil {
+			t.Errorf("GetRecord(%q) should fail", ptr)
+		}
+	}
+}
+
+func TestSource_OpenFromPtr(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{MessageColumn: "level"})
+	ptr := source.MakeSQLitePtr(src.path, "logs", 50)
+	metadata := src.Metadata()
+
+	reopened, err := source.OpenFromPtr(ptr, &metadata)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+
+	entry, err := reopened.GetRecord(context.Background(), ptr)
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if entry.Message != "ERROR" {
+		t.Errorf("Message = %q, want the level column", entry.Message)
+	}
+}
+
+func TestSource_FetchContext(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+	ctx := context.Background()
+
+	entry, err := src.GetRecord(ctx, source.MakeSQLitePtr(src.path, "logs", 2))
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+
+	before, after, err := src.FetchContext(ctx, *entry, 3, 2)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	var got []string
+	for _, e := range append(before, after...) {
+		got = append(got, e.Message)
+	}
+	want := []string{"request 1 served in 1ms", "request 3 served in 3ms", "request 4 served in 4ms"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("context = %q, want %q", got, want)
+	}
+}
+
+func TestSource_Query_WithContext(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	entries, err := src.Query(context.Background(), source.QueryParams{
+		Filter:  regexp.MustCompile(`request 150\)`),
+		Context: 1,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	c := entries[0].Context
+	if len(c.Before) != 1 || c.Before[0].Message != "request 149 served in 13ms" ||
+		len(c.After) != 1 || c.After[0].Message != "request 151 served in 15ms" {
+		t.Errorf("Context = %+v", c)
+	}
+}
+
+func TestSource_ListStreams(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	streams, err := src.ListStreams(context.Background())
+	if err != nil {
+		t.Fatalf("ListStreams failed: %v", err)
+	}
+	if len(streams) != 1 {
+		t.Fatalf("got %d streams, want 1", len(streams))
+	}
+	s := streams[0]
+	if s.Name != "logs" || s.Size != 300 ||
+		!s.FirstTime.Equal(testBase.Add(time.Second)) || !s.LastTime.Equal(testBase.Add(300*time.Second)) {
+		t.Errorf("stream = %+v", s)
+	}
+}
+
+func TestSource_rowsAfter(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	last, err := src.lastRow()
+	if err != nil || last == nil || last.rowid != 300 {
+		t.Fatalf("lastRow = %v, %v; want 300", last, err)
+	}
+
+	entries, newLast, err := src.rowsAfter(context.Background(), &rowKey{rowid: 297})
+	if err != nil {
+		t.Fatalf("rowsAfter failed: %v", err)
+	}
+	if newLast.rowid != 300 || len(entries) != 3 || entries[0].Message != "request 298 served in 9ms" {
+		t.Errorf("rowsAfter = %q, %v", messages(entries), newLast)
+	}
+
+	entries, newLast, err = src.rowsAfter(context.Background(), last)
+	if err != nil || len(entries) != 0 || newLast != last {
+		t.Errorf("rowsAfter(300) = %d entries, %v, %v", len(entries), newLast, err)
+	}
+}
+
+func TestSource_WithoutRowid(t *testing.T) {
+	src := newTestSource(t, "keyed.db", "audit", Options{})
+	ctx := context.Background()
+
+	entries, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile(`permission denied`)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want := []string{"permission denied for job 200", "permission denied for job 160", "permission denied for job 120",
+		"permission denied for job 80", "permission denied for job 40"}
+	if strings.Join(messages(entries), "|") != strings.Join(want, "|") {
+		t.Fatalf("messages = %q, want %q", messages(entries), want)
+	}
+	e := entries[2]
+	if !e.Timestamp.Equal(testBase.Add(120*time.Second)) || e.Fields["seq"] != "1" || e.Fields["actor"] != "user-0" {
+		t.Errorf("entry = %+v", e)
+	}
+
+	// Pointers hold the primary key
+	info, ok := source.ParseSQLitePtr(e.Ptr)
+	if !ok || info.Key == "" {
+		t.Fatalf("Ptr = %q, want a primary key pointer", e.Ptr)
+	}
+	metadata := src.Metadata()
+	reopened, err := source.OpenFromPtr(e.Ptr, &metadata)
+	if err != nil {
+		t.Fatalf("OpenFromPtr failed: %v", err)
+	}
+	got, err := reopened.GetRecord(ctx, e.Ptr)
+	if err != nil || got.Message != e.Message {
+		t.Fatalf("GetRecord = %+v, %v; want %q", got, err, e.Message)
+	}
+
+	before, after, err := src.FetchContext(ctx, e, 2, 1)
+	if err != nil {
+		t.Fatalf("FetchContext failed: %v", err)
+	}
+	var context []string
+	for _, c := range append(before, after...) {
+		context = append(context, c.Message)
+	}
+	if strings.Join(context, "|") != "job 118 started|job 119 started|job 121 started" {
+		t.Errorf("context = %q", context)
+	}
+
+	for _, ptr := range []string{
+		source.MakeSQLitePtr(src.path, "audit", 120),
+		source.MakeSQLiteKeyPtr(src.path, "audit", "zz"),
+	} {
+		if _, err := src.GetRecord(ctx, ptr); err == nil {
+			t.Errorf("GetRecord(%q) should fail", ptr)
+		}
+	}
+
+	streams, err := src.ListStreams(ctx)
+	if err != nil || len(streams) != 1 || streams[0].Size != 200 || !streams[0].LastTime.Equal(testBase.Add(200*time.Second)) {
+		t.Errorf("ListStreams = %+v, %v", streams, err)
+	}
+
+	last, err := src.lastRow()
+	if err != nil || last == nil {
+		t.Fatalf("lastRow = %v, %v", last, err)
+	}
+	tail, newLast, err := src.rowsAfter(ctx, &rowKey{pk: []value{{kind: kindText, s: "2024-03-01 10:03:18"}}})
+	if err != nil || newLast.String() != last.String() || strings.Join(messages(tail), "|") != "job 199 started|permission denied for job 200" {
+		t.Errorf("rowsAfter = %q, %v, %v", messages(tail), newLast, err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value value
+	}{
+		{"text", value{kind: kindText, s: "2024-03-01 10:00:00"}},
+		{"text with T", value{kind: kindText, s: "2024-03-01T10:00:00"}},
+		{"RFC3339", value{kind: kindText, s: "2024-03-01T12:00:00+02:00"}},
+		{"numeric text", value{kind: kindText, s: "1709287200"}},
+		{"seconds", value{kind: kindInteger, i: 1709287200}},
+		{"milliseconds", value{kind: kindInteger, i: 1709287200000}},
+		{"microseconds", value{kind: kindInteger, i: 1709287200000000}},
+		{"nanoseconds", value{kind: kindInteger, i: 1709287200000000000}},
+		{"real seconds", value{kind: kindReal, f: 1709287200.0}},
+		{"julian day", value{kind: kindReal, f: 2460371.0 - 2.0/24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTime(tt.value)
+			if !ok || got.Sub(want).Abs() > time.Millisecond {
+				t.Errorf("parseTime = %v, %v; want %v", got, ok, want)
+			}
+		})
+	}
+
+	for _, v := range []value{{kind: kindNull}, {kind: kindText, s: "yesterday"}, {kind: kindBlob, s: "x"}} {
+		if _, ok := parseTime(v); ok {
+			t.Errorf("parseTime(%+v) should fail", v)
+		}
+	}
+}
+
+func TestSource_TypeAndMetadata(t *testing.T) {
+	src := newTestSource(t, "logs.db", "logs", Options{})
+
+	if src.Type() != "sqlite" {
+		t.Errorf("Type = %q", src.Type())
+	}
+	want := "sqlite://" + src.path + "?msg=message&table=logs&ts=created_at"
+	if got := src.Metadata().URI; got != want {
+		t.Errorf("URI = %q, want %q", got, want)
+	}
+}
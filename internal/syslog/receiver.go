@@ -0,0 +1,165 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jmurray2011/clew/internal/logging"
+)
+
+// maxOctetCountDigits bounds the length prefix of an octet-counted TCP frame.
+var maxOctetCountDigits = len(strconv.Itoa(MaxMessageSize))
+
+// serveUDP receives one message per datagram until the connection is closed.
+func (s *Source) serveUDP(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, MaxMessageSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logging.Debug("syslog receiver: %v", err)
+			continue
+		}
+		s.receive(string(buf[:n]), senderHost(from))
+	}
+}
+
+// serveTCP accepts connections until the listener is closed.
+func (s *Source) serveTCP(ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logging.Debug("syslog receiver: %v", err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn receives the messages sent over one TCP connection.
+func (s *Source) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	sender := senderHost(conn.RemoteAddr())
+	r := bufio.NewReaderSize(conn, MaxMessageSize)
+	for {
+		msg, err := readFrame(r)
+		if msg != "" {
+			s.receive(msg, sender)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logging.Debug("syslog receiver: closing connection from %s: %v", sender, err)
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads one message from a TCP stream framed as in RFC 6587:
+// octet counting ("<length> <message>"), used when the frame starts with a
+// digit, or newline-terminated. Newline-terminated messages longer than
+// MaxMessageSize are truncated.
+func readFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		length, err := readOctetCount(r)
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", fmt.Errorf("truncated frame: %w", err)
+		}
+		return string(buf), nil
+	}
+
+	line, err := r.ReadSlice('\n')
+	msg := string(line)
+	// Skip the rest of an overlong message
+	for errors.Is(err, bufio.ErrBufferFull) {
+		_, err = r.ReadSlice('\n')
+	}
+	if errors.Is(err, io.EOF) && msg != "" {
+		// A final message without a newline
+		return msg, nil
+	}
+	return msg, err
+}
+
+// readOctetCount reads the length prefix of an octet-counted frame and the
+// space that follows it.
+func readOctetCount(r *bufio.Reader) (int, error) {
+	var digits []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' {
+			break
+		}
+		if b < '0' || b > '9' || len(digits) == maxOctetCountDigits {
+			return 0, fmt.Errorf("invalid octet count %q", string(append(digits, b)))
+		}
+		digits = append(digits, b)
+	}
+
+	length, _ := strconv.Atoi(string(digits))
+	if length > MaxMessageSize {
+		return 0, fmt.Errorf("frame of %d bytes exceeds the %d byte limit", length, MaxMessageSize)
+	}
+	return length, nil
+}
+
+// senderHost returns the IP address of a sender.
+func senderHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// cleanMessage trims the trailing newline and NUL characters senders add, and
+// joins the lines of a multiline message so it fits on one spool line.
+func cleanMessage(msg string) string {
+	msg = strings.TrimRight(msg, "\r\n\x00")
+	msg = strings.ReplaceAll(msg, "\r\n", " ")
+	return strings.ReplaceAll(msg, "\n", " ")
+}
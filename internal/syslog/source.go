@@ -0,0 +1,612 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/local"
+	"github.com/jmurray2011/clew/internal/logging"
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// Default configuration values
+const (
+	// DefaultBufferSize is how many messages the receiver keeps in memory
+	DefaultBufferSize = 10000
+
+	// DefaultEventChanBuffer is the default buffer size for tail event channels
+	DefaultEventChanBuffer = 100
+
+	// DefaultPort is the standard syslog port, used when the URI has none
+	DefaultPort = "514"
+
+	// MaxMessageSize is the largest message the receiver accepts (64KB)
+	MaxMessageSize = 64 * 1024
+)
+
+// defaultPri is the priority RFC 3164 assigns to messages without one (user.notice).
+const defaultPri = "13"
+
+// priPattern matches the "<pri>" prefix of a syslog message.
+var priPattern = regexp.MustCompile(`^<(\d{1,3})>`)
+
+func init() {
+	source.Register("syslog+udp", openSource)
+	source.Register("syslog+tcp", openSource)
+}
+
+// Source implements source.Source for a syslog receiver. Received messages
+// are kept in a ring buffer of the last bufferSize messages and, with a
+// spool file, appended to it as JSON lines so queries can reach older
+// messages.
+type Source struct {
+	scheme     string
+	addr       string
+	uri        string
+	bufferSize int
+	parser     local.SyslogParser
+
+	packetConn net.PacketConn // syslog+udp
+	listener   net.Listener   // syslog+tcp
+	wg         sync.WaitGroup
+
+	// spool is nil when the receiver keeps messages in memory only
+	spoolPath  string
+	spool      *os.File
+	spoolLines int
+	spoolSrc   *local.Source
+
+	// receiving is false when another receiver holds the address, in which
+	// case the source reads that receiver's spool file instead
+	receiving bool
+
+	mu            sync.Mutex
+	ring          []source.Entry
+	received      int64 // messages received, the sequence number of the newest
+	conns         map[net.Conn]struct{}
+	tails         map[chan source.Event]*regexp.Regexp
+	closed        bool
+	droppedEvents int64 // atomic counter for dropped events during tail
+}
+
+// openSource opens a syslog receiver from a parsed URL.
+// URL format: syslog+udp://host:port?buffer=10000&spool=/path/to/file
+func openSource(u *url.URL, _ source.OpenOptions) (source.Source, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%s:// URI requires a listen address (e.g., %s://0.0.0.0:5514)", u.Scheme, u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+
+	query := u.Query()
+
+	bufferSize := DefaultBufferSize
+	if v := query.Get("buffer"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid buffer size %q: must be a positive number of messages", v)
+		}
+		bufferSize = n
+	}
+
+	spoolPath := query.Get("spool")
+	if strings.HasPrefix(spoolPath, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			spoolPath = filepath.Join(home, spoolPath[2:])
+		}
+	}
+
+	return NewSource(u.Scheme, addr, bufferSize, spoolPath)
+}
+
+// NewSource starts a syslog receiver listening on addr. The scheme selects
+// the transport: syslog+udp or syslog+tcp. Messages are appended to
+// spoolPath, if set. If another receiver already listens on addr and spools
+// to the same file, the source reads that file instead of receiving.
+func NewSource(scheme, addr string, bufferSize int, spoolPath string) (*Source, error) {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+
+	s := &Source{
+		scheme:     scheme,
+		addr:       addr,
+		bufferSize: bufferSize,
+		ring:       make([]source.Entry, bufferSize),
+		conns:      make(map[net.Conn]struct{}),
+		tails:      make(map[chan source.Event]*regexp.Regexp),
+		receiving:  true,
+	}
+
+	if spoolPath != "" {
+		abs, err := filepath.Abs(spoolPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spool path %q: %w", spoolPath, err)
+		}
+		s.spoolPath = abs
+	}
+
+	var err error
+	switch scheme {
+	case "syslog+udp":
+		s.packetConn, err = net.ListenPacket("udp", addr)
+		if err == nil {
+			s.addr = s.packetConn.LocalAddr().String()
+		}
+	case "syslog+tcp":
+		s.listener, err = net.Listen("tcp", addr)
+		if err == nil {
+			s.addr = s.listener.Addr().String()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme: %s", scheme)
+	}
+
+	if err != nil {
+		if s.spoolPath == "" || !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fmt.Errorf("cannot listen on %s: %w", addr, err)
+		}
+		// Another receiver (usually a clew tail) holds the address; read its spool
+		logging.Warn("Address %s is in use, reading the spool %s instead of receiving", addr, s.spoolPath)
+		s.receiving = false
+	}
+
+	if s.spoolPath != "" {
+		if err := s.openSpool(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+
+	s.uri = s.buildURI()
+
+	if s.packetConn != nil {
+		s.wg.Add(1)
+		go s.serveUDP(s.packetConn)
+	}
+	if s.listener != nil {
+		s.wg.Add(1)
+		go s.serveTCP(s.listener)
+	}
+
+	logging.Debug("Syslog receiver listening on %s://%s", scheme, s.addr)
+	return s, nil
+}
+
+// openSpool opens the spool file for appending, counting the lines already
+// in it so new messages get the right line numbers.
+func (s *Source) openSpool() error {
+	if s.receiving {
+		f, err := os.OpenFile(s.spoolPath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot open spool file: %w", err)
+		}
+		s.spool = f
+
+		lines, err := countLines(f)
+		if err != nil {
+			return fmt.Errorf("cannot read spool file: %w", err)
+		}
+		s.spoolLines = lines
+	}
+
+	spoolSrc, err := local.NewSource(s.spoolPath, "json")
+	if err != nil {
+		return fmt.Errorf("cannot read spool file: %w", err)
+	}
+	s.spoolSrc = spoolSrc
+	return nil
+}
+
+// countLines counts the lines in a file.
+func countLines(f *os.File) (int, error) {
+	r := bufio.NewReader(f)
+	lines := 0
+	for {
+		_, err := r.ReadSlice('\n')
+		if err == nil {
+			lines++
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		return 0, err
+	}
+}
+
+// buildURI returns the URI of the source, with the address it listens on.
+func (s *Source) buildURI() string {
+	u := url.URL{Scheme: s.scheme, Host: s.addr}
+	query := url.Values{}
+	if s.bufferSize != DefaultBufferSize {
+		query.Set("buffer", strconv.Itoa(s.bufferSize))
+	}
+	if s.spoolPath != "" {
+		query.Set("spool", s.spoolPath)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// receive parses a message, stores it and sends it to the running tails.
+func (s *Source) receive(raw, sender string) {
+	line := normalize(cleanMessage(raw), sender, time.Now())
+	if line == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.received++
+	entry := *s.parser.ParseLine(line, 0, "")
+	entry.Fields["sender"] = sender
+	entry = s.relabel(entry)
+	entry.Ptr = source.MakeSyslogPtr(s.scheme, s.addr, s.received)
+	if s.spool != nil {
+		spooled, err := spoolLine(entry)
+		if err == nil {
+			_, err = s.spool.WriteString(spooled + "\n")
+		}
+		if err != nil {
+			logging.Warn("Cannot write to spool file %s: %v", s.spoolPath, err)
+		} else {
+			s.spoolLines++
+			entry.Ptr = source.MakeLocalPtr(s.spoolPath, s.spoolLines)
+		}
+	}
+	s.ring[(s.received-1)%int64(s.bufferSize)] = entry
+
+	for events, filter := range s.tails {
+		s.emitEntry(&entry, filter, events)
+	}
+}
+
+// spoolLine returns the JSON line a parsed message is spooled as. It holds
+// the message's fields, so queries of the spool read them back as received.
+func spoolLine(entry source.Entry) (string, error) {
+	record := make(map[string]string, len(entry.Fields)+2)
+	for k, v := range entry.Fields {
+		record[k] = v
+	}
+	record["timestamp"] = entry.Timestamp.Format(time.RFC3339Nano)
+	record["message"] = entry.Message
+	line, err := json.Marshal(record)
+	return string(line), err
+}
+
+// normalize returns a message in a form SyslogParser reads with a timestamp
+// and hostname, filling in those a sender left out with the time of receipt
+// and the sender's address, as RFC 3164 relays do.
+func normalize(msg, sender string, now time.Time) string {
+	if msg == "" {
+		return ""
+	}
+
+	var parser local.SyslogParser
+	entry := parser.ParseLine(msg, 0, "")
+	if !entry.Timestamp.IsZero() {
+		return msg
+	}
+
+	// RFC 5424 with a nil timestamp ("<pri>1 - host app ...")
+	if _, ok := entry.Fields["app"]; ok {
+		if version, rest, ok := strings.Cut(msg, " "); ok && strings.HasPrefix(rest, "- ") {
+			return version + " " + now.UTC().Format(time.RFC3339Nano) + rest[1:]
+		}
+		return msg
+	}
+
+	pri := defaultPri
+	if m := priPattern.FindStringSubmatch(msg); m != nil {
+		pri = m[1]
+		msg = msg[len(m[0]):]
+	}
+	return "<" + pri + ">" + now.Format(time.Stamp) + " " + sender + " " + msg
+}
+
+// relabel sets the stream of a parsed message to the host that sent it, or
+// the sender's address if it named none, and its source to the receiver.
+func (s *Source) relabel(entry source.Entry) source.Entry {
+	entry.Stream = entry.Fields["hostname"]
+	if entry.Stream == "" || entry.Stream == "-" {
+		entry.Stream = entry.Fields["sender"]
+	}
+	entry.Source = s.scheme + "://" + s.addr
+	return entry
+}
+
+// emitEntry sends an entry to the events channel if it matches the filter.
+func (s *Source) emitEntry(entry *source.Entry, filter *regexp.Regexp, events chan<- source.Event) {
+	// Apply filter
+	if filter != nil && !filter.MatchString(entry.Message) {
+		return
+	}
+
+	event := source.Event{
+		Timestamp: entry.Timestamp,
+		Message:   entry.Message,
+		Stream:    entry.Stream,
+	}
+
+	select {
+	case events <- event:
+	default:
+		// Channel full, drop event and track it
+		dropped := atomic.AddInt64(&s.droppedEvents, 1)
+		// Log warning on first drop and every 100 drops thereafter
+		if dropped == 1 || dropped%100 == 0 {
+			logging.Warn("Event buffer full, dropped %d event(s) - consider increasing buffer size", dropped)
+		}
+	}
+}
+
+// buffered returns the messages in the ring buffer, oldest first.
+func (s *Source) buffered() []source.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.received - int64(s.bufferSize) + 1
+	if first < 1 {
+		first = 1
+	}
+	entries := make([]source.Entry, 0, s.received-first+1)
+	for seq := first; seq <= s.received; seq++ {
+		entries = append(entries, s.ring[(seq-1)%int64(s.bufferSize)])
+	}
+	return entries
+}
+
+// lookup returns the buffered message with a sequence number, and the
+// buffered messages from the same host around it.
+func (s *Source) lookup(seq int64, before, after int) (*source.Entry, []source.Event, []source.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.received - int64(s.bufferSize) + 1
+	if seq < first || seq > s.received {
+		return nil, nil, nil, fmt.Errorf("message %d is no longer buffered (the receiver keeps the last %d messages)", seq, s.bufferSize)
+	}
+	if first < 1 {
+		first = 1
+	}
+
+	at := func(seq int64) source.Entry {
+		return s.ring[(seq-1)%int64(s.bufferSize)]
+	}
+	entry := at(seq)
+
+	var beforeEvents, afterEvents []source.Event
+	for n := seq - 1; n >= first && len(beforeEvents) < before; n-- {
+		if e := at(n); e.Stream == entry.Stream {
+			beforeEvents = append([]source.Event{{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream}}, beforeEvents...)
+		}
+	}
+	for n := seq + 1; n <= s.received && len(afterEvents) < after; n++ {
+		if e := at(n); e.Stream == entry.Stream {
+			afterEvents = append(afterEvents, source.Event{Timestamp: e.Timestamp, Message: e.Message, Stream: e.Stream})
+		}
+	}
+
+	return &entry, beforeEvents, afterEvents, nil
+}
+
+// Query returns received messages matching the given parameters, from the
+// spool file if there is one, or else from the ring buffer.
+func (s *Source) Query(ctx context.Context, params source.QueryParams) ([]source.Entry, error) {
+	if s.spoolSrc != nil {
+		results, err := s.spoolSrc.Query(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for i := range results {
+			results[i] = s.relabel(results[i])
+		}
+		return results, nil
+	}
+
+	var results []source.Entry
+	for _, entry := range s.buffered() {
+		if local.MatchesParams(entry, params) {
+			results = append(results, entry)
+		}
+	}
+
+	// Sort by timestamp (newest first for consistency with CloudWatch)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+
+	// Apply limit
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+
+	// Fetch context lines if requested
+	if params.Context > 0 {
+		for i := range results {
+			before, after, err := s.FetchContext(ctx, results[i], params.Context, params.Context)
+			if err == nil {
+				results[i].Context = source.EntryContext{
+					Before: before,
+					After:  after,
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Tail streams messages as they are received.
+func (s *Source) Tail(ctx context.Context, params source.TailParams) (<-chan source.Event, error) {
+	if !s.receiving {
+		return s.spoolSrc.Tail(ctx, params)
+	}
+
+	events := make(chan source.Event, DefaultEventChanBuffer)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("syslog receiver is closed")
+	}
+	s.tails[events] = params.Filter
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.tails[events]; ok {
+			delete(s.tails, events)
+			close(events)
+		}
+	}()
+
+	return events, nil
+}
+
+// GetRecord retrieves a single message by its pointer.
+func (s *Source) GetRecord(ctx context.Context, ptr string) (*source.Entry, error) {
+	if _, ok := source.ParseLocalPtr(ptr); ok && s.spoolSrc != nil {
+		entry, err := s.spoolSrc.GetRecord(ctx, ptr)
+		if err != nil {
+			return nil, err
+		}
+		relabeled := s.relabel(*entry)
+		return &relabeled, nil
+	}
+
+	info, ok := source.ParseSyslogPtr(ptr)
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog pointer: %s", ptr)
+	}
+	if info.Scheme != s.scheme || info.Addr != s.addr {
+		return nil, fmt.Errorf("pointer %s is from another receiver than %s", ptr, s.uri)
+	}
+
+	entry, _, _, err := s.lookup(info.Seq, 0, 0)
+	return entry, err
+}
+
+// FetchContext retrieves the messages around a message: the neighbouring
+// lines of the spool file, or the buffered messages from the same host.
+func (s *Source) FetchContext(ctx context.Context, entry source.Entry, before, after int) ([]source.Event, []source.Event, error) {
+	if _, ok := source.ParseLocalPtr(entry.Ptr); ok && s.spoolSrc != nil {
+		return s.spoolSrc.FetchContext(ctx, entry, before, after)
+	}
+
+	info, ok := source.ParseSyslogPtr(entry.Ptr)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid syslog pointer: %s", entry.Ptr)
+	}
+
+	_, beforeEvents, afterEvents, err := s.lookup(info.Seq, before, after)
+	return beforeEvents, afterEvents, err
+}
+
+// ListStreams returns the hosts messages were received from.
+func (s *Source) ListStreams(ctx context.Context) ([]source.StreamInfo, error) {
+	entries := s.buffered()
+	if s.spoolSrc != nil {
+		var err error
+		if entries, err = s.Query(ctx, source.QueryParams{}); err != nil {
+			return nil, err
+		}
+	}
+
+	byHost := make(map[string]int)
+	var streams []source.StreamInfo
+	for _, e := range entries {
+		i, ok := byHost[e.Stream]
+		if !ok {
+			i = len(streams)
+			byHost[e.Stream] = i
+			streams = append(streams, source.StreamInfo{Name: e.Stream})
+		}
+		info := &streams[i]
+		info.Size += int64(len(e.Message))
+		if info.FirstTime.IsZero() || e.Timestamp.Before(info.FirstTime) {
+			info.FirstTime = e.Timestamp
+		}
+		if e.Timestamp.After(info.LastTime) {
+			info.LastTime = e.Timestamp
+		}
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Name < streams[j].Name
+	})
+	return streams, nil
+}
+
+// Type returns the source type identifier.
+func (s *Source) Type() string {
+	return "syslog"
+}
+
+// Metadata returns source metadata for caching and evidence collection.
+func (s *Source) Metadata() source.SourceMetadata {
+	return source.SourceMetadata{
+		Type: "syslog",
+		URI:  s.uri,
+	}
+}
+
+// Close stops the receiver and ends any running tails.
+func (s *Source) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	for events := range s.tails {
+		delete(s.tails, events)
+		close(events)
+	}
+	s.mu.Unlock()
+
+	if s.packetConn != nil {
+		_ = s.packetConn.Close()
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.wg.Wait()
+
+	if s.spool != nil {
+		return s.spool.Close()
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmurray2011/clew/internal/source"
+)
+
+// openReceiver starts a receiver on a free local port.
+func openReceiver(t *testing.T, scheme string, bufferSize int, spoolPath string) *Source {
+	t.Helper()
+	src, err := NewSource(scheme, "127.0.0.1:0", bufferSize, spoolPath)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := src.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return src
+}
+
+// send writes raw data to a receiver over its transport.
+func send(t *testing.T, src *Source, data string) {
+	t.Helper()
+	network := strings.TrimPrefix(src.scheme, "syslog+")
+	conn, err := net.Dial(network, src.addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte(data)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+// waitReceived waits until a receiver has received n messages.
+func waitReceived(t *testing.T, src *Source, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		src.mu.Lock()
+		received := src.received
+		src.mu.Unlock()
+		if received >= n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d messages", n)
+}
+
+func TestReadFrame(t *testing.T) {
+	long := strings.Repeat("x", MaxMessageSize+10)
+	input := "13 <13>octet msg" + // octet counted
+		"<13>newline msg\n" + // newline framed
+		"10 line\nbreak" + // octet counted, with a newline inside
+		long + "\n" + // truncated
+		"<13>last" // unterminated at EOF
+
+	r := bufio.NewReaderSize(strings.NewReader(input), MaxMessageSize)
+	var got []string
+	for {
+		msg, err := readFrame(r)
+		if msg != "" {
+			got = append(got, msg)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	want := []string{"<13>octet msg", "<13>newline msg\n", "line\nbreak", long[:MaxMessageSize], "<13>last"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d frames, got %d: %q", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d: expected %.40q, got %.40q", i, want[i], got[i])
+		}
+	}
+
+	if _, err := readFrame(bufio.NewReader(strings.NewReader("99999999 x"))); err == nil {
+		t.Error("expected an error for an oversized octet count")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2025, 1, 5, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "rfc3164",
+			msg:  "<34>Oct 11 22:14:15 mymachine su: 'su root' failed",
+			want: "<34>Oct 11 22:14:15 mymachine su: 'su root' failed",
+		},
+		{
+			name: "rfc5424",
+			msg:  "<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - message",
+			want: "<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - message",
+		},
+		{
+			name: "rfc5424 nil timestamp",
+			msg:  "<165>1 - mymachine evntslog - ID47 - message",
+			want: "<165>1 " + now.UTC().Format(time.RFC3339Nano) + " mymachine evntslog - ID47 - message",
+		},
+		{
+			name: "no header",
+			msg:  "<11>app: disk full",
+			want: "<11>Jan  5 10:30:00 10.0.0.7 app: disk full",
+		},
+		{
+			name: "no priority",
+			msg:  "disk full",
+			want: "<13>Jan  5 10:30:00 10.0.0.7 disk full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalize(tt.msg, "10.0.0.7", now); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_UDP(t *testing.T) {
+	src := openReceiver(t, "syslog+udp", 2, "")
+	ctx := context.Background()
+
+	if !strings.HasPrefix(src.Metadata().URI, "syslog+udp://127.0.0.1:") || !strings.HasSuffix(src.Metadata().URI, "?buffer=2") {
+		t.Errorf("unexpected URI %s", src.Metadata().URI)
+	}
+
+	events, err := src.Tail(ctx, source.TailParams{Filter: regexp.MustCompile("refused")})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	send(t, src, "<34>Jan 15 10:00:00 web1 app[42]: starting\n")
+	send(t, src, "<34>Jan 15 10:00:05 web1 app[42]: connection refused")
+	// RFC 3164 timestamps are local times in the current year
+	ts := time.Date(time.Now().Year(), 1, 15, 10, 0, 10, 0, time.Local)
+	send(t, src, "<165>1 "+ts.Format(time.RFC3339)+" db1 postgres 7 - - checkpoint")
+	waitReceived(t, src, 3)
+
+	select {
+	case ev := <-events:
+		if ev.Message != "connection refused" || ev.Stream != "web1" {
+			t.Errorf("unexpected tail event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tail event")
+	}
+
+	// The buffer holds the last 2 messages
+	results, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Stream != "db1" || results[1].Message != "connection refused" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[1].Fields["program"] != "app" || results[1].Fields["sender"] != "127.0.0.1" {
+		t.Errorf("unexpected fields %v", results[1].Fields)
+	}
+
+	entry, err := src.GetRecord(ctx, results[1].Ptr)
+	if err != nil || entry.Message != "connection refused" {
+		t.Errorf("GetRecord: got %+v, %v", entry, err)
+	}
+	if _, err := src.GetRecord(ctx, source.MakeSyslogPtr("syslog+udp", src.addr, 1)); err == nil {
+		t.Error("expected an error for a message no longer buffered")
+	}
+
+	streams, err := src.ListStreams(ctx)
+	if err != nil || len(streams) != 2 || streams[1].Name != "web1" {
+		t.Errorf("ListStreams: got %+v, %v", streams, err)
+	}
+}
+
+func TestSource_TCP(t *testing.T) {
+	src := openReceiver(t, "syslog+tcp", DefaultBufferSize, "")
+	ctx := context.Background()
+
+	send(t, src, "26 <13>Jan 15 10:00:00 web1 a"+
+		"<13>Jan 15 10:00:01 web1 b\n"+
+		"<13>Jan 15 10:00:02 web2 c\n"+
+		"<13>Jan 15 10:00:03 web1 d\n")
+	waitReceived(t, src, 4)
+
+	results, err := src.Query(ctx, source.QueryParams{Filter: regexp.MustCompile("^[bd]$"), Context: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Message != "d" || results[1].Message != "b" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	// Context comes from the same host
+	ctxB := results[1].Context
+	if len(ctxB.Before) != 1 || ctxB.Before[0].Message != "a" || len(ctxB.After) != 1 || ctxB.After[0].Message != "d" {
+		t.Errorf("unexpected context %+v", ctxB)
+	}
+
+	streams, err := src.ListStreams(ctx)
+	if err != nil || len(streams) != 2 || streams[0].Name != "web1" || streams[0].Size != 3 {
+		t.Errorf("ListStreams: got %+v, %v", streams, err)
+	}
+}
+
+func TestSource_Spool(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "syslog.log")
+	ctx := context.Background()
+
+	first, err := NewSource("syslog+udp", "127.0.0.1:0", 1, spool)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	send(t, first, "<13>Jan 15 10:00:00 web1 app: one")
+	waitReceived(t, first, 1)
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A new receiver appends to the spool, and queries reach past its buffer
+	src := openReceiver(t, "syslog+udp", 1, spool)
+	send(t, src, "<13>Jan 15 10:00:01 web2 app: two")
+	send(t, src, "<13>Jan 15 10:00:02 web1 app: three")
+	waitReceived(t, src, 2)
+
+	results, err := src.Query(ctx, source.QueryParams{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Message != "three" || results[1].Stream != "web2" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[1].Ptr != source.MakeLocalPtr(spool, 2) {
+		t.Errorf("expected a spool pointer, got %s", results[1].Ptr)
+	}
+	// Fields are spooled with the message, including the sender
+	if f := results[2].Fields; f["sender"] != "127.0.0.1" || f["program"] != "app" || f["hostname"] != "web1" {
+		t.Errorf("unexpected spooled fields %v", f)
+	}
+	if want := time.Date(time.Now().Year(), 1, 15, 10, 0, 2, 0, time.Local); !results[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", results[0].Timestamp, want)
+	}
+
+	entry, err := src.GetRecord(ctx, results[2].Ptr)
+	if err != nil || entry.Message != "one" || entry.Stream != "web1" || entry.Fields["sender"] != "127.0.0.1" {
+		t.Errorf("GetRecord: got %+v, %v", entry, err)
+	}
+
+	// A second source on the same address reads the spool
+	if _, err := NewSource("syslog+udp", src.addr, 1, ""); err == nil {
+		t.Error("expected an error for an address in use without a spool")
+	}
+	spoolReader, err := NewSource("syslog+udp", src.addr, 1, spool)
+	if err != nil {
+		t.Fatalf("NewSource on an address in use failed: %v", err)
+	}
+	defer func() { _ = spoolReader.Close() }()
+	if results, err := spoolReader.Query(ctx, source.QueryParams{}); err != nil || len(results) != 3 {
+		t.Errorf("Query of the spool: got %d results, %v", len(results), err)
+	}
+}
@@ -0,0 +1,359 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default timeouts
+const (
+	// OpenTimeout is how long a plugin has to answer the open request
+	OpenTimeout = 30 * time.Second
+
+	// ExitTimeout is how long a plugin has to exit after its stdin is closed,
+	// or to end a cancelled tail, before it is killed or abandoned
+	ExitTimeout = 5 * time.Second
+)
+
+// Client talks to a running plugin.
+type Client struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	info  OpenResult
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+	nextID  atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]*call
+
+	done    chan struct{} // closed when the plugin's stdout ends
+	readErr error         // why stdout ended; set before done is closed
+}
+
+// call is a request waiting for its response.
+type call struct {
+	ch   chan Response // events and the final response
+	gone chan struct{} // closed when the caller stops waiting
+}
+
+// Start runs a plugin executable and opens uri with it.
+func Start(path, uri string) (*Client, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+
+	c := newClient(stdout, stdin)
+	c.cmd = cmd
+	if err := c.open(uri); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// newClient creates a client reading responses from r and writing requests to w.
+func newClient(r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		stdin:   w,
+		enc:     json.NewEncoder(w),
+		pending: make(map[int64]*call),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+// open performs the open handshake.
+func (c *Client) open(uri string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), OpenTimeout)
+	defer cancel()
+
+	var result OpenResult
+	err := c.call(ctx, MethodOpen, OpenParams{URI: uri, ProtocolVersion: ProtocolVersion}, &result)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("no response to open within %s", OpenTimeout)
+		}
+		return err
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("plugin speaks protocol version %d, clew speaks %d", result.ProtocolVersion, ProtocolVersion)
+	}
+	if result.Type == "" {
+		return errors.New("plugin did not report a source type")
+	}
+	for _, capability := range result.Capabilities {
+		if !slices.Contains(Capabilities, capability) {
+			return fmt.Errorf("plugin declared unknown capability %q", capability)
+		}
+	}
+	if result.Metadata.Type == "" {
+		result.Metadata.Type = result.Type
+	}
+	if result.Metadata.URI == "" {
+		result.Metadata.URI = uri
+	}
+	c.info = result
+	return nil
+}
+
+// Info returns the plugin's response to open.
+func (c *Client) Info() OpenResult {
+	return c.info
+}
+
+// Supports reports whether the plugin declared an optional method.
+func (c *Client) Supports(method string) bool {
+	return method == MethodQuery || slices.Contains(c.info.Capabilities, method)
+}
+
+// Query runs a query request.
+func (c *Client) Query(ctx context.Context, params QueryParams) ([]Entry, error) {
+	var result QueryResult
+	if err := c.call(ctx, MethodQuery, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// GetRecord runs a get_record request.
+func (c *Client) GetRecord(ctx context.Context, ptr string) (*Entry, error) {
+	if err := c.require(MethodGetRecord); err != nil {
+		return nil, err
+	}
+	var result GetRecordResult
+	if err := c.call(ctx, MethodGetRecord, GetRecordParams{Ptr: ptr}, &result); err != nil {
+		return nil, err
+	}
+	return &result.Entry, nil
+}
+
+// FetchContext runs a fetch_context request.
+func (c *Client) FetchContext(ctx context.Context, entry Entry, before, after int) ([]Event, []Event, error) {
+	if err := c.require(MethodFetchContext); err != nil {
+		return nil, nil, err
+	}
+	var result FetchContextResult
+	params := FetchContextParams{Entry: entry, Before: before, After: after}
+	if err := c.call(ctx, MethodFetchContext, params, &result); err != nil {
+		return nil, nil, err
+	}
+	return result.Before, result.After, nil
+}
+
+// ListStreams runs a list_streams request.
+func (c *Client) ListStreams(ctx context.Context) ([]StreamInfo, error) {
+	if err := c.require(MethodListStreams); err != nil {
+		return nil, err
+	}
+	var result ListStreamsResult
+	if err := c.call(ctx, MethodListStreams, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Streams, nil
+}
+
+// Tail runs a tail request, calling emit for each event, until ctx is
+// cancelled or the plugin ends the stream. On cancellation the plugin is
+// asked to end the stream; Tail returns nil once it has, or an error if it
+// has not within ExitTimeout.
+func (c *Client) Tail(ctx context.Context, params TailParams, emit func(Event)) error {
+	if err := c.require(MethodTail); err != nil {
+		return err
+	}
+	id, pc, err := c.send(MethodTail, params)
+	if err != nil {
+		return err
+	}
+	defer c.abandon(id, pc)
+
+	cancelled := ctx.Done()
+	var deadline <-chan time.Time
+	for {
+		select {
+		case resp := <-pc.ch:
+			if resp.Event != nil {
+				emit(*resp.Event)
+				continue
+			}
+			if resp.Error != "" && deadline == nil {
+				return errors.New(resp.Error)
+			}
+			return nil
+		case <-cancelled:
+			cancelled = nil
+			if err := c.write(Request{Method: MethodCancel, Params: mustMarshal(CancelParams{ID: id})}); err != nil {
+				return nil
+			}
+			deadline = time.After(ExitTimeout)
+		case <-deadline:
+			return fmt.Errorf("plugin did not end tail within %s of cancel", ExitTimeout)
+		case <-c.done:
+			if deadline != nil {
+				return nil
+			}
+			return c.readErr
+		}
+	}
+}
+
+// Close closes the plugin's stdin and waits for it to exit, killing it
+// after ExitTimeout.
+func (c *Client) Close() error {
+	err := c.stdin.Close()
+	if c.cmd == nil {
+		return err
+	}
+
+	// Wait closes stdout, so it is called once the plugin has closed it
+	select {
+	case <-c.done:
+		return c.cmd.Wait()
+	case <-time.After(ExitTimeout):
+		_ = c.cmd.Process.Kill()
+		_ = c.cmd.Wait()
+		return fmt.Errorf("plugin did not exit within %s and was killed", ExitTimeout)
+	}
+}
+
+// require returns an error if the plugin did not declare method.
+func (c *Client) require(method string) error {
+	if !c.Supports(method) {
+		return fmt.Errorf("%s plugin does not support %s: %w", c.info.Type, method, errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// call sends a request and decodes its result into result, which may be nil.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id, pc, err := c.send(method, params)
+	if err != nil {
+		return err
+	}
+	defer c.abandon(id, pc)
+
+	for {
+		select {
+		case resp := <-pc.ch:
+			if resp.Event != nil {
+				// Events belong to tail requests only
+				continue
+			}
+			if resp.Error != "" {
+				return errors.New(resp.Error)
+			}
+			if result == nil || len(resp.Result) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("invalid %s result: %w", method, err)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return c.readErr
+		}
+	}
+}
+
+// send registers and writes a request.
+func (c *Client) send(method string, params any) (int64, *call, error) {
+	req := Request{ID: c.nextID.Add(1), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Params = data
+	}
+
+	pc := &call{ch: make(chan Response, 1), gone: make(chan struct{})}
+	c.mu.Lock()
+	c.pending[req.ID] = pc
+	c.mu.Unlock()
+
+	if err := c.write(req); err != nil {
+		c.abandon(req.ID, pc)
+		return 0, nil, err
+	}
+	return req.ID, pc, nil
+}
+
+// abandon stops waiting for responses to a request.
+func (c *Client) abandon(id int64, pc *call) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+	close(pc.gone)
+}
+
+// write sends one message to the plugin.
+func (c *Client) write(req Request) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.enc.Encode(req); err != nil {
+		return fmt.Errorf("failed to write to plugin: %w", err)
+	}
+	return nil
+}
+
+// readLoop delivers responses to the requests waiting for them until the
+// plugin's stdout ends.
+func (c *Client) readLoop(r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				c.readErr = errors.New("plugin exited")
+			} else {
+				c.readErr = fmt.Errorf("invalid response from plugin: %w", err)
+			}
+			close(c.done)
+			return
+		}
+
+		c.mu.Lock()
+		pc, ok := c.pending[resp.ID]
+		c.mu.Unlock()
+		if !ok {
+			// Late response to an abandoned request
+			continue
+		}
+		select {
+		case pc.ch <- resp:
+		case <-pc.gone:
+		}
+	}
+}
+
+// mustMarshal encodes params that cannot fail to encode.
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
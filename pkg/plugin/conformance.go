@@ -0,0 +1,219 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tailCheckDuration is how long the conformance tail check lets a tail run
+// before cancelling it.
+const tailCheckDuration = 500 * time.Millisecond
+
+// CheckResult is the outcome of one conformance check.
+type CheckResult struct {
+	Name    string
+	Err     error  // nil if the check passed or was skipped
+	Skipped string // why the check was skipped
+}
+
+// Check runs the plugin executable at path against uri and checks that it
+// follows the protocol: the open handshake, query limits, ordering, filters
+// and time ranges, each declared optional method, error responses for
+// unknown methods, cancelling a tail, and exiting when stdin is closed. The
+// URI should name a source with at least a few entries with timestamps.
+func Check(ctx context.Context, path, uri string) []CheckResult {
+	var results []CheckResult
+	pass := func(name string, err error) {
+		results = append(results, CheckResult{Name: name, Err: err})
+	}
+	skip := func(name, reason string) {
+		results = append(results, CheckResult{Name: name, Skipped: reason})
+	}
+
+	c, err := Start(path, uri)
+	pass("open", err)
+	if err != nil {
+		return results
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			_ = c.Close()
+		}
+	}()
+
+	entries, err := c.Query(ctx, QueryParams{Limit: 10})
+	if err == nil {
+		err = checkEntries(c, entries, QueryParams{Limit: 10})
+	}
+	pass("query", err)
+	if err != nil || len(entries) == 0 {
+		reason := "query returned no entries"
+		if err != nil {
+			reason = "query failed"
+		}
+		for _, name := range []string{"query filter", "query time range", MethodGetRecord, MethodFetchContext} {
+			skip(name, reason)
+		}
+	} else {
+		pass("query filter", checkFilter(ctx, c, entries[0]))
+		if entries[0].Timestamp.IsZero() {
+			skip("query time range", "entries have no timestamps")
+		} else {
+			pass("query time range", checkTimeRange(ctx, c, entries[0]))
+		}
+		optional(c, MethodGetRecord, pass, skip, func() error { return checkGetRecord(ctx, c, entries[0]) })
+		optional(c, MethodFetchContext, pass, skip, func() error { return checkFetchContext(ctx, c, entries[0]) })
+	}
+
+	optional(c, MethodListStreams, pass, skip, func() error {
+		_, err := c.ListStreams(ctx)
+		return err
+	})
+	optional(c, MethodTail, pass, skip, func() error { return checkTail(ctx, c) })
+
+	pass("unknown method", checkUnknownMethod(ctx, c))
+
+	closed = true
+	pass("close", c.Close())
+	return results
+}
+
+// optional runs a check for an optional method if the plugin declared it.
+func optional(c *Client, method string, pass func(string, error), skip func(string, string), check func() error) {
+	if !c.Supports(method) {
+		skip(method, "not declared")
+		return
+	}
+	pass(method, check())
+}
+
+// checkEntries checks query results against params.
+func checkEntries(c *Client, entries []Entry, params QueryParams) error {
+	if params.Limit > 0 && len(entries) > params.Limit {
+		return fmt.Errorf("limit %d: got %d entries", params.Limit, len(entries))
+	}
+	for i, e := range entries {
+		if i > 0 && e.Timestamp.After(entries[i-1].Timestamp) {
+			return fmt.Errorf("entries not newest first: %d (%s) is newer than %d (%s)",
+				i, e.Timestamp.Format(time.RFC3339Nano), i-1, entries[i-1].Timestamp.Format(time.RFC3339Nano))
+		}
+		needPtr := c.Supports(MethodGetRecord) || c.Supports(MethodFetchContext)
+		if needPtr && !strings.HasPrefix(e.Ptr, c.scheme()+"://") {
+			return fmt.Errorf("entry %d: pointer %q is not a %s:// URI", i, e.Ptr, c.scheme())
+		}
+		if params.StartTime != nil && e.Timestamp.Before(*params.StartTime) {
+			return fmt.Errorf("entry %d at %s is before the start time", i, e.Timestamp.Format(time.RFC3339Nano))
+		}
+		if params.EndTime != nil && e.Timestamp.After(*params.EndTime) {
+			return fmt.Errorf("entry %d at %s is after the end time", i, e.Timestamp.Format(time.RFC3339Nano))
+		}
+	}
+	return nil
+}
+
+// checkFilter queries for an entry's message and checks every result matches.
+func checkFilter(ctx context.Context, c *Client, want Entry) error {
+	firstLine, _, _ := strings.Cut(want.Message, "\n")
+	filter := regexp.QuoteMeta(firstLine)
+	re := regexp.MustCompile(filter)
+
+	entries, err := c.Query(ctx, QueryParams{Filter: filter})
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("filter %q matched no entries", filter)
+	}
+	for i, e := range entries {
+		if !re.MatchString(e.Message) {
+			return fmt.Errorf("filter %q: entry %d %q does not match", filter, i, e.Message)
+		}
+	}
+	return checkEntries(c, entries, QueryParams{})
+}
+
+// checkTimeRange queries for the instant of an entry's timestamp.
+func checkTimeRange(ctx context.Context, c *Client, want Entry) error {
+	params := QueryParams{StartTime: &want.Timestamp, EndTime: &want.Timestamp}
+	entries, err := c.Query(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no entries at %s", want.Timestamp.Format(time.RFC3339Nano))
+	}
+	return checkEntries(c, entries, params)
+}
+
+// checkGetRecord resolves an entry's pointer.
+func checkGetRecord(ctx context.Context, c *Client, want Entry) error {
+	got, err := c.GetRecord(ctx, want.Ptr)
+	if err != nil {
+		return err
+	}
+	if got.Message != want.Message || !got.Timestamp.Equal(want.Timestamp) {
+		return fmt.Errorf("pointer %s: got %s %q, want %s %q", want.Ptr,
+			got.Timestamp.Format(time.RFC3339Nano), got.Message, want.Timestamp.Format(time.RFC3339Nano), want.Message)
+	}
+	return nil
+}
+
+// checkFetchContext checks context is limited to the lines asked for.
+func checkFetchContext(ctx context.Context, c *Client, entry Entry) error {
+	before, after, err := c.FetchContext(ctx, entry, 2, 2)
+	if err != nil {
+		return err
+	}
+	if len(before) > 2 || len(after) > 2 {
+		return fmt.Errorf("asked for 2 lines each side, got %d before and %d after", len(before), len(after))
+	}
+	return nil
+}
+
+// checkTail starts a tail, cancels it and checks the plugin ends the stream.
+func checkTail(ctx context.Context, c *Client) error {
+	tailCtx, cancel := context.WithTimeout(ctx, tailCheckDuration)
+	defer cancel()
+
+	ended := make(chan error, 1)
+	go func() {
+		ended <- c.Tail(tailCtx, TailParams{}, func(Event) {})
+	}()
+
+	err := <-ended
+	if tailCtx.Err() == nil && err == nil {
+		return errors.New("stream ended before it was cancelled")
+	}
+	return err
+}
+
+// checkUnknownMethod checks the plugin answers an unknown method with an error.
+func checkUnknownMethod(ctx context.Context, c *Client) error {
+	ctx, cancel := context.WithTimeout(ctx, ExitTimeout)
+	defer cancel()
+
+	err := c.call(ctx, "no_such_method", nil, nil)
+	if err == nil {
+		return errors.New("unknown method succeeded; expected an error response")
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("no response to an unknown method within %s", ExitTimeout)
+	}
+	select {
+	case <-c.done:
+		return err
+	default:
+		return nil
+	}
+}
+
+// scheme returns the URI scheme of the plugin's source.
+func (c *Client) scheme() string {
+	scheme, _, _ := strings.Cut(c.info.Metadata.URI, "://")
+	return scheme
+}
@@ -0,0 +1,300 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveEnv makes the test binary serve a test source, so Check can run it
+// as a plugin executable. Its value names the source: "good" or "broken".
+const serveEnv = "CLEW_PLUGIN_TEST_SERVE"
+
+func TestMain(m *testing.M) {
+	if kind := os.Getenv(serveEnv); kind != "" {
+		err := Serve(func(uri string) (Source, error) {
+			return &testSource{uri: uri, broken: kind == "broken"}, nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+var testEntries = []Entry{
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 10, 0, time.UTC), Message: "retrying", Ptr: "test://db#3"},
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 5, 0, time.UTC), Message: "connection refused", Ptr: "test://db#2"},
+	{Timestamp: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), Message: "starting", Ptr: "test://db#1"},
+}
+
+// testSource implements every optional method. A broken source ignores
+// limits and filters, returns entries oldest first, and ends tails at once.
+type testSource struct {
+	uri    string
+	broken bool
+}
+
+func (s *testSource) Metadata() Metadata {
+	return Metadata{Type: "test", URI: s.uri}
+}
+
+func (s *testSource) Query(ctx context.Context, params QueryParams) ([]Entry, error) {
+	if params.Query == "fail" {
+		return nil, errors.New("bad query")
+	}
+	if params.Query == "block" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.broken {
+		return []Entry{testEntries[2], testEntries[1], testEntries[0], testEntries[0]}, nil
+	}
+
+	filter := regexp.MustCompile(params.Filter)
+	var entries []Entry
+	for _, e := range testEntries {
+		switch {
+		case params.StartTime != nil && e.Timestamp.Before(*params.StartTime):
+		case params.EndTime != nil && e.Timestamp.After(*params.EndTime):
+		case !filter.MatchString(e.Message):
+		default:
+			entries = append(entries, e)
+		}
+	}
+	if params.Limit > 0 && len(entries) > params.Limit {
+		entries = entries[:params.Limit]
+	}
+	return entries, nil
+}
+
+func (s *testSource) Tail(ctx context.Context, params TailParams, emit func(Event)) error {
+	if s.broken {
+		return nil
+	}
+	for i := 0; ; i++ {
+		emit(Event{Timestamp: time.Now(), Message: fmt.Sprintf("event %d", i)})
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (s *testSource) GetRecord(ctx context.Context, ptr string) (*Entry, error) {
+	for _, e := range testEntries {
+		if e.Ptr == ptr {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no record %s", ptr)
+}
+
+func (s *testSource) FetchContext(ctx context.Context, entry Entry, before, after int) ([]Event, []Event, error) {
+	return []Event{{Message: "before"}}, []Event{{Message: "after"}}, nil
+}
+
+func (s *testSource) ListStreams(ctx context.Context) ([]StreamInfo, error) {
+	return []StreamInfo{{Name: "db", Size: 42}}, nil
+}
+
+// queryOnly hides the optional methods of a testSource.
+type queryOnly struct {
+	Source
+}
+
+// connect serves src in-process and returns a client opened on it.
+func connect(t *testing.T, open OpenFunc) (*Client, error) {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeIO(context.Background(), reqR, respW, open)
+		_ = respW.Close()
+	}()
+	t.Cleanup(func() {
+		_ = reqW.Close()
+		if err := <-served; err != nil {
+			t.Errorf("ServeIO failed: %v", err)
+		}
+	})
+
+	c := newClient(respR, reqW)
+	return c, c.open("test://db")
+}
+
+func TestClientServer(t *testing.T) {
+	c, err := connect(t, func(uri string) (Source, error) {
+		return &testSource{uri: uri}, nil
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	ctx := context.Background()
+
+	info := c.Info()
+	if info.Type != "test" || info.Metadata.URI != "test://db" {
+		t.Errorf("unexpected open result %+v", info)
+	}
+	for _, method := range append([]string{MethodQuery}, Capabilities...) {
+		if !c.Supports(method) {
+			t.Errorf("expected %s to be supported", method)
+		}
+	}
+
+	start := testEntries[1].Timestamp
+	entries, err := c.Query(ctx, QueryParams{StartTime: &start, Filter: "refused|retry", Limit: 5})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "retrying" || !entries[1].Timestamp.Equal(start) {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	if _, err := c.Query(ctx, QueryParams{Query: "fail"}); err == nil || err.Error() != "bad query" {
+		t.Errorf("expected the plugin's error, got %v", err)
+	}
+
+	entry, err := c.GetRecord(ctx, "test://db#2")
+	if err != nil || entry.Message != "connection refused" {
+		t.Errorf("GetRecord: got %+v, %v", entry, err)
+	}
+
+	before, after, err := c.FetchContext(ctx, *entry, 1, 1)
+	if err != nil || len(before) != 1 || len(after) != 1 {
+		t.Errorf("FetchContext: got %v, %v, %v", before, after, err)
+	}
+
+	streams, err := c.ListStreams(ctx)
+	if err != nil || len(streams) != 1 || streams[0].Size != 42 {
+		t.Errorf("ListStreams: got %+v, %v", streams, err)
+	}
+}
+
+func TestClientServer_Concurrent(t *testing.T) {
+	c, err := connect(t, func(uri string) (Source, error) {
+		return &testSource{uri: uri}, nil
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	// A blocked request does not hold up later ones, and is cancelled by its context
+	blockCtx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := c.Query(blockCtx, QueryParams{Query: "block"})
+		blocked <- err
+	}()
+
+	if _, err := c.Query(context.Background(), QueryParams{Limit: 1}); err != nil {
+		t.Errorf("Query failed while another request was blocked: %v", err)
+	}
+	cancel()
+	if err := <-blocked; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientServer_Tail(t *testing.T) {
+	c, err := connect(t, func(uri string) (Source, error) {
+		return &testSource{uri: uri}, nil
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var events []Event
+	err = c.Tail(ctx, TailParams{}, func(ev Event) {
+		events = append(events, ev)
+		if len(events) == 3 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+	if len(events) < 3 || events[0].Message != "event 0" {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	// The connection is still usable after the tail ends
+	if _, err := c.Query(context.Background(), QueryParams{}); err != nil {
+		t.Errorf("Query after tail failed: %v", err)
+	}
+}
+
+func TestClientServer_Capabilities(t *testing.T) {
+	c, err := connect(t, func(uri string) (Source, error) {
+		return queryOnly{&testSource{uri: uri}}, nil
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if len(c.Info().Capabilities) != 0 {
+		t.Errorf("expected no capabilities, got %v", c.Info().Capabilities)
+	}
+	if _, err := c.GetRecord(ctx, "test://db#1"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported from GetRecord, got %v", err)
+	}
+	if err := c.Tail(ctx, TailParams{}, func(Event) {}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported from Tail, got %v", err)
+	}
+
+	// The server rejects undeclared methods too
+	if err := c.call(ctx, MethodListStreams, nil, nil); err == nil {
+		t.Error("expected an error for an undeclared method")
+	}
+}
+
+func TestClientServer_OpenError(t *testing.T) {
+	_, err := connect(t, func(uri string) (Source, error) {
+		return nil, errors.New("no such database")
+	})
+	if err == nil || !strings.Contains(err.Error(), "no such database") {
+		t.Errorf("expected the plugin's open error, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		kind       string
+		wantFailed []string
+	}{
+		{kind: "good"},
+		{kind: "broken", wantFailed: []string{"query", "tail"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			t.Setenv(serveEnv, tt.kind)
+
+			var failed []string
+			for _, result := range Check(context.Background(), os.Args[0], "test://db") {
+				if result.Skipped != "" && tt.kind == "good" {
+					t.Errorf("%s skipped: %s", result.Name, result.Skipped)
+				}
+				if result.Err != nil {
+					failed = append(failed, result.Name)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("expected failed checks %v, got %v", tt.wantFailed, failed)
+			}
+		})
+	}
+}
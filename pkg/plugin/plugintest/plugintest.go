@@ -0,0 +1,32 @@
+// Package plugintest runs the clew source plugin conformance checks from Go
+// tests. Plugins written in other languages can be checked with
+// `clew plugins check <uri>`, which runs the same checks.
+package plugintest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmurray2011/clew/pkg/plugin"
+)
+
+// Run runs the plugin executable at path against uri as a subtest per
+// conformance check. Checks for optional methods the plugin does not declare
+// are skipped.
+//
+//	func TestConformance(t *testing.T) {
+//		plugintest.Run(t, "./clew-source-mydb", "mydb://localhost/testdata")
+//	}
+func Run(t *testing.T, path, uri string) {
+	t.Helper()
+	for _, result := range plugin.Check(context.Background(), path, uri) {
+		t.Run(result.Name, func(t *testing.T) {
+			if result.Skipped != "" {
+				t.Skip(result.Skipped)
+			}
+			if result.Err != nil {
+				t.Error(result.Err)
+			}
+		})
+	}
+}
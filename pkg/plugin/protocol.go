@@ -0,0 +1,199 @@
+// Package plugin implements the protocol clew uses to talk to source plugins:
+// executables named clew-source-<scheme> that serve <scheme>:// URIs for log
+// stores clew has no built-in support for.
+//
+// # Transport
+//
+// clew starts one plugin process per opened source and exchanges
+// newline-delimited JSON messages with it: requests on the plugin's stdin,
+// responses on its stdout. Anything the plugin writes to stderr is passed
+// through to the user. The plugin should exit when its stdin is closed.
+//
+// A request names a method and carries a numeric ID:
+//
+//	{"id": 1, "method": "query", "params": {"limit": 10}}
+//
+// Every request gets exactly one response with the same ID, holding either a
+// result or an error message:
+//
+//	{"id": 1, "result": {"entries": [...]}}
+//	{"id": 1, "error": "index not found"}
+//
+// Requests may be sent before earlier ones are answered, and responses may
+// arrive in any order. The only message without an ID is cancel, which asks
+// the plugin to end the tail request with the given ID:
+//
+//	{"method": "cancel", "params": {"id": 7}}
+//
+// # Methods
+//
+// open is always the first request. Its params hold the source URI and the
+// protocol version clew speaks; the result describes the source and lists the
+// optional methods the plugin supports (see Capabilities). clew never sends a
+// method the plugin did not declare, and reports an error to the user instead.
+//
+// query is required and returns matching entries, newest first. The filter is
+// a regular expression in Go's RE2 syntax and must match each entry's message.
+// Context lines are fetched separately with fetch_context.
+//
+// tail streams events matching the filter as they are written. Before its
+// response, the plugin sends any number of event messages with the request's
+// ID. It ends the stream with a response when it receives a cancel for the
+// request or cannot continue:
+//
+//	{"id": 7, "event": {"timestamp": "...", "message": "..."}}
+//	{"id": 7, "result": null}
+//
+// get_record returns the entry for a pointer from a query result. Pointers
+// should be URIs with the plugin's scheme: clew reopens the source the
+// pointer came from, or the pointer itself when that is not known, to
+// resolve it.
+//
+// fetch_context returns the lines before and after an entry, nearest last
+// and first respectively.
+//
+// list_streams returns the streams (files, tables, services) in the source.
+//
+// Timestamps are RFC 3339 strings with optional fractional seconds.
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion is the version of the protocol described in the package
+// documentation. It changes only for incompatible changes.
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the name prefix of plugin executables; the rest of the
+// name is the URI scheme the plugin serves.
+const ExecutablePrefix = "clew-source-"
+
+// Method names.
+const (
+	MethodOpen         = "open"
+	MethodQuery        = "query"
+	MethodTail         = "tail"
+	MethodGetRecord    = "get_record"
+	MethodFetchContext = "fetch_context"
+	MethodListStreams  = "list_streams"
+	MethodCancel       = "cancel"
+)
+
+// Capabilities are the optional methods a plugin may declare in its open
+// result. query is always supported.
+var Capabilities = []string{MethodTail, MethodGetRecord, MethodFetchContext, MethodListStreams}
+
+// Request is a message sent to a plugin.
+type Request struct {
+	ID     int64           `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a message sent by a plugin: a tail event, or the final
+// response to a request.
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Event  *Event          `json:"event,omitempty"`
+}
+
+// OpenParams are the params of an open request.
+type OpenParams struct {
+	URI             string `json:"uri"`
+	ProtocolVersion int    `json:"protocol_version"`
+}
+
+// OpenResult describes an opened source.
+type OpenResult struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Type            string   `json:"type"`         // Source type shown to users, e.g. "clickhouse"
+	Capabilities    []string `json:"capabilities"` // Optional methods the plugin supports
+	Metadata        Metadata `json:"metadata"`
+}
+
+// Metadata identifies a source for caching and evidence collection.
+type Metadata struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"` // Canonical URI that reopens the source
+}
+
+// QueryParams are the params of a query request. Zero values mean no limit.
+type QueryParams struct {
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Filter    string     `json:"filter,omitempty"` // RE2 regular expression matched against messages
+	Query     string     `json:"query,omitempty"`  // Backend-specific query language
+	Limit     int        `json:"limit,omitempty"`
+}
+
+// QueryResult is the result of a query request.
+type QueryResult struct {
+	Entries []Entry `json:"entries"`
+}
+
+// TailParams are the params of a tail request.
+type TailParams struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+// GetRecordParams are the params of a get_record request.
+type GetRecordParams struct {
+	Ptr string `json:"ptr"`
+}
+
+// GetRecordResult is the result of a get_record request.
+type GetRecordResult struct {
+	Entry Entry `json:"entry"`
+}
+
+// FetchContextParams are the params of a fetch_context request.
+type FetchContextParams struct {
+	Entry  Entry `json:"entry"`
+	Before int   `json:"before"`
+	After  int   `json:"after"`
+}
+
+// FetchContextResult is the result of a fetch_context request.
+type FetchContextResult struct {
+	Before []Event `json:"before"`
+	After  []Event `json:"after"`
+}
+
+// ListStreamsResult is the result of a list_streams request.
+type ListStreamsResult struct {
+	Streams []StreamInfo `json:"streams"`
+}
+
+// CancelParams are the params of a cancel message.
+type CancelParams struct {
+	ID int64 `json:"id"`
+}
+
+// Entry is a log entry returned by query and get_record.
+type Entry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Message   string            `json:"message"`
+	Stream    string            `json:"stream,omitempty"`
+	Source    string            `json:"source,omitempty"`
+	Ptr       string            `json:"ptr,omitempty"` // Pointer for get_record and fetch_context
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// Event is a line streamed by tail or returned as context.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Stream    string    `json:"stream,omitempty"`
+}
+
+// StreamInfo describes a stream in a source.
+type StreamInfo struct {
+	Name      string     `json:"name"`
+	Size      int64      `json:"size,omitempty"`
+	FirstTime *time.Time `json:"first_time,omitempty"`
+	LastTime  *time.Time `json:"last_time,omitempty"`
+}
@@ -0,0 +1,330 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Source is a log source served by a plugin. A source declares optional
+// methods by also implementing Tailer, RecordGetter, ContextFetcher or
+// StreamLister, and may implement io.Closer to release resources on exit.
+type Source interface {
+	// Query returns entries matching params, newest first.
+	Query(ctx context.Context, params QueryParams) ([]Entry, error)
+
+	// Metadata describes the source.
+	Metadata() Metadata
+}
+
+// Tailer is implemented by sources that support tail.
+type Tailer interface {
+	// Tail calls emit for each event matching params as it is written,
+	// until ctx is cancelled.
+	Tail(ctx context.Context, params TailParams, emit func(Event)) error
+}
+
+// RecordGetter is implemented by sources that support get_record.
+type RecordGetter interface {
+	GetRecord(ctx context.Context, ptr string) (*Entry, error)
+}
+
+// ContextFetcher is implemented by sources that support fetch_context.
+type ContextFetcher interface {
+	FetchContext(ctx context.Context, entry Entry, before, after int) ([]Event, []Event, error)
+}
+
+// StreamLister is implemented by sources that support list_streams.
+type StreamLister interface {
+	ListStreams(ctx context.Context) ([]StreamInfo, error)
+}
+
+// OpenFunc opens the source for a URI given to the plugin.
+type OpenFunc func(uri string) (Source, error)
+
+// Serve serves the protocol on stdin and stdout until stdin is closed.
+// Plugins written in Go call it from main:
+//
+//	func main() {
+//		if err := plugin.Serve(openMySource); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+func Serve(open OpenFunc) error {
+	return ServeIO(context.Background(), os.Stdin, os.Stdout, open)
+}
+
+// ServeIO serves the protocol, reading requests from r and writing responses
+// to w, until r is exhausted or ctx is cancelled. Requests other than open
+// are handled concurrently.
+func ServeIO(ctx context.Context, r io.Reader, w io.Writer, open OpenFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &server{
+		open:    open,
+		enc:     json.NewEncoder(w),
+		cancels: make(map[int64]context.CancelFunc),
+	}
+	defer s.close()
+
+	dec := json.NewDecoder(r)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		s.handle(ctx, req)
+	}
+}
+
+// server holds the state of one plugin connection.
+type server struct {
+	open OpenFunc
+	src  Source
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu      sync.Mutex
+	cancels map[int64]context.CancelFunc // in-flight requests by ID
+	wg      sync.WaitGroup
+}
+
+// handle dispatches a request. Open is handled before the next request is
+// read, so every later request sees the opened source.
+func (s *server) handle(ctx context.Context, req Request) {
+	switch {
+	case req.Method == MethodCancel:
+		var params CancelParams
+		if json.Unmarshal(req.Params, &params) == nil {
+			s.mu.Lock()
+			if cancel, ok := s.cancels[params.ID]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+		return
+
+	case req.Method == MethodOpen:
+		result, err := s.handleOpen(req.Params)
+		s.respond(req.ID, result, err)
+		return
+
+	case s.src == nil:
+		s.respond(req.ID, nil, fmt.Errorf("%s before open", req.Method))
+		return
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancels[req.ID] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.cancels, req.ID)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		result, err := s.call(reqCtx, req)
+		s.respond(req.ID, result, err)
+	}()
+}
+
+// handleOpen opens the source and describes it.
+func (s *server) handleOpen(raw json.RawMessage) (any, error) {
+	if s.src != nil {
+		return nil, errors.New("source already open")
+	}
+	var params OpenParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("invalid open params: %w", err)
+	}
+	if params.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d (plugin speaks %d)", params.ProtocolVersion, ProtocolVersion)
+	}
+
+	src, err := s.open(params.URI)
+	if err != nil {
+		return nil, err
+	}
+	s.src = src
+
+	metadata := src.Metadata()
+	return OpenResult{
+		ProtocolVersion: ProtocolVersion,
+		Type:            metadata.Type,
+		Capabilities:    capabilities(src),
+		Metadata:        metadata,
+	}, nil
+}
+
+// capabilities lists the optional methods a source implements.
+func capabilities(src Source) []string {
+	caps := []string{}
+	if _, ok := src.(Tailer); ok {
+		caps = append(caps, MethodTail)
+	}
+	if _, ok := src.(RecordGetter); ok {
+		caps = append(caps, MethodGetRecord)
+	}
+	if _, ok := src.(ContextFetcher); ok {
+		caps = append(caps, MethodFetchContext)
+	}
+	if _, ok := src.(StreamLister); ok {
+		caps = append(caps, MethodListStreams)
+	}
+	return caps
+}
+
+// call runs a request against the opened source.
+func (s *server) call(ctx context.Context, req Request) (any, error) {
+	switch req.Method {
+	case MethodQuery:
+		var params QueryParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		entries, err := s.src.Query(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return QueryResult{Entries: nonNil(entries)}, nil
+
+	case MethodTail:
+		tailer, ok := s.src.(Tailer)
+		if !ok {
+			return nil, unsupported(req.Method)
+		}
+		var params TailParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		err := tailer.Tail(ctx, params, func(ev Event) {
+			s.write(Response{ID: req.ID, Event: &ev})
+		})
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		return nil, nil
+
+	case MethodGetRecord:
+		getter, ok := s.src.(RecordGetter)
+		if !ok {
+			return nil, unsupported(req.Method)
+		}
+		var params GetRecordParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		entry, err := getter.GetRecord(ctx, params.Ptr)
+		if err != nil {
+			return nil, err
+		}
+		return GetRecordResult{Entry: *entry}, nil
+
+	case MethodFetchContext:
+		fetcher, ok := s.src.(ContextFetcher)
+		if !ok {
+			return nil, unsupported(req.Method)
+		}
+		var params FetchContextParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		before, after, err := fetcher.FetchContext(ctx, params.Entry, params.Before, params.After)
+		if err != nil {
+			return nil, err
+		}
+		return FetchContextResult{Before: nonNil(before), After: nonNil(after)}, nil
+
+	case MethodListStreams:
+		lister, ok := s.src.(StreamLister)
+		if !ok {
+			return nil, unsupported(req.Method)
+		}
+		streams, err := lister.ListStreams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ListStreamsResult{Streams: nonNil(streams)}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown method %q", req.Method)
+	}
+}
+
+// respond writes the final response to a request.
+func (s *server) respond(id int64, result any, err error) {
+	resp := Response{ID: id}
+	if err != nil {
+		resp.Error = err.Error()
+	} else if result != nil {
+		data, merr := json.Marshal(result)
+		if merr != nil {
+			resp.Error = fmt.Sprintf("invalid result: %v", merr)
+		} else {
+			resp.Result = data
+		}
+	}
+	s.write(resp)
+}
+
+// write sends one message; messages from concurrent requests are not interleaved.
+func (s *server) write(resp Response) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	// A write error means clew has gone away; the next read ends the loop
+	_ = s.enc.Encode(resp)
+}
+
+// close cancels in-flight requests and closes the source.
+func (s *server) close() {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	if closer, ok := s.src.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// unmarshalParams decodes request params, which may be omitted.
+func unmarshalParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+// unsupported is the error for an optional method the source does not implement.
+func unsupported(method string) error {
+	return fmt.Errorf("%s: %w", method, errors.ErrUnsupported)
+}
+
+// nonNil returns s, or an empty slice so results encode as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+// Command clew-source-jsonl is the reference clew source plugin. It serves
+// jsonl:// URIs naming JSON-lines files:
+//
+//	clew query jsonl:///var/log/app.jsonl -f timeout
+//
+// Each line is a JSON object. The timestamp is read from a "timestamp",
+// "time" or "ts" field (RFC 3339) and the message from "message" or "msg";
+// other fields with string values become entry fields. Pointers are
+// jsonl:///path#line.
+//
+// The plugin implements query, get_record and fetch_context. It does not
+// declare tail or list_streams, to show how optional methods are left out:
+// clew tails it by polling with query instead.
+//
+// Install it on PATH to use it:
+//
+//	go install github.com/jmurray2011/clew/plugins/clew-source-jsonl@latest
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmurray2011/clew/pkg/plugin"
+)
+
+// Field names read for timestamps and messages, in order of preference
+var (
+	timestampFields = []string{"timestamp", "time", "ts"}
+	messageFields   = []string{"message", "msg"}
+)
+
+func main() {
+	if err := plugin.Serve(open); err != nil {
+		fmt.Fprintln(os.Stderr, "clew-source-jsonl:", err)
+		os.Exit(1)
+	}
+}
+
+// jsonlSource serves one JSON-lines file.
+type jsonlSource struct {
+	path string
+}
+
+// record is a parsed line of the file.
+type record struct {
+	line  int
+	entry plugin.Entry
+}
+
+// open opens a jsonl:///path URI.
+func open(uri string) (plugin.Source, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "jsonl" || u.Path == "" {
+		return nil, fmt.Errorf("expected a jsonl:///path URI, got %q", uri)
+	}
+	if _, err := os.Stat(u.Path); err != nil {
+		return nil, err
+	}
+	return &jsonlSource{path: u.Path}, nil
+}
+
+// Metadata describes the source.
+func (s *jsonlSource) Metadata() plugin.Metadata {
+	return plugin.Metadata{Type: "jsonl", URI: s.uri()}
+}
+
+// Query returns the entries matching params, newest first.
+func (s *jsonlSource) Query(ctx context.Context, params plugin.QueryParams) ([]plugin.Entry, error) {
+	var filter *regexp.Regexp
+	if params.Filter != "" {
+		re, err := regexp.Compile(params.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		filter = re
+	}
+
+	var entries []plugin.Entry
+	err := s.scan(ctx, func(r record) bool {
+		e := r.entry
+		switch {
+		case params.StartTime != nil && e.Timestamp.Before(*params.StartTime):
+		case params.EndTime != nil && e.Timestamp.After(*params.EndTime):
+		case filter != nil && !filter.MatchString(e.Message):
+		default:
+			entries = append(entries, e)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	if params.Limit > 0 && len(entries) > params.Limit {
+		entries = entries[:params.Limit]
+	}
+	return entries, nil
+}
+
+// GetRecord returns the entry at a pointer's line.
+func (s *jsonlSource) GetRecord(ctx context.Context, ptr string) (*plugin.Entry, error) {
+	line, err := s.parsePtr(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *plugin.Entry
+	err = s.scan(ctx, func(r record) bool {
+		if r.line == line {
+			entry = &r.entry
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("line %d not found in %s", line, s.path)
+	}
+	return entry, nil
+}
+
+// FetchContext returns the entries on the lines around an entry.
+func (s *jsonlSource) FetchContext(ctx context.Context, entry plugin.Entry, before, after int) ([]plugin.Event, []plugin.Event, error) {
+	line, err := s.parsePtr(entry.Ptr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var beforeEvents, afterEvents []plugin.Event
+	err = s.scan(ctx, func(r record) bool {
+		event := plugin.Event{Timestamp: r.entry.Timestamp, Message: r.entry.Message, Stream: r.entry.Stream}
+		switch {
+		case r.line >= line-before && r.line < line:
+			beforeEvents = append(beforeEvents, event)
+		case r.line > line && r.line <= line+after:
+			afterEvents = append(afterEvents, event)
+		}
+		return r.line < line+after
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeEvents, afterEvents, nil
+}
+
+// scan calls fn for each parsable line of the file until fn returns false.
+// Lines that are not JSON objects are skipped.
+func (s *jsonlSource) scan(ctx context.Context, fn func(r record) bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var fields map[string]any
+		if json.Unmarshal(scanner.Bytes(), &fields) != nil {
+			continue
+		}
+		if !fn(record{line: line, entry: s.entry(fields, line)}) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// entry converts the fields of a line to an entry.
+func (s *jsonlSource) entry(fields map[string]any, line int) plugin.Entry {
+	e := plugin.Entry{
+		Stream: s.path,
+		Source: s.path,
+		Ptr:    s.uri() + "#" + strconv.Itoa(line),
+		Fields: make(map[string]string),
+	}
+	for _, name := range timestampFields {
+		if v, ok := fields[name].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				e.Timestamp = t
+				break
+			}
+		}
+	}
+	for _, name := range messageFields {
+		if v, ok := fields[name].(string); ok {
+			e.Message = v
+			break
+		}
+	}
+	for name, v := range fields {
+		if str, ok := v.(string); ok {
+			e.Fields[name] = str
+		}
+	}
+	return e
+}
+
+// parsePtr returns the line number of a pointer into this file.
+func (s *jsonlSource) parsePtr(ptr string) (int, error) {
+	u, err := url.Parse(ptr)
+	if err != nil || u.Scheme != "jsonl" || u.Path != s.path {
+		return 0, fmt.Errorf("invalid pointer for %s: %s", s.path, ptr)
+	}
+	line, err := strconv.Atoi(u.Fragment)
+	if err != nil || line < 1 {
+		return 0, fmt.Errorf("invalid line in pointer: %s", ptr)
+	}
+	return line, nil
+}
+
+// uri returns the canonical URI of the file.
+func (s *jsonlSource) uri() string {
+	return (&url.URL{Scheme: "jsonl", Path: s.path}).String()
+}
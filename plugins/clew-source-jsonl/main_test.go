@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmurray2011/clew/pkg/plugin/plugintest"
+)
+
+// serveEnv makes the test binary serve the protocol, so the conformance
+// checks can run it as the plugin executable.
+const serveEnv = "CLEW_SOURCE_JSONL_SERVE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(serveEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestConformance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.jsonl")
+	data := `{"timestamp":"2025-01-15T10:00:00Z","level":"info","message":"starting"}
+{"timestamp":"2025-01-15T10:00:05Z","level":"error","message":"connection refused"}
+not json
+{"time":"2025-01-15T10:00:10Z","level":"info","msg":"retrying"}
+{"ts":"2025-01-15T10:00:15.5Z","level":"info","message":"connected"}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(serveEnv, "1")
+	plugintest.Run(t, os.Args[0], "jsonl://"+path)
+}